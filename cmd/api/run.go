@@ -1,31 +1,199 @@
 package api
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/pathi14/AuthentificationGO/internal"
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/health"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
 	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/server"
+	"github.com/pathi14/AuthentificationGO/internal/ldapauth"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
 	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+	"github.com/pathi14/AuthentificationGO/internal/samlauth"
+	"github.com/pathi14/AuthentificationGO/internal/scim"
+	"github.com/pathi14/AuthentificationGO/internal/social"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
 	"github.com/pathi14/AuthentificationGO/internal/user"
+	"github.com/pathi14/AuthentificationGO/internal/webhook"
 )
 
-func Run() {
-	router := gin.Default()
+// Run démarre le serveur d'API avec une configuration déjà validée et le sert jusqu'à
+// l'annulation de ctx. L'arrêt laisse les requêtes en cours se terminer, attend les tâches de
+// fond puis ferme le pool de connexions.
+func Run(ctx context.Context, cfg *config.Config) error {
+	if err := i18n.SetDefault(cfg.DefaultLocale); err != nil {
+		return fmt.Errorf("error configuring default locale: %w", err)
+	}
+
+	// Journaux JSON sur la sortie d'erreur ; les valeurs sensibles sont masquées par le handler
+	level, err := logging.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return fmt.Errorf("error configuring logging: %w", err)
+	}
+	logger := logging.New(os.Stderr, level)
+	slog.SetDefault(logger)
 
-	db, err := database.ConnectDB()
+	shutdownTracing, err := tracing.Setup(ctx, cfg.Tracing)
 	if err != nil {
-		log.Fatalf("Error connecting to the database: %v", err)
+		return fmt.Errorf("error configuring tracing: %w", err)
+	}
+	defer func() {
+		// Les derniers spans sont exportés même après l'annulation de ctx
+		flush, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(flush); err != nil {
+			logger.Error("error flushing traces", "error", err)
+		}
+	}()
+
+	router := gin.New()
+	router.Use(gin.Recovery(), middleware.RequestID(logger))
+	router.NoRoute(problem.NotFound)
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return fmt.Errorf("error connecting to the database: %w", err)
 	}
 	defer db.Close()
 
+	if cfg.Database.AutoMigrate {
+		if err := database.Migrate(db); err != nil {
+			return fmt.Errorf("error migrating the database: %w", err)
+		}
+	}
+
+	// Un seul pool de connexions, partagé par tous les dépôts, le middleware et les services
+	blacklistStore := blacklist.NewBlacklistStore(db)
+
+	// Les tâches de fond s'arrêtent avec le serveur, avant la fermeture du pool
+	workers, stopWorkers := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	defer func() {
+		stopWorkers()
+		wg.Wait()
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		purgeBlacklist(workers, blacklistStore, time.Hour)
+	}()
+
+	mailer := mail.New(cfg.Mail, os.Stdout)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		return fmt.Errorf("error loading migrations: %w", err)
+	}
+	checks := health.NewRegistry(2 * time.Second)
+	checks.Register("database", health.Database(db))
+	checks.Register("migrations", health.Migrations(migrator))
+	checks.Register("mailer", health.Mailer(mailer))
+	checks.Register("signing_key", health.SigningKey(cfg.Auth.JWTSecret))
+	router.GET("/livez", checks.Live)
+	router.GET("/readyz", checks.Ready)
+
+	if cfg.Server.MetricsPath != "" {
+		if err := metrics.RegisterDB(db); err != nil {
+			return fmt.Errorf("error registering metrics: %w", err)
+		}
+		if err := metrics.RegisterBlacklistSize(blacklistStore.Count); err != nil {
+			return fmt.Errorf("error registering metrics: %w", err)
+		}
+		router.GET(cfg.Server.MetricsPath, metrics.Handler())
+	}
+
+	// Les sondes et la collecte des métriques, très fréquentes, sont enregistrées avant le
+	// traçage, le journal d'accès et la mesure des requêtes
+	router.Use(middleware.Tracing(), middleware.AccessLog(), middleware.Metrics())
+
+	oauthRepo := oauth.NewOAuthRepository(db)
+	oauthService := oauth.NewOAuthService(oauthRepo)
+	oauthHandler := oauth.NewOAuthHandler(oauthService)
+
+	// Chaque destination d'export du journal est servie par une tâche de fond qui vide sa file
+	// à l'arrêt
+	auditExporters, err := audit.NewExporters(cfg.Audit)
+	if err != nil {
+		return fmt.Errorf("error configuring audit export: %w", err)
+	}
+	for _, x := range auditExporters {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			x.Run(workers)
+		}()
+	}
+	auditService := audit.NewAuditService(audit.NewAuditStore(db), auditExporters...)
+	auditHandler := audit.NewAuditHandler(auditService)
+
+	// Les événements d'identité écrits dans l'outbox par UserRepository sont livrés en tâche
+	// de fond aux endpoints enregistrés
+	webhookRepo := webhook.NewWebhookRepository(db)
+	webhookHandler := webhook.NewWebhookHandler(webhook.NewWebhookService(webhookRepo))
+	dispatcher := webhook.NewDispatcher(webhookRepo, cfg.Webhook)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dispatcher.Run(workers)
+	}()
+
 	userRepo := user.NewUserRepository(db)
-	userService := user.NewUserService(userRepo)
+	authenticators := []user.Authenticator{user.NewLocalAuthenticator(userRepo)}
+	if cfg.LDAP.Enabled() {
+		ldapConfig, err := ldapauth.NewConfig(cfg.LDAP)
+		if err != nil {
+			return fmt.Errorf("error loading LDAP configuration: %w", err)
+		}
+		authenticators = append(authenticators, ldapauth.NewAuthenticator(ldapConfig))
+	}
+	userService := user.NewUserService(userRepo, blacklistStore, oauthService, cfg.Auth, mailer, auditService, authenticators...)
 	userHandler := user.NewUserHandler(userService)
 
-	api := router.Group("/44df37e7-fe2a-404f-917b-399f5c5ffd12")
+	var providers []social.Provider
+	for _, providerConfig := range social.NewProviderConfigs(cfg.Social) {
+		provider, err := social.NewProvider(providerConfig, nil)
+		if err != nil {
+			return fmt.Errorf("error configuring identity provider: %w", err)
+		}
+		providers = append(providers, provider)
+	}
+	socialService := social.NewSocialService(userService, providers, cfg.Auth.JWTSecret)
+	socialHandler := social.NewSocialHandler(socialService)
+
+	var samlHandler *samlauth.SAMLHandler
+	if cfg.SAML.Enabled() {
+		samlConfig, err := samlauth.NewSPConfig(cfg.SAML, []byte(cfg.Auth.JWTSecret))
+		if err != nil {
+			return fmt.Errorf("error loading SAML configuration: %w", err)
+		}
+		samlService := samlauth.NewSAMLService(samlauth.NewSAMLRepository(db), userService, samlConfig)
+		samlHandler = samlauth.NewSAMLHandler(samlService)
+	}
+
+	scimService := scim.NewSCIMService(scim.NewSCIMRepository(db), userService)
+	scimHandler := scim.NewSCIMHandler(scimService)
+
+	apiKeyRepo := apikey.NewAPIKeyRepository(db)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo, userService, blacklistStore)
+	apiKeyHandler := apikey.NewAPIKeyHandler(apiKeyService)
+
+	api := router.Group(cfg.Server.RoutePrefix)
 	{
 		// Routes accessibles à tous
 		api.GET("/health", internal.Health)
@@ -33,23 +201,138 @@ func Run() {
 		api.POST("/login", userHandler.Login)
 		api.POST("/forgot-password", userHandler.ForgotPassword)
 		api.POST("/reset-password", userHandler.ResetPassword)
+		api.POST("/logins/report", userHandler.ReportLogin)
+		api.GET("/auth/providers", socialHandler.Providers)
+		api.GET("/auth/:provider/login", socialHandler.Login)
+		api.GET("/auth/:provider/callback", socialHandler.Callback)
+		if samlHandler != nil {
+			api.GET("/saml/:tenant/metadata", samlHandler.Metadata)
+			api.GET("/saml/:tenant/login", samlHandler.Login)
+			api.POST("/saml/:tenant/acs", samlHandler.ACS)
+		}
+
+		// Provisionnement SCIM 2.0, authentifié par le jeton bearer du tenant
+		scimAPI := api.Group("/scim/v2", scimHandler.Authenticate)
+		scimAPI.GET("/ServiceProviderConfig", scimHandler.ServiceProviderConfig)
+		scimAPI.GET("/Users", scimHandler.ListUsers)
+		scimAPI.POST("/Users", scimHandler.CreateUser)
+		scimAPI.GET("/Users/:id", scimHandler.GetUser)
+		scimAPI.PUT("/Users/:id", scimHandler.ReplaceUser)
+		scimAPI.PATCH("/Users/:id", scimHandler.PatchUser)
+		scimAPI.DELETE("/Users/:id", scimHandler.DeleteUser)
+		scimAPI.GET("/Groups", scimHandler.ListGroups)
+		scimAPI.POST("/Groups", scimHandler.CreateGroup)
+		scimAPI.GET("/Groups/:id", scimHandler.GetGroup)
+		scimAPI.PUT("/Groups/:id", scimHandler.ReplaceGroup)
+		scimAPI.PATCH("/Groups/:id", scimHandler.PatchGroup)
+		scimAPI.DELETE("/Groups/:id", scimHandler.DeleteGroup)
 
 		// Routes protégées
-		api.Use(middleware.JWTAuth())
+		api.Use(middleware.JWTAuth(cfg.Auth.JWTSecret, blacklistStore, apiKeyService))
 		{
-			api.GET("/me", userHandler.Profile)
 			api.POST("/logout", userHandler.Logout)
 			api.POST("/refresh", userHandler.RefreshToken)
+
+			profile := api.Group("", middleware.RequireScope(oauth.ScopeProfileRead))
+			profile.GET("/me", userHandler.Profile)
+			profile.GET("/me/identities", userHandler.ListIdentities)
+			profile.GET("/me/logins", userHandler.Logins)
+			profile.GET("/me/audit-events", auditHandler.Mine)
+
+			profileWrite := api.Group("/me", middleware.RequireScope(oauth.ScopeProfileWrite))
+			profileWrite.PUT("/locale", userHandler.UpdateLocale)
+			profileWrite.POST("/identities/:provider/link", socialHandler.Link)
+			profileWrite.DELETE("/identities/:id", userHandler.UnlinkIdentity)
+
+			apiKeysRead := api.Group("/api-keys", middleware.RequireScope(oauth.ScopeAPIKeysRead))
+			apiKeysRead.GET("", apiKeyHandler.List)
+
+			apiKeysWrite := api.Group("/api-keys", middleware.RequireScope(oauth.ScopeAPIKeysWrite))
+			apiKeysWrite.POST("", apiKeyHandler.Create)
+			apiKeysWrite.DELETE("/:id", apiKeyHandler.Revoke)
+
+			clients := api.Group("/oauth/clients", middleware.RequireScope(oauth.ScopeClientsManage))
+			clients.POST("", oauthHandler.RegisterClient)
+			clients.GET("", oauthHandler.ListClients)
+
+			consents := api.Group("/consents", middleware.RequireScope(oauth.ScopeConsentsManage))
+			consents.GET("", oauthHandler.ListConsents)
+			consents.PUT("/:client_id", oauthHandler.GrantConsent)
+			consents.DELETE("/:client_id", oauthHandler.RevokeConsent)
+
+			auditAdmin := api.Group("/audit", middleware.RequireScope(oauth.ScopeAdmin))
+			auditAdmin.GET("/events", auditHandler.List)
+			auditAdmin.GET("/verify", auditHandler.Verify)
+
+			webhooks := api.Group("/webhooks", middleware.RequireScope(oauth.ScopeAdmin))
+			webhooks.POST("", webhookHandler.Create)
+			webhooks.GET("", webhookHandler.List)
+			webhooks.DELETE("/:id", webhookHandler.Delete)
+			webhooks.GET("/:id/deliveries", webhookHandler.Deliveries)
+			webhooks.GET("/deliveries/:id", webhookHandler.Delivery)
+			webhooks.POST("/deliveries/:id/redeliver", webhookHandler.Redeliver)
+
+			scimTokens := api.Group("/scim/tokens", middleware.RequireScope(oauth.ScopeAdmin))
+			scimTokens.PUT("/:tenant", scimHandler.IssueToken)
+			scimTokens.DELETE("/:tenant", scimHandler.RevokeToken)
+
+			scimRoles := api.Group("/scim/roles", middleware.RequireScope(oauth.ScopeAdmin))
+			scimRoles.GET("/:tenant", scimHandler.RoleMapping)
+			scimRoles.PUT("/:tenant", scimHandler.SetRoleMapping)
+
+			if samlHandler != nil {
+				samlAdmin := api.Group("/saml/connections", middleware.RequireScope(oauth.ScopeAdmin))
+				samlAdmin.GET("", samlHandler.ListConnections)
+				samlAdmin.PUT("/:tenant", samlHandler.SaveConnection)
+				samlAdmin.DELETE("/:tenant", samlHandler.DeleteConnection)
+			}
 		}
 	}
 
-	fmt.Println("Server is listening on port 8080")
+	scheme := "http"
+	if cfg.Server.TLS() {
+		scheme = "https"
+	}
+	logger.Info("server listening", "address", scheme+"://"+cfg.Server.Address())
 
-	port := os.Getenv("PORT") // Utilise le port fourni par Render
+	// /readyz échoue dès le signal d'arrêt ; le serveur continue de répondre pendant
+	// ShutdownDelay, le temps que les répartiteurs de charge le retirent
+	serving, stopServing := context.WithCancel(context.Background())
+	defer stopServing()
+	go func() {
+		select {
+		case <-ctx.Done():
+		case <-serving.Done():
+			return
+		}
+		checks.Drain()
+		time.Sleep(cfg.Server.ShutdownDelay)
+		stopServing()
+	}()
 
-	if port == "" {
-		port = "8080" // Port par défaut si non défini
+	if err := server.Run(serving, cfg.Server, router); err != nil {
+		return err
 	}
+	logger.Info("server stopped")
+	return nil
+}
 
-	router.Run("0.0.0.0:" + port) // Écoute sur 0.0.0.0 pour accepter les connexions externes
+// purgeBlacklist supprime régulièrement les jetons révoqués qui ont de toute façon expiré,
+// jusqu'à l'annulation de ctx
+func purgeBlacklist(ctx context.Context, store blacklist.TokenStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if n, err := store.PurgeExpired(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "error purging token blacklist", "error", err)
+			} else if n > 0 {
+				slog.InfoContext(ctx, "expired blacklisted tokens purged", "count", n)
+			}
+		}
+	}
 }
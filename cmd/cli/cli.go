@@ -0,0 +1,198 @@
+// Package cli regroupe les sous-commandes du binaire : le serveur d'API et les commandes
+// d'exploitation (migrations, comptes, clés d'API, jetons, configuration). Elles partagent la
+// configuration et les dépôts de l'application, ce qui évite toute intervention en SQL.
+package cli
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	osuser "os/user"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+type command struct {
+	name  string
+	usage string
+	help  string
+	run   func(a *app, args []string) error
+}
+
+var commands = []command{
+	{"serve", "[OPTIONS]", "démarre le serveur d'API (commande par défaut)", serve},
+	{"migrate up", "[-config FICHIER]", "applique les migrations en attente", migrateUp},
+	{"migrate down", "[-steps N] [-config FICHIER]", "annule les dernières migrations", migrateDown},
+	{"migrate status", "[-config FICHIER]", "liste les migrations et leur état", migrateStatus},
+	{"user create", "-email EMAIL -name NOM [-role user|admin] [-password MOT_DE_PASSE]", "crée un compte", userCreate},
+	{"user disable", "-email EMAIL", "désactive un compte", userDisable},
+	{"user enable", "-email EMAIL", "réactive un compte", userEnable},
+	{"user reset-password", "-email EMAIL [-password MOT_DE_PASSE]", "remplace le mot de passe d'un compte", userResetPassword},
+	{"user set-role", "-email EMAIL -role user|admin", "change le rôle d'un compte", userSetRole},
+	{"keys list", "-email EMAIL", "liste les clés d'API d'un compte", keysList},
+	{"keys rotate", "-email EMAIL -id ID", "remplace une clé d'API par une nouvelle", keysRotate},
+	{"tokens purge-expired", "", "supprime les jetons révoqués expirés", tokensPurgeExpired},
+	{"config check", "[OPTIONS]", "valide la configuration et affiche les valeurs retenues", configCheck},
+}
+
+// errUsage signale des arguments invalides : le code de sortie est alors 2
+var errUsage = errors.New("invalid usage")
+
+// Run exécute la sous-commande désignée par args (sans le nom du programme) et retourne le code
+// de sortie du processus. Sans sous-commande, ou avec des options seules, le serveur démarre.
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"serve"}, args...)
+	}
+
+	if args[0] == "help" {
+		printUsage(stdout)
+		return 0
+	}
+
+	cmd, rest, ok := lookup(args)
+	if !ok {
+		printUsage(stderr)
+		return 2
+	}
+
+	a := &app{cmd: cmd, stdin: bufio.NewReader(stdin), stdout: stdout, stderr: stderr}
+	err := cmd.run(a, rest)
+	if a.close != nil {
+		a.close()
+	}
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return 0
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "error: %v\nusage: %s %s\n", err, cmd.name, cmd.usage)
+		return 2
+	default:
+		fmt.Fprintf(stderr, "error: %v\n", err)
+		return 1
+	}
+}
+
+// lookup retrouve la commande dont le nom, en un ou deux mots, commence args
+func lookup(args []string) (command, []string, bool) {
+	for _, cmd := range commands {
+		words := strings.Fields(cmd.name)
+		if len(args) >= len(words) && strings.Join(args[:len(words)], " ") == cmd.name {
+			return cmd, args[len(words):], true
+		}
+	}
+	return command{}, nil, false
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: authentificationgo <commande> [options]")
+	fmt.Fprintln(w)
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-22s %s\n", cmd.name, cmd.help)
+	}
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "Les commandes d'exploitation acceptent -config FICHIER ; serve et config check acceptent toutes les options de configuration (-h pour la liste).")
+}
+
+// app porte les entrées-sorties de la commande en cours et les services ouverts pour elle
+type app struct {
+	cmd    command
+	stdin  *bufio.Reader
+	stdout io.Writer
+	stderr io.Writer
+
+	configFile string
+	close      func()
+}
+
+// flags crée le jeu d'options de la commande, avec -config commun à toutes les commandes
+// d'exploitation
+func (a *app) flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(a.cmd.name, flag.ContinueOnError)
+	fs.SetOutput(a.stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(a.stderr, "usage: %s %s\n", a.cmd.name, a.cmd.usage)
+		fs.PrintDefaults()
+	}
+	fs.StringVar(&a.configFile, "config", "", "fichier de configuration YAML ou TOML")
+	return fs
+}
+
+func (a *app) parse(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		return fmt.Errorf("%w: unexpected argument %q", errUsage, fs.Arg(0))
+	}
+	return nil
+}
+
+// services regroupe les dépôts et services utilisés par les commandes d'exploitation
+type services struct {
+	// ctx attribue les opérations à la CLI (et au compte système qui l'exécute) dans le
+	// journal d'audit
+	ctx       context.Context
+	db        *sql.DB
+	users     *user.UserService
+	apiKeys   *apikey.APIKeyService
+	blacklist *blacklist.BlacklistStore
+}
+
+// open lit la configuration et ouvre la base. Seule la section database est validée : les
+// commandes d'exploitation n'émettent pas de jetons.
+func (a *app) open() (*services, error) {
+	var args []string
+	if a.configFile != "" {
+		args = []string{"-config", a.configFile}
+	}
+	cfg, err := config.Parse(args)
+	if err == nil {
+		err = cfg.ValidateDatabase()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading configuration: %w", err)
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to the database: %w", err)
+	}
+	a.close = func() { db.Close() }
+
+	blacklistStore := blacklist.NewBlacklistStore(db)
+	oauthService := oauth.NewOAuthService(oauth.NewOAuthRepository(db))
+	auditService := audit.NewAuditService(audit.NewAuditStore(db))
+	actor := audit.Actor{Type: audit.ActorCLI}
+	if u, err := osuser.Current(); err == nil {
+		actor.ID = u.Username
+	}
+	userService := user.NewUserService(user.NewUserRepository(db), blacklistStore, oauthService, cfg.Auth, mail.New(cfg.Mail, a.stdout), auditService)
+	return &services{
+		ctx:       audit.WithActor(context.Background(), actor),
+		db:        db,
+		users:     userService,
+		apiKeys:   apikey.NewAPIKeyService(apikey.NewAPIKeyRepository(db), userService, blacklistStore),
+		blacklist: blacklistStore,
+	}, nil
+}
+
+// required vérifie qu'une option obligatoire est renseignée
+func required(name, value string) error {
+	if value == "" {
+		return fmt.Errorf("%w: -%s is required", errUsage, name)
+	}
+	return nil
+}
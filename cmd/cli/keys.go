@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+)
+
+func keysList(a *app, args []string) error {
+	fs := a.flags()
+	email := fs.String("email", "", "email du propriétaire des clés")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if err := required("email", *email); err != nil {
+		return err
+	}
+
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	u, err := findUser(s, *email)
+	if err != nil {
+		return err
+	}
+	keys, err := s.apiKeys.List(s.ctx, u.ID)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(a.stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tPREFIX\tNAME\tSCOPES\tCREATED\tEXPIRES\tLAST USED\tSTATUS")
+	now := time.Now()
+	for _, k := range keys {
+		status := "active"
+		if !k.IsActive(now) {
+			status = "revoked"
+			if k.RevokedAt == nil {
+				status = "expired"
+			}
+		}
+		fmt.Fprintf(w, "%d\t%s%s\t%s\t%s\t%s\t%s\t%s\t%s\n", k.ID, apikey.KeyPrefix, k.Prefix, k.Name,
+			strings.Join(k.Scopes, " "), formatTime(&k.CreatedAt), formatTime(k.ExpiresAt), formatTime(k.LastUsedAt), status)
+	}
+	return w.Flush()
+}
+
+func keysRotate(a *app, args []string) error {
+	fs := a.flags()
+	email := fs.String("email", "", "email du propriétaire de la clé")
+	id := fs.Int("id", 0, "identifiant de la clé à remplacer (voir keys list)")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if err := required("email", *email); err != nil {
+		return err
+	}
+	if *id <= 0 {
+		return fmt.Errorf("%w: -id is required", errUsage)
+	}
+
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	u, err := findUser(s, *email)
+	if err != nil {
+		return err
+	}
+	key, k, err := s.apiKeys.Rotate(s.ctx, u.ID, *id)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "api key %d revoked, replaced by key %d:\n%s\n", *id, k.ID, key)
+	return nil
+}
+
+func tokensPurgeExpired(a *app, args []string) error {
+	if err := a.parse(a.flags(), args); err != nil {
+		return err
+	}
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	n, err := s.blacklist.PurgeExpired(context.Background())
+	if err != nil {
+		return fmt.Errorf("error purging token blacklist: %w", err)
+	}
+	fmt.Fprintf(a.stdout, "%d expired token(s) purged\n", n)
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format("2006-01-02 15:04")
+}
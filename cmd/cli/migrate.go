@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+)
+
+func openMigrator(a *app) (*database.Migrator, error) {
+	s, err := a.open()
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := database.NewMigrator(s.db)
+	if err != nil {
+		return nil, fmt.Errorf("error loading migrations: %w", err)
+	}
+	return migrator, nil
+}
+
+func migrateUp(a *app, args []string) error {
+	if err := a.parse(a.flags(), args); err != nil {
+		return err
+	}
+	migrator, err := openMigrator(a)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	if err != nil {
+		return fmt.Errorf("error applying migrations: %w", err)
+	}
+	fmt.Fprintf(a.stdout, "%d migration(s) applied\n", len(applied))
+	return nil
+}
+
+func migrateDown(a *app, args []string) error {
+	fs := a.flags()
+	steps := fs.Int("steps", 1, "nombre de migrations à annuler")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if *steps < 1 {
+		return fmt.Errorf("%w: -steps must be at least 1", errUsage)
+	}
+	migrator, err := openMigrator(a)
+	if err != nil {
+		return err
+	}
+	reverted, err := migrator.Down(context.Background(), *steps)
+	if err != nil {
+		return fmt.Errorf("error reverting migrations: %w", err)
+	}
+	fmt.Fprintf(a.stdout, "%d migration(s) reverted\n", len(reverted))
+	return nil
+}
+
+func migrateStatus(a *app, args []string) error {
+	if err := a.parse(a.flags(), args); err != nil {
+		return err
+	}
+	migrator, err := openMigrator(a)
+	if err != nil {
+		return err
+	}
+	statuses, err := migrator.Status(context.Background())
+	if err != nil {
+		return fmt.Errorf("error reading migration status: %w", err)
+	}
+	for _, s := range statuses {
+		applied := "pending"
+		if s.AppliedAt != nil {
+			applied = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		fmt.Fprintf(a.stdout, "%04d_%-30s %s\n", s.Version, s.Name, applied)
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pathi14/AuthentificationGO/cmd/api"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+)
+
+// serve accepte toutes les options de configuration, comme l'ancien binaire. SIGINT et SIGTERM
+// déclenchent l'arrêt propre du serveur.
+func serve(a *app, args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return api.Run(ctx, cfg)
+}
+
+// configCheck valide la configuration complète, telle que serve la lirait
+func configCheck(a *app, args []string) error {
+	cfg, err := config.Load(args)
+	if err != nil {
+		return err
+	}
+	if err := cfg.Write(a.stdout); err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, "configuration OK")
+	return nil
+}
@@ -0,0 +1,174 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// findUser charge le compte complet (rôle et état compris) désigné par son email
+func findUser(s *services, email string) (*user.User, error) {
+	u, err := s.users.FindByEmail(s.ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, fmt.Errorf("%w: no user with email %s", apperror.ErrNotFound, email)
+	}
+	return s.users.GetUserByID(s.ctx, u.ID)
+}
+
+// password retourne la valeur de -password ou, à défaut, la première ligne de l'entrée
+// standard, pour ne pas laisser le mot de passe dans l'historique du shell
+func (a *app) password(value string) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	fmt.Fprint(a.stderr, "Password: ")
+	line, err := a.stdin.ReadString('\n')
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		if err != nil {
+			return "", fmt.Errorf("error reading password: %w", err)
+		}
+		return "", fmt.Errorf("%w: password is required", errUsage)
+	}
+	return line, nil
+}
+
+func userCreate(a *app, args []string) error {
+	fs := a.flags()
+	email := fs.String("email", "", "email du compte")
+	name := fs.String("name", "", "nom de l'utilisateur")
+	role := fs.String("role", user.RoleUser, "rôle : user ou admin")
+	pass := fs.String("password", "", "mot de passe (lu sur l'entrée standard s'il est absent)")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if err := required("email", *email); err != nil {
+		return err
+	}
+	if err := required("name", *name); err != nil {
+		return err
+	}
+	if *role != user.RoleUser && *role != user.RoleAdmin {
+		return fmt.Errorf("%w: unknown role %q", errUsage, *role)
+	}
+
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	password, err := a.password(*pass)
+	if err != nil {
+		return err
+	}
+	if err := s.users.Create(s.ctx, user.User{Name: *name, Email: *email, Password: password}); err != nil {
+		return err
+	}
+	u, err := findUser(s, *email)
+	if err != nil {
+		return err
+	}
+	if *role != user.RoleUser {
+		if err := s.users.SetRole(s.ctx, u.ID, *role); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(a.stdout, "user %s created (id %d, role %s)\n", u.Email, u.ID, *role)
+	return nil
+}
+
+func userDisable(a *app, args []string) error {
+	return setActive(a, args, false)
+}
+
+func userEnable(a *app, args []string) error {
+	return setActive(a, args, true)
+}
+
+func setActive(a *app, args []string, active bool) error {
+	fs := a.flags()
+	email := fs.String("email", "", "email du compte")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if err := required("email", *email); err != nil {
+		return err
+	}
+
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	u, err := findUser(s, *email)
+	if err != nil {
+		return err
+	}
+	if err := s.users.SetActive(s.ctx, u.ID, active); err != nil {
+		return err
+	}
+	state := "disabled"
+	if active {
+		state = "enabled"
+	}
+	fmt.Fprintf(a.stdout, "user %s %s\n", u.Email, state)
+	return nil
+}
+
+func userResetPassword(a *app, args []string) error {
+	fs := a.flags()
+	email := fs.String("email", "", "email du compte")
+	pass := fs.String("password", "", "nouveau mot de passe (lu sur l'entrée standard s'il est absent)")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if err := required("email", *email); err != nil {
+		return err
+	}
+
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	password, err := a.password(*pass)
+	if err != nil {
+		return err
+	}
+	if err := s.users.SetPassword(s.ctx, *email, password); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "password of %s updated\n", *email)
+	return nil
+}
+
+func userSetRole(a *app, args []string) error {
+	fs := a.flags()
+	email := fs.String("email", "", "email du compte")
+	role := fs.String("role", "", "rôle : user ou admin")
+	if err := a.parse(fs, args); err != nil {
+		return err
+	}
+	if err := required("email", *email); err != nil {
+		return err
+	}
+	if err := required("role", *role); err != nil {
+		return err
+	}
+
+	s, err := a.open()
+	if err != nil {
+		return err
+	}
+	u, err := findUser(s, *email)
+	if err != nil {
+		return err
+	}
+	if err := s.users.SetRole(s.ctx, u.ID, *role); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.stdout, "user %s is now %s\n", u.Email, *role)
+	return nil
+}
@@ -0,0 +1,17 @@
+// Commande migrate : applique, annule ou liste les migrations du schéma. Elle équivaut à la
+// sous-commande migrate du binaire principal, conservée pour les scripts existants.
+//
+//	go run ./cmd/migrate up [-config FICHIER]
+//	go run ./cmd/migrate down [-steps N] [-config FICHIER]
+//	go run ./cmd/migrate status [-config FICHIER]
+package main
+
+import (
+	"os"
+
+	"github.com/pathi14/AuthentificationGO/cmd/cli"
+)
+
+func main() {
+	os.Exit(cli.Run(append([]string{"migrate"}, os.Args[1:]...), os.Stdin, os.Stdout, os.Stderr))
+}
@@ -0,0 +1,75 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyPrefix identifie les clés d'API dans l'en-tête Authorization
+const KeyPrefix = "agk_"
+
+type APIKey struct {
+	ID         int        `json:"id"`
+	UserID     int        `json:"-"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	hash       string
+	// generation est la génération des sessions du compte à la création de la clé
+	generation int
+}
+
+// IsActive indique si la clé peut encore être utilisée
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// IsAPIKey indique si la valeur présentée ressemble à une clé d'API plutôt qu'à un JWT
+func IsAPIKey(value string) bool {
+	return strings.HasPrefix(value, KeyPrefix)
+}
+
+// generateKey retourne la clé complète (montrée une seule fois), son préfixe de recherche et son empreinte
+func generateKey() (key, prefix, hash string, err error) {
+	prefixBytes := make([]byte, 4)
+	secretBytes := make([]byte, 24)
+	if _, err = rand.Read(prefixBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate key prefix: %w", err)
+	}
+	if _, err = rand.Read(secretBytes); err != nil {
+		return "", "", "", fmt.Errorf("failed to generate key secret: %w", err)
+	}
+
+	prefix = hex.EncodeToString(prefixBytes)
+	key = KeyPrefix + prefix + "_" + hex.EncodeToString(secretBytes)
+	return key, prefix, hashKey(key), nil
+}
+
+// parseKey extrait le préfixe de recherche d'une clé présentée par un client
+func parseKey(key string) (string, bool) {
+	rest, ok := strings.CutPrefix(key, KeyPrefix)
+	if !ok {
+		return "", false
+	}
+	prefix, secret, ok := strings.Cut(rest, "_")
+	if !ok || len(prefix) != 8 || secret == "" {
+		return "", false
+	}
+	return prefix, true
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,81 @@
+package apikey
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+type APIKeyHandler struct {
+	service *APIKeyService
+}
+
+func NewAPIKeyHandler(service *APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{service: service}
+}
+
+func (h *APIKeyHandler) Create(c *gin.Context) {
+	var request struct {
+		Name      string     `json:"name" binding:"required,max=100"`
+		Scopes    []string   `json:"scopes"`
+		ExpiresAt *time.Time `json:"expires_at"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+
+	key, apiKey, err := h.service.Create(c.Request.Context(), c.GetInt("userID"), request.Name, request.Scopes, c.GetStringSlice("scopes"), request.ExpiresAt)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "apikey.create_failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": i18n.Localize(c, "apikey.created"),
+		"key":     key,
+		"api_key": apiKey,
+	})
+}
+
+func (h *APIKeyHandler) List(c *gin.Context) {
+	keys, err := h.service.List(c.Request.Context(), c.GetInt("userID"))
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "apikey.list_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"api_keys": keys})
+}
+
+func (h *APIKeyHandler) Revoke(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "apikey.invalid_id")
+		return
+	}
+
+	if err := h.service.Revoke(c.Request.Context(), c.GetInt("userID"), id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "apikey.not_found")
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "apikey.revoke_failed")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
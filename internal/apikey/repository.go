@@ -0,0 +1,138 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type APIKeyRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewAPIKeyRepository(db *sql.DB) *APIKeyRepository {
+	return &APIKeyRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// startQuery ouvre le span d'une requête du dépôt
+func (r *APIKeyRepository) startQuery(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracing.StartQuery(ctx, "APIKeyRepository."+method, r.dialect.System(), operation)
+}
+
+const selectColumns = "id, user_id, name, prefix, key_hash, scopes, session_generation, created_at, expires_at, last_used_at, revoked_at"
+
+func (r *APIKeyRepository) Create(ctx context.Context, k *APIKey) (err error) {
+	ctx, span := r.startQuery(ctx, "Create", "INSERT api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, session_generation, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at"),
+		k.UserID, k.Name, k.Prefix, k.hash, strings.Join(k.Scopes, " "), k.generation, k.ExpiresAt).
+		Scan(&k.ID, &k.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting api key: %w", err)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID int) (_ []APIKey, err error) {
+	ctx, span := r.startQuery(ctx, "ListByUser", "SELECT api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind("SELECT "+selectColumns+" FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing api keys: %w", err)
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+func (r *APIKeyRepository) FindByPrefix(ctx context.Context, prefix string) (_ *APIKey, err error) {
+	ctx, span := r.startQuery(ctx, "FindByPrefix", "SELECT api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	k, err := scanKey(r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+selectColumns+" FROM api_keys WHERE prefix = $1"), prefix))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("api key %w", apperror.ErrNotFound)
+	}
+	return k, err
+}
+
+func (r *APIKeyRepository) FindByID(ctx context.Context, userID, id int) (_ *APIKey, err error) {
+	ctx, span := r.startQuery(ctx, "FindByID", "SELECT api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	k, err := scanKey(r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+selectColumns+" FROM api_keys WHERE id = $1 AND user_id = $2"), id, userID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("api key %w", apperror.ErrNotFound)
+	}
+	return k, err
+}
+
+func (r *APIKeyRepository) Revoke(ctx context.Context, userID, id int) (err error) {
+	ctx, span := r.startQuery(ctx, "Revoke", "UPDATE api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx,
+		r.dialect.Rebind("UPDATE api_keys SET revoked_at = $1 WHERE id = $2 AND user_id = $3 AND revoked_at IS NULL"),
+		time.Now(), id, userID)
+	if err != nil {
+		return fmt.Errorf("error revoking api key: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("api key %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *APIKeyRepository) TouchLastUsed(ctx context.Context, id int, at time.Time) (err error) {
+	ctx, span := r.startQuery(ctx, "TouchLastUsed", "UPDATE api_keys")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE api_keys SET last_used_at = $1 WHERE id = $2"), at, id)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	var scopes string
+	var expiresAt, lastUsedAt, revokedAt sql.NullTime
+	err := row.Scan(&k.ID, &k.UserID, &k.Name, &k.Prefix, &k.hash, &scopes, &k.generation,
+		&k.CreatedAt, &expiresAt, &lastUsedAt, &revokedAt)
+	if err != nil {
+		return nil, err
+	}
+	k.Scopes = strings.Fields(scopes)
+	if expiresAt.Valid {
+		k.ExpiresAt = &expiresAt.Time
+	}
+	if lastUsedAt.Valid {
+		k.LastUsedAt = &lastUsedAt.Time
+	}
+	if revokedAt.Valid {
+		k.RevokedAt = &revokedAt.Time
+	}
+	return &k, nil
+}
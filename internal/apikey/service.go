@@ -0,0 +1,182 @@
+package apikey
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// Owners donne l'état courant du compte propriétaire d'une clé
+type Owners interface {
+	GetUserByID(ctx context.Context, id int) (*user.User, error)
+}
+
+// Sessions donne la génération des sessions d'un compte (voir blacklist.TokenStore) : comme un
+// jeton, une clé créée avant la révocation de toutes les sessions du compte est refusée
+type Sessions interface {
+	SessionGeneration(ctx context.Context, userID int) (int, error)
+}
+
+type APIKeyService struct {
+	repo     *APIKeyRepository
+	owners   Owners
+	sessions Sessions
+}
+
+func NewAPIKeyService(repo *APIKeyRepository, owners Owners, sessions Sessions) *APIKeyService {
+	return &APIKeyService{repo: repo, owners: owners, sessions: sessions}
+}
+
+// Create génère une nouvelle clé et retourne sa valeur en clair, qui n'est plus récupérable ensuite.
+// Les scopes de la clé ne peuvent excéder ceux de l'appelant (callerScopes) ; vides, ils en héritent.
+func (s *APIKeyService) Create(ctx context.Context, userID int, name string, scopes, callerScopes []string, expiresAt *time.Time) (string, *APIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", nil, fmt.Errorf("%w: name is required", apperror.ErrValidation)
+	}
+	if len(scopes) == 0 {
+		scopes = callerScopes
+	}
+	if err := oauth.ValidateScopes(scopes); err != nil {
+		return "", nil, fmt.Errorf("%w: %v", apperror.ErrValidation, err)
+	}
+	if !oauth.HasAll(callerScopes, scopes) {
+		return "", nil, fmt.Errorf("%w: api key scopes exceed those of the current session", apperror.ErrValidation)
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return "", nil, fmt.Errorf("%w: expiration must be in the future", apperror.ErrValidation)
+	}
+
+	generation, err := s.sessions.SessionGeneration(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("internal error: %v", err)
+	}
+	key, prefix, hash, err := generateKey()
+	if err != nil {
+		return "", nil, fmt.Errorf("internal error: %v", err)
+	}
+
+	k := &APIKey{
+		UserID:     userID,
+		Name:       name,
+		Prefix:     prefix,
+		Scopes:     scopes,
+		ExpiresAt:  expiresAt,
+		hash:       hash,
+		generation: generation,
+	}
+	if err := s.repo.Create(ctx, k); err != nil {
+		return "", nil, fmt.Errorf("internal error: %v", err)
+	}
+	return key, k, nil
+}
+
+func (s *APIKeyService) List(ctx context.Context, userID int) ([]APIKey, error) {
+	keys, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return keys, nil
+}
+
+func (s *APIKeyService) Revoke(ctx context.Context, userID, id int) error {
+	if err := s.repo.Revoke(ctx, userID, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: api key %d does not exist", apperror.ErrNotFound, id)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// Rotate remplace une clé active par une nouvelle clé de même nom, scopes et expiration, puis
+// révoque l'ancienne. La nouvelle valeur en clair n'est retournée qu'une fois.
+func (s *APIKeyService) Rotate(ctx context.Context, userID, id int) (string, *APIKey, error) {
+	old, err := s.repo.FindByID(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return "", nil, fmt.Errorf("%w: api key %d does not exist", apperror.ErrNotFound, id)
+		}
+		return "", nil, fmt.Errorf("internal error: %v", err)
+	}
+	generation, err := s.sessions.SessionGeneration(ctx, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("internal error: %v", err)
+	}
+	if !old.IsActive(time.Now()) || old.generation < generation {
+		return "", nil, fmt.Errorf("%w: api key %d is revoked or expired", apperror.ErrConflict, id)
+	}
+
+	key, k, err := s.Create(ctx, userID, old.Name, old.Scopes, old.Scopes, old.ExpiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	if err := s.Revoke(ctx, userID, id); err != nil {
+		return "", nil, err
+	}
+	return key, k, nil
+}
+
+// Authenticate vérifie une clé présentée par un client et enregistre sa date de dernière utilisation.
+// La clé suit l'état actuel de son propriétaire : elle est refusée si le compte est désactivé ou si
+// ses sessions ont été révoquées depuis sa création, et perd les scopes d'administration si le
+// compte n'a plus le rôle admin.
+func (s *APIKeyService) Authenticate(ctx context.Context, key string) (int, []string, error) {
+	prefix, ok := parseKey(key)
+	if !ok {
+		return 0, nil, fmt.Errorf("%w: malformed api key", apperror.ErrUnauthorized)
+	}
+
+	k, err := s.repo.FindByPrefix(ctx, prefix)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return 0, nil, fmt.Errorf("%w: invalid api key", apperror.ErrUnauthorized)
+		}
+		return 0, nil, fmt.Errorf("internal error: %v", err)
+	}
+
+	if subtle.ConstantTimeCompare([]byte(k.hash), []byte(hashKey(key))) != 1 {
+		return 0, nil, fmt.Errorf("%w: invalid api key", apperror.ErrUnauthorized)
+	}
+
+	now := time.Now()
+	if !k.IsActive(now) {
+		return 0, nil, fmt.Errorf("%w: api key revoked or expired", apperror.ErrUnauthorized)
+	}
+
+	generation, err := s.sessions.SessionGeneration(ctx, k.UserID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("internal error: %v", err)
+	}
+	if k.generation < generation {
+		return 0, nil, fmt.Errorf("%w: api key revoked with the account sessions", apperror.ErrUnauthorized)
+	}
+
+	owner, err := s.owners.GetUserByID(ctx, k.UserID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return 0, nil, fmt.Errorf("%w: invalid api key", apperror.ErrUnauthorized)
+		}
+		return 0, nil, fmt.Errorf("internal error: %v", err)
+	}
+	if !owner.Active {
+		return 0, nil, fmt.Errorf("%w: %w", apperror.ErrUnauthorized, apperror.ErrLocked)
+	}
+	scopes := k.Scopes
+	if owner.Role != user.RoleAdmin {
+		scopes = oauth.Without(scopes, oauth.AdminScopes)
+	}
+
+	if err := s.repo.TouchLastUsed(ctx, k.ID, now); err != nil {
+		return 0, nil, fmt.Errorf("internal error: %v", err)
+	}
+
+	return k.UserID, scopes, nil
+}
@@ -0,0 +1,50 @@
+package apperror
+
+import "errors"
+
+// Catégories d'erreurs métier partagées par les dépôts et les services. Elles sont
+// enveloppées avec %w (« fmt.Errorf("%w: email already in use", ErrConflict) ») et
+// reconnues avec errors.Is par les handlers, qui en déduisent le statut HTTP.
+var (
+	// ErrValidation signale une entrée invalide (400)
+	ErrValidation = errors.New("validation error")
+	// ErrUnauthorized signale des identifiants ou un jeton refusés (401)
+	ErrUnauthorized = errors.New("authentication error")
+	// ErrNotFound signale une ressource inexistante (404)
+	ErrNotFound = errors.New("not found")
+	// ErrConflict signale une violation d'unicité ou un état incompatible (409)
+	ErrConflict = errors.New("conflict")
+	// ErrLocked signale un compte désactivé ou verrouillé (423)
+	ErrLocked = errors.New("account locked")
+)
+
+// Localized est une erreur d'une catégorie ci-dessus dont le message destiné à l'utilisateur
+// est une clé du catalogue de traductions (internal/i18n). Error() conserve le texte anglais,
+// destiné aux journaux.
+// Localized est une erreur dont le message destiné au client est traduit à partir d'une clé du
+// catalogue (internal/i18n). Text reste le message anglais utilisé dans les journaux.
+type Localized struct {
+	Kind  error
+	Key   string
+	Args  []interface{}
+	Text  string
+	Cause error
+}
+
+func (e *Localized) Error() string {
+	return e.Kind.Error() + ": " + e.Text
+}
+
+// Unwrap expose la catégorie et, le cas échéant, l'erreur d'origine (par exemple les erreurs du validateur)
+func (e *Localized) Unwrap() []error {
+	if e.Cause == nil {
+		return []error{e.Kind}
+	}
+	return []error{e.Kind, e.Cause}
+}
+
+// Localize construit une erreur de catégorie kind traduisible par key ; args complète le
+// message traduit et text est le message anglais des journaux
+func Localize(kind error, key, text string, args ...interface{}) error {
+	return &Localized{Kind: kind, Key: key, Args: args, Text: text}
+}
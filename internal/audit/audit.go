@@ -0,0 +1,154 @@
+// Package audit tient le journal de sécurité : connexions, échecs, réinitialisations de mot de
+// passe, modifications de comptes par un administrateur… Le journal est en ajout seul et chaîné :
+// chaque événement porte l'empreinte du précédent, ce qui rend détectable toute modification ou
+// suppression d'une ligne.
+package audit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+)
+
+// Actions enregistrées par UserService
+const (
+	ActionLogin                = "login"
+	ActionLogout               = "logout"
+	ActionTokenRefresh         = "token.refresh"
+	ActionPasswordResetRequest = "password.reset_requested"
+	ActionPasswordReset        = "password.reset"
+	ActionPasswordChange       = "password.changed"
+	ActionUserCreate           = "user.created"
+	ActionUserUpdate           = "user.updated"
+	ActionUserDelete           = "user.deleted"
+	ActionUserDisable          = "user.disabled"
+	ActionUserEnable           = "user.enabled"
+	ActionUserRoleChange       = "user.role_changed"
+	ActionIdentityLink         = "identity.linked"
+	ActionIdentityUnlink       = "identity.unlinked"
+	ActionLoginReported        = "login.reported"
+)
+
+const (
+	OutcomeSuccess = "success"
+	OutcomeFailure = "failure"
+)
+
+// Types d'acteurs : un utilisateur authentifié, un tenant SCIM, la CLI d'exploitation, ou
+// personne (requête non authentifiée, comme une connexion)
+const (
+	ActorAnonymous = "anonymous"
+	ActorUser      = "user"
+	ActorSCIM      = "scim"
+	ActorCLI       = "cli"
+)
+
+// Event est une entrée du journal. SubjectID est le compte concerné (0 s'il est inconnu, par
+// exemple une connexion avec un email qui n'existe pas) ; ActorType et ActorID désignent
+// l'auteur de l'opération.
+// Reason donne la catégorie d'un échec, jamais le texte de l'erreur qui peut contenir des
+// données personnelles.
+type Event struct {
+	ID        int64     `json:"id"`
+	Time      time.Time `json:"time"`
+	Action    string    `json:"action"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+	ActorType string    `json:"actor_type"`
+	ActorID   string    `json:"actor_id,omitempty"`
+	SubjectID int       `json:"subject_id,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+	PrevHash  string    `json:"prev_hash"`
+	Hash      string    `json:"hash"`
+}
+
+// seal chaîne l'événement à prev, l'empreinte du dernier événement du journal (vide pour le
+// premier). L'heure est ramenée à la microseconde en UTC, la précision des bases utilisées.
+func (e *Event) seal(prev string) {
+	e.Time = e.Time.UTC().Truncate(time.Microsecond)
+	e.PrevHash = prev
+	e.Hash = e.digest()
+}
+
+// digest calcule l'empreinte SHA-256 de l'événement et de l'empreinte qui le précède. L'ID,
+// attribué par la base à l'insertion, n'en fait pas partie : l'ordre est garanti par PrevHash.
+func (e *Event) digest() string {
+	payload, _ := json.Marshal([]string{
+		e.PrevHash,
+		e.Time.UTC().Format(time.RFC3339Nano),
+		e.Action,
+		e.Outcome,
+		e.Reason,
+		e.ActorType,
+		e.ActorID,
+		strconv.Itoa(e.SubjectID),
+		e.IP,
+		e.UserAgent,
+		e.RequestID,
+	})
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:])
+}
+
+// Actor désigne l'auteur des opérations d'une requête
+type Actor struct {
+	Type string
+	ID   string
+}
+
+// UserActor est l'acteur d'une requête authentifiée par l'utilisateur userID
+func UserActor(userID int) Actor {
+	return Actor{Type: ActorUser, ID: strconv.Itoa(userID)}
+}
+
+// Source décrit la requête à l'origine des opérations
+type Source struct {
+	IP        string
+	UserAgent string
+	RequestID string
+}
+
+type actorKey struct{}
+
+type sourceKey struct{}
+
+// WithActor retourne un contexte dont les opérations sont attribuées à actor
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// WithSource retourne un contexte dont les opérations proviennent de source
+func WithSource(ctx context.Context, source Source) context.Context {
+	return context.WithValue(ctx, sourceKey{}, source)
+}
+
+// SourceFrom retourne l'origine de la requête enregistrée par WithSource, vide à défaut
+func SourceFrom(ctx context.Context) Source {
+	source, _ := ctx.Value(sourceKey{}).(Source)
+	return source
+}
+
+// reason est la catégorie d'une erreur enregistrée dans le journal
+func reason(err error) string {
+	switch {
+	case errors.Is(err, apperror.ErrValidation):
+		return "invalid_request"
+	case errors.Is(err, apperror.ErrLocked):
+		return "account_locked"
+	case errors.Is(err, apperror.ErrUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, apperror.ErrNotFound):
+		return "not_found"
+	case errors.Is(err, apperror.ErrConflict):
+		return "conflict"
+	}
+	return "internal_error"
+}
@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+)
+
+const (
+	exportBatchSize = 100
+	retryMinDelay   = 100 * time.Millisecond
+	retryMaxDelay   = 30 * time.Second
+	// drainTimeout borne la dernière tentative d'export à l'arrêt
+	drainTimeout = 5 * time.Second
+)
+
+// Exporter transmet les événements enregistrés à une destination (fichier, collecteur syslog)
+// depuis sa propre goroutine. Publish ne bloque jamais : lorsque la destination ne suit plus
+// et que la file est pleine, les nouveaux événements sont abandonnés et comptés, le journal en
+// base restant la référence. Les écritures refusées sont réessayées avec un délai croissant.
+type Exporter struct {
+	name  string
+	sink  Sink
+	queue chan Event
+}
+
+// NewExporter crée un exportateur vers sink dont la file contient au plus bufferSize
+// événements ; name identifie la destination dans les métriques et les journaux
+func NewExporter(name string, sink Sink, bufferSize int) *Exporter {
+	return &Exporter{name: name, sink: sink, queue: make(chan Event, bufferSize)}
+}
+
+// NewExporters crée les exportateurs activés par cfg : fichier et collecteur syslog
+func NewExporters(cfg config.Audit) ([]*Exporter, error) {
+	var exporters []*Exporter
+	if cfg.File != "" {
+		format, err := ParseFormat(cfg.FileFormat)
+		if err != nil {
+			return nil, err
+		}
+		sink, err := OpenFileSink(cfg.File, format)
+		if err != nil {
+			return nil, err
+		}
+		exporters = append(exporters, NewExporter("file", sink, cfg.BufferSize))
+	}
+	if cfg.Syslog != "" {
+		format, err := ParseFormat(cfg.SyslogFormat)
+		if err != nil {
+			return nil, err
+		}
+		u, err := url.Parse(cfg.Syslog)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") {
+			return nil, fmt.Errorf("invalid syslog address %q", cfg.Syslog)
+		}
+		exporters = append(exporters, NewExporter("syslog", NewSyslogSink(u.Scheme, u.Host, format), cfg.BufferSize))
+	}
+	return exporters, nil
+}
+
+// Publish met l'événement en file d'attente, ou l'abandonne si la file est pleine
+func (x *Exporter) Publish(event Event) {
+	select {
+	case x.queue <- event:
+	default:
+		metrics.AuditExports.WithLabelValues(x.name, "dropped").Inc()
+	}
+}
+
+// Run exporte les événements par lots jusqu'à l'annulation de ctx, puis tente une dernière
+// fois d'écrire ceux qui restent en file et ferme la destination
+func (x *Exporter) Run(ctx context.Context) {
+	defer x.sink.Close()
+
+	batch := make([]Event, 0, exportBatchSize)
+	delay := retryMinDelay
+	for {
+		if len(batch) == 0 {
+			select {
+			case e := <-x.queue:
+				batch = append(batch, e)
+			case <-ctx.Done():
+				x.drain(batch)
+				return
+			}
+		}
+		batch = x.fill(batch)
+
+		n, err := x.sink.Write(ctx, batch)
+		x.exported(n)
+		batch = append(batch[:0], batch[n:]...)
+		if err == nil {
+			delay = retryMinDelay
+			continue
+		}
+
+		metrics.AuditExportErrors.WithLabelValues(x.name).Inc()
+		slog.Warn("error exporting audit events", "exporter", x.name, "pending", len(batch)+len(x.queue), "retry_in", delay, "error", err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			x.drain(batch)
+			return
+		}
+		delay = min(delay*2, retryMaxDelay)
+	}
+}
+
+// fill complète le lot avec les événements déjà en file, sans attendre
+func (x *Exporter) fill(batch []Event) []Event {
+	for len(batch) < exportBatchSize {
+		select {
+		case e := <-x.queue:
+			batch = append(batch, e)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain écrit, sans nouvelle tentative, le lot en cours et les événements restés en file
+func (x *Exporter) drain(batch []Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer cancel()
+
+	for batch = x.fill(batch); len(batch) > 0; batch = x.fill(batch[:0]) {
+		n, err := x.sink.Write(ctx, batch)
+		x.exported(n)
+		if err != nil {
+			slog.Error("error flushing audit events", "exporter", x.name, "lost", len(batch)-n+len(x.queue), "error", err)
+			return
+		}
+	}
+}
+
+func (x *Exporter) exported(n int) {
+	if n > 0 {
+		metrics.AuditExports.WithLabelValues(x.name, "exported").Add(float64(n))
+	}
+}
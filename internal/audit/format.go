@@ -0,0 +1,85 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Format met en forme un événement pour une destination d'export, sans saut de ligne final
+type Format func(Event) []byte
+
+// ParseFormat retourne le format nommé : json (un objet par ligne) ou cef
+func ParseFormat(name string) (Format, error) {
+	switch name {
+	case "json":
+		return FormatJSON, nil
+	case "cef":
+		return FormatCEF, nil
+	}
+	return nil, fmt.Errorf("unknown audit format %q (json or cef)", name)
+}
+
+// FormatJSON écrit l'événement tel que le retourne l'API, empreintes comprises
+func FormatJSON(e Event) []byte {
+	b, _ := json.Marshal(e)
+	return b
+}
+
+// Identification du produit dans l'en-tête CEF
+const (
+	cefVendor  = "AuthentificationGO"
+	cefProduct = "AuthentificationGO"
+	cefVersion = "1"
+)
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\r", " ", "\n", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\r\n", `\n`, "\n", `\n`, "\r", `\r`)
+)
+
+// FormatCEF écrit l'événement au format ArcSight Common Event Format. L'action sert
+// d'identifiant de signature ; les champs sans équivalent standard (type d'acteur, requête,
+// empreinte) sont portés par les extensions csN et leur libellé.
+func FormatCEF(e Event) []byte {
+	severity := 3
+	if e.Outcome == OutcomeFailure {
+		severity = 5
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "CEF:0|%s|%s|%s|%s|%s|%d|",
+		cefHeaderEscaper.Replace(cefVendor), cefHeaderEscaper.Replace(cefProduct), cefVersion,
+		cefHeaderEscaper.Replace(e.Action), cefHeaderEscaper.Replace(e.Action+" "+e.Outcome), severity)
+
+	sep := ""
+	add := func(key, value string) {
+		if value != "" {
+			b.WriteString(sep + key + "=" + cefExtensionEscaper.Replace(value))
+			sep = " "
+		}
+	}
+	custom := func(n int, label, value string) {
+		if value != "" {
+			add("cs"+strconv.Itoa(n)+"Label", label)
+			add("cs"+strconv.Itoa(n), value)
+		}
+	}
+
+	add("externalId", strconv.FormatInt(e.ID, 10))
+	add("rt", strconv.FormatInt(e.Time.UnixMilli(), 10))
+	add("act", e.Action)
+	add("outcome", e.Outcome)
+	add("reason", e.Reason)
+	custom(1, "actorType", e.ActorType)
+	add("suid", e.ActorID)
+	if e.SubjectID != 0 {
+		add("duid", strconv.Itoa(e.SubjectID))
+	}
+	add("src", e.IP)
+	add("requestClientApplication", e.UserAgent)
+	custom(2, "requestId", e.RequestID)
+	custom(3, "hash", e.Hash)
+	return []byte(b.String())
+}
@@ -0,0 +1,105 @@
+package audit
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+type AuditHandler struct {
+	service *AuditService
+}
+
+func NewAuditHandler(service *AuditService) *AuditHandler {
+	return &AuditHandler{service: service}
+}
+
+// List recherche dans tout le journal (administrateurs). Paramètres : subject_id, actor_type,
+// actor_id, action, outcome, since et until (RFC 3339), before (ID) et limit.
+func (h *AuditHandler) List(c *gin.Context) {
+	filter, ok := parseFilter(c)
+	if !ok {
+		return
+	}
+	if raw := c.Query("subject_id"); raw != "" {
+		id, err := strconv.Atoi(raw)
+		if err != nil || id <= 0 {
+			problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "audit.invalid_filter")
+			return
+		}
+		filter.SubjectID = id
+	}
+	filter.ActorType = c.Query("actor_type")
+	filter.ActorID = c.Query("actor_id")
+	h.list(c, filter)
+}
+
+// Mine retourne les événements concernant le compte de l'utilisateur authentifié
+func (h *AuditHandler) Mine(c *gin.Context) {
+	filter, ok := parseFilter(c)
+	if !ok {
+		return
+	}
+	filter.SubjectID = c.GetInt("userID")
+	h.list(c, filter)
+}
+
+func (h *AuditHandler) list(c *gin.Context, filter Filter) {
+	events, err := h.service.List(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "audit.list_failed")
+		return
+	}
+
+	response := gin.H{"events": events}
+	if filter.Limit == 0 {
+		filter.Limit = defaultLimit
+	}
+	if len(events) == filter.Limit {
+		response["next_before"] = events[len(events)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Verify contrôle la chaîne d'empreintes de tout le journal
+func (h *AuditHandler) Verify(c *gin.Context) {
+	v, err := h.service.Verify(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "audit.verify_failed")
+		return
+	}
+	c.JSON(http.StatusOK, v)
+}
+
+// parseFilter lit les paramètres communs aux deux recherches ; une valeur illisible est
+// refusée (400)
+func parseFilter(c *gin.Context) (Filter, bool) {
+	filter := Filter{Action: c.Query("action"), Outcome: c.Query("outcome")}
+
+	var err error
+	for name, target := range map[string]*time.Time{"since": &filter.Since, "until": &filter.Until} {
+		if raw := c.Query(name); raw != "" && err == nil {
+			*target, err = time.Parse(time.RFC3339, raw)
+		}
+	}
+	if raw := c.Query("before"); raw != "" && err == nil {
+		filter.BeforeID, err = strconv.ParseInt(raw, 10, 64)
+	}
+	if raw := c.Query("limit"); raw != "" && err == nil {
+		filter.Limit, err = strconv.Atoi(raw)
+	}
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "audit.invalid_filter")
+		return Filter{}, false
+	}
+	return filter, true
+}
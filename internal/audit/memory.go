@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore est une implémentation d'EventStore en mémoire, pour les tests et les instances
+// uniques sans base de données. Le journal ne survit pas au redémarrage.
+type MemoryStore struct {
+	mu     sync.Mutex
+	events []Event
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{}
+}
+
+var _ EventStore = (*MemoryStore)(nil)
+
+func (s *MemoryStore) Append(_ context.Context, event *Event) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var prev string
+	if n := len(s.events); n > 0 {
+		prev = s.events[n-1].Hash
+	}
+	event.seal(prev)
+	event.ID = int64(len(s.events) + 1)
+	s.events = append(s.events, *event)
+	return nil
+}
+
+func (s *MemoryStore) List(_ context.Context, filter Filter) ([]Event, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := []Event{}
+	for i := len(s.events) - 1; i >= 0 && len(events) < filter.Limit; i-- {
+		if e := s.events[i]; filter.matches(e) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func (s *MemoryStore) Each(_ context.Context, fn func(Event) error) error {
+	s.mu.Lock()
+	events := append([]Event(nil), s.events...)
+	s.mu.Unlock()
+
+	for _, e := range events {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f Filter) matches(e Event) bool {
+	switch {
+	case f.SubjectID != 0 && e.SubjectID != f.SubjectID,
+		f.ActorType != "" && e.ActorType != f.ActorType,
+		f.ActorID != "" && e.ActorID != f.ActorID,
+		f.Action != "" && e.Action != f.Action,
+		f.Outcome != "" && e.Outcome != f.Outcome,
+		!f.Since.IsZero() && e.Time.Before(f.Since),
+		!f.Until.IsZero() && !e.Time.Before(f.Until),
+		f.BeforeID != 0 && e.ID >= f.BeforeID:
+		return false
+	}
+	return true
+}
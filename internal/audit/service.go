@@ -0,0 +1,108 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+type AuditService struct {
+	store     EventStore
+	exporters []*Exporter
+}
+
+// NewAuditService crée le service du journal ; les événements enregistrés sont en outre
+// transmis à chacun des exportateurs
+func NewAuditService(store EventStore, exporters ...*Exporter) *AuditService {
+	return &AuditService{store: store, exporters: exporters}
+}
+
+// Record ajoute un événement au journal. L'acteur et la requête d'origine sont repris de ctx
+// (WithActor, WithSource) ; l'issue est déduite de err. Un échec d'écriture est journalisé
+// sans interrompre l'opération auditée. Seuls les événements enregistrés, donc chaînés, sont
+// exportés.
+func (s *AuditService) Record(ctx context.Context, event Event, err error) {
+	event.Time = time.Now()
+	event.Outcome = OutcomeSuccess
+	if err != nil {
+		event.Outcome = OutcomeFailure
+		if event.Reason == "" {
+			event.Reason = reason(err)
+		}
+	}
+
+	actor, ok := ctx.Value(actorKey{}).(Actor)
+	if !ok {
+		actor = Actor{Type: ActorAnonymous}
+	}
+	event.ActorType, event.ActorID = actor.Type, actor.ID
+
+	if source, ok := ctx.Value(sourceKey{}).(Source); ok {
+		event.IP, event.UserAgent, event.RequestID = source.IP, source.UserAgent, source.RequestID
+	}
+
+	if err := s.store.Append(ctx, &event); err != nil {
+		logging.FromContext(ctx).Error("error recording audit event", "action", event.Action, "subject_id", event.SubjectID, "error", err)
+		return
+	}
+	for _, x := range s.exporters {
+		x.Publish(event)
+	}
+}
+
+// List recherche dans le journal. Sans limite, 50 événements sont retournés, 200 au plus.
+func (s *AuditService) List(ctx context.Context, filter Filter) ([]Event, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultLimit
+	case filter.Limit < 0 || filter.Limit > maxLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", apperror.ErrValidation, maxLimit)
+	}
+	if !filter.Since.IsZero() && !filter.Until.IsZero() && !filter.Since.Before(filter.Until) {
+		return nil, fmt.Errorf("%w: since must be before until", apperror.ErrValidation)
+	}
+
+	events, err := s.store.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return events, nil
+}
+
+// Verification est le résultat du contrôle de la chaîne. BrokenAt est l'ID du premier
+// événement dont l'empreinte ne correspond pas : lui, ou la ligne qui le précédait, a été
+// modifié ou supprimé. Head est l'empreinte du dernier événement : conservée hors de la base,
+// elle permet de détecter aussi la suppression des derniers événements.
+type Verification struct {
+	Valid    bool   `json:"valid"`
+	Events   int64  `json:"events"`
+	Head     string `json:"head,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+}
+
+// Verify recalcule les empreintes de tout le journal
+func (s *AuditService) Verify(ctx context.Context) (*Verification, error) {
+	v := &Verification{Valid: true}
+	err := s.store.Each(ctx, func(e Event) error {
+		v.Events++
+		if e.PrevHash != v.Head || e.digest() != e.Hash {
+			if v.Valid {
+				v.Valid, v.BrokenAt = false, e.ID
+			}
+		}
+		v.Head = e.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return v, nil
+}
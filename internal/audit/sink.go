@@ -0,0 +1,173 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sink est une destination d'export. Write retourne le nombre d'événements effectivement
+// transmis, en tête de events : en cas d'erreur, seuls les suivants sont réessayés. Un Sink
+// n'est utilisé que par la goroutine de son Exporter.
+type Sink interface {
+	Write(ctx context.Context, events []Event) (int, error)
+	Close() error
+}
+
+// FileSink ajoute les événements à un fichier, un par ligne. Après une erreur d'écriture, le
+// fichier est rouvert à la tentative suivante, ce qui suit aussi une rotation externe.
+type FileSink struct {
+	path   string
+	format Format
+	file   *os.File
+}
+
+// OpenFileSink ouvre path en ajout, pour signaler dès le démarrage un chemin inutilisable
+func OpenFileSink(path string, format Format) (*FileSink, error) {
+	s := &FileSink{path: path, format: format}
+	if err := s.open(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileSink) open() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening audit file: %w", err)
+	}
+	s.file = f
+	return nil
+}
+
+func (s *FileSink) Write(_ context.Context, events []Event) (int, error) {
+	if s.file == nil {
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	var buf bytes.Buffer
+	for _, e := range events {
+		buf.Write(s.format(e))
+		buf.WriteByte('\n')
+	}
+	n, err := s.file.Write(buf.Bytes())
+	if err != nil {
+		s.file.Close()
+		s.file = nil
+		// Les lignes écrites en entier ne sont pas répétées
+		return bytes.Count(buf.Bytes()[:n], []byte{'\n'}), fmt.Errorf("error writing audit file: %w", err)
+	}
+	return len(events), nil
+}
+
+func (s *FileSink) Close() error {
+	if s.file == nil {
+		return nil
+	}
+	return s.file.Close()
+}
+
+const (
+	// syslogFacility est authpriv, réservée aux messages de sécurité et d'autorisation
+	syslogFacility = 10
+	syslogAppName  = "authentificationgo"
+	syslogTimeout  = 5 * time.Second
+)
+
+// SyslogSink envoie les événements à un collecteur syslog au format RFC 5424 : un datagramme
+// par message en UDP, et en TCP un message précédé de sa longueur (RFC 6587). La connexion est
+// établie au premier envoi et rétablie après une erreur.
+type SyslogSink struct {
+	network  string
+	addr     string
+	format   Format
+	hostname string
+	procID   string
+	conn     net.Conn
+}
+
+// NewSyslogSink prépare l'envoi vers addr (hôte:port) ; network est udp ou tcp
+func NewSyslogSink(network, addr string, format Format) *SyslogSink {
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+	return &SyslogSink{
+		network:  network,
+		addr:     addr,
+		format:   format,
+		hostname: headerField(hostname, 255),
+		procID:   strconv.Itoa(os.Getpid()),
+	}
+}
+
+func (s *SyslogSink) Write(ctx context.Context, events []Event) (int, error) {
+	if s.conn == nil {
+		dialer := net.Dialer{Timeout: syslogTimeout}
+		conn, err := dialer.DialContext(ctx, s.network, s.addr)
+		if err != nil {
+			return 0, fmt.Errorf("error connecting to syslog collector: %w", err)
+		}
+		s.conn = conn
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(syslogTimeout))
+	for i, e := range events {
+		msg := s.message(e)
+		if s.network == "tcp" {
+			msg = append([]byte(strconv.Itoa(len(msg))+" "), msg...)
+		}
+		if _, err := s.conn.Write(msg); err != nil {
+			s.conn.Close()
+			s.conn = nil
+			return i, fmt.Errorf("error writing to syslog collector: %w", err)
+		}
+	}
+	return len(events), nil
+}
+
+// message construit le message RFC 5424 ; l'action sert de MSGID, sans données structurées.
+// Les échecs ont la sévérité warning, les autres événements info.
+func (s *SyslogSink) message(e Event) []byte {
+	severity := 6
+	if e.Outcome == OutcomeFailure {
+		severity = 4
+	}
+	header := fmt.Sprintf("<%d>1 %s %s %s %s %s - ",
+		syslogFacility*8+severity,
+		e.Time.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		s.hostname, syslogAppName, s.procID, headerField(e.Action, 32))
+	return append([]byte(header), s.format(e)...)
+}
+
+func (s *SyslogSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// headerField ramène une valeur aux caractères ASCII imprimables, sans espace, et à limit
+// octets, comme l'exigent les champs d'en-tête RFC 5424
+func headerField(v string, limit int) string {
+	v = strings.Map(func(r rune) rune {
+		if r < '!' || r > '~' {
+			return '_'
+		}
+		return r
+	}, v)
+	if len(v) > limit {
+		v = v[:limit]
+	}
+	if v == "" {
+		return "-"
+	}
+	return v
+}
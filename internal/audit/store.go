@@ -0,0 +1,193 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+)
+
+// EventStore conserve le journal. Append chaîne l'événement au dernier enregistré (voir
+// Event.seal) de façon atomique : deux ajouts concurrents ne peuvent pas partir du même
+// prédécesseur. AuditStore l'implémente sur PostgreSQL et SQLite, MemoryStore en mémoire.
+type EventStore interface {
+	Append(ctx context.Context, event *Event) error
+	List(ctx context.Context, filter Filter) ([]Event, error)
+	// Each parcourt tout le journal dans l'ordre d'insertion
+	Each(ctx context.Context, fn func(Event) error) error
+}
+
+// Filter restreint une recherche dans le journal ; les champs vides sont ignorés. Les
+// événements sont retournés du plus récent au plus ancien, par pages de Limit événements :
+// BeforeID reprend la recherche après le dernier ID de la page précédente.
+type Filter struct {
+	SubjectID int
+	ActorType string
+	ActorID   string
+	Action    string
+	Outcome   string
+	Since     time.Time
+	Until     time.Time
+	BeforeID  int64
+	Limit     int
+}
+
+var _ EventStore = (*AuditStore)(nil)
+
+// chainLockKey identifie le verrou consultatif PostgreSQL qui sérialise les ajouts, y compris
+// entre plusieurs instances. Sous SQLite, les transactions prennent déjà le verrou d'écriture.
+const chainLockKey int64 = 4270517395
+
+// AuditStore enregistre le journal dans la table audit_events, protégée des UPDATE et DELETE
+// par des triggers
+type AuditStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewAuditStore(db *sql.DB) *AuditStore {
+	return &AuditStore{db: db, dialect: database.DialectOf(db)}
+}
+
+const selectColumns = "id, created_at, action, outcome, reason, actor_type, actor_id, subject_id, ip, user_agent, request_id, prev_hash, hash"
+
+func (s *AuditStore) Append(ctx context.Context, event *Event) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "AuditStore.Append", s.dialect.System(), "INSERT audit_events")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if s.dialect == database.Postgres {
+		if _, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1)", chainLockKey); err != nil {
+			return fmt.Errorf("error locking audit log: %w", err)
+		}
+	}
+
+	var prev string
+	err = tx.QueryRowContext(ctx, "SELECT hash FROM audit_events ORDER BY id DESC LIMIT 1").Scan(&prev)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("error reading last audit event: %w", err)
+	}
+	event.seal(prev)
+
+	var subject sql.NullInt64
+	if event.SubjectID != 0 {
+		subject = sql.NullInt64{Int64: int64(event.SubjectID), Valid: true}
+	}
+	err = tx.QueryRowContext(ctx, s.dialect.Rebind(
+		"INSERT INTO audit_events (created_at, action, outcome, reason, actor_type, actor_id, subject_id, ip, user_agent, request_id, prev_hash, hash) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) RETURNING id"),
+		event.Time, event.Action, event.Outcome, event.Reason, event.ActorType, event.ActorID, subject,
+		event.IP, event.UserAgent, event.RequestID, event.PrevHash, event.Hash).Scan(&event.ID)
+	if err != nil {
+		return fmt.Errorf("error inserting audit event: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (s *AuditStore) List(ctx context.Context, filter Filter) (_ []Event, err error) {
+	ctx, span := tracing.StartQuery(ctx, "AuditStore.List", s.dialect.System(), "SELECT audit_events")
+	defer func() { tracing.End(span, err) }()
+
+	var conditions []string
+	var args []any
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", "$"+strconv.Itoa(len(args))))
+	}
+	if filter.SubjectID != 0 {
+		where("subject_id = ?", filter.SubjectID)
+	}
+	if filter.ActorType != "" {
+		where("actor_type = ?", filter.ActorType)
+	}
+	if filter.ActorID != "" {
+		where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		where("outcome = ?", filter.Outcome)
+	}
+	if !filter.Since.IsZero() {
+		where("created_at >= ?", filter.Since.UTC())
+	}
+	if !filter.Until.IsZero() {
+		where("created_at < ?", filter.Until.UTC())
+	}
+	if filter.BeforeID != 0 {
+		where("id < ?", filter.BeforeID)
+	}
+
+	query := "SELECT " + selectColumns + " FROM audit_events"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit)
+	query += " ORDER BY id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := s.db.QueryContext(ctx, s.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing audit events: %w", err)
+	}
+	defer rows.Close()
+
+	events := []Event{}
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, *e)
+	}
+	return events, rows.Err()
+}
+
+func (s *AuditStore) Each(ctx context.Context, fn func(Event) error) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "AuditStore.Each", s.dialect.System(), "SELECT audit_events")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT "+selectColumns+" FROM audit_events ORDER BY id")
+	if err != nil {
+		return fmt.Errorf("error reading audit events: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEvent(rows)
+		if err != nil {
+			return err
+		}
+		if err := fn(*e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEvent(row rowScanner) (*Event, error) {
+	var e Event
+	var subject sql.NullInt64
+	err := row.Scan(&e.ID, &e.Time, &e.Action, &e.Outcome, &e.Reason, &e.ActorType, &e.ActorID, &subject,
+		&e.IP, &e.UserAgent, &e.RequestID, &e.PrevHash, &e.Hash)
+	if err != nil {
+		return nil, err
+	}
+	e.Time = e.Time.UTC()
+	e.SubjectID = int(subject.Int64)
+	return &e, nil
+}
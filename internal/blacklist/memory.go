@@ -0,0 +1,80 @@
+package blacklist
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore est une implémentation de TokenStore en mémoire, pour les tests et les
+// instances uniques sans base de données. Les révocations ne survivent pas au redémarrage.
+type MemoryStore struct {
+	mu          sync.Mutex
+	tokens      map[string]time.Time
+	generations map[int]int
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{tokens: make(map[string]time.Time), generations: make(map[int]int)}
+}
+
+var _ TokenStore = (*MemoryStore)(nil)
+
+func (s *MemoryStore) Add(ctx context.Context, token string, expiration time.Time) error {
+	_, err := s.Claim(ctx, token, expiration)
+	return err
+}
+
+func (s *MemoryStore) Claim(_ context.Context, token string, expiration time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hash := hashToken(token)
+	if _, ok := s.tokens[hash]; ok {
+		return false, nil
+	}
+	s.tokens[hash] = expiration
+	return true, nil
+}
+
+func (s *MemoryStore) Contains(_ context.Context, token string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expiration, ok := s.tokens[hashToken(token)]
+	return ok && expiration.After(time.Now()), nil
+}
+
+func (s *MemoryStore) RevokeAll(_ context.Context, userID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.generations[userID]++
+	return nil
+}
+
+func (s *MemoryStore) SessionGeneration(_ context.Context, userID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.generations[userID], nil
+}
+
+func (s *MemoryStore) PurgeExpired(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged int64
+	now := time.Now()
+	for hash, expiration := range s.tokens {
+		if !expiration.After(now) {
+			delete(s.tokens, hash)
+			purged++
+		}
+	}
+	return purged, nil
+}
+
+func (s *MemoryStore) Count(_ context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return int64(len(s.tokens)), nil
+}
@@ -0,0 +1,145 @@
+package blacklist
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+)
+
+// TokenStore conserve les jetons révoqués jusqu'à leur expiration, ainsi que la génération des
+// sessions de chaque compte : les jetons portent la génération en vigueur à leur émission, et
+// RevokeAll la fait avancer pour refuser d'un coup tous les jetons déjà émis. BlacklistStore
+// l'implémente sur Postgres, MemoryStore en mémoire.
+type TokenStore interface {
+	Add(ctx context.Context, token string, expiration time.Time) error
+	Claim(ctx context.Context, token string, expiration time.Time) (bool, error)
+	Contains(ctx context.Context, token string) (bool, error)
+	RevokeAll(ctx context.Context, userID int) error
+	SessionGeneration(ctx context.Context, userID int) (int, error)
+	PurgeExpired(ctx context.Context) (int64, error)
+	Count(ctx context.Context) (int64, error)
+}
+
+var _ TokenStore = (*BlacklistStore)(nil)
+
+// BlacklistStore conserve les jetons révoqués (déconnexion, refresh déjà utilisé, lien de
+// réinitialisation consommé) jusqu'à leur expiration. Seule l'empreinte SHA-256 est stockée.
+// Les expirations sont enregistrées en UTC et comparées à l'heure de l'application, ce qui
+// fonctionne à l'identique sur PostgreSQL et SQLite.
+type BlacklistStore struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewBlacklistStore(db *sql.DB) *BlacklistStore {
+	return &BlacklistStore{db: db, dialect: database.DialectOf(db)}
+}
+
+func (s *BlacklistStore) Add(ctx context.Context, token string, expiration time.Time) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "BlacklistStore.Add", s.dialect.System(), "INSERT blacklisted_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx,
+		s.dialect.Rebind("INSERT INTO blacklisted_tokens (token_hash, expiration) VALUES ($1, $2) ON CONFLICT (token_hash) DO NOTHING"),
+		hashToken(token), expiration.UTC())
+	if err != nil {
+		return fmt.Errorf("failed to add token to blacklist: %w", err)
+	}
+	return nil
+}
+
+// Claim ajoute le jeton et indique s'il ne l'était pas déjà : deux requêtes concurrentes
+// présentant le même jeton à usage unique ne peuvent pas l'obtenir toutes les deux
+func (s *BlacklistStore) Claim(ctx context.Context, token string, expiration time.Time) (_ bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "BlacklistStore.Claim", s.dialect.System(), "INSERT blacklisted_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := s.db.ExecContext(ctx,
+		s.dialect.Rebind("INSERT INTO blacklisted_tokens (token_hash, expiration) VALUES ($1, $2) ON CONFLICT (token_hash) DO NOTHING"),
+		hashToken(token), expiration.UTC())
+	if err != nil {
+		return false, fmt.Errorf("failed to add token to blacklist: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Contains indique si le jeton a été révoqué et n'est pas encore expiré
+func (s *BlacklistStore) Contains(ctx context.Context, token string) (_ bool, err error) {
+	ctx, span := tracing.StartQuery(ctx, "BlacklistStore.Contains", s.dialect.System(), "SELECT blacklisted_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	var revoked bool
+	err = s.db.QueryRowContext(ctx,
+		s.dialect.Rebind("SELECT EXISTS (SELECT 1 FROM blacklisted_tokens WHERE token_hash = $1 AND expiration > $2)"),
+		hashToken(token), time.Now().UTC()).Scan(&revoked)
+	if err != nil {
+		return false, fmt.Errorf("failed to check token blacklist: %w", err)
+	}
+	return revoked, nil
+}
+
+// RevokeAll révoque tous les jetons déjà émis pour le compte userID en faisant avancer la
+// génération de ses sessions
+func (s *BlacklistStore) RevokeAll(ctx context.Context, userID int) (err error) {
+	ctx, span := tracing.StartQuery(ctx, "BlacklistStore.RevokeAll", s.dialect.System(), "INSERT session_revocations")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = s.db.ExecContext(ctx,
+		s.dialect.Rebind("INSERT INTO session_revocations (user_id, generation, revoked_at) VALUES ($1, 1, $2) "+
+			"ON CONFLICT (user_id) DO UPDATE SET generation = session_revocations.generation + 1, revoked_at = excluded.revoked_at"),
+		userID, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// SessionGeneration retourne la génération des sessions du compte : 0 tant qu'elles n'ont
+// jamais été révoquées. Un jeton d'une génération antérieure est refusé.
+func (s *BlacklistStore) SessionGeneration(ctx context.Context, userID int) (_ int, err error) {
+	ctx, span := tracing.StartQuery(ctx, "BlacklistStore.SessionGeneration", s.dialect.System(), "SELECT session_revocations")
+	defer func() { tracing.End(span, err) }()
+
+	var generation int
+	err = s.db.QueryRowContext(ctx, s.dialect.Rebind("SELECT generation FROM session_revocations WHERE user_id = $1"), userID).Scan(&generation)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check session revocation: %w", err)
+	}
+	return generation, nil
+}
+
+// PurgeExpired supprime les entrées dont le jeton a de toute façon expiré
+func (s *BlacklistStore) PurgeExpired(ctx context.Context) (int64, error) {
+	res, err := s.db.ExecContext(ctx, s.dialect.Rebind("DELETE FROM blacklisted_tokens WHERE expiration <= $1"), time.Now().UTC())
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge token blacklist: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// Count retourne le nombre d'entrées, expirées comprises tant qu'elles ne sont pas purgées
+func (s *BlacklistStore) Count(ctx context.Context) (int64, error) {
+	var n int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM blacklisted_tokens").Scan(&n); err != nil {
+		return 0, fmt.Errorf("failed to count blacklisted tokens: %w", err)
+	}
+	return n, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
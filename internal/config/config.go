@@ -0,0 +1,611 @@
+// Package config regroupe la configuration typée de l'application. Elle est lue au démarrage
+// dans cet ordre de priorité croissante : valeurs par défaut, fichier YAML ou TOML, variables
+// d'environnement (ou fichiers désignés par les variables *_FILE), puis options de la ligne de
+// commande. Elle est validée avant d'être transmise explicitement aux services et middlewares.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/mail"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+)
+
+// MinSecretLength est la taille minimale du secret de signature des jetons (256 bits pour HS256)
+const MinSecretLength = 32
+
+// Les tags décrivent chaque réglage : key est son nom dans le fichier de configuration (les
+// sections sont séparées par des points), env sa variable d'environnement, flag son option de
+// ligne de commande et help la description affichée par -h. Les réglages marqués secret ne sont
+// jamais exposés en option ni affichés.
+type Config struct {
+	Server        Server   `key:"server"`
+	Auth          Auth     `key:"auth"`
+	Database      Database `key:"database"`
+	Mail          Mail     `key:"mail"`
+	Log           Log      `key:"log"`
+	Tracing       Tracing  `key:"tracing"`
+	Audit         Audit    `key:"audit"`
+	Webhook       Webhook  `key:"webhook"`
+	LDAP          LDAP     `key:"ldap"`
+	SAML          SAML     `key:"saml"`
+	Social        Social   `key:"social"`
+	DefaultLocale string   `key:"default_locale" env:"DEFAULT_LOCALE" flag:"default-locale" help:"langue des messages lorsque la requête n'en indique aucune"`
+}
+
+type Server struct {
+	Host        string `key:"host" env:"HOST" flag:"host" help:"adresse d'écoute"`
+	Port        int    `key:"port" env:"PORT" flag:"port" help:"port d'écoute"`
+	RoutePrefix string `key:"route_prefix" env:"ROUTE_PREFIX" flag:"route-prefix" help:"préfixe des routes de l'API"`
+	PublicURL   string `key:"public_url" env:"PUBLIC_URL" flag:"public-url" help:"URL publique du service, sans le préfixe des routes"`
+	MetricsPath string `key:"metrics_path" env:"METRICS_PATH" flag:"metrics-path" help:"route des métriques Prometheus, hors préfixe (vide : désactivées)"`
+
+	ReadTimeout       time.Duration `key:"read_timeout" env:"HTTP_READ_TIMEOUT" flag:"read-timeout" help:"durée maximale de lecture d'une requête, corps compris"`
+	ReadHeaderTimeout time.Duration `key:"read_header_timeout" env:"HTTP_READ_HEADER_TIMEOUT" flag:"read-header-timeout" help:"durée maximale de lecture des en-têtes"`
+	WriteTimeout      time.Duration `key:"write_timeout" env:"HTTP_WRITE_TIMEOUT" flag:"write-timeout" help:"durée maximale d'écriture de la réponse"`
+	IdleTimeout       time.Duration `key:"idle_timeout" env:"HTTP_IDLE_TIMEOUT" flag:"idle-timeout" help:"durée de vie d'une connexion keep-alive inactive"`
+	MaxHeaderBytes    int           `key:"max_header_bytes" env:"HTTP_MAX_HEADER_BYTES" flag:"max-header-bytes" help:"taille maximale des en-têtes d'une requête"`
+	ShutdownTimeout   time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" flag:"shutdown-timeout" help:"délai laissé aux requêtes en cours à l'arrêt"`
+	// ShutdownDelay laisse aux répartiteurs de charge le temps de constater que /readyz échoue
+	// avant que le serveur ne refuse les connexions
+	ShutdownDelay time.Duration `key:"shutdown_delay" env:"SHUTDOWN_DELAY" flag:"shutdown-delay" help:"délai entre le passage de /readyz en échec et l'arrêt du serveur"`
+
+	// Avec un certificat et sa clé, le serveur écoute en HTTPS. Les fichiers sont relus à
+	// chaque modification (et sur SIGHUP) sans redémarrage.
+	TLSCertFile       string        `key:"tls_cert_file" env:"TLS_CERT_FILE" flag:"tls-cert" help:"certificat TLS (PEM, chaîne complète)"`
+	TLSKeyFile        string        `key:"tls_key_file" env:"TLS_KEY_FILE" flag:"tls-key" help:"clé privée TLS (PEM)"`
+	TLSReloadInterval time.Duration `key:"tls_reload_interval" env:"TLS_RELOAD_INTERVAL" flag:"tls-reload-interval" help:"intervalle de vérification des fichiers TLS (0 : SIGHUP uniquement)"`
+}
+
+type Auth struct {
+	JWTSecret       string        `key:"jwt_secret" env:"JWT_SECRET" secret:"true"`
+	AccessTokenTTL  time.Duration `key:"access_token_ttl" env:"ACCESS_TOKEN_TTL" flag:"access-token-ttl" help:"durée de vie des jetons d'accès"`
+	RefreshTokenTTL time.Duration `key:"refresh_token_ttl" env:"REFRESH_TOKEN_TTL" flag:"refresh-token-ttl" help:"durée de vie des refresh tokens"`
+	ResetTokenTTL   time.Duration `key:"reset_token_ttl" env:"RESET_TOKEN_TTL" flag:"reset-token-ttl" help:"durée de vie des liens de réinitialisation du mot de passe"`
+	// ResetURL est la page qui reçoit le jeton de réinitialisation ; par défaut la route
+	// /reset-password de l'API
+	ResetURL string `key:"reset_url" env:"RESET_PASSWORD_URL" flag:"reset-url" help:"URL du lien de réinitialisation envoyé par email"`
+	// LoginReportURL est la page qui reçoit le jeton « ce n'était pas moi » des alertes de
+	// connexion ; par défaut la route /logins/report de l'API
+	LoginReportURL string        `key:"login_report_url" env:"LOGIN_REPORT_URL" flag:"login-report-url" help:"URL du lien « ce n'était pas moi » des alertes de connexion"`
+	LoginReportTTL time.Duration `key:"login_report_ttl" env:"LOGIN_REPORT_TTL" flag:"login-report-ttl" help:"durée de validité du lien « ce n'était pas moi »"`
+}
+
+type Database struct {
+	Driver     string `key:"driver" env:"DB_DRIVER" flag:"db-driver" help:"stockage : postgres ou sqlite"`
+	Host       string `key:"host" env:"DB_HOST" flag:"db-host" help:"hôte PostgreSQL"`
+	Port       int    `key:"port" env:"DB_PORT" flag:"db-port" help:"port PostgreSQL"`
+	User       string `key:"user" env:"DB_USER" flag:"db-user" help:"utilisateur PostgreSQL"`
+	Password   string `key:"password" env:"DB_PASSWORD" secret:"true"`
+	Name       string `key:"name" env:"DB_NAME" flag:"db-name" help:"base PostgreSQL"`
+	SSLMode    string `key:"sslmode" env:"DB_SSLMODE" flag:"db-sslmode" help:"mode TLS de la connexion PostgreSQL"`
+	SQLitePath string `key:"sqlite_path" env:"SQLITE_PATH" flag:"sqlite-path" help:"fichier de la base SQLite"`
+
+	AutoMigrate     bool          `key:"auto_migrate" env:"DB_AUTO_MIGRATE" flag:"auto-migrate" help:"appliquer les migrations au démarrage"`
+	MaxOpenConns    int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `key:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+}
+
+// Mail configure l'envoi des emails. Sans serveur SMTP, les messages sont écrits sur la sortie
+// standard (développement).
+type Mail struct {
+	SMTPHost     string `key:"smtp_host" env:"SMTP_HOST" flag:"smtp-host" help:"serveur SMTP (vide : emails écrits sur la sortie standard)"`
+	SMTPPort     int    `key:"smtp_port" env:"SMTP_PORT" flag:"smtp-port" help:"port du serveur SMTP"`
+	SMTPUsername string `key:"smtp_username" env:"SMTP_USERNAME" flag:"smtp-username" help:"utilisateur SMTP"`
+	SMTPPassword string `key:"smtp_password" env:"SMTP_PASSWORD" secret:"true"`
+	From         string `key:"from" env:"MAIL_FROM" flag:"mail-from" help:"expéditeur des emails"`
+}
+
+type Log struct {
+	Level string `key:"level" env:"LOG_LEVEL" flag:"log-level" help:"niveau minimal des journaux : debug, info, warn ou error"`
+}
+
+// Tracing configure l'export des traces OpenTelemetry. Sans exportateur, le contexte de trace
+// W3C reçu est tout de même propagé.
+type Tracing struct {
+	Exporter    string  `key:"exporter" env:"TRACING_EXPORTER" flag:"tracing-exporter" help:"exportateur des traces : none, otlp ou stdout"`
+	Endpoint    string  `key:"otlp_endpoint" env:"OTLP_ENDPOINT" flag:"otlp-endpoint" help:"URL du collecteur OTLP/HTTP (vide : variables OTEL_EXPORTER_OTLP_*)"`
+	File        string  `key:"file" env:"TRACING_FILE" flag:"tracing-file" help:"fichier des traces de l'exportateur stdout (vide : sortie standard)"`
+	SampleRatio float64 `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO" flag:"tracing-sample-ratio" help:"proportion des nouvelles traces enregistrées, entre 0 et 1"`
+	ServiceName string  `key:"service_name" env:"OTEL_SERVICE_NAME" flag:"service-name" help:"nom du service dans les traces"`
+}
+
+// Audit configure l'export du journal de sécurité vers un SIEM : fichier JSON lines ou CEF, et
+// collecteur syslog RFC 5424. Chaque destination a sa propre file d'attente : un collecteur lent
+// ou injoignable ne ralentit pas les connexions.
+type Audit struct {
+	File         string `key:"file" env:"AUDIT_FILE" flag:"audit-file" help:"fichier où ajouter les événements d'audit (vide : pas d'export)"`
+	FileFormat   string `key:"file_format" env:"AUDIT_FILE_FORMAT" flag:"audit-file-format" help:"format du fichier d'audit : json ou cef"`
+	Syslog       string `key:"syslog" env:"AUDIT_SYSLOG" flag:"audit-syslog" help:"collecteur syslog, udp://hôte:port ou tcp://hôte:port (vide : pas d'export)"`
+	SyslogFormat string `key:"syslog_format" env:"AUDIT_SYSLOG_FORMAT" flag:"audit-syslog-format" help:"format des messages syslog : cef ou json"`
+	BufferSize   int    `key:"buffer_size" env:"AUDIT_BUFFER_SIZE" flag:"audit-buffer-size" help:"événements en attente par destination au-delà desquels les suivants sont abandonnés"`
+}
+
+// Webhook règle la livraison des événements d'identité aux endpoints enregistrés. Une livraison
+// refusée est réessayée après RetryBase, puis un délai doublé à chaque échec ; après
+// MaxAttempts tentatives, elle est classée en lettre morte.
+type Webhook struct {
+	PollInterval time.Duration `key:"poll_interval" env:"WEBHOOK_POLL_INTERVAL" flag:"webhook-poll-interval" help:"intervalle de lecture de l'outbox et des livraisons à réessayer"`
+	Timeout      time.Duration `key:"timeout" env:"WEBHOOK_TIMEOUT" flag:"webhook-timeout" help:"délai de réponse accordé à un endpoint"`
+	MaxAttempts  int           `key:"max_attempts" env:"WEBHOOK_MAX_ATTEMPTS" flag:"webhook-max-attempts" help:"tentatives avant de classer une livraison en lettre morte"`
+	RetryBase    time.Duration `key:"retry_base" env:"WEBHOOK_RETRY_BASE" flag:"webhook-retry-base" help:"délai avant la deuxième tentative, doublé ensuite"`
+}
+
+// LDAP configure l'annuaire essayé après le mot de passe local à la connexion ; il est désactivé
+// tant que l'URL n'est pas renseignée
+type LDAP struct {
+	URL          string `key:"url" env:"LDAP_URL" flag:"ldap-url" help:"URL de l'annuaire, ldap:// ou ldaps:// (vide : désactivé)"`
+	StartTLS     bool   `key:"start_tls" env:"LDAP_START_TLS" flag:"ldap-start-tls" help:"passer la connexion ldap:// en TLS (StartTLS)"`
+	BindDN       string `key:"bind_dn" env:"LDAP_BIND_DN" flag:"ldap-bind-dn" help:"DN du compte de service utilisé pour les recherches"`
+	BindPassword string `key:"bind_password" env:"LDAP_BIND_PASSWORD" secret:"true"`
+
+	BaseDN         string `key:"base_dn" env:"LDAP_BASE_DN" flag:"ldap-base-dn" help:"base de recherche des utilisateurs"`
+	UserFilter     string `key:"user_filter" env:"LDAP_USER_FILTER" flag:"ldap-user-filter" help:"filtre de recherche d'un utilisateur, %s étant son email"`
+	IDAttribute    string `key:"id_attribute" env:"LDAP_ID_ATTRIBUTE" flag:"ldap-id-attribute" help:"attribut stable identifiant l'utilisateur (vide : son DN)"`
+	EmailAttribute string `key:"email_attribute" env:"LDAP_EMAIL_ATTRIBUTE" flag:"ldap-email-attribute" help:"attribut de l'email"`
+	NameAttribute  string `key:"name_attribute" env:"LDAP_NAME_ATTRIBUTE" flag:"ldap-name-attribute" help:"attribut du nom affiché"`
+
+	GroupBaseDN string `key:"group_base_dn" env:"LDAP_GROUP_BASE_DN" flag:"ldap-group-base-dn" help:"base de recherche des groupes (vide : ldap.base_dn)"`
+	GroupFilter string `key:"group_filter" env:"LDAP_GROUP_FILTER" flag:"ldap-group-filter" help:"filtre des groupes d'un utilisateur, %s étant son DN"`
+	// RoleMapping s'écrit « admins=admin,staff=user » ; le premier groupe correspondant l'emporte
+	RoleMapping string        `key:"role_mapping" env:"LDAP_ROLE_MAPPING" flag:"ldap-role-mapping" help:"rôle accordé par groupe, ex. admins=admin,staff=user"`
+	DefaultRole string        `key:"default_role" env:"LDAP_DEFAULT_ROLE" flag:"ldap-default-role" help:"rôle des utilisateurs sans groupe correspondant"`
+	Timeout     time.Duration `key:"timeout" env:"LDAP_TIMEOUT" flag:"ldap-timeout" help:"délai de connexion et de réponse de l'annuaire"`
+}
+
+// SAML configure notre fournisseur de services, commun aux IdP de tous les tenants ; il est
+// désactivé tant que l'URL de base n'est pas renseignée
+type SAML struct {
+	BaseURL  string `key:"sp_base_url" env:"SAML_SP_BASE_URL" flag:"saml-sp-base-url" help:"URL publique des routes SAML, ex. https://api.example.com/<préfixe>/saml (vide : désactivé)"`
+	CertFile string `key:"sp_cert_file" env:"SAML_SP_CERT_FILE" flag:"saml-sp-cert" help:"certificat du fournisseur de services (PEM)"`
+	KeyFile  string `key:"sp_key_file" env:"SAML_SP_KEY_FILE" flag:"saml-sp-key" help:"clé privée RSA du fournisseur de services (PEM)"`
+}
+
+// Social configure la connexion par des fournisseurs d'identité externes. Les réglages de
+// chaque fournisseur activé sont lus sous social.<nom> et SOCIAL_<NOM>_* (voir SocialProvider).
+type Social struct {
+	Providers       string `key:"providers" env:"SOCIAL_PROVIDERS" flag:"social-providers" help:"fournisseurs d'identité activés, séparés par des virgules (ex. google,github)"`
+	RedirectBaseURL string `key:"redirect_base_url" env:"SOCIAL_REDIRECT_BASE_URL" flag:"social-redirect-base-url" help:"URL de base des callbacks, complétée par /<nom>/callback"`
+	// Provider est rempli par Parse pour chaque nom de Providers
+	Provider map[string]*SocialProvider `key:"-"`
+}
+
+// SocialProvider configure un fournisseur OIDC ou OAuth2 de type GitHub. google, microsoft,
+// keycloak et github ont des valeurs par défaut ; tout autre nom est un fournisseur OIDC.
+type SocialProvider struct {
+	Type         string `key:"type" env:"TYPE"`
+	ClientID     string `key:"client_id" env:"CLIENT_ID"`
+	ClientSecret string `key:"client_secret" env:"CLIENT_SECRET" secret:"true"`
+	// RedirectURL vaut par défaut social.redirect_base_url suivi de /<nom>/callback
+	RedirectURL string `key:"redirect_url" env:"REDIRECT_URL"`
+	// Scopes sont séparés par des espaces ou des virgules
+	Scopes     string `key:"scopes" env:"SCOPES"`
+	TrustEmail bool   `key:"trust_email" env:"TRUST_EMAIL"`
+
+	IssuerURL   string `key:"issuer" env:"ISSUER"`
+	AuthURL     string `key:"auth_url" env:"AUTH_URL"`
+	TokenURL    string `key:"token_url" env:"TOKEN_URL"`
+	UserInfoURL string `key:"userinfo_url" env:"USERINFO_URL"`
+	EmailsURL   string `key:"emails_url" env:"EMAILS_URL"`
+}
+
+// Default retourne la configuration par défaut. Le secret des jetons n'a pas de valeur par
+// défaut : il doit être fourni.
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Host:        "0.0.0.0",
+			Port:        8080,
+			RoutePrefix: "/44df37e7-fe2a-404f-917b-399f5c5ffd12",
+			PublicURL:   "https://go-auth-api-latest.onrender.com",
+			MetricsPath: "/metrics",
+
+			ReadTimeout:       15 * time.Second,
+			ReadHeaderTimeout: 5 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			MaxHeaderBytes:    1 << 20,
+			ShutdownTimeout:   15 * time.Second,
+			TLSReloadInterval: time.Minute,
+		},
+		Auth: Auth{
+			AccessTokenTTL:  2 * time.Hour,
+			RefreshTokenTTL: 7 * 24 * time.Hour,
+			ResetTokenTTL:   15 * time.Minute,
+			LoginReportTTL:  7 * 24 * time.Hour,
+		},
+		Database: Database{
+			Driver:          "postgres",
+			Port:            5432,
+			SSLMode:         "disable",
+			SQLitePath:      "authentificationgo.db",
+			AutoMigrate:     true,
+			MaxOpenConns:    25,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: 30 * time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+		},
+		Mail: Mail{
+			SMTPPort: 587,
+			From:     "AuthentificationGO <no-reply@authentificationgo.local>",
+		},
+		Log: Log{Level: "info"},
+		Tracing: Tracing{
+			Exporter:    "none",
+			SampleRatio: 1,
+			ServiceName: "authentificationgo",
+		},
+		Audit: Audit{
+			FileFormat:   "json",
+			SyslogFormat: "cef",
+			BufferSize:   1024,
+		},
+		Webhook: Webhook{
+			PollInterval: 5 * time.Second,
+			Timeout:      10 * time.Second,
+			MaxAttempts:  8,
+			RetryBase:    30 * time.Second,
+		},
+		LDAP: LDAP{
+			UserFilter:     "(&(objectClass=person)(mail=%s))",
+			EmailAttribute: "mail",
+			NameAttribute:  "cn",
+			GroupFilter:    "(&(objectClass=groupOfNames)(member=%s))",
+			DefaultRole:    "user",
+			Timeout:        10 * time.Second,
+		},
+		DefaultLocale: i18n.French,
+	}
+}
+
+// Load lit la configuration avec Parse puis la valide entièrement
+func Load(args []string) (*Config, error) {
+	cfg, err := Parse(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Parse lit la configuration sans la valider. args sont les options de la ligne de commande
+// (sans le nom du programme) ; le fichier de configuration est désigné par -config ou
+// CONFIG_FILE. Un fichier .env du répertoire courant est chargé dans l'environnement s'il existe.
+func Parse(args []string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("error reading .env: %w", err)
+	}
+
+	cfg := Default()
+	settings := cfg.settings()
+
+	flags := flag.NewFlagSet("authentificationgo", flag.ContinueOnError)
+	file := flags.String("config", os.Getenv("CONFIG_FILE"), "fichier de configuration YAML ou TOML")
+	overrides := make(map[string]string)
+	for _, s := range settings {
+		if s.flag == "" {
+			continue
+		}
+		name := s.flag
+		flags.Func(name, s.help, func(v string) error {
+			overrides[name] = v
+			return nil
+		})
+	}
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+
+	// Les sections des fournisseurs d'identité ne sont connues qu'une fois social.providers lu :
+	// les valeurs du fichier qui les concernent sont appliquées ensuite
+	var providerValues map[string]string
+	if *file != "" {
+		var err error
+		if providerValues, err = loadFile(*file, settings); err != nil {
+			return nil, err
+		}
+	}
+	if err := loadEnv(settings); err != nil {
+		return nil, err
+	}
+	for _, s := range settings {
+		if v, ok := overrides[s.flag]; ok {
+			if err := s.set(v); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.flag, err)
+			}
+		}
+	}
+	if err := cfg.Social.load(*file, providerValues); err != nil {
+		return nil, err
+	}
+
+	if cfg.Auth.ResetURL == "" {
+		cfg.Auth.ResetURL = strings.TrimSuffix(cfg.Server.PublicURL, "/") + cfg.Server.RoutePrefix + "/reset-password"
+	}
+	if cfg.Auth.LoginReportURL == "" {
+		cfg.Auth.LoginReportURL = strings.TrimSuffix(cfg.Server.PublicURL, "/") + cfg.Server.RoutePrefix + "/logins/report"
+	}
+	if cfg.LDAP.GroupBaseDN == "" {
+		cfg.LDAP.GroupBaseDN = cfg.LDAP.BaseDN
+	}
+	if cfg.Database.MaxIdleConns > cfg.Database.MaxOpenConns {
+		cfg.Database.MaxIdleConns = cfg.Database.MaxOpenConns
+	}
+	return cfg, nil
+}
+
+// Validate vérifie l'ensemble des réglages et retourne toutes les erreurs à la fois
+func (c *Config) Validate() error {
+	v := &validation{cfg: c}
+	c.validateServer(v)
+	c.validateAuth(v)
+	c.validateDatabase(v)
+	c.validateMail(v)
+	c.validateTracing(v)
+	c.validateAudit(v)
+	c.validateWebhook(v)
+	c.validateLDAP(v)
+	c.validateSAML(v)
+	c.validateSocial(v)
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		v.invalid("log.level", "unknown level %q (debug, info, warn or error)", c.Log.Level)
+	}
+	return v.err()
+}
+
+// ValidateDatabase ne vérifie que la connexion à la base, pour les commandes qui n'exposent pas
+// l'API (migrations)
+func (c *Config) ValidateDatabase() error {
+	v := &validation{cfg: c}
+	c.validateDatabase(v)
+	return v.err()
+}
+
+// validation accumule les erreurs pour les présenter toutes ensemble
+type validation struct {
+	cfg  *Config
+	errs []error
+}
+
+func (v *validation) invalid(key, format string, args ...interface{}) {
+	v.errs = append(v.errs, fmt.Errorf("%s: %s", v.cfg.describe(key), fmt.Sprintf(format, args...)))
+}
+
+func (v *validation) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid configuration:\n%w", errors.Join(v.errs...))
+}
+
+func (c *Config) validateServer(v *validation) {
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		v.invalid("server.port", "must be between 1 and 65535")
+	}
+	if p := c.Server.RoutePrefix; p != "" && (!strings.HasPrefix(p, "/") || strings.HasSuffix(p, "/")) {
+		v.invalid("server.route_prefix", "must start with / and not end with /")
+	}
+	if p := c.Server.MetricsPath; p != "" && !strings.HasPrefix(p, "/") {
+		v.invalid("server.metrics_path", "must start with /")
+	}
+	if !absoluteURL(c.Server.PublicURL) {
+		v.invalid("server.public_url", "must be an absolute URL")
+	}
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"server.read_timeout", c.Server.ReadTimeout},
+		{"server.read_header_timeout", c.Server.ReadHeaderTimeout},
+		{"server.write_timeout", c.Server.WriteTimeout},
+		{"server.idle_timeout", c.Server.IdleTimeout},
+		{"server.shutdown_timeout", c.Server.ShutdownTimeout},
+	}
+	for _, t := range timeouts {
+		if t.value <= 0 {
+			v.invalid(t.key, "must be positive")
+		}
+	}
+	if c.Server.ShutdownDelay < 0 {
+		v.invalid("server.shutdown_delay", "must not be negative")
+	}
+	if c.Server.MaxHeaderBytes < 4096 {
+		v.invalid("server.max_header_bytes", "must be at least 4096")
+	}
+	if (c.Server.TLSCertFile == "") != (c.Server.TLSKeyFile == "") {
+		v.invalid("server.tls_cert_file", "and server.tls_key_file must be set together")
+	}
+	for _, f := range []struct{ key, path string }{
+		{"server.tls_cert_file", c.Server.TLSCertFile},
+		{"server.tls_key_file", c.Server.TLSKeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			v.invalid(f.key, "%v", err)
+		}
+	}
+	if c.Server.TLSReloadInterval < 0 {
+		v.invalid("server.tls_reload_interval", "must not be negative")
+	}
+	if !i18n.Supported(c.DefaultLocale) {
+		v.invalid("default_locale", "unsupported locale %q (%s)", c.DefaultLocale, strings.Join(i18n.Locales(), ", "))
+	}
+}
+
+func (c *Config) validateAuth(v *validation) {
+	switch {
+	case c.Auth.JWTSecret == "":
+		v.invalid("auth.jwt_secret", "is required")
+	case len(c.Auth.JWTSecret) < MinSecretLength:
+		v.invalid("auth.jwt_secret", "is too weak: use at least %d bytes (got %d)", MinSecretLength, len(c.Auth.JWTSecret))
+	}
+	if c.Auth.AccessTokenTTL <= 0 {
+		v.invalid("auth.access_token_ttl", "must be positive")
+	}
+	if c.Auth.RefreshTokenTTL < c.Auth.AccessTokenTTL {
+		v.invalid("auth.refresh_token_ttl", "must not be shorter than auth.access_token_ttl")
+	}
+	if c.Auth.ResetTokenTTL <= 0 {
+		v.invalid("auth.reset_token_ttl", "must be positive")
+	}
+	if !absoluteURL(c.Auth.ResetURL) {
+		v.invalid("auth.reset_url", "must be an absolute URL")
+	}
+	if c.Auth.LoginReportTTL <= 0 {
+		v.invalid("auth.login_report_ttl", "must be positive")
+	}
+	if !absoluteURL(c.Auth.LoginReportURL) {
+		v.invalid("auth.login_report_url", "must be an absolute URL")
+	}
+}
+
+func (c *Config) validateDatabase(v *validation) {
+	switch c.Database.Driver {
+	case "postgres":
+		required := []struct{ key, value string }{
+			{"database.host", c.Database.Host},
+			{"database.user", c.Database.User},
+			{"database.name", c.Database.Name},
+		}
+		for _, r := range required {
+			if r.value == "" {
+				v.invalid(r.key, "is required with the postgres driver")
+			}
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			v.invalid("database.port", "must be between 1 and 65535")
+		}
+	case "sqlite":
+		if c.Database.SQLitePath == "" {
+			v.invalid("database.sqlite_path", "is required with the sqlite driver")
+		}
+	default:
+		v.invalid("database.driver", "unsupported driver %q (postgres or sqlite)", c.Database.Driver)
+	}
+	if c.Database.MaxOpenConns < 1 {
+		v.invalid("database.max_open_conns", "must be at least 1")
+	}
+	if c.Database.MaxIdleConns < 0 {
+		v.invalid("database.max_idle_conns", "must not be negative")
+	}
+}
+
+func (c *Config) validateMail(v *validation) {
+	if _, err := mail.ParseAddress(c.Mail.From); err != nil {
+		v.invalid("mail.from", "must be an email address: %v", err)
+	}
+	if c.Mail.SMTPHost == "" {
+		return
+	}
+	if c.Mail.SMTPPort < 1 || c.Mail.SMTPPort > 65535 {
+		v.invalid("mail.smtp_port", "must be between 1 and 65535")
+	}
+	if c.Mail.SMTPPassword != "" && c.Mail.SMTPUsername == "" {
+		v.invalid("mail.smtp_username", "is required with mail.smtp_password")
+	}
+}
+
+func (c *Config) validateTracing(v *validation) {
+	switch c.Tracing.Exporter {
+	case "none", "stdout":
+	case "otlp":
+		if c.Tracing.Endpoint != "" && !absoluteURL(c.Tracing.Endpoint) {
+			v.invalid("tracing.otlp_endpoint", "must be an absolute URL")
+		}
+	default:
+		v.invalid("tracing.exporter", "unknown exporter %q (none, otlp or stdout)", c.Tracing.Exporter)
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		v.invalid("tracing.sample_ratio", "must be between 0 and 1")
+	}
+	if c.Tracing.ServiceName == "" {
+		v.invalid("tracing.service_name", "is required")
+	}
+}
+
+func (c *Config) validateAudit(v *validation) {
+	if f := c.Audit.FileFormat; f != "json" && f != "cef" {
+		v.invalid("audit.file_format", "unknown format %q (json or cef)", f)
+	}
+	if f := c.Audit.SyslogFormat; f != "json" && f != "cef" {
+		v.invalid("audit.syslog_format", "unknown format %q (json or cef)", f)
+	}
+	if c.Audit.Syslog != "" {
+		u, err := url.Parse(c.Audit.Syslog)
+		if err != nil || (u.Scheme != "udp" && u.Scheme != "tcp") || u.Hostname() == "" || u.Port() == "" {
+			v.invalid("audit.syslog", "must be udp://host:port or tcp://host:port")
+		}
+	}
+	if c.Audit.BufferSize <= 0 {
+		v.invalid("audit.buffer_size", "must be positive")
+	}
+}
+
+func (c *Config) validateWebhook(v *validation) {
+	if c.Webhook.PollInterval <= 0 {
+		v.invalid("webhook.poll_interval", "must be positive")
+	}
+	if c.Webhook.Timeout <= 0 {
+		v.invalid("webhook.timeout", "must be positive")
+	}
+	if c.Webhook.MaxAttempts < 1 {
+		v.invalid("webhook.max_attempts", "must be at least 1")
+	}
+	if c.Webhook.RetryBase <= 0 {
+		v.invalid("webhook.retry_base", "must be positive")
+	}
+}
+
+// describe nomme un réglage avec sa variable d'environnement : « auth.jwt_secret (JWT_SECRET) »
+func (c *Config) describe(key string) string {
+	for _, s := range c.settings() {
+		if s.key == key && s.env != "" {
+			return fmt.Sprintf("%s (%s)", key, s.env)
+		}
+	}
+	return key
+}
+
+// Write écrit la configuration effective, un réglage par ligne, en masquant les secrets
+func (c *Config) Write(w io.Writer) error {
+	for _, s := range c.settings() {
+		value := fmt.Sprint(s.value.Interface())
+		if s.secret && value != "" {
+			value = "********"
+		}
+		if _, err := fmt.Fprintf(w, "%-28s %s\n", s.key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Address retourne l'adresse d'écoute du serveur HTTP
+func (s Server) Address() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.Port)
+}
+
+// TLS indique si le serveur écoute en HTTPS
+func (s Server) TLS() bool {
+	return s.TLSCertFile != ""
+}
+
+func absoluteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"reflect"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// roles sont les rôles applicatifs (user.RoleUser et user.RoleAdmin, que config ne peut importer)
+var roles = []string{"user", "admin"}
+
+// providerName restreint les noms de fournisseurs à ce qui peut figurer dans une variable
+// d'environnement et dans les routes /auth/<nom>
+var providerName = regexp.MustCompile(`^[a-z][a-z0-9]*$`)
+
+// socialPresets fournit les valeurs par défaut des fournisseurs courants
+var socialPresets = map[string]SocialProvider{
+	"google": {
+		Type:      "oidc",
+		IssuerURL: "https://accounts.google.com",
+		Scopes:    "openid email profile",
+	},
+	"microsoft": {
+		Type:   "oidc",
+		Scopes: "openid email profile",
+	},
+	"keycloak": {
+		Type:   "oidc",
+		Scopes: "openid email profile",
+	},
+	"github": {
+		Type:        "github",
+		AuthURL:     "https://github.com/login/oauth/authorize",
+		TokenURL:    "https://github.com/login/oauth/access_token",
+		UserInfoURL: "https://api.github.com/user",
+		EmailsURL:   "https://api.github.com/user/emails",
+		Scopes:      "read:user user:email",
+	},
+}
+
+// GroupRole associe le cn d'un groupe de l'annuaire à un rôle applicatif
+type GroupRole struct {
+	Group string
+	Role  string
+}
+
+// Enabled indique si l'annuaire LDAP est configuré
+func (l LDAP) Enabled() bool {
+	return l.URL != ""
+}
+
+// GroupRoles décode ldap.role_mapping (« admins=admin,staff=user »)
+func (l LDAP) GroupRoles() ([]GroupRole, error) {
+	var mapping []GroupRole
+	for _, pair := range strings.Split(l.RoleMapping, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, found := strings.Cut(pair, "=")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !found || group == "" || role == "" {
+			return nil, fmt.Errorf("invalid entry %q", pair)
+		}
+		if !slices.Contains(roles, role) {
+			return nil, fmt.Errorf("invalid entry %q: unknown role %q", pair, role)
+		}
+		mapping = append(mapping, GroupRole{Group: group, Role: role})
+	}
+	return mapping, nil
+}
+
+// Enabled indique si le fournisseur de services SAML est configuré
+func (s SAML) Enabled() bool {
+	return s.BaseURL != ""
+}
+
+// Names retourne les fournisseurs activés, dans l'ordre de social.providers
+func (s Social) Names() []string {
+	var names []string
+	for _, name := range strings.Split(s.Providers, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// load crée la section de chaque fournisseur activé avec ses valeurs par défaut, puis lui
+// applique les valeurs lues dans le fichier path et les variables SOCIAL_<NOM>_*. Une valeur du
+// fichier pour un fournisseur non activé est signalée comme un réglage inconnu.
+func (s *Social) load(path string, values map[string]string) error {
+	s.Provider = make(map[string]*SocialProvider)
+	for _, name := range s.Names() {
+		p, ok := socialPresets[name]
+		if !ok {
+			p = SocialProvider{Type: "oidc", Scopes: "openid email profile"}
+		}
+		s.Provider[name] = &p
+	}
+
+	settings := s.settings()
+	if err := apply(path, values, settings); err != nil {
+		return err
+	}
+	if err := loadEnv(settings); err != nil {
+		return err
+	}
+
+	base := strings.TrimSuffix(s.RedirectBaseURL, "/")
+	for name, p := range s.Provider {
+		if p.RedirectURL == "" && base != "" {
+			p.RedirectURL = base + "/" + name + "/callback"
+		}
+	}
+	return nil
+}
+
+// settings énumère les réglages des fournisseurs activés : social.<nom>.client_id est lu dans
+// SOCIAL_<NOM>_CLIENT_ID
+func (s *Social) settings() []setting {
+	var settings []setting
+	for _, name := range s.Names() {
+		p, ok := s.Provider[name]
+		if !ok {
+			continue
+		}
+		for _, setting := range collect(reflect.ValueOf(p).Elem(), "social."+name+".") {
+			setting.env = "SOCIAL_" + strings.ToUpper(name) + "_" + setting.env
+			settings = append(settings, setting)
+		}
+	}
+	return settings
+}
+
+func (c *Config) validateLDAP(v *validation) {
+	if !c.LDAP.Enabled() {
+		return
+	}
+	if u, err := url.Parse(c.LDAP.URL); err != nil || (u.Scheme != "ldap" && u.Scheme != "ldaps") || u.Host == "" {
+		v.invalid("ldap.url", "must be ldap://host[:port] or ldaps://host[:port]")
+	}
+	if c.LDAP.BaseDN == "" {
+		v.invalid("ldap.base_dn", "is required with ldap.url")
+	}
+	if c.LDAP.BindPassword != "" && c.LDAP.BindDN == "" {
+		v.invalid("ldap.bind_dn", "is required with ldap.bind_password")
+	}
+	if !strings.Contains(c.LDAP.UserFilter, "%s") {
+		v.invalid("ldap.user_filter", "must contain %%s, replaced by the email")
+	}
+	if !strings.Contains(c.LDAP.GroupFilter, "%s") {
+		v.invalid("ldap.group_filter", "must contain %%s, replaced by the user DN")
+	}
+	if _, err := c.LDAP.GroupRoles(); err != nil {
+		v.invalid("ldap.role_mapping", "%v", err)
+	}
+	if !slices.Contains(roles, c.LDAP.DefaultRole) {
+		v.invalid("ldap.default_role", "unknown role %q (user or admin)", c.LDAP.DefaultRole)
+	}
+	if c.LDAP.Timeout <= 0 {
+		v.invalid("ldap.timeout", "must be positive")
+	}
+}
+
+func (c *Config) validateSAML(v *validation) {
+	if !c.SAML.Enabled() {
+		return
+	}
+	if !absoluteURL(c.SAML.BaseURL) {
+		v.invalid("saml.sp_base_url", "must be an absolute URL")
+	}
+	for _, f := range []struct{ key, path string }{
+		{"saml.sp_cert_file", c.SAML.CertFile},
+		{"saml.sp_key_file", c.SAML.KeyFile},
+	} {
+		if f.path == "" {
+			v.invalid(f.key, "is required with saml.sp_base_url")
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			v.invalid(f.key, "%v", err)
+		}
+	}
+}
+
+func (c *Config) validateSocial(v *validation) {
+	if c.Social.RedirectBaseURL != "" && !absoluteURL(c.Social.RedirectBaseURL) {
+		v.invalid("social.redirect_base_url", "must be an absolute URL")
+	}
+	for _, name := range c.Social.Names() {
+		key := "social." + name
+		p, ok := c.Social.Provider[name]
+		if !providerName.MatchString(name) || !ok {
+			v.invalid("social.providers", "invalid provider name %q (lowercase letters and digits)", name)
+			continue
+		}
+
+		var urls []struct{ key, value string }
+		switch p.Type {
+		case "oidc":
+			urls = []struct{ key, value string }{{"issuer", p.IssuerURL}}
+		case "github":
+			urls = []struct{ key, value string }{{"auth_url", p.AuthURL}, {"token_url", p.TokenURL}, {"userinfo_url", p.UserInfoURL}}
+		default:
+			v.invalid(key+".type", "unknown type %q (oidc or github)", p.Type)
+		}
+		for _, u := range urls {
+			if !absoluteURL(u.value) {
+				v.invalid(key+"."+u.key, "must be an absolute URL for a %s provider", p.Type)
+			}
+		}
+
+		if p.ClientID == "" {
+			v.invalid(key+".client_id", "is required")
+		}
+		switch {
+		case p.RedirectURL == "":
+			v.invalid(key+".redirect_url", "is required when social.redirect_base_url is not set")
+		case !absoluteURL(p.RedirectURL):
+			v.invalid(key+".redirect_url", "must be an absolute URL")
+		}
+	}
+}
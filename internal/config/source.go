@@ -0,0 +1,206 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// setting est un champ de Config, décrit par ses tags et modifiable à partir d'une chaîne
+type setting struct {
+	key    string
+	env    string
+	flag   string
+	help   string
+	secret bool
+	value  reflect.Value
+}
+
+// settings énumère les champs de la configuration, sections des fournisseurs d'identité comprises
+func (c *Config) settings() []setting {
+	return append(collect(reflect.ValueOf(c).Elem(), ""), c.Social.settings()...)
+}
+
+func collect(v reflect.Value, prefix string) []setting {
+	var settings []setting
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("key") == "-" {
+			continue
+		}
+		key := prefix + field.Tag.Get("key")
+		if field.Type.Kind() == reflect.Struct {
+			settings = append(settings, collect(v.Field(i), key+".")...)
+			continue
+		}
+		settings = append(settings, setting{
+			key:    key,
+			env:    field.Tag.Get("env"),
+			flag:   field.Tag.Get("flag"),
+			help:   field.Tag.Get("help"),
+			secret: field.Tag.Get("secret") == "true",
+			value:  v.Field(i),
+		})
+	}
+	return settings
+}
+
+// set convertit raw dans le type du champ : les durées s'écrivent au format Go (« 15m », « 2h »)
+func (s setting) set(raw string) error {
+	if s.value.Type() == reflect.TypeOf(time.Duration(0)) {
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q", raw)
+		}
+		s.value.SetInt(int64(d))
+		return nil
+	}
+
+	switch s.value.Kind() {
+	case reflect.String:
+		s.value.SetString(raw)
+	case reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", raw)
+		}
+		s.value.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", raw)
+		}
+		s.value.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", raw)
+		}
+		s.value.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported setting type %s", s.value.Type())
+	}
+	return nil
+}
+
+// loadFile applique un fichier YAML (.yaml, .yml) ou TOML (.toml). Une clé inconnue est une
+// erreur, pour ne pas ignorer silencieusement une faute de frappe. Les valeurs des sections
+// social.<nom>, qui ne sont pas encore connues, sont retournées pour Social.load.
+func loadFile(path string, settings []setting) (map[string]string, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading config file: %w", err)
+	}
+
+	var document map[string]interface{}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &document)
+	case ".toml":
+		err = toml.Unmarshal(raw, &document)
+	default:
+		return nil, fmt.Errorf("unsupported config file format %q (use .yaml, .yml or .toml)", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error parsing config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string)
+	flatten(document, "", values)
+
+	providers := make(map[string]string)
+	for key, value := range values {
+		if strings.HasPrefix(key, "social.") && !slices.ContainsFunc(settings, func(s setting) bool { return s.key == key }) {
+			providers[key] = value
+			delete(values, key)
+		}
+	}
+	if err := apply(path, values, settings); err != nil {
+		return nil, err
+	}
+	return providers, nil
+}
+
+// apply affecte les valeurs lues dans le fichier path aux réglages correspondants
+func apply(path string, values map[string]string, settings []setting) error {
+	byKey := make(map[string]setting, len(settings))
+	for _, s := range settings {
+		byKey[s.key] = s
+	}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("%s: unknown setting %q", path, key)
+		}
+		if err := s.set(values[key]); err != nil {
+			return fmt.Errorf("%s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// flatten aplatit les sections imbriquées en clés pointées (database.host)
+func flatten(document map[string]interface{}, prefix string, values map[string]string) {
+	for key, value := range document {
+		if section, ok := value.(map[string]interface{}); ok {
+			flatten(section, prefix+key+".", values)
+			continue
+		}
+		values[prefix+key] = fmt.Sprint(value)
+	}
+}
+
+// loadEnv applique les variables d'environnement non vides
+func loadEnv(settings []setting) error {
+	for _, s := range settings {
+		if s.env == "" {
+			continue
+		}
+		v, ok, err := lookupSecret(s.env)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			continue
+		}
+		if err := s.set(v); err != nil {
+			return fmt.Errorf("%s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+// lookupSecret lit la variable d'environnement key ou, si key_FILE est défini, le contenu du
+// fichier qu'elle désigne (secrets Docker ou Kubernetes). ok vaut false si aucune des deux n'est
+// renseignée ; définir les deux est une erreur.
+func lookupSecret(key string) (value string, ok bool, err error) {
+	value = os.Getenv(key)
+	path := os.Getenv(key + "_FILE")
+	switch {
+	case path != "" && value != "":
+		return "", false, fmt.Errorf("%s and %s_FILE are both set", key, key)
+	case path != "":
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("%s_FILE: %w", key, err)
+		}
+		return strings.TrimRight(string(raw), "\r\n"), true, nil
+	default:
+		return value, value != "", nil
+	}
+}
@@ -0,0 +1,49 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+)
+
+// Database vérifie que le pool obtient une connexion et que la base répond
+func Database(db *sql.DB) Check {
+	return func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	}
+}
+
+// Migrations échoue tant que des migrations embarquées ne sont pas appliquées, par exemple
+// lorsqu'une nouvelle version démarre avant la migration du schéma
+func Migrations(migrator *database.Migrator) Check {
+	return func(ctx context.Context) error {
+		pending, err := migrator.Pending(ctx)
+		if err != nil {
+			return err
+		}
+		if len(pending) > 0 {
+			return fmt.Errorf("%d pending migration(s), next is %04d_%s", len(pending), pending[0].Version, pending[0].Name)
+		}
+		return nil
+	}
+}
+
+// Mailer vérifie que les emails peuvent être envoyés
+func Mailer(m mail.Mailer) Check {
+	return m.Check
+}
+
+// SigningKey vérifie que le secret de signature des jetons est chargé
+func SigningKey(secret string) Check {
+	return func(context.Context) error {
+		if len(secret) < config.MinSecretLength {
+			return errors.New("jwt signing key not loaded")
+		}
+		return nil
+	}
+}
@@ -0,0 +1,131 @@
+// Package health expose l'état du service aux orchestrateurs : /livez indique que le processus
+// répond, /readyz qu'il peut recevoir du trafic, d'après un registre de contrôles nommés
+// (base de données, schéma, envoi d'emails…). Avec ?verbose, le détail de chaque contrôle est
+// retourné.
+package health
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Check retourne une erreur lorsque la dépendance contrôlée n'est pas disponible. Il doit
+// respecter l'échéance de ctx.
+type Check func(ctx context.Context) error
+
+// Result est le résultat d'un contrôle, tel qu'il apparaît en mode verbeux
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+const (
+	StatusOK   = "ok"
+	StatusFail = "fail"
+)
+
+type namedCheck struct {
+	name  string
+	check Check
+}
+
+// Registry regroupe les contrôles de disponibilité
+type Registry struct {
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	checks   []namedCheck
+	draining atomic.Bool
+}
+
+// NewRegistry construit un registre vide ; chaque contrôle dispose au plus de timeout
+func NewRegistry(timeout time.Duration) *Registry {
+	return &Registry{timeout: timeout}
+}
+
+// Register ajoute un contrôle, exécuté à chaque appel de /readyz
+func (r *Registry) Register(name string, check Check) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.checks = append(r.checks, namedCheck{name: name, check: check})
+}
+
+// Drain fait échouer /readyz à partir de maintenant : le service s'arrête et ne doit plus
+// recevoir de nouvelles requêtes
+func (r *Registry) Drain() {
+	r.draining.Store(true)
+}
+
+// Run exécute tous les contrôles en parallèle et retourne leurs résultats dans l'ordre
+// d'enregistrement
+func (r *Registry) Run(ctx context.Context) []Result {
+	r.mu.RLock()
+	checks := append([]namedCheck(nil), r.checks...)
+	r.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c namedCheck) {
+			defer wg.Done()
+			results[i] = r.run(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+	return results
+}
+
+func (r *Registry) run(ctx context.Context, c namedCheck) Result {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := c.check(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+	result := Result{Name: c.name, Status: StatusOK, Duration: time.Since(start).Round(time.Microsecond).String()}
+	if err != nil {
+		result.Status = StatusFail
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Live répond tant que le processus sert des requêtes
+func (r *Registry) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": StatusOK})
+}
+
+// Ready répond 200 si tous les contrôles réussissent, 503 sinon ou pendant l'arrêt
+func (r *Registry) Ready(c *gin.Context) {
+	status, code := StatusOK, http.StatusOK
+	body := gin.H{}
+
+	if r.draining.Load() {
+		status, code = StatusFail, http.StatusServiceUnavailable
+		body["reason"] = "shutting down"
+	} else {
+		results := r.Run(c.Request.Context())
+		for _, result := range results {
+			if result.Status != StatusOK {
+				status, code = StatusFail, http.StatusServiceUnavailable
+			}
+		}
+		if _, verbose := c.GetQuery("verbose"); verbose {
+			body["checks"] = results
+		}
+	}
+
+	body["status"] = status
+	c.Header("Cache-Control", "no-store")
+	c.JSON(code, body)
+}
@@ -0,0 +1,54 @@
+package i18n
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"path"
+	"text/template"
+)
+
+//go:embed templates/*.tmpl
+var templateFiles embed.FS
+
+// templates associe chaque fichier à son propre ensemble : tous définissent « subject » et « body »
+var templates = mustLoadTemplates()
+
+func mustLoadTemplates() map[string]*template.Template {
+	entries, err := templateFiles.ReadDir("templates")
+	if err != nil {
+		panic(err)
+	}
+	templates := make(map[string]*template.Template, len(entries))
+	for _, entry := range entries {
+		templates[entry.Name()] = template.Must(template.ParseFS(templateFiles, path.Join("templates", entry.Name())))
+	}
+	return templates
+}
+
+// Email est un message rendu, prêt à être envoyé
+type Email struct {
+	Subject string
+	Body    string
+}
+
+// RenderEmail rend le modèle name (par exemple « password_reset ») dans la langue demandée,
+// ou dans la langue par défaut lorsque le modèle n'est pas traduit
+func RenderEmail(locale, name string, data interface{}) (Email, error) {
+	tmpl, ok := templates[fmt.Sprintf("%s.%s.tmpl", name, locale)]
+	if !ok {
+		tmpl, ok = templates[fmt.Sprintf("%s.%s.tmpl", name, defaultLocale)]
+	}
+	if !ok {
+		return Email{}, fmt.Errorf("unknown email template %q", name)
+	}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Email{}, err
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return Email{}, err
+	}
+	return Email{Subject: subject.String(), Body: body.String()}, nil
+}
@@ -0,0 +1,138 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/text/language"
+)
+
+// Langues prises en charge
+const (
+	English = "en"
+	French  = "fr"
+	German  = "de"
+)
+
+// ContextKey est la clé du contexte gin où JWTAuth dépose la langue préférée de l'utilisateur
+const ContextKey = "locale"
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var (
+	catalogs = mustLoadCatalogs()
+
+	supported = []language.Tag{language.French, language.English, language.German}
+	matcher   = language.NewMatcher(supported)
+
+	defaultLocale = French
+)
+
+func mustLoadCatalogs() map[string]map[string]string {
+	entries, err := localeFiles.ReadDir("locales")
+	if err != nil {
+		panic(err)
+	}
+	catalogs := make(map[string]map[string]string, len(entries))
+	for _, entry := range entries {
+		raw, err := localeFiles.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			panic(err)
+		}
+		var messages map[string]string
+		if err := json.Unmarshal(raw, &messages); err != nil {
+			panic(fmt.Sprintf("invalid catalog %s: %v", entry.Name(), err))
+		}
+		catalogs[strings.TrimSuffix(entry.Name(), ".json")] = messages
+	}
+	return catalogs
+}
+
+// Supported indique si des catalogues existent pour la langue
+func Supported(locale string) bool {
+	_, ok := catalogs[locale]
+	return ok
+}
+
+// Locales retourne les langues prises en charge, triées
+func Locales() []string {
+	locales := make([]string, 0, len(catalogs))
+	for locale := range catalogs {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// SetDefault choisit la langue utilisée lorsque ni l'utilisateur ni la requête n'en indiquent
+func SetDefault(locale string) error {
+	if !Supported(locale) {
+		return fmt.Errorf("unsupported locale %q", locale)
+	}
+	defaultLocale = locale
+	return nil
+}
+
+// Default retourne la langue par défaut
+func Default() string {
+	return defaultLocale
+}
+
+// Negotiate choisit la langue la mieux adaptée à un en-tête Accept-Language
+// (« de-CH, en;q=0.8 » donne « de »), ou la langue par défaut si aucune ne convient
+func Negotiate(acceptLanguage string) string {
+	tags, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(tags) == 0 {
+		return defaultLocale
+	}
+	_, index, confidence := matcher.Match(tags...)
+	if confidence == language.No {
+		return defaultLocale
+	}
+	base, _ := supported[index].Base()
+	return base.String()
+}
+
+// FromContext retourne la langue de la requête : la préférence enregistrée de l'utilisateur
+// authentifié, sinon celle négociée à partir de l'en-tête Accept-Language
+func FromContext(c *gin.Context) string {
+	if locale := c.GetString(ContextKey); Supported(locale) {
+		return locale
+	}
+	return Negotiate(c.GetHeader("Accept-Language"))
+}
+
+// T traduit une clé du catalogue. Une clé absente de la langue demandée est cherchée dans la
+// langue par défaut puis en anglais ; à défaut, la clé elle-même est retournée.
+func T(locale, key string, args ...interface{}) string {
+	for _, l := range []string{locale, defaultLocale, English} {
+		if message, ok := catalogs[l][key]; ok {
+			if len(args) > 0 {
+				return fmt.Sprintf(message, args...)
+			}
+			return message
+		}
+	}
+	return key
+}
+
+// Localize traduit une clé dans la langue de la requête
+func Localize(c *gin.Context, key string, args ...interface{}) string {
+	return T(FromContext(c), key, args...)
+}
+
+// Keys retourne les clés du catalogue d'une langue, triées
+func Keys(locale string) []string {
+	keys := make([]string, 0, len(catalogs[locale]))
+	for key := range catalogs[locale] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -1,81 +1,109 @@
 package database
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
+	"time"
 
-	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/pathi14/AuthentificationGO/internal/config"
 )
 
-func init() {
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("No .env file found, using system environment variables")
+// Connect ouvre la base choisie par cfg.Driver : PostgreSQL ou un fichier SQLite
+func Connect(cfg config.Database) (*sql.DB, error) {
+	switch cfg.Driver {
+	case "postgres":
+	case "sqlite":
+		return openSQLite(cfg.SQLitePath, NewPoolConfig(cfg))
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
 	}
-}
 
-func ConnectDB() (*sql.DB, error) {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	pgConnStr := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname)
+	pgConnStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Name, cfg.SSLMode)
 
 	conn, err := sql.Open("postgres", pgConnStr)
 	if err != nil {
 		return nil, fmt.Errorf("error opening database connection: %w", err)
 	}
 
+	ConfigurePool(conn, NewPoolConfig(cfg))
+
 	err = conn.Ping()
 	if err != nil {
 		return nil, fmt.Errorf("error connecting to the database: %w", err)
 	}
 
-	err = CreateTableIfNotExists(conn)
+	slog.Info("connected to the database", "driver", "postgres")
+	return conn, nil
+}
+
+// ConnectSQLite ouvre (ou crée) le fichier SQLite path avec le pool de connexions par défaut
+func ConnectSQLite(path string) (*sql.DB, error) {
+	return openSQLite(path, NewPoolConfig(config.Default().Database))
+}
+
+// openSQLite active les clés étrangères et fait prendre aux transactions le verrou d'écriture
+// dès leur début : deux transactions concurrentes attendent leur tour (busy_timeout) au lieu
+// d'échouer en cours de route.
+func openSQLite(path string, pool PoolConfig) (*sql.DB, error) {
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate", path)
+
+	conn, err := sql.Open("sqlite3", dsn)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("error opening database connection: %w", err)
 	}
 
-	log.Println("Connected to the PostgreSQL database")
+	ConfigurePool(conn, pool)
+
+	if err := conn.Ping(); err != nil {
+		return nil, fmt.Errorf("error connecting to the database: %w", err)
+	}
+
+	slog.Info("connected to the database", "driver", "sqlite", "path", path)
 	return conn, nil
 }
 
-func CreateTableIfNotExists(db *sql.DB) error {
-	createUserTableQuery := `
-	CREATE TABLE IF NOT EXISTS users (
-		id SERIAL PRIMARY KEY,
-		name VARCHAR(100),
-		age INT,
-		mobile_number VARCHAR(20),
-		email VARCHAR(100) UNIQUE,
-		password VARCHAR(255)
-	);`
-
-	createBlacklistTableQuery := `
-	CREATE TABLE IF NOT EXISTS blacklisted_tokens (
-		token VARCHAR(255) PRIMARY KEY,
-		expiration TIMESTAMP NOT NULL
-	);`
-
-	_, err := db.Exec(createUserTableQuery)
-	if err != nil {
-		log.Printf("Error creating table: %v", err)
-		return fmt.Errorf("failed to create users table: %w", err)
+// PoolConfig règle le pool de connexions partagé par toute l'application
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// NewPoolConfig reprend les réglages du pool de la configuration de la base
+func NewPoolConfig(cfg config.Database) PoolConfig {
+	return PoolConfig{
+		MaxOpenConns:    cfg.MaxOpenConns,
+		MaxIdleConns:    cfg.MaxIdleConns,
+		ConnMaxLifetime: cfg.ConnMaxLifetime,
+		ConnMaxIdleTime: cfg.ConnMaxIdleTime,
 	}
+}
+
+func ConfigurePool(db *sql.DB, cfg PoolConfig) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
 
-	_, err = db.Exec(createBlacklistTableQuery)
+// Migrate applique les migrations embarquées qui ne le sont pas encore
+func Migrate(db *sql.DB) error {
+	migrator, err := NewMigrator(db)
 	if err != nil {
-		log.Printf("Error creating 'blacklisted_tokens' table: %v", err)
-		return fmt.Errorf("failed to create 'blacklisted_tokens' table: %w", err)
+		return err
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
 	}
 
-	log.Println("Table 'users' is ready.")
+	slog.Info("database schema is up to date")
 	return nil
 }
 
@@ -94,15 +122,17 @@ func ConnectTestDB() (*sql.DB, error) {
 		return nil, fmt.Errorf("erreur de connexion à la DB de test : %w", err)
 	}
 
+	ConfigurePool(db, NewPoolConfig(config.Default().Database))
+
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("échec de connexion à la DB de test : %w", err)
 	}
 
-	err = CreateTableIfNotExists(db)
+	err = Migrate(db)
 	if err != nil {
 		return nil, err
 	}
 
-	log.Println("✅ Connexion réussie à la DB de test")
+	slog.Info("connected to the test database")
 	return db, nil
 }
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"database/sql"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// Dialect désigne le moteur SQL derrière une connexion. Les requêtes des dépôts sont écrites
+// avec les paramètres PostgreSQL ($1, $2…) et réécrites par Rebind pour les autres moteurs.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite3"
+)
+
+var positionalParam = regexp.MustCompile(`\$(\d+)`)
+
+// DialectOf déduit le dialecte du pilote de la connexion
+func DialectOf(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(*sqlite3.SQLiteDriver); ok {
+		return SQLite
+	}
+	return Postgres
+}
+
+// System est le nom du moteur selon les conventions OpenTelemetry (attribut db.system)
+func (d Dialect) System() string {
+	if d == SQLite {
+		return "sqlite"
+	}
+	return "postgresql"
+}
+
+// Rebind adapte une requête écrite avec des paramètres $n : SQLite les attend sous la
+// forme ?n, ce qui conserve la correspondance avec l'ordre des arguments
+func (d Dialect) Rebind(query string) string {
+	if d != SQLite {
+		return query
+	}
+	return positionalParam.ReplaceAllString(query, "?$1")
+}
+
+// InList construit la liste de paramètres « ($first, $first+1, …) » d'une clause IN portant
+// sur n valeurs, pour les moteurs qui ne savent pas lier un tableau
+func InList(first, n int) string {
+	params := make([]string, n)
+	for i := range params {
+		params[i] = "$" + strconv.Itoa(first+i)
+	}
+	return "(" + strings.Join(params, ", ") + ")"
+}
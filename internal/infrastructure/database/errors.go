@@ -0,0 +1,30 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+)
+
+// uniqueViolation est le code SQLSTATE d'une violation de contrainte UNIQUE sous PostgreSQL
+const uniqueViolation = "23505"
+
+// MapError traduit les erreurs du pilote en erreurs métier : une violation d'unicité
+// (PostgreSQL 23505 ou contrainte UNIQUE / PRIMARY KEY de SQLite) devient apperror.ErrConflict.
+// L'erreur d'origine reste accessible avec errors.As.
+func MapError(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == uniqueViolation {
+		return fmt.Errorf("%w: %w", apperror.ErrConflict, err)
+	}
+
+	var sqliteErr sqlite3.Error
+	if errors.As(err, &sqliteErr) &&
+		(sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey) {
+		return fmt.Errorf("%w: %w", apperror.ErrConflict, err)
+	}
+	return err
+}
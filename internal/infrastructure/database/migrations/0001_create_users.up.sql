@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS users (
+	id SERIAL PRIMARY KEY,
+	name VARCHAR(100),
+	age INT,
+	mobile_number VARCHAR(20),
+	email VARCHAR(100) UNIQUE,
+	password VARCHAR(255)
+);
+
+CREATE TABLE IF NOT EXISTS blacklisted_tokens (
+	token VARCHAR(255) PRIMARY KEY,
+	expiration TIMESTAMP NOT NULL
+);
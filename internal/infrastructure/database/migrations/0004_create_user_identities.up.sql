@@ -0,0 +1,26 @@
+-- user_identities remplace social_accounts : les comptes existants y sont recopiés,
+-- et les utilisateurs sans identité reçoivent leur méthode de connexion par mot de passe
+CREATE TABLE IF NOT EXISTS user_identities (
+	id SERIAL PRIMARY KEY,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	provider VARCHAR(50) NOT NULL,
+	subject VARCHAR(255) NOT NULL,
+	email VARCHAR(100),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (provider, subject)
+);
+
+DO $$
+BEGIN
+	IF to_regclass('social_accounts') IS NOT NULL THEN
+		INSERT INTO user_identities (user_id, provider, subject, email, created_at)
+		SELECT user_id, provider, subject, email, created_at FROM social_accounts
+		ON CONFLICT (provider, subject) DO NOTHING;
+		DROP TABLE social_accounts;
+	END IF;
+END $$;
+
+INSERT INTO user_identities (user_id, provider, subject, email)
+SELECT u.id, 'password', u.id::text, u.email FROM users u
+WHERE NOT EXISTS (SELECT 1 FROM user_identities i WHERE i.user_id = u.id)
+ON CONFLICT (provider, subject) DO NOTHING;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS saml_connections (
+	tenant VARCHAR(40) PRIMARY KEY,
+	idp_entity_id VARCHAR(255) NOT NULL,
+	metadata TEXT NOT NULL,
+	attribute_mapping TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
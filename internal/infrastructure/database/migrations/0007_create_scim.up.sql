@@ -0,0 +1,33 @@
+-- Provisionnement SCIM : jeton par tenant, utilisateurs provisionnés et groupes
+ALTER TABLE users ADD COLUMN IF NOT EXISTS active BOOLEAN NOT NULL DEFAULT TRUE;
+
+CREATE TABLE IF NOT EXISTS scim_tokens (
+	tenant VARCHAR(40) PRIMARY KEY,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS scim_users (
+	tenant VARCHAR(40) NOT NULL,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	external_id VARCHAR(255),
+	PRIMARY KEY (tenant, user_id),
+	UNIQUE (tenant, external_id)
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+	id SERIAL PRIMARY KEY,
+	tenant VARCHAR(40) NOT NULL,
+	display_name VARCHAR(255) NOT NULL,
+	external_id VARCHAR(255),
+	role VARCHAR(50),
+	created_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	updated_at TIMESTAMP NOT NULL DEFAULT NOW(),
+	UNIQUE (tenant, display_name)
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+	group_id INT NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	PRIMARY KEY (group_id, user_id)
+);
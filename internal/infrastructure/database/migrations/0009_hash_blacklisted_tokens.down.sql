@@ -0,0 +1,4 @@
+-- Les empreintes ne peuvent pas être inversées : les entrées existantes sont conservées telles quelles
+DROP INDEX IF EXISTS blacklisted_tokens_expiration_idx;
+ALTER TABLE blacklisted_tokens ALTER COLUMN token_hash TYPE VARCHAR(255);
+ALTER TABLE blacklisted_tokens RENAME COLUMN token_hash TO token;
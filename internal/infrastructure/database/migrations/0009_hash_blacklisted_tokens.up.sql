@@ -0,0 +1,5 @@
+-- Les JWT portant des scopes dépassent 255 caractères : on ne stocke plus que leur empreinte SHA-256
+UPDATE blacklisted_tokens SET token = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+ALTER TABLE blacklisted_tokens RENAME COLUMN token TO token_hash;
+ALTER TABLE blacklisted_tokens ALTER COLUMN token_hash TYPE VARCHAR(64);
+CREATE INDEX IF NOT EXISTS blacklisted_tokens_expiration_idx ON blacklisted_tokens (expiration);
@@ -0,0 +1,31 @@
+-- Journal de sécurité en ajout seul : chaque ligne porte l'empreinte de la précédente
+-- (prev_hash). subject_id n'a pas de clé étrangère : les événements survivent au compte.
+CREATE TABLE IF NOT EXISTS audit_events (
+	id BIGSERIAL PRIMARY KEY,
+	created_at TIMESTAMP NOT NULL,
+	action VARCHAR(50) NOT NULL,
+	outcome VARCHAR(10) NOT NULL,
+	reason VARCHAR(50) NOT NULL DEFAULT '',
+	actor_type VARCHAR(20) NOT NULL,
+	actor_id VARCHAR(100) NOT NULL DEFAULT '',
+	subject_id INT,
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	request_id VARCHAR(100) NOT NULL DEFAULT '',
+	prev_hash VARCHAR(64) NOT NULL,
+	hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_subject_idx ON audit_events (subject_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+CREATE OR REPLACE FUNCTION audit_events_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER audit_events_no_update BEFORE UPDATE OR DELETE ON audit_events
+	FOR EACH ROW EXECUTE FUNCTION audit_events_append_only();
+CREATE TRIGGER audit_events_no_truncate BEFORE TRUNCATE ON audit_events
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_events_append_only();
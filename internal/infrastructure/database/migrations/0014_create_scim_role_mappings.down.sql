@@ -0,0 +1,2 @@
+ALTER TABLE groups ADD COLUMN IF NOT EXISTS role VARCHAR(50);
+DROP TABLE IF EXISTS scim_role_mappings;
@@ -0,0 +1,10 @@
+-- Les rôles des groupes SCIM ne sont plus choisis par le client SCIM du tenant : ils proviennent
+-- d'une correspondance nom de groupe → rôle configurée par un administrateur
+CREATE TABLE IF NOT EXISTS scim_role_mappings (
+	tenant VARCHAR(40) NOT NULL,
+	group_name VARCHAR(255) NOT NULL,
+	role VARCHAR(50) NOT NULL,
+	PRIMARY KEY (tenant, group_name)
+);
+
+ALTER TABLE groups DROP COLUMN IF EXISTS role;
@@ -0,0 +1 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS session_generation INT NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS blacklisted_tokens;
+DROP TABLE IF EXISTS users;
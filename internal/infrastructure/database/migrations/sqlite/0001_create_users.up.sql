@@ -0,0 +1,21 @@
+-- Schéma SQLite : état final des migrations PostgreSQL 0001, 0005, 0007 (colonne active),
+-- 0008 et 0009 pour les tables users et blacklisted_tokens
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	name VARCHAR(100),
+	age INT,
+	mobile_number VARCHAR(20),
+	email VARCHAR(100) UNIQUE,
+	password VARCHAR(255),
+	role VARCHAR(50) NOT NULL DEFAULT 'user',
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS blacklisted_tokens (
+	token_hash VARCHAR(64) PRIMARY KEY,
+	expiration TIMESTAMP NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS blacklisted_tokens_expiration_idx ON blacklisted_tokens (expiration);
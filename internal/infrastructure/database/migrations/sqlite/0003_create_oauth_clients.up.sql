@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS oauth_clients (
+	client_id VARCHAR(36) PRIMARY KEY,
+	owner_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	name VARCHAR(100) NOT NULL,
+	allowed_scopes TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_consents (
+	user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+	client_id VARCHAR(36) NOT NULL REFERENCES oauth_clients(client_id) ON DELETE CASCADE,
+	scopes TEXT NOT NULL,
+	granted_at TIMESTAMP NOT NULL,
+	PRIMARY KEY (user_id, client_id)
+);
@@ -0,0 +1,2 @@
+-- Équivalent SQLite de la migration PostgreSQL 0010
+ALTER TABLE users ADD COLUMN locale VARCHAR(5) NOT NULL DEFAULT '';
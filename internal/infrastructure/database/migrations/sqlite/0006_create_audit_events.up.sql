@@ -0,0 +1,29 @@
+-- Équivalent SQLite de la migration PostgreSQL 0011
+CREATE TABLE IF NOT EXISTS audit_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	created_at TIMESTAMP NOT NULL,
+	action VARCHAR(50) NOT NULL,
+	outcome VARCHAR(10) NOT NULL,
+	reason VARCHAR(50) NOT NULL DEFAULT '',
+	actor_type VARCHAR(20) NOT NULL,
+	actor_id VARCHAR(100) NOT NULL DEFAULT '',
+	subject_id INT,
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	request_id VARCHAR(100) NOT NULL DEFAULT '',
+	prev_hash VARCHAR(64) NOT NULL,
+	hash VARCHAR(64) NOT NULL UNIQUE
+);
+
+CREATE INDEX IF NOT EXISTS audit_events_subject_idx ON audit_events (subject_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_created_at_idx ON audit_events (created_at);
+
+CREATE TRIGGER IF NOT EXISTS audit_events_no_update BEFORE UPDATE ON audit_events
+BEGIN
+	SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
+
+CREATE TRIGGER IF NOT EXISTS audit_events_no_delete BEFORE DELETE ON audit_events
+BEGIN
+	SELECT RAISE(ABORT, 'audit_events is append-only');
+END;
@@ -0,0 +1,48 @@
+-- Équivalent SQLite de la migration PostgreSQL 0012
+CREATE TABLE IF NOT EXISTS outbox_events (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	event_id VARCHAR(36) NOT NULL UNIQUE,
+	type VARCHAR(50) NOT NULL,
+	payload TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL,
+	dispatched_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS outbox_events_pending_idx ON outbox_events (id) WHERE dispatched_at IS NULL;
+
+-- events est la liste des types d'événements séparés par des virgules (vide : tous)
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	url TEXT NOT NULL,
+	secret VARCHAR(64) NOT NULL,
+	events TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- status : pending (à livrer à next_attempt_at), delivered ou dead (tentatives épuisées)
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	endpoint_id INT NOT NULL REFERENCES webhook_endpoints (id) ON DELETE CASCADE,
+	event_id BIGINT NOT NULL REFERENCES outbox_events (id) ON DELETE CASCADE,
+	status VARCHAR(10) NOT NULL,
+	attempts INT NOT NULL DEFAULT 0,
+	next_attempt_at TIMESTAMP NOT NULL,
+	last_error TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL,
+	updated_at TIMESTAMP NOT NULL,
+	UNIQUE (endpoint_id, event_id)
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS webhook_deliveries_endpoint_idx ON webhook_deliveries (endpoint_id, id);
+
+CREATE TABLE IF NOT EXISTS webhook_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	delivery_id BIGINT NOT NULL REFERENCES webhook_deliveries (id) ON DELETE CASCADE,
+	attempted_at TIMESTAMP NOT NULL,
+	status_code INT NOT NULL DEFAULT 0,
+	error TEXT NOT NULL DEFAULT '',
+	duration_ms INT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS webhook_attempts_delivery_idx ON webhook_attempts (delivery_id, id);
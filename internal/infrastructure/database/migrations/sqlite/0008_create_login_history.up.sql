@@ -0,0 +1,25 @@
+-- Équivalent SQLite de la migration PostgreSQL 0013
+CREATE TABLE IF NOT EXISTS login_attempts (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	attempted_at TIMESTAMP NOT NULL,
+	success BOOLEAN NOT NULL,
+	reason VARCHAR(50) NOT NULL DEFAULT '',
+	ip VARCHAR(45) NOT NULL DEFAULT '',
+	network VARCHAR(50) NOT NULL DEFAULT '',
+	user_agent TEXT NOT NULL DEFAULT '',
+	device VARCHAR(64) NOT NULL DEFAULT '',
+	new_device BOOLEAN NOT NULL DEFAULT FALSE,
+	new_network BOOLEAN NOT NULL DEFAULT FALSE,
+	reported_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS login_attempts_user_idx ON login_attempts (user_id, id);
+
+-- Révocation de toutes les sessions d'un compte : les jetons portent la génération en vigueur à
+-- leur émission, ceux d'une génération antérieure sont refusés
+CREATE TABLE IF NOT EXISTS session_revocations (
+	user_id INT PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+	generation INT NOT NULL,
+	revoked_at TIMESTAMP NOT NULL
+);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS groups;
+DROP TABLE IF EXISTS scim_users;
+DROP TABLE IF EXISTS scim_tokens;
+DROP TABLE IF EXISTS saml_connections;
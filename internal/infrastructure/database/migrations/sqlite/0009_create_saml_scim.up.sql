@@ -0,0 +1,41 @@
+-- Équivalent SQLite des migrations PostgreSQL 0006 et 0007 (la colonne users.active est créée
+-- par 0001)
+CREATE TABLE IF NOT EXISTS saml_connections (
+	tenant VARCHAR(40) PRIMARY KEY,
+	idp_entity_id VARCHAR(255) NOT NULL,
+	metadata TEXT NOT NULL,
+	attribute_mapping TEXT NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scim_tokens (
+	tenant VARCHAR(40) PRIMARY KEY,
+	token_hash VARCHAR(64) NOT NULL UNIQUE,
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS scim_users (
+	tenant VARCHAR(40) NOT NULL,
+	user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	external_id VARCHAR(255),
+	PRIMARY KEY (tenant, user_id),
+	UNIQUE (tenant, external_id)
+);
+
+CREATE TABLE IF NOT EXISTS groups (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	tenant VARCHAR(40) NOT NULL,
+	display_name VARCHAR(255) NOT NULL,
+	external_id VARCHAR(255),
+	role VARCHAR(50),
+	created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (tenant, display_name)
+);
+
+CREATE TABLE IF NOT EXISTS group_members (
+	group_id INT NOT NULL REFERENCES groups (id) ON DELETE CASCADE,
+	user_id INT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	PRIMARY KEY (group_id, user_id)
+);
@@ -0,0 +1,2 @@
+ALTER TABLE groups ADD COLUMN role VARCHAR(50);
+DROP TABLE IF EXISTS scim_role_mappings;
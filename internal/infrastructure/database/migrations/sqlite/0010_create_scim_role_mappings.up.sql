@@ -0,0 +1,9 @@
+-- Équivalent SQLite de la migration PostgreSQL 0014
+CREATE TABLE IF NOT EXISTS scim_role_mappings (
+	tenant VARCHAR(40) NOT NULL,
+	group_name VARCHAR(255) NOT NULL,
+	role VARCHAR(50) NOT NULL,
+	PRIMARY KEY (tenant, group_name)
+);
+
+ALTER TABLE groups DROP COLUMN role;
@@ -0,0 +1,2 @@
+-- Équivalent SQLite de la migration PostgreSQL 0015
+ALTER TABLE api_keys ADD COLUMN session_generation INT NOT NULL DEFAULT 0;
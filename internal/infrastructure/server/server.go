@@ -0,0 +1,112 @@
+// Package server construit le serveur HTTP de l'API : délais et limites de taille, arrêt
+// propre sur annulation du contexte et HTTPS avec rechargement à chaud du certificat.
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+)
+
+// New construit le serveur avec les délais et la taille d'en-têtes de la configuration
+func New(cfg config.Server, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              cfg.Address(),
+		Handler:           handler,
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+	}
+}
+
+// Run écoute sur l'adresse de la configuration jusqu'à l'annulation de ctx
+func Run(ctx context.Context, cfg config.Server, handler http.Handler) error {
+	ln, err := net.Listen("tcp", cfg.Address())
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", cfg.Address(), err)
+	}
+	return Serve(ctx, ln, cfg, handler)
+}
+
+// Serve sert les requêtes reçues sur ln jusqu'à l'annulation de ctx, puis arrête le serveur :
+// les nouvelles connexions sont refusées et les requêtes en cours disposent de
+// cfg.ShutdownTimeout pour se terminer. Serve retourne nil après un arrêt complet.
+func Serve(ctx context.Context, ln net.Listener, cfg config.Server, handler http.Handler) error {
+	srv := New(cfg, handler)
+
+	if cfg.TLS() {
+		certs, err := NewCertReloader(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			ln.Close()
+			return err
+		}
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.GetCertificate,
+		}
+
+		watchCtx, stopWatch := context.WithCancel(ctx)
+		defer stopWatch()
+		go certs.Watch(watchCtx, cfg.TLSReloadInterval)
+		go reloadOnHangup(watchCtx, certs)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		if cfg.TLS() {
+			// Les fichiers sont fournis par GetCertificate
+			serveErr <- srv.ServeTLS(ln, "", "")
+		} else {
+			serveErr <- srv.Serve(ln)
+		}
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("error serving HTTP: %w", err)
+	case <-ctx.Done():
+	}
+
+	slog.Info("shutting down, draining in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		srv.Close()
+		return fmt.Errorf("error draining HTTP connections: %w", err)
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error serving HTTP: %w", err)
+	}
+	return nil
+}
+
+// reloadOnHangup relit le certificat à chaque SIGHUP, comme après un renouvellement
+func reloadOnHangup(ctx context.Context, certs *CertReloader) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			if err := certs.Reload(); err != nil {
+				slog.Error("error reloading TLS certificate", "error", err)
+			} else {
+				slog.Info("TLS certificate reloaded")
+			}
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package ldapauth
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Provider est le nom sous lequel les identités d'annuaire sont rattachées aux comptes
+const Provider = "ldap"
+
+// Authenticator authentifie les utilisateurs d'un annuaire LDAP / Active Directory par
+// recherche puis bind : le compte de service retrouve le DN à partir de l'email, puis un
+// bind avec ce DN et le mot de passe fourni vérifie les identifiants.
+type Authenticator struct {
+	cfg Config
+}
+
+func NewAuthenticator(cfg Config) *Authenticator {
+	return &Authenticator{cfg: cfg}
+}
+
+func (a *Authenticator) Name() string {
+	return Provider
+}
+
+func (a *Authenticator) Authenticate(ctx context.Context, email, password string) (_ *user.AuthResult, err error) {
+	_, span := tracing.Start(ctx, "ldap.Authenticate", attribute.String("ldap.url", a.cfg.URL))
+	defer func() { tracing.End(span, err) }()
+
+	// Un bind avec un mot de passe vide est un bind anonyme qui réussit sur la plupart des annuaires
+	if password == "" {
+		return nil, fmt.Errorf("%w: invalid password", apperror.ErrUnauthorized)
+	}
+
+	conn, err := a.dial()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if err := a.bindService(conn); err != nil {
+		return nil, err
+	}
+
+	idAttribute := a.cfg.IDAttribute
+	attributes := []string{a.cfg.EmailAttribute, a.cfg.NameAttribute}
+	if idAttribute != "" {
+		attributes = append(attributes, idAttribute)
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.UserFilter, ldap.EscapeFilter(email)), attributes, nil))
+	if err != nil {
+		return nil, fmt.Errorf("ldap user search failed: %w", err)
+	}
+	if len(res.Entries) == 0 {
+		return nil, fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	if len(res.Entries) > 1 {
+		return nil, fmt.Errorf("ldap user search returned several entries for %s", email)
+	}
+	entry := res.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, fmt.Errorf("%w: invalid password", apperror.ErrUnauthorized)
+		}
+		return nil, fmt.Errorf("ldap user bind failed: %w", err)
+	}
+
+	// Retour au compte de service : l'utilisateur n'a pas forcément le droit de lire les groupes
+	if err := a.bindService(conn); err != nil {
+		return nil, err
+	}
+	role, err := a.lookupRole(conn, entry.DN)
+	if err != nil {
+		return nil, err
+	}
+
+	subject := entry.DN
+	if idAttribute != "" && entry.GetAttributeValue(idAttribute) != "" {
+		subject = entry.GetAttributeValue(idAttribute)
+	}
+	mail := entry.GetAttributeValue(a.cfg.EmailAttribute)
+	if mail == "" {
+		mail = email
+	}
+
+	return &user.AuthResult{
+		Provider: Provider,
+		Subject:  subject,
+		Email:    mail,
+		Name:     entry.GetAttributeValue(a.cfg.NameAttribute),
+		Role:     role,
+	}, nil
+}
+
+func (a *Authenticator) lookupRole(conn *ldap.Conn, userDN string) (string, error) {
+	if len(a.cfg.RoleMapping) == 0 {
+		return a.cfg.DefaultRole, nil
+	}
+
+	res, err := conn.Search(ldap.NewSearchRequest(
+		a.cfg.GroupBaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 0, int(a.cfg.Timeout.Seconds()), false,
+		fmt.Sprintf(a.cfg.GroupFilter, ldap.EscapeFilter(userDN)), []string{"cn"}, nil))
+	if err != nil {
+		return "", fmt.Errorf("ldap group search failed: %w", err)
+	}
+
+	groups := make(map[string]bool, len(res.Entries))
+	for _, e := range res.Entries {
+		groups[strings.ToLower(e.GetAttributeValue("cn"))] = true
+	}
+	for _, m := range a.cfg.RoleMapping {
+		if groups[strings.ToLower(m.Group)] {
+			return m.Role, nil
+		}
+	}
+	return a.cfg.DefaultRole, nil
+}
+
+func (a *Authenticator) dial() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(a.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: a.cfg.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("ldap connection failed: %w", err)
+	}
+	conn.SetTimeout(a.cfg.Timeout)
+
+	if a.cfg.StartTLS {
+		u, _ := url.Parse(a.cfg.URL)
+		if err := conn.StartTLS(&tls.Config{ServerName: u.Hostname()}); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("ldap starttls failed: %w", err)
+		}
+	}
+	return conn, nil
+}
+
+func (a *Authenticator) bindService(conn *ldap.Conn) error {
+	if a.cfg.BindDN == "" {
+		return nil
+	}
+	if err := conn.Bind(a.cfg.BindDN, a.cfg.BindPassword); err != nil {
+		return fmt.Errorf("ldap service bind failed: %w", err)
+	}
+	return nil
+}
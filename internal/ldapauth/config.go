@@ -0,0 +1,64 @@
+package ldapauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+)
+
+// RoleMapping associe le cn d'un groupe de l'annuaire à un rôle applicatif
+type RoleMapping struct {
+	Group string
+	Role  string
+}
+
+type Config struct {
+	URL          string
+	StartTLS     bool
+	BindDN       string
+	BindPassword string
+
+	BaseDN         string
+	UserFilter     string // %s est remplacé par l'email échappé
+	IDAttribute    string // attribut stable servant de sujet ; le DN à défaut
+	EmailAttribute string
+	NameAttribute  string
+
+	GroupBaseDN string
+	GroupFilter string // %s est remplacé par le DN de l'utilisateur échappé
+	RoleMapping []RoleMapping
+	DefaultRole string
+
+	Timeout time.Duration
+}
+
+// NewConfig construit la configuration de l'authentificateur à partir de la section ldap de la
+// configuration, déjà validée
+func NewConfig(c config.LDAP) (Config, error) {
+	groupRoles, err := c.GroupRoles()
+	if err != nil {
+		return Config{}, fmt.Errorf("ldap.role_mapping: %w", err)
+	}
+	mapping := make([]RoleMapping, 0, len(groupRoles))
+	for _, gr := range groupRoles {
+		mapping = append(mapping, RoleMapping{Group: gr.Group, Role: gr.Role})
+	}
+
+	return Config{
+		URL:            c.URL,
+		StartTLS:       c.StartTLS,
+		BindDN:         c.BindDN,
+		BindPassword:   c.BindPassword,
+		BaseDN:         c.BaseDN,
+		UserFilter:     c.UserFilter,
+		IDAttribute:    c.IDAttribute,
+		EmailAttribute: c.EmailAttribute,
+		NameAttribute:  c.NameAttribute,
+		GroupBaseDN:    c.GroupBaseDN,
+		GroupFilter:    c.GroupFilter,
+		RoleMapping:    mapping,
+		DefaultRole:    c.DefaultRole,
+		Timeout:        c.Timeout,
+	}, nil
+}
@@ -0,0 +1,47 @@
+// Package logging configure les journaux structurés (log/slog, JSON) de l'application. Chaque
+// requête porte dans son contexte un logger enrichi de son identifiant, transmis aux services et
+// aux dépôts. Les données personnelles et les secrets sont masqués avant l'écriture.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+)
+
+type contextKey struct{}
+
+// New construit un logger JSON de niveau minimal level, qui masque les données sensibles
+func New(w io.Writer, level slog.Leveler) *slog.Logger {
+	return slog.New(slog.NewJSONHandler(w, &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: redactAttr,
+	}))
+}
+
+// ParseLevel convertit un niveau (« debug », « info », « warn », « error »)
+func ParseLevel(raw string) (slog.Level, error) {
+	var level slog.Level
+	err := level.UnmarshalText([]byte(raw))
+	return level, err
+}
+
+// WithLogger retourne un contexte qui transporte logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, logger)
+}
+
+// FromContext retourne le logger de la requête, ou le logger par défaut hors requête
+func FromContext(ctx context.Context) *slog.Logger {
+	if ctx != nil {
+		if logger, ok := ctx.Value(contextKey{}).(*slog.Logger); ok {
+			return logger
+		}
+	}
+	return slog.Default()
+}
+
+// Discard est un logger qui n'écrit rien, pour les tests
+func Discard() *slog.Logger {
+	return slog.New(slog.NewJSONHandler(io.Discard, nil))
+}
@@ -0,0 +1,60 @@
+package logging
+
+import (
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted remplace la valeur d'un attribut sensible
+const Redacted = "[REDACTED]"
+
+// secretKeys sont les attributs dont la valeur n'est jamais écrite, quel que soit le groupe
+var secretKeys = map[string]bool{
+	"password":      true,
+	"new_password":  true,
+	"token":         true,
+	"access_token":  true,
+	"refresh_token": true,
+	"id_token":      true,
+	"authorization": true,
+	"cookie":        true,
+	"set-cookie":    true,
+	"secret":        true,
+	"client_secret": true,
+	"api_key":       true,
+}
+
+var (
+	emailPattern = regexp.MustCompile(`([A-Za-z0-9._%+\-])[A-Za-z0-9._%+\-]*@([A-Za-z0-9.\-]+\.[A-Za-z]{2,})`)
+	// jetons JWT, en-têtes Bearer et clés d'API (agk_…) qui se glisseraient dans un message
+	tokenPattern = regexp.MustCompile(`eyJ[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]+\.[A-Za-z0-9_\-]*|(?i:bearer)\s+[A-Za-z0-9\-._~+/]{16,}=*|agk_[A-Za-z0-9_]+`)
+)
+
+// Email masque une adresse en ne gardant que sa première lettre et son domaine :
+// « ada@example.com » devient « a***@example.com »
+func Email(addr string) string {
+	return emailPattern.ReplaceAllString(addr, "$1***@$2")
+}
+
+// RedactString masque les adresses email et les jetons contenus dans un texte libre (messages
+// d'erreur notamment)
+func RedactString(s string) string {
+	return Email(tokenPattern.ReplaceAllString(s, Redacted))
+}
+
+func redactAttr(_ []string, a slog.Attr) slog.Attr {
+	if secretKeys[strings.ToLower(a.Key)] {
+		return slog.String(a.Key, Redacted)
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactString(a.Value.String()))
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			return slog.String(a.Key, RedactString(err.Error()))
+		}
+	}
+	return a
+}
@@ -0,0 +1,179 @@
+// Package mail envoie les emails de l'application (liens de réinitialisation, notifications)
+// par SMTP ou, en développement, sur la sortie standard.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Message est un email en texte brut
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer envoie les emails. Check vérifie que l'envoi est possible (contrôle de disponibilité).
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+	Check(ctx context.Context) error
+}
+
+// New retourne le Mailer de la configuration : SMTP si un serveur est renseigné, sinon la
+// sortie standard w
+func New(cfg config.Mail, w io.Writer) Mailer {
+	if cfg.SMTPHost == "" {
+		return NewLogMailer(w)
+	}
+	return NewSMTPMailer(cfg)
+}
+
+// LogMailer écrit les emails au lieu de les envoyer
+type LogMailer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewLogMailer(w io.Writer) *LogMailer {
+	return &LogMailer{w: w}
+}
+
+func (m *LogMailer) Send(_ context.Context, msg Message) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, err := fmt.Fprintf(m.w, "To: %s\nSubject: %s\nBody: %s\n", msg.To, msg.Subject, msg.Body)
+	return err
+}
+
+func (m *LogMailer) Check(context.Context) error {
+	return nil
+}
+
+// SMTPMailer envoie les emails par SMTP, avec STARTTLS lorsque le serveur le propose
+type SMTPMailer struct {
+	cfg  config.Mail
+	addr string
+}
+
+func NewSMTPMailer(cfg config.Mail) *SMTPMailer {
+	return &SMTPMailer{cfg: cfg, addr: net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, msg Message) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "smtp.Send", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attribute.String("server.address", m.cfg.SMTPHost), attribute.Int("server.port", m.cfg.SMTPPort)))
+	defer func() { tracing.End(span, err) }()
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if m.cfg.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", m.cfg.SMTPUsername, m.cfg.SMTPPassword, m.cfg.SMTPHost)
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("smtp authentication failed: %w", err)
+		}
+	}
+
+	from := envelopeAddress(m.cfg.From)
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("smtp MAIL FROM refused: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("smtp RCPT TO refused: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("smtp DATA refused: %w", err)
+	}
+	if _, err := io.WriteString(w, m.format(msg)); err != nil {
+		return fmt.Errorf("error writing email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("error sending email: %w", err)
+	}
+	return client.Quit()
+}
+
+// Check ouvre une session SMTP puis la referme sans rien envoyer
+func (m *SMTPMailer) Check(ctx context.Context) error {
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if err := client.Noop(); err != nil {
+		return fmt.Errorf("smtp server not responding: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial ouvre la connexion en respectant l'échéance de ctx et passe en TLS si possible
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", m.addr)
+	if err != nil {
+		return nil, fmt.Errorf("error connecting to smtp server: %w", err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	} else {
+		conn.SetDeadline(time.Now().Add(30 * time.Second))
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("error connecting to smtp server: %w", err)
+	}
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(nil); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("smtp STARTTLS failed: %w", err)
+		}
+	}
+	return client, nil
+}
+
+func (m *SMTPMailer) format(msg Message) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mimeHeader(msg.Subject))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	b.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(msg.Body, "\n", "\r\n"))
+	return b.String()
+}
+
+// envelopeAddress extrait l'adresse seule de « Nom <adresse> » pour la commande MAIL FROM
+func envelopeAddress(from string) string {
+	addr, err := netmail.ParseAddress(from)
+	if err != nil {
+		return from
+	}
+	return addr.Address
+}
+
+// mimeHeader encode un en-tête non ASCII (sujets traduits)
+func mimeHeader(value string) string {
+	return mime.QEncoding.Encode("utf-8", value)
+}
@@ -0,0 +1,190 @@
+// Package metrics expose les métriques Prometheus du service : requêtes HTTP, parcours
+// d'authentification, hachage des mots de passe, pool de connexions et liste de révocation.
+// Les compteurs sont globaux, comme slog.Default, pour que les services les incrémentent sans
+// dépendance supplémentaire ; ils sont enregistrés dans Registry, servi par Handler.
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "authgo"
+
+// Résultats et motifs d'échec utilisés comme valeurs de labels
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+
+	ReasonInvalidCredentials = "invalid_credentials"
+	ReasonUnknownUser        = "unknown_user"
+	ReasonAccountLocked      = "account_locked"
+	ReasonLinkRequired       = "link_required"
+	ReasonConsentRequired    = "consent_required"
+	ReasonInvalidRequest     = "invalid_request"
+	ReasonInvalidToken       = "invalid_token"
+	ReasonTokenReused        = "token_reused"
+	ReasonInternal           = "internal_error"
+)
+
+// Registry regroupe les métriques de l'application, du runtime Go et du processus
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests mesure la durée des requêtes par route (modèle du routeur, /users/:id, et
+	// non le chemin reçu, pour borner le nombre de séries) et par statut
+	HTTPRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Durée des requêtes HTTP par méthode, route et statut.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// Logins compte les tentatives de connexion par mot de passe, par résultat et motif d'échec
+	Logins = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "logins_total",
+		Help:      "Tentatives de connexion par résultat et motif d'échec.",
+	}, []string{"result", "reason"})
+
+	// Registrations compte les comptes créés, par méthode de connexion initiale
+	Registrations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "registrations_total",
+		Help:      "Comptes créés par méthode de connexion initiale.",
+	}, []string{"provider"})
+
+	// TokenRefreshes compte les renouvellements de jetons par résultat et motif d'échec
+	TokenRefreshes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "token_refreshes_total",
+		Help:      "Renouvellements de jetons par résultat et motif d'échec.",
+	}, []string{"result", "reason"})
+
+	// PasswordResets compte les liens de réinitialisation envoyés (requested) et les mots de
+	// passe effectivement remplacés (completed)
+	PasswordResets = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "password_resets_total",
+		Help:      "Réinitialisations de mot de passe par étape.",
+	}, []string{"stage"})
+
+	// Lockouts compte les comptes actifs désactivés (administration, provisionnement SCIM)
+	Lockouts = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "account_lockouts_total",
+		Help:      "Comptes actifs désactivés.",
+	})
+
+	// PasswordHashing mesure le coût de bcrypt, au hachage comme à la vérification
+	PasswordHashing = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "password_hash_duration_seconds",
+		Help:      "Durée des opérations bcrypt.",
+		Buckets:   []float64{.01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	// LoginAlerts compte les alertes de connexion inhabituelle envoyées (sent) et celles que
+	// l'utilisateur a signalées comme frauduleuses (reported)
+	LoginAlerts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "login_alerts_total",
+		Help:      "Alertes de connexion inhabituelle par étape.",
+	}, []string{"stage"})
+
+	// AuditExports compte les événements d'audit par destination d'export : transmis (exported)
+	// ou abandonnés parce que la file d'attente était pleine (dropped)
+	AuditExports = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_export_events_total",
+		Help:      "Événements d'audit exportés ou abandonnés, par destination.",
+	}, []string{"exporter", "result"})
+
+	// AuditExportErrors compte les écritures refusées par une destination, chacune réessayée
+	AuditExportErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_export_errors_total",
+		Help:      "Échecs d'écriture vers une destination d'export d'audit.",
+	}, []string{"exporter"})
+
+	// WebhookAttempts compte les tentatives de livraison des webhooks : réussies (delivered),
+	// à réessayer (failed) ou dernière tentative échouée (dead)
+	WebhookAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_attempts_total",
+		Help:      "Tentatives de livraison des webhooks par résultat.",
+	}, []string{"result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests, Logins, Registrations, TokenRefreshes, PasswordResets, Lockouts, PasswordHashing, LoginAlerts,
+		AuditExports, AuditExportErrors, WebhookAttempts,
+	)
+}
+
+// ObserveHashing enregistre la durée d'une opération bcrypt commencée à start
+func ObserveHashing(operation string, start time.Time) {
+	PasswordHashing.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// RegisterDB expose les statistiques du pool de connexions (connexions ouvertes, en attente…)
+func RegisterDB(db *sql.DB) error {
+	return Registry.Register(collectors.NewDBStatsCollector(db, "main"))
+}
+
+// RegisterBlacklistSize expose le nombre de jetons révoqués, compté à chaque collecte
+func RegisterBlacklistSize(count func(ctx context.Context) (int64, error)) error {
+	return Registry.Register(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "blacklisted_tokens",
+		Help:      "Jetons révoqués conservés jusqu'à leur expiration.",
+	}, func() float64 {
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		n, err := count(ctx)
+		if err != nil {
+			slog.Error("error counting blacklisted tokens", "error", err)
+			return math.NaN()
+		}
+		return float64(n)
+	}))
+}
+
+// Handler sert les métriques au format d'exposition Prometheus
+func Handler() gin.HandlerFunc {
+	h := promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+	return func(c *gin.Context) {
+		h.ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// LoginSucceeded compte une connexion réussie
+func LoginSucceeded() {
+	Logins.WithLabelValues(ResultSuccess, "").Inc()
+}
+
+// LoginFailed compte une connexion refusée pour reason
+func LoginFailed(reason string) {
+	Logins.WithLabelValues(ResultFailure, reason).Inc()
+}
+
+// RefreshSucceeded compte un renouvellement de jetons réussi
+func RefreshSucceeded() {
+	TokenRefreshes.WithLabelValues(ResultSuccess, "").Inc()
+}
+
+// RefreshFailed compte un renouvellement refusé pour reason
+func RefreshFailed(reason string) {
+	TokenRefreshes.WithLabelValues(ResultFailure, reason).Inc()
+}
@@ -1,51 +1,94 @@
 package middleware
 
 import (
-	"database/sql"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v4"
-	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
 )
 
-// JWTAuth vérifie le token JWT et extrait l'ID utilisateur
-func JWTAuth() gin.HandlerFunc {
+// APIKeyAuthenticator valide les clés d'API longue durée présentées à la place d'un JWT
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, key string) (int, []string, error)
+}
+
+// TokenBlacklist indique si un JWT a été révoqué avant son expiration (déconnexion, refresh),
+// seul ou avec toutes les sessions de son compte
+type TokenBlacklist interface {
+	Contains(ctx context.Context, token string) (bool, error)
+	SessionGeneration(ctx context.Context, userID int) (int, error)
+}
+
+// JWTAuth vérifie le token JWT signé avec secret (ou la clé d'API si keys est fourni) et extrait
+// l'ID utilisateur. Les jetons présents dans tokens, ou émis avant la révocation de toutes
+// les sessions du compte (claim « gen » antérieur à sa génération), sont refusés.
+func JWTAuth(secret string, tokens TokenBlacklist, keys APIKeyAuthenticator) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Récupérer le token depuis l'en-tête Authorization
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header is required"})
-			c.Abort() // Important : arrête l'exécution des middlewares suivants
+			// Important : Abort arrête l'exécution des middlewares suivants
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "auth.header_required")
 			return
 		}
 
 		// Format Bearer token
 		tokenString := strings.Replace(authHeader, "Bearer ", "", 1)
-		if IsTokenBlacklisted(tokenString) {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalid or expired"})
-			c.Abort()
+		if keys != nil && apikey.IsAPIKey(tokenString) {
+			userID, scopes, err := keys.Authenticate(c.Request.Context(), tokenString)
+			if errors.Is(err, apperror.ErrUnauthorized) || errors.Is(err, apperror.ErrNotFound) {
+				problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidAPIKey, "auth.invalid_api_key")
+				return
+			}
+			if err != nil {
+				// La clé n'a pas pu être vérifiée (base indisponible) : elle n'est pas déclarée invalide
+				problem.Abort(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "auth.token_unverifiable")
+				return
+			}
+			c.Set("userID", userID)
+			c.Set("authMethod", "api_key")
+			c.Set("scopes", scopes)
+			withUser(c, userID, "api_key")
+			c.Next()
 			return
 		}
 
+		if tokens != nil {
+			revoked, err := tokens.Contains(c.Request.Context(), tokenString)
+			if err != nil {
+				problem.Abort(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "auth.token_unverifiable")
+				return
+			}
+			if revoked {
+				problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_invalid_or_expired")
+				return
+			}
+		}
+
 		// Valider et décoder le token
-		secretKey := os.Getenv("JWT_SECRET")
 		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 			// Vérifier l'algorithme de signature
 			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 			}
 			// Retourner la clé secrète pour la vérification
-			return []byte(secretKey), nil // Stockez cette clé dans une variable d'environnement
+			return []byte(secret), nil
 		})
 
 		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token: " + err.Error()})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_invalid")
 			return
 		}
 
@@ -53,64 +96,54 @@ func JWTAuth() gin.HandlerFunc {
 		if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 			exp, ok := claims["exp"].(float64)
 			if !ok {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token expiration"})
-				c.Abort()
+				problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_expiration_invalid")
 				return
 			}
 			// Vérifie si le token est expiré
 			expirationTime := time.Unix(int64(exp), 0)
 			if time.Now().After(expirationTime) {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Token has expired"})
-				c.Abort()
+				problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_expired")
 				return
 			}
 			userIDFloat, ok := claims["user_id"].(float64)
 			if !ok {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID in token"})
-				c.Abort()
+				problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_subject_invalid")
 				return
 			}
 
 			userID := int(userIDFloat)
+			if tokens != nil {
+				generation, err := tokens.SessionGeneration(c.Request.Context(), userID)
+				if err != nil {
+					problem.Abort(c, http.StatusServiceUnavailable, problem.CodeUnavailable, "auth.token_unverifiable")
+					return
+				}
+				if gen, _ := claims["gen"].(float64); int(gen) < generation {
+					problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_invalid_or_expired")
+					return
+				}
+			}
+			scope, _ := claims["scope"].(string)
 			c.Set("userID", userID) // Stocke l'ID utilisateur dans le contexte
+			c.Set("authMethod", "jwt")
+			c.Set("scopes", oauth.ParseScope(scope))
+			if locale, ok := claims["locale"].(string); ok {
+				c.Set(i18n.ContextKey, locale)
+			}
+			withUser(c, userID, "jwt")
 			c.Next()
 		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
-			c.Abort()
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeInvalidToken, "auth.token_claims_invalid")
 			return
 		}
 	}
 }
 
-func AddToBlacklist(db *sql.DB, token string, expiration time.Time) error {
-	_, err := db.Exec("INSERT INTO blacklisted_tokens (token, expiration) VALUES ($1, $2)", token, expiration)
-	if err != nil {
-		return fmt.Errorf("failed to add token to blacklist: %w", err)
-	}
-	return nil
-}
-
-func IsTokenBlacklisted(token string) bool {
-	db, err := database.ConnectDB()
-	if err != nil {
-		return true
-	}
-	defer db.Close()
-
-	var expiration time.Time
-	err = db.QueryRow("SELECT expiration FROM blacklisted_tokens WHERE token = $1", token).Scan(&expiration)
-
-	if err != nil {
-		return false
-	}
-
-	if time.Now().After(expiration) {
-		_, err := db.Exec("DELETE FROM blacklisted_tokens WHERE token = $1", token)
-		if err != nil {
-			return true
-		}
-		return false
-	}
-
-	return true
+// withUser ajoute l'utilisateur authentifié au logger de la requête et en fait l'auteur des
+// opérations enregistrées dans le journal d'audit
+func withUser(c *gin.Context, userID int, method string) {
+	ctx := c.Request.Context()
+	logger := logging.FromContext(ctx).With("user_id", userID, "auth_method", method)
+	ctx = audit.WithActor(logging.WithLogger(ctx, logger), audit.UserActor(userID))
+	c.Request = c.Request.WithContext(ctx)
 }
@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RequestID attribue à chaque requête un identifiant, repris de l'en-tête X-Request-ID (ou
+// X-Correlation-ID) lorsque le client en fournit un valide, et le renvoie dans la réponse. Les
+// réponses d'erreur le reprennent dans leur champ correlation_id. Le contexte de la requête
+// reçoit un logger dérivé de logger qui porte cet identifiant, pour les services et les dépôts,
+// ainsi que l'origine de la requête (adresse IP, user agent) reprise par le journal d'audit.
+func RequestID(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := problem.CorrelationID(c)
+		ctx := logging.WithLogger(c.Request.Context(), logger.With("request_id", id))
+		ctx = audit.WithSource(ctx, audit.Source{IP: c.ClientIP(), UserAgent: c.Request.UserAgent(), RequestID: id})
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// AccessLog journalise chaque requête une fois traitée. La route est celle du routeur
+// (/users/:id) et non le chemin reçu, et la chaîne de requête est omise : elles peuvent porter
+// des jetons ou des adresses email.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		logging.FromContext(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request",
+			slog.String("method", c.Request.Method),
+			slog.String("route", route(c)),
+			slog.Int("status", status),
+			slog.Duration("duration", time.Since(start)),
+			slog.Int("bytes", c.Writer.Size()),
+			slog.String("client_ip", c.ClientIP()),
+			slog.String("user_agent", c.Request.UserAgent()),
+		)
+	}
+}
+
+// Metrics mesure la durée de chaque requête, par méthode, route et statut
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		metrics.HTTPRequests.
+			WithLabelValues(c.Request.Method, route(c), strconv.Itoa(c.Writer.Status())).
+			Observe(time.Since(start).Seconds())
+	}
+}
+
+// route retourne le modèle de la route (/users/:id) ; les chemins inconnus du routeur sont
+// regroupés pour ne pas créer une série par URL
+func route(c *gin.Context) string {
+	if route := c.FullPath(); route != "" {
+		return route
+	}
+	return "unmatched"
+}
+
+// Tracing ouvre le span serveur de chaque requête, en reprenant le contexte de trace W3C
+// (traceparent) envoyé par l'appelant. L'identifiant de trace est ajouté au logger de la
+// requête pour relier journaux et traces.
+func Tracing() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+		ctx, span := tracing.Tracer().Start(ctx, c.Request.Method+" "+route(c),
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(c.Request.Method),
+				semconv.HTTPRoute(route(c)),
+				semconv.ClientAddress(c.ClientIP()),
+				semconv.UserAgentOriginal(c.Request.UserAgent()),
+			))
+		defer span.End()
+
+		if sc := span.SpanContext(); sc.IsValid() {
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With("trace_id", sc.TraceID().String()))
+		}
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+
+		status := c.Writer.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= 500 {
+			span.SetStatus(codes.Error, http.StatusText(status))
+		}
+	}
+}
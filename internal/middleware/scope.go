@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+// RequireScope refuse la requête si le jeton ou la clé d'API ne porte pas tous les scopes demandés.
+// Doit être placé après JWTAuth.
+func RequireScope(scopes ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !oauth.HasAll(c.GetStringSlice("scopes"), scopes) {
+			required := oauth.FormatScope(scopes)
+			problem.Send(c, problem.New(http.StatusForbidden, problem.CodeInsufficientScope,
+				"auth.insufficient_scope", required).With("required_scope", required))
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,108 @@
+package oauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+type OAuthHandler struct {
+	service *OAuthService
+}
+
+func NewOAuthHandler(service *OAuthService) *OAuthHandler {
+	return &OAuthHandler{service: service}
+}
+
+func (h *OAuthHandler) RegisterClient(c *gin.Context) {
+	var request struct {
+		Name   string   `json:"name" binding:"required,max=100"`
+		Scopes []string `json:"scopes" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+
+	client, err := h.service.RegisterClient(c.Request.Context(), c.GetInt("userID"), request.Name, request.Scopes)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "oauth.client_register_failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": i18n.Localize(c, "oauth.client_registered"),
+		"client":  client,
+	})
+}
+
+func (h *OAuthHandler) ListClients(c *gin.Context) {
+	clients, err := h.service.ListClients(c.Request.Context(), c.GetInt("userID"))
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "oauth.clients_list_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"clients": clients})
+}
+
+func (h *OAuthHandler) ListConsents(c *gin.Context) {
+	consents, err := h.service.ListConsents(c.Request.Context(), c.GetInt("userID"))
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "oauth.consents_list_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"consents": consents})
+}
+
+func (h *OAuthHandler) GrantConsent(c *gin.Context) {
+	var request struct {
+		Scopes []string `json:"scopes" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+
+	consent, err := h.service.GrantConsent(c.Request.Context(), c.GetInt("userID"), c.Param("client_id"), request.Scopes)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "oauth.consent_grant_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": i18n.Localize(c, "oauth.consent_granted"),
+		"consent": consent,
+	})
+}
+
+func (h *OAuthHandler) RevokeConsent(c *gin.Context) {
+	if err := h.service.RevokeConsent(c.Request.Context(), c.GetInt("userID"), c.Param("client_id")); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "oauth.consent_not_found")
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "oauth.consent_revoke_failed")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
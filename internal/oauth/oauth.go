@@ -0,0 +1,20 @@
+package oauth
+
+import "time"
+
+// Client est une application tierce autorisée à demander des jetons au nom d'un utilisateur
+type Client struct {
+	ClientID      string    `json:"client_id"`
+	OwnerID       int       `json:"-"`
+	Name          string    `json:"name"`
+	AllowedScopes []string  `json:"allowed_scopes"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Consent enregistre les scopes qu'un utilisateur a approuvés pour un client
+type Consent struct {
+	UserID    int       `json:"-"`
+	ClientID  string    `json:"client_id"`
+	Scopes    []string  `json:"scopes"`
+	GrantedAt time.Time `json:"granted_at"`
+}
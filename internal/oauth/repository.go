@@ -0,0 +1,157 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type OAuthRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewOAuthRepository(db *sql.DB) *OAuthRepository {
+	return &OAuthRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// startQuery ouvre le span d'une requête du dépôt
+func (r *OAuthRepository) startQuery(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracing.StartQuery(ctx, "OAuthRepository."+method, r.dialect.System(), operation)
+}
+
+func (r *OAuthRepository) CreateClient(ctx context.Context, c *Client) (err error) {
+	ctx, span := r.startQuery(ctx, "CreateClient", "INSERT oauth_clients")
+	defer func() { tracing.End(span, err) }()
+
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("INSERT INTO oauth_clients (client_id, owner_id, name, allowed_scopes) VALUES ($1, $2, $3, $4) RETURNING created_at"),
+		c.ClientID, c.OwnerID, c.Name, FormatScope(c.AllowedScopes)).
+		Scan(&c.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting oauth client: %w", err)
+	}
+	return nil
+}
+
+func (r *OAuthRepository) FindClient(ctx context.Context, clientID string) (_ *Client, err error) {
+	ctx, span := r.startQuery(ctx, "FindClient", "SELECT oauth_clients")
+	defer func() { tracing.End(span, err) }()
+
+	var c Client
+	var scopes string
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("SELECT client_id, owner_id, name, allowed_scopes, created_at FROM oauth_clients WHERE client_id = $1"), clientID).
+		Scan(&c.ClientID, &c.OwnerID, &c.Name, &scopes, &c.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("client %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.AllowedScopes = ParseScope(scopes)
+	return &c, nil
+}
+
+func (r *OAuthRepository) ListClientsByOwner(ctx context.Context, ownerID int) (_ []Client, err error) {
+	ctx, span := r.startQuery(ctx, "ListClientsByOwner", "SELECT oauth_clients")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind("SELECT client_id, owner_id, name, allowed_scopes, created_at FROM oauth_clients WHERE owner_id = $1 ORDER BY created_at"), ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing oauth clients: %w", err)
+	}
+	defer rows.Close()
+
+	clients := []Client{}
+	for rows.Next() {
+		var c Client
+		var scopes string
+		if err := rows.Scan(&c.ClientID, &c.OwnerID, &c.Name, &scopes, &c.CreatedAt); err != nil {
+			return nil, err
+		}
+		c.AllowedScopes = ParseScope(scopes)
+		clients = append(clients, c)
+	}
+	return clients, rows.Err()
+}
+
+func (r *OAuthRepository) SaveConsent(ctx context.Context, c *Consent) (err error) {
+	ctx, span := r.startQuery(ctx, "SaveConsent", "INSERT user_consents")
+	defer func() { tracing.End(span, err) }()
+
+	c.GrantedAt = time.Now()
+	_, err = r.db.ExecContext(ctx,
+		r.dialect.Rebind(`INSERT INTO user_consents (user_id, client_id, scopes, granted_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, client_id) DO UPDATE SET scopes = EXCLUDED.scopes, granted_at = EXCLUDED.granted_at`),
+		c.UserID, c.ClientID, FormatScope(c.Scopes), c.GrantedAt)
+	if err != nil {
+		return fmt.Errorf("error saving consent: %w", err)
+	}
+	return nil
+}
+
+func (r *OAuthRepository) FindConsent(ctx context.Context, userID int, clientID string) (_ *Consent, err error) {
+	ctx, span := r.startQuery(ctx, "FindConsent", "SELECT user_consents")
+	defer func() { tracing.End(span, err) }()
+
+	c := Consent{UserID: userID, ClientID: clientID}
+	var scopes string
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("SELECT scopes, granted_at FROM user_consents WHERE user_id = $1 AND client_id = $2"), userID, clientID).
+		Scan(&scopes, &c.GrantedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("consent %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Scopes = ParseScope(scopes)
+	return &c, nil
+}
+
+func (r *OAuthRepository) ListConsents(ctx context.Context, userID int) (_ []Consent, err error) {
+	ctx, span := r.startQuery(ctx, "ListConsents", "SELECT user_consents")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind("SELECT client_id, scopes, granted_at FROM user_consents WHERE user_id = $1 ORDER BY granted_at DESC"), userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing consents: %w", err)
+	}
+	defer rows.Close()
+
+	consents := []Consent{}
+	for rows.Next() {
+		c := Consent{UserID: userID}
+		var scopes string
+		if err := rows.Scan(&c.ClientID, &scopes, &c.GrantedAt); err != nil {
+			return nil, err
+		}
+		c.Scopes = ParseScope(scopes)
+		consents = append(consents, c)
+	}
+	return consents, rows.Err()
+}
+
+func (r *OAuthRepository) DeleteConsent(ctx context.Context, userID int, clientID string) (err error) {
+	ctx, span := r.startQuery(ctx, "DeleteConsent", "DELETE user_consents")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM user_consents WHERE user_id = $1 AND client_id = $2"), userID, clientID)
+	if err != nil {
+		return fmt.Errorf("error deleting consent: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("consent %w", apperror.ErrNotFound)
+	}
+	return nil
+}
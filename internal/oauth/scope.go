@@ -0,0 +1,85 @@
+package oauth
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Vocabulaire des scopes reconnus par l'API
+const (
+	ScopeProfileRead    = "profile:read"
+	ScopeProfileWrite   = "profile:write"
+	ScopeAPIKeysRead    = "api_keys:read"
+	ScopeAPIKeysWrite   = "api_keys:write"
+	ScopeConsentsManage = "consents:manage"
+	ScopeClientsManage  = "clients:manage"
+	// ScopeAdmin n'est accordé qu'aux utilisateurs ayant le rôle admin
+	ScopeAdmin = "admin"
+)
+
+// AllScopes est accordé aux connexions de première partie (sans client_id)
+var AllScopes = []string{
+	ScopeProfileRead,
+	ScopeProfileWrite,
+	ScopeAPIKeysRead,
+	ScopeAPIKeysWrite,
+	ScopeConsentsManage,
+	ScopeClientsManage,
+	ScopeAdmin,
+}
+
+// AdminScopes sont retirés des jetons des utilisateurs qui n'ont pas le rôle admin
+var AdminScopes = []string{ScopeAdmin}
+
+// ParseScope découpe une valeur de scope séparée par des espaces (RFC 6749 §3.3)
+func ParseScope(scope string) []string {
+	return strings.Fields(scope)
+}
+
+// FormatScope produit la valeur du claim "scope"
+func FormatScope(scopes []string) string {
+	return strings.Join(scopes, " ")
+}
+
+// ValidateScopes vérifie que chaque scope appartient au vocabulaire
+func ValidateScopes(scopes []string) error {
+	for _, s := range scopes {
+		if !slices.Contains(AllScopes, s) {
+			return fmt.Errorf("unknown scope %q", s)
+		}
+	}
+	return nil
+}
+
+// Intersect retourne les scopes de requested présents dans allowed, sans doublon
+func Intersect(requested, allowed []string) []string {
+	granted := []string{}
+	for _, s := range requested {
+		if slices.Contains(allowed, s) && !slices.Contains(granted, s) {
+			granted = append(granted, s)
+		}
+	}
+	return granted
+}
+
+// Without retourne scopes privé des éléments de removed
+func Without(scopes, removed []string) []string {
+	kept := []string{}
+	for _, s := range scopes {
+		if !slices.Contains(removed, s) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// HasAll indique si granted couvre tous les scopes required
+func HasAll(granted, required []string) bool {
+	for _, s := range required {
+		if !slices.Contains(granted, s) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,142 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+)
+
+// ErrConsentRequired signale que l'utilisateur n'a pas, ou plus, approuvé les scopes
+// demandés par un client tiers
+var ErrConsentRequired = errors.New("consent required")
+
+type OAuthService struct {
+	repo *OAuthRepository
+}
+
+func NewOAuthService(repo *OAuthRepository) *OAuthService {
+	return &OAuthService{repo: repo}
+}
+
+func (s *OAuthService) RegisterClient(ctx context.Context, ownerID int, name string, scopes []string) (*Client, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", apperror.ErrValidation)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", apperror.ErrValidation)
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperror.ErrValidation, err)
+	}
+
+	c := &Client{
+		ClientID:      uuid.New().String(),
+		OwnerID:       ownerID,
+		Name:          name,
+		AllowedScopes: Intersect(scopes, AllScopes),
+	}
+	if err := s.repo.CreateClient(ctx, c); err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return c, nil
+}
+
+func (s *OAuthService) ListClients(ctx context.Context, ownerID int) ([]Client, error) {
+	clients, err := s.repo.ListClientsByOwner(ctx, ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return clients, nil
+}
+
+// GrantConsent enregistre l'approbation par l'utilisateur d'un sous-ensemble des scopes du client
+func (s *OAuthService) GrantConsent(ctx context.Context, userID int, clientID string, scopes []string) (*Consent, error) {
+	client, err := s.findClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ValidateScopes(scopes); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperror.ErrValidation, err)
+	}
+	if !HasAll(client.AllowedScopes, scopes) {
+		return nil, fmt.Errorf("%w: scopes exceed those allowed for client %s", apperror.ErrValidation, clientID)
+	}
+
+	c := &Consent{UserID: userID, ClientID: clientID, Scopes: Intersect(scopes, client.AllowedScopes)}
+	if err := s.repo.SaveConsent(ctx, c); err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return c, nil
+}
+
+func (s *OAuthService) ListConsents(ctx context.Context, userID int) ([]Consent, error) {
+	consents, err := s.repo.ListConsents(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return consents, nil
+}
+
+func (s *OAuthService) RevokeConsent(ctx context.Context, userID int, clientID string) error {
+	if err := s.repo.DeleteConsent(ctx, userID, clientID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: no consent for client %s", apperror.ErrNotFound, clientID)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// GrantedScopes calcule les scopes à inscrire dans un jeton. Sans client, il s'agit d'une
+// connexion de première partie ; sinon seuls les scopes autorisés pour le client et
+// approuvés par l'utilisateur sont accordés.
+func (s *OAuthService) GrantedScopes(ctx context.Context, userID int, clientID string, requested []string) ([]string, error) {
+	if err := ValidateScopes(requested); err != nil {
+		return nil, fmt.Errorf("%w: %v", apperror.ErrValidation, err)
+	}
+
+	if clientID == "" {
+		if len(requested) == 0 {
+			return AllScopes, nil
+		}
+		return Intersect(requested, AllScopes), nil
+	}
+
+	client, err := s.findClient(ctx, clientID)
+	if err != nil {
+		return nil, err
+	}
+	if len(requested) == 0 {
+		requested = client.AllowedScopes
+	}
+
+	consent, err := s.repo.FindConsent(ctx, userID, clientID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user has not approved client %s", ErrConsentRequired, clientID)
+		}
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+
+	granted := Intersect(Intersect(requested, client.AllowedScopes), consent.Scopes)
+	if len(granted) == 0 {
+		return nil, fmt.Errorf("%w: none of the requested scopes were approved", ErrConsentRequired)
+	}
+	return granted, nil
+}
+
+func (s *OAuthService) findClient(ctx context.Context, clientID string) (*Client, error) {
+	client, err := s.repo.FindClient(ctx, clientID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, fmt.Errorf("%w: unknown client %s", apperror.ErrValidation, clientID)
+		}
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return client, nil
+}
@@ -0,0 +1,27 @@
+package problem
+
+// typePrefix forme l'URI de type à partir du code (RFC 7807 §3.1)
+const typePrefix = "urn:authentificationgo:problem:"
+
+// Codes d'erreur stables. Les clients s'appuient sur ces valeurs : un code existant ne doit
+// jamais être renommé. Le titre de chaque code est l'entrée « problem.<code> » du catalogue.
+const (
+	CodeInvalidRequest     = "invalid_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeInvalidToken       = "invalid_token"
+	CodeInvalidAPIKey      = "invalid_api_key"
+	CodeInsufficientScope  = "insufficient_scope"
+	CodeConsentRequired    = "consent_required"
+	CodeNotFound           = "not_found"
+	CodeConflict           = "conflict"
+	CodeEmailInUse         = "email_in_use"
+	CodeIdentityLinked     = "identity_linked"
+	CodeLinkRequired       = "link_required"
+	CodeLastLoginMethod    = "last_login_method"
+	CodeAccountLocked      = "account_locked"
+	CodeProviderError      = "provider_error"
+	CodeInternal           = "internal_error"
+	CodeUnavailable        = "service_unavailable"
+)
@@ -0,0 +1,167 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+)
+
+// ContentType est le type des réponses d'erreur (RFC 7807)
+const ContentType = "application/problem+json"
+
+// RequestIDHeader transporte l'identifiant de la requête entre le client, les journaux et la
+// réponse. CorrelationHeader, son ancien nom, reste accepté et renvoyé.
+const (
+	RequestIDHeader   = "X-Request-ID"
+	CorrelationHeader = "X-Correlation-ID"
+)
+
+const correlationKey = "correlationID"
+
+// Problem est le corps de toutes les réponses d'erreur de l'API. Code est stable et destiné
+// aux programmes ; Title et Detail sont destinés aux personnes et ne doivent pas être analysés.
+type Problem struct {
+	Type          string       `json:"type"`
+	Title         string       `json:"title"`
+	Status        int          `json:"status"`
+	Detail        string       `json:"detail,omitempty"`
+	Instance      string       `json:"instance,omitempty"`
+	Code          string       `json:"code"`
+	CorrelationID string       `json:"correlation_id,omitempty"`
+	Errors        []FieldError `json:"errors,omitempty"`
+
+	// Extensions ajoute des membres propres au problème (par exemple le scope requis)
+	Extensions map[string]interface{} `json:"-"`
+
+	// detailKey est la clé du catalogue de Detail, traduite par Send dans la langue de la requête
+	detailKey  string
+	detailArgs []interface{}
+}
+
+// New construit un problème pour un code stable. key est la clé du catalogue de traductions du
+// message détaillé ; le titre et le détail sont traduits par Send dans la langue de la requête.
+func New(status int, code, key string, args ...interface{}) *Problem {
+	return &Problem{
+		Type:       typePrefix + code,
+		Status:     status,
+		Code:       code,
+		detailKey:  key,
+		detailArgs: args,
+	}
+}
+
+// FromError construit un problème dont le détail provient d'une erreur du service : traduit
+// lorsqu'elle porte une clé (apperror.Localized), repris tel quel sinon. Réservé aux erreurs
+// dont le texte est destiné au client, comme les erreurs de validation.
+func FromError(status int, code string, err error) *Problem {
+	var localized *apperror.Localized
+	if errors.As(err, &localized) {
+		return New(status, code, localized.Key, localized.Args...)
+	}
+	p := New(status, code, "")
+	p.Detail = err.Error()
+	return p
+}
+
+// With ajoute un membre d'extension et retourne le problème pour chaîner les appels
+func (p *Problem) With(key string, value interface{}) *Problem {
+	if p.Extensions == nil {
+		p.Extensions = map[string]interface{}{}
+	}
+	p.Extensions[key] = value
+	return p
+}
+
+// MarshalJSON place les extensions au même niveau que les membres standard, comme le prévoit la RFC
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	type plain Problem
+	raw, err := json.Marshal((*plain)(p))
+	if err != nil || len(p.Extensions) == 0 {
+		return raw, err
+	}
+
+	members := map[string]interface{}{}
+	for k, v := range p.Extensions {
+		members[k] = v
+	}
+	if err := json.Unmarshal(raw, &members); err != nil {
+		return nil, err
+	}
+	return json.Marshal(members)
+}
+
+// Abort envoie le problème et interrompt la chaîne des handlers
+func Abort(c *gin.Context, status int, code, key string, args ...interface{}) {
+	Send(c, New(status, code, key, args...))
+}
+
+// Validation répond à une erreur apperror.ErrValidation du service, avec le détail par champ
+// lorsqu'elle provient du validateur
+func Validation(c *gin.Context, err error) {
+	p := FromError(http.StatusBadRequest, CodeValidationFailed, err)
+	p.Errors = FieldErrors(err)
+	Send(c, p)
+}
+
+// Send traduit le problème dans la langue de la requête, le complète avec l'URL de la requête
+// et l'identifiant de corrélation, puis l'envoie
+func Send(c *gin.Context, p *Problem) {
+	locale := i18n.FromContext(c)
+	p.Title = i18n.T(locale, "problem."+p.Code)
+	if p.detailKey != "" {
+		p.Detail = i18n.T(locale, p.detailKey, p.detailArgs...)
+	}
+	for i := range p.Errors {
+		p.Errors[i].Detail = i18n.T(locale, p.Errors[i].key, p.Errors[i].args...)
+	}
+
+	p.Instance = c.Request.URL.Path
+	p.CorrelationID = CorrelationID(c)
+	c.Header("Content-Type", ContentType)
+	c.Header("Content-Language", locale)
+	c.AbortWithStatusJSON(p.Status, p)
+}
+
+// CorrelationID retourne l'identifiant de la requête, en le créant au besoin : celui de
+// l'en-tête X-Request-ID (ou X-Correlation-ID) s'il est valide, un UUID sinon
+func CorrelationID(c *gin.Context) string {
+	if id := c.GetString(correlationKey); id != "" {
+		return id
+	}
+
+	id := c.GetHeader(RequestIDHeader)
+	if id == "" {
+		id = c.GetHeader(CorrelationHeader)
+	}
+	if !validCorrelationID(id) {
+		id = uuid.NewString()
+	}
+	c.Set(correlationKey, id)
+	c.Header(RequestIDHeader, id)
+	c.Header(CorrelationHeader, id)
+	return id
+}
+
+// validCorrelationID n'accepte que des identifiants courts et sans caractères de contrôle, pour
+// qu'une valeur fournie par le client ne puisse pas polluer les journaux
+func validCorrelationID(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NotFound répond aux routes inconnues
+func NotFound(c *gin.Context) {
+	Abort(c, http.StatusNotFound, CodeNotFound, "request.unknown_route")
+}
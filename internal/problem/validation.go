@@ -0,0 +1,117 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError décrit une erreur de validation sur un champ du corps de la requête
+type FieldError struct {
+	Field  string `json:"field"`
+	Code   string `json:"code"`
+	Param  string `json:"param,omitempty"`
+	Detail string `json:"detail"`
+
+	// key et args désignent le message du catalogue, traduit par Send
+	key  string
+	args []interface{}
+}
+
+// Le validateur de gin rapporte les champs sous leur nom JSON plutôt que sous le nom Go
+func init() {
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonName)
+	}
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// InvalidInput répond à une erreur de binding (ShouldBindJSON, ShouldBindQuery…) par un problème
+// 400 qui liste les champs en erreur, sans exposer le message brut du décodeur
+func InvalidInput(c *gin.Context, err error) {
+	p := New(http.StatusBadRequest, CodeInvalidRequest, "request.invalid_input")
+	p.Errors = FieldErrors(err)
+	Send(c, p)
+}
+
+// FieldErrors extrait le détail par champ d'une erreur de validation ou de décodage JSON
+func FieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			key, args := describe(fe)
+			fields = append(fields, FieldError{
+				Field: fieldPath(fe),
+				Code:  fe.Tag(),
+				Param: fe.Param(),
+				key:   key,
+				args:  args,
+			})
+		}
+		return fields
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return []FieldError{{
+			Field: typeError.Field,
+			Code:  "type",
+			Param: typeError.Type.String(),
+			key:   "field.type",
+		}}
+	}
+	return nil
+}
+
+// fieldPath retire le nom de la structure racine de l'espace de noms (User.email → email)
+func fieldPath(fe validator.FieldError) string {
+	if _, path, ok := strings.Cut(fe.Namespace(), "."); ok {
+		return path
+	}
+	return fe.Field()
+}
+
+// describe retourne la clé du catalogue décrivant la règle non respectée, et ses paramètres
+func describe(fe validator.FieldError) (string, []interface{}) {
+	param := []interface{}{fe.Param()}
+	switch fe.Tag() {
+	case "required", "email":
+		return "field." + fe.Tag(), nil
+	case "min", "max":
+		return "field." + fe.Tag() + "." + measure(fe.Kind()), param
+	case "gt", "oneof":
+		return "field." + fe.Tag(), param
+	default:
+		return "field.invalid", nil
+	}
+}
+
+// measure indique sur quoi portent les règles min/max : une longueur pour les chaînes, un
+// nombre d'éléments pour les collections, une valeur pour les nombres
+func measure(kind reflect.Kind) string {
+	switch kind {
+	case reflect.String:
+		return "length"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return "items"
+	default:
+		return "value"
+	}
+}
@@ -0,0 +1,44 @@
+package samlauth
+
+import (
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+)
+
+// SPConfig regroupe la clé et le certificat de notre fournisseur de services, communs à tous les tenants
+type SPConfig struct {
+	BaseURL     url.URL // ex. https://api.example.com/<prefix>/saml
+	Key         *rsa.PrivateKey
+	Certificate *x509.Certificate
+	StateSecret []byte // signe l'état conservé pendant l'aller-retour chez l'IdP
+}
+
+// NewSPConfig charge la paire clé/certificat désignée par la section saml de la configuration,
+// déjà validée. stateSecret signe l'état conservé pendant l'aller-retour chez l'IdP.
+func NewSPConfig(c config.SAML, stateSecret []byte) (SPConfig, error) {
+	u, err := url.Parse(strings.TrimSuffix(c.BaseURL, "/"))
+	if err != nil {
+		return SPConfig{}, fmt.Errorf("invalid saml.sp_base_url: %w", err)
+	}
+
+	pair, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+	if err != nil {
+		return SPConfig{}, fmt.Errorf("failed to load SAML service provider key pair: %w", err)
+	}
+	key, isRSA := pair.PrivateKey.(*rsa.PrivateKey)
+	if !isRSA {
+		return SPConfig{}, fmt.Errorf("SAML service provider key must be an RSA key")
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return SPConfig{}, fmt.Errorf("invalid SAML service provider certificate: %w", err)
+	}
+
+	return SPConfig{BaseURL: *u, Key: key, Certificate: cert, StateSecret: stateSecret}, nil
+}
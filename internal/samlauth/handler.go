@@ -0,0 +1,142 @@
+package samlauth
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+const stateCookie = "saml_request_state"
+
+type SAMLHandler struct {
+	service *SAMLService
+}
+
+func NewSAMLHandler(service *SAMLService) *SAMLHandler {
+	return &SAMLHandler{service: service}
+}
+
+func (h *SAMLHandler) SaveConnection(c *gin.Context) {
+	var request struct {
+		MetadataXML      string            `json:"metadata_xml"`
+		MetadataURL      string            `json:"metadata_url"`
+		AttributeMapping *AttributeMapping `json:"attribute_mapping"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+
+	conn, err := h.service.SaveConnection(c.Request.Context(), c.Param("tenant"),
+		request.MetadataXML, request.MetadataURL, request.AttributeMapping)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "saml.save_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    i18n.Localize(c, "saml.connection_saved"),
+		"connection": conn,
+	})
+}
+
+func (h *SAMLHandler) ListConnections(c *gin.Context) {
+	connections, err := h.service.ListConnections(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "saml.list_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"connections": connections})
+}
+
+func (h *SAMLHandler) DeleteConnection(c *gin.Context) {
+	if err := h.service.DeleteConnection(c.Request.Context(), c.Param("tenant")); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "saml.not_found")
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "saml.delete_failed")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SAMLHandler) Metadata(c *gin.Context) {
+	metadata, err := h.service.Metadata(c.Request.Context(), c.Param("tenant"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	c.Data(http.StatusOK, "application/samlmetadata+xml", metadata)
+}
+
+func (h *SAMLHandler) Login(c *gin.Context) {
+	redirectURL, state, err := h.service.BeginLogin(c.Request.Context(), c.Param("tenant"))
+	if err != nil {
+		h.abort(c, err)
+		return
+	}
+
+	// L'ACS reçoit un POST inter-site depuis l'IdP : le cookie doit être SameSite=None (donc Secure)
+	if isSecure(c) {
+		c.SetSameSite(http.SameSiteNoneMode)
+	} else {
+		c.SetSameSite(http.SameSiteLaxMode)
+	}
+	c.SetCookie(stateCookie, state, int(stateLifetime.Seconds()), "/", "", isSecure(c), true)
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+func (h *SAMLHandler) ACS(c *gin.Context) {
+	state, _ := c.Cookie(stateCookie)
+	c.SetCookie(stateCookie, "", -1, "/", "", isSecure(c), true)
+
+	accessToken, refreshToken, err := h.service.ConsumeAssertion(c.Request, c.Param("tenant"), state)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "saml.assertion_invalid")
+			return
+		}
+		if errors.Is(err, user.ErrLinkRequired) {
+			problem.Abort(c, http.StatusConflict, problem.CodeLinkRequired, "user.link_required")
+			return
+		}
+
+		h.abort(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      i18n.Localize(c, "user.login.success"),
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+	})
+}
+
+func (h *SAMLHandler) abort(c *gin.Context, err error) {
+	if errors.Is(err, apperror.ErrNotFound) {
+		problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "saml.tenant_unknown")
+		return
+	}
+
+	problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "error.internal")
+}
+
+func isSecure(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
@@ -0,0 +1,102 @@
+package samlauth
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// newServiceProvider construit le fournisseur de services propre à un tenant : chaque tenant
+// a son propre entityID et sa propre URL ACS sous SAML_SP_BASE_URL
+func newServiceProvider(cfg SPConfig, conn *Connection) (*saml.ServiceProvider, error) {
+	idp, err := samlsp.ParseMetadata([]byte(conn.Metadata))
+	if err != nil {
+		return nil, fmt.Errorf("invalid idp metadata for tenant %s: %w", conn.Tenant, err)
+	}
+
+	metadataURL := cfg.BaseURL.JoinPath(conn.Tenant, "metadata")
+	acsURL := cfg.BaseURL.JoinPath(conn.Tenant, "acs")
+
+	return &saml.ServiceProvider{
+		EntityID:          metadataURL.String(),
+		Key:               cfg.Key,
+		Certificate:       cfg.Certificate,
+		MetadataURL:       *metadataURL,
+		AcsURL:            *acsURL,
+		IDPMetadata:       idp,
+		AuthnNameIDFormat: saml.UnspecifiedNameIDFormat,
+		SignatureMethod:   "http://www.w3.org/2001/04/xmldsig-more#rsa-sha256",
+	}, nil
+}
+
+// mapAssertion convertit une assertion validée en résultat d'authentification externe
+func mapAssertion(conn *Connection, assertion *saml.Assertion) (*user.AuthResult, error) {
+	if assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return nil, fmt.Errorf("assertion has no NameID")
+	}
+	nameID := assertion.Subject.NameID.Value
+	mapping := conn.AttributeMapping
+
+	result := &user.AuthResult{
+		Provider:     "saml:" + conn.Tenant,
+		Subject:      nameID,
+		Tenant:       conn.Tenant,
+		Email:        firstValue(assertion, mapping.Email),
+		Name:         firstValue(assertion, mapping.Name),
+		MobileNumber: firstValue(assertion, mapping.MobileNumber),
+	}
+	if result.Email == "" && strings.Contains(nameID, "@") {
+		result.Email = nameID
+	}
+	if result.Email == "" {
+		return nil, fmt.Errorf("assertion has no email attribute")
+	}
+
+	if mapping.Role != "" {
+		result.Role = user.RoleUser
+		for _, v := range values(assertion, mapping.Role) {
+			if role, ok := mapping.RoleValues[v]; ok {
+				result.Role = role
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+func values(assertion *saml.Assertion, name string) []string {
+	if name == "" {
+		return nil
+	}
+	var out []string
+	for _, statement := range assertion.AttributeStatements {
+		for _, attr := range statement.Attributes {
+			if attr.Name != name && attr.FriendlyName != name {
+				continue
+			}
+			for _, v := range attr.Values {
+				out = append(out, v.Value)
+			}
+		}
+	}
+	return out
+}
+
+func firstValue(assertion *saml.Assertion, name string) string {
+	if v := values(assertion, name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func metadataEndpoint(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") {
+		return nil, fmt.Errorf("invalid metadata url %q", raw)
+	}
+	return u, nil
+}
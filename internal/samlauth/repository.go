@@ -0,0 +1,115 @@
+package samlauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type SAMLRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewSAMLRepository(db *sql.DB) *SAMLRepository {
+	return &SAMLRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// startQuery ouvre le span d'une requête du dépôt
+func (r *SAMLRepository) startQuery(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracing.StartQuery(ctx, "SAMLRepository."+method, r.dialect.System(), operation)
+}
+
+func (r *SAMLRepository) Save(ctx context.Context, c *Connection) (err error) {
+	ctx, span := r.startQuery(ctx, "Save", "INSERT saml_connections")
+	defer func() { tracing.End(span, err) }()
+
+	mapping, err := json.Marshal(c.AttributeMapping)
+	if err != nil {
+		return fmt.Errorf("error encoding attribute mapping: %w", err)
+	}
+
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind(`INSERT INTO saml_connections (tenant, idp_entity_id, metadata, attribute_mapping) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant) DO UPDATE SET idp_entity_id = EXCLUDED.idp_entity_id, metadata = EXCLUDED.metadata,
+			attribute_mapping = EXCLUDED.attribute_mapping, updated_at = CURRENT_TIMESTAMP
+		RETURNING created_at, updated_at`),
+		c.Tenant, c.IDPEntityID, c.Metadata, string(mapping)).
+		Scan(&c.CreatedAt, &c.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error saving saml connection: %w", err)
+	}
+	return nil
+}
+
+func (r *SAMLRepository) Find(ctx context.Context, tenant string) (_ *Connection, err error) {
+	ctx, span := r.startQuery(ctx, "Find", "SELECT saml_connections")
+	defer func() { tracing.End(span, err) }()
+
+	c, err := scanConnection(r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("SELECT tenant, idp_entity_id, metadata, attribute_mapping, created_at, updated_at FROM saml_connections WHERE tenant = $1"),
+		tenant))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("saml connection %w", apperror.ErrNotFound)
+	}
+	return c, err
+}
+
+func (r *SAMLRepository) List(ctx context.Context) (_ []Connection, err error) {
+	ctx, span := r.startQuery(ctx, "List", "SELECT saml_connections")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		"SELECT tenant, idp_entity_id, metadata, attribute_mapping, created_at, updated_at FROM saml_connections ORDER BY tenant")
+	if err != nil {
+		return nil, fmt.Errorf("error listing saml connections: %w", err)
+	}
+	defer rows.Close()
+
+	connections := []Connection{}
+	for rows.Next() {
+		c, err := scanConnection(rows)
+		if err != nil {
+			return nil, err
+		}
+		connections = append(connections, *c)
+	}
+	return connections, rows.Err()
+}
+
+func (r *SAMLRepository) Delete(ctx context.Context, tenant string) (err error) {
+	ctx, span := r.startQuery(ctx, "Delete", "DELETE saml_connections")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM saml_connections WHERE tenant = $1"), tenant)
+	if err != nil {
+		return fmt.Errorf("error deleting saml connection: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("saml connection %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanConnection(row rowScanner) (*Connection, error) {
+	var c Connection
+	var mapping string
+	if err := row.Scan(&c.Tenant, &c.IDPEntityID, &c.Metadata, &mapping, &c.CreatedAt, &c.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(mapping), &c.AttributeMapping); err != nil {
+		return nil, fmt.Errorf("invalid attribute mapping for tenant %s: %w", c.Tenant, err)
+	}
+	return &c, nil
+}
@@ -0,0 +1,29 @@
+package samlauth
+
+import "time"
+
+// AttributeMapping indique quels attributs de l'assertion alimentent les champs de User.
+// Un nom vide désactive le champ ; l'email se rabat sur le NameID s'il n'est pas fourni.
+type AttributeMapping struct {
+	Email        string            `json:"email"`
+	Name         string            `json:"name"`
+	MobileNumber string            `json:"mobile_number"`
+	Role         string            `json:"role"`
+	RoleValues   map[string]string `json:"role_values"` // valeur de l'attribut -> rôle applicatif
+}
+
+// DefaultAttributeMapping correspond aux noms d'attributs les plus courants
+var DefaultAttributeMapping = AttributeMapping{
+	Email: "email",
+	Name:  "displayName",
+}
+
+// Connection est la configuration SAML d'un tenant : l'IdP de confiance et la correspondance d'attributs
+type Connection struct {
+	Tenant           string           `json:"tenant"`
+	IDPEntityID      string           `json:"idp_entity_id"`
+	Metadata         string           `json:"-"`
+	AttributeMapping AttributeMapping `json:"attribute_mapping"`
+	CreatedAt        time.Time        `json:"created_at"`
+	UpdatedAt        time.Time        `json:"updated_at"`
+}
@@ -0,0 +1,227 @@
+package samlauth
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+var tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+// UserProvisioner regroupe les opérations du domaine utilisateur nécessaires après une assertion valide
+type UserProvisioner interface {
+	ProvisionExternal(ctx context.Context, result *user.AuthResult) (int, error)
+	IssueTokens(ctx context.Context, userID int) (string, string, error)
+}
+
+type SAMLService struct {
+	repo   *SAMLRepository
+	users  UserProvisioner
+	cfg    SPConfig
+	client *http.Client
+}
+
+func NewSAMLService(repo *SAMLRepository, users UserProvisioner, cfg SPConfig) *SAMLService {
+	return &SAMLService{
+		repo:   repo,
+		users:  users,
+		cfg:    cfg,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// SaveConnection importe les métadonnées de l'IdP d'un tenant, soit fournies directement
+// en XML, soit téléchargées depuis metadataURL
+func (s *SAMLService) SaveConnection(ctx context.Context, tenant, metadataXML, metadataURL string, mapping *AttributeMapping) (*Connection, error) {
+	if !tenantPattern.MatchString(tenant) {
+		return nil, fmt.Errorf("%w: tenant must match %s", apperror.ErrValidation, tenantPattern)
+	}
+	if (metadataXML == "") == (metadataURL == "") {
+		return nil, fmt.Errorf("%w: exactly one of metadata_xml or metadata_url is required", apperror.ErrValidation)
+	}
+
+	if metadataURL != "" {
+		fetched, err := s.fetchMetadata(ctx, metadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", apperror.ErrValidation, err)
+		}
+		metadataXML = fetched
+	}
+
+	idp, err := samlsp.ParseMetadata([]byte(metadataXML))
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid idp metadata: %v", apperror.ErrValidation, err)
+	}
+	if len(idp.IDPSSODescriptors) == 0 {
+		return nil, fmt.Errorf("%w: metadata does not describe an identity provider", apperror.ErrValidation)
+	}
+
+	conn := &Connection{
+		Tenant:           tenant,
+		IDPEntityID:      idp.EntityID,
+		Metadata:         metadataXML,
+		AttributeMapping: DefaultAttributeMapping,
+	}
+	if mapping != nil {
+		for _, role := range mapping.RoleValues {
+			if role != user.RoleUser && role != user.RoleAdmin {
+				return nil, fmt.Errorf("%w: unknown role %q in attribute mapping", apperror.ErrValidation, role)
+			}
+		}
+		conn.AttributeMapping = *mapping
+	}
+
+	if err := s.repo.Save(ctx, conn); err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return conn, nil
+}
+
+func (s *SAMLService) ListConnections(ctx context.Context) ([]Connection, error) {
+	connections, err := s.repo.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return connections, nil
+}
+
+func (s *SAMLService) DeleteConnection(ctx context.Context, tenant string) error {
+	if err := s.repo.Delete(ctx, tenant); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: no saml connection for tenant %s", apperror.ErrNotFound, tenant)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// Metadata retourne les métadonnées SP à transmettre à l'IdP du tenant
+func (s *SAMLService) Metadata(ctx context.Context, tenant string) ([]byte, error) {
+	sp, _, err := s.serviceProvider(ctx, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := xml.MarshalIndent(sp.Metadata(), "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return out, nil
+}
+
+// BeginLogin construit l'AuthnRequest (binding Redirect) et l'état signé contenant son ID
+func (s *SAMLService) BeginLogin(ctx context.Context, tenant string) (string, string, error) {
+	sp, _, err := s.serviceProvider(ctx, tenant)
+	if err != nil {
+		return "", "", err
+	}
+
+	req, err := sp.MakeAuthenticationRequest(sp.GetSSOBindingLocation(saml.HTTPRedirectBinding), saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: %v", err)
+	}
+	redirectURL, err := req.Redirect("", sp)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: %v", err)
+	}
+
+	state := &requestState{Tenant: tenant, RequestID: req.ID}
+	signed, err := state.sign(s.cfg.StateSecret)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: failed to sign request state: %v", err)
+	}
+	return redirectURL.String(), signed, nil
+}
+
+// ConsumeAssertion valide la réponse postée sur l'ACS (signature, audience, destination,
+// validité temporelle, InResponseTo), provisionne le compte et émet nos jetons. Un compte existant
+// hors du tenant retourne user.ErrLinkRequired.
+func (s *SAMLService) ConsumeAssertion(r *http.Request, tenant, signedState string) (string, string, error) {
+	sp, conn, err := s.serviceProvider(r.Context(), tenant)
+	if err != nil {
+		return "", "", err
+	}
+
+	state, err := parseRequestState(signedState, s.cfg.StateSecret)
+	if err != nil || state.Tenant != tenant {
+		return "", "", fmt.Errorf("%w: invalid or expired saml request state", apperror.ErrUnauthorized)
+	}
+
+	if err := r.ParseForm(); err != nil {
+		return "", "", fmt.Errorf("%w: invalid saml response: %v", apperror.ErrUnauthorized, err)
+	}
+	assertion, err := sp.ParseResponse(r, []string{state.RequestID})
+	if err != nil {
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		return "", "", fmt.Errorf("%w: invalid saml response: %v", apperror.ErrUnauthorized, err)
+	}
+
+	result, err := mapAssertion(conn, assertion)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %v", apperror.ErrUnauthorized, err)
+	}
+
+	userID, err := s.users.ProvisionExternal(r.Context(), result)
+	if err != nil {
+		if errors.Is(err, user.ErrLinkRequired) {
+			return "", "", err
+		}
+		return "", "", fmt.Errorf("internal error: %v", err)
+	}
+	return s.users.IssueTokens(r.Context(), userID)
+}
+
+func (s *SAMLService) serviceProvider(ctx context.Context, tenant string) (*saml.ServiceProvider, *Connection, error) {
+	conn, err := s.repo.Find(ctx, tenant)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil, fmt.Errorf("%w: no saml connection for tenant %s", apperror.ErrNotFound, tenant)
+		}
+		return nil, nil, fmt.Errorf("internal error: %v", err)
+	}
+
+	sp, err := newServiceProvider(s.cfg, conn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("internal error: %v", err)
+	}
+	return sp, conn, nil
+}
+
+func (s *SAMLService) fetchMetadata(ctx context.Context, raw string) (string, error) {
+	u, err := metadataEndpoint(raw)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch metadata: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch metadata: %w", err)
+	}
+	return string(body), nil
+}
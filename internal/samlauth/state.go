@@ -0,0 +1,36 @@
+package samlauth
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+const stateLifetime = 10 * time.Minute
+
+// requestState mémorise l'ID de l'AuthnRequest émise, pour vérifier l'InResponseTo de la réponse
+type requestState struct {
+	Tenant    string `json:"tenant"`
+	RequestID string `json:"request_id"`
+	jwt.RegisteredClaims
+}
+
+func (s *requestState) sign(secret []byte) (string, error) {
+	s.ExpiresAt = jwt.NewNumericDate(time.Now().Add(stateLifetime))
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, s).SignedString(secret)
+}
+
+func parseRequestState(raw string, secret []byte) (*requestState, error) {
+	var s requestState
+	_, err := jwt.ParseWithClaims(raw, &s, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return secret, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
@@ -0,0 +1,11 @@
+package scim
+
+import "errors"
+
+// Erreurs de requête propres au protocole, que abort traduit en scimType (RFC 7644 §3.12)
+var (
+	ErrInvalidFilter = errors.New("invalid filter")
+	ErrInvalidPath   = errors.New("invalid path")
+	ErrNoTarget      = errors.New("no target")
+	ErrInvalidValue  = errors.New("invalid value")
+)
@@ -0,0 +1,103 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Condition est une comparaison « attribut eq valeur » d'un filtre SCIM
+type Condition struct {
+	Attribute string
+	Value     string
+}
+
+// ParseFilter analyse le sous-ensemble de filtres pris en charge : des comparaisons « eq »
+// éventuellement combinées par « and », par exemple userName eq "alice@example.com".
+// allowed associe les noms d'attributs (en minuscules) à leur nom canonique ; nil accepte tout attribut.
+func ParseFilter(raw string, allowed map[string]string) ([]Condition, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+
+	tokens, err := tokenize(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	var conditions []Condition
+	for i := 0; i < len(tokens); i += 4 {
+		if len(tokens)-i < 3 {
+			return nil, fmt.Errorf("%w: incomplete expression", ErrInvalidFilter)
+		}
+		attribute, ok := tokens[i], true
+		if allowed != nil {
+			attribute, ok = allowed[strings.ToLower(tokens[i])]
+		}
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported attribute %q", ErrInvalidFilter, tokens[i])
+		}
+		if !strings.EqualFold(tokens[i+1], "eq") {
+			return nil, fmt.Errorf("%w: unsupported operator %q", ErrInvalidFilter, tokens[i+1])
+		}
+		value, err := literal(tokens[i+2])
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, Condition{Attribute: attribute, Value: value})
+
+		if i+3 < len(tokens) && !strings.EqualFold(tokens[i+3], "and") {
+			return nil, fmt.Errorf("%w: unsupported logical operator %q", ErrInvalidFilter, tokens[i+3])
+		}
+		if i+3 == len(tokens)-1 {
+			return nil, fmt.Errorf("%w: dangling %q", ErrInvalidFilter, tokens[i+3])
+		}
+	}
+	return conditions, nil
+}
+
+// tokenize découpe le filtre sur les espaces en conservant les chaînes entre guillemets
+func tokenize(raw string) ([]string, error) {
+	var tokens []string
+	for i := 0; i < len(raw); {
+		switch {
+		case raw[i] == ' ':
+			i++
+		case raw[i] == '"':
+			end := i + 1
+			for ; end < len(raw) && raw[end] != '"'; end++ {
+				if raw[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(raw) {
+				return nil, fmt.Errorf("%w: unterminated string", ErrInvalidFilter)
+			}
+			tokens = append(tokens, raw[i:end+1])
+			i = end + 1
+		default:
+			end := strings.IndexByte(raw[i:], ' ')
+			if end < 0 {
+				end = len(raw) - i
+			}
+			tokens = append(tokens, raw[i:i+end])
+			i += end
+		}
+	}
+	return tokens, nil
+}
+
+func literal(token string) (string, error) {
+	if strings.HasPrefix(token, `"`) {
+		value, err := strconv.Unquote(token)
+		if err != nil {
+			return "", fmt.Errorf("%w: malformed string %s", ErrInvalidFilter, token)
+		}
+		return value, nil
+	}
+	if strings.EqualFold(token, "true") || strings.EqualFold(token, "false") {
+		return strings.ToLower(token), nil
+	}
+	return "", fmt.Errorf("%w: unsupported value %s", ErrInvalidFilter, token)
+}
@@ -0,0 +1,418 @@
+package scim
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+const contentType = "application/scim+json"
+
+type SCIMHandler struct {
+	service *SCIMService
+}
+
+func NewSCIMHandler(service *SCIMService) *SCIMHandler {
+	return &SCIMHandler{service: service}
+}
+
+// IssueToken crée ou remplace le jeton SCIM d'un tenant (route d'administration)
+func (h *SCIMHandler) IssueToken(c *gin.Context) {
+	token, err := h.service.IssueToken(c.Request.Context(), c.Param("tenant"))
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "scim.token_create_failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": i18n.Localize(c, "scim.token_created"),
+		"tenant":  c.Param("tenant"),
+		"token":   token,
+	})
+}
+
+func (h *SCIMHandler) RevokeToken(c *gin.Context) {
+	if err := h.service.RevokeToken(c.Request.Context(), c.Param("tenant")); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "scim.token_not_found")
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "scim.token_revoke_failed")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RoleMapping retourne les rôles accordés aux groupes SCIM du tenant
+func (h *SCIMHandler) RoleMapping(c *gin.Context) {
+	mapping, err := h.service.RoleMapping(c.Request.Context(), c.Param("tenant"))
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "scim.roles_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenant": c.Param("tenant"), "groups": mapping})
+}
+
+// SetRoleMapping remplace les rôles accordés aux groupes SCIM du tenant, par nom de groupe
+func (h *SCIMHandler) SetRoleMapping(c *gin.Context) {
+	var request struct {
+		Groups map[string]string `json:"groups"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+	if request.Groups == nil {
+		request.Groups = map[string]string{}
+	}
+
+	if err := h.service.SetRoleMapping(c.Request.Context(), c.Param("tenant"), request.Groups); err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "scim.roles_failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": i18n.Localize(c, "scim.roles_saved"),
+		"tenant":  c.Param("tenant"),
+		"groups":  request.Groups,
+	})
+}
+
+// Authenticate vérifie le jeton bearer du tenant et le place dans le contexte
+func (h *SCIMHandler) Authenticate(c *gin.Context) {
+	token, found := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !found {
+		writeError(c, http.StatusUnauthorized, "", "missing bearer token")
+		return
+	}
+
+	tenant, err := h.service.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			writeError(c, http.StatusUnauthorized, "", "invalid bearer token")
+			return
+		}
+		writeError(c, http.StatusInternalServerError, "", "internal error")
+		return
+	}
+
+	c.Set("scimTenant", tenant)
+	c.Request = c.Request.WithContext(audit.WithActor(c.Request.Context(), audit.Actor{Type: audit.ActorSCIM, ID: tenant}))
+	c.Next()
+}
+
+func (h *SCIMHandler) ServiceProviderConfig(c *gin.Context) {
+	c.Header("Content-Type", contentType)
+	c.JSON(http.StatusOK, gin.H{
+		"schemas":        []string{ServiceConfigSchema},
+		"patch":          gin.H{"supported": true},
+		"bulk":           gin.H{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         gin.H{"supported": true, "maxResults": maxCount},
+		"changePassword": gin.H{"supported": false},
+		"sort":           gin.H{"supported": false},
+		"etag":           gin.H{"supported": false},
+		"authenticationSchemes": []gin.H{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Jeton SCIM propre au tenant",
+		}},
+	})
+}
+
+func (h *SCIMHandler) CreateUser(c *gin.Context) {
+	var res UserResource
+	if !bind(c, &res) {
+		return
+	}
+
+	created, err := h.service.CreateUser(c.Request.Context(), c.GetString("scimTenant"), &res)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeUser(c, http.StatusCreated, created)
+}
+
+func (h *SCIMHandler) GetUser(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.GetUser(c.Request.Context(), c.GetString("scimTenant"), id)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeUser(c, http.StatusOK, res)
+}
+
+func (h *SCIMHandler) ListUsers(c *gin.Context) {
+	page := ParsePage(c.Query("startIndex"), c.Query("count"))
+	resources, total, err := h.service.ListUsers(c.Request.Context(), c.GetString("scimTenant"), c.Query("filter"), page)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	for _, res := range resources {
+		res.Meta.Location = location(c, "Users", res.ID)
+	}
+	writeList(c, resources, len(resources), total, page)
+}
+
+func (h *SCIMHandler) ReplaceUser(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+	var res UserResource
+	if !bind(c, &res) {
+		return
+	}
+
+	updated, err := h.service.ReplaceUser(c.Request.Context(), c.GetString("scimTenant"), id, &res)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeUser(c, http.StatusOK, updated)
+}
+
+func (h *SCIMHandler) PatchUser(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+	var patch PatchRequest
+	if !bind(c, &patch) {
+		return
+	}
+
+	updated, err := h.service.PatchUser(c.Request.Context(), c.GetString("scimTenant"), id, &patch)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeUser(c, http.StatusOK, updated)
+}
+
+func (h *SCIMHandler) DeleteUser(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteUser(c.Request.Context(), c.GetString("scimTenant"), id); err != nil {
+		abort(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) CreateGroup(c *gin.Context) {
+	var res GroupResource
+	if !bind(c, &res) {
+		return
+	}
+
+	created, err := h.service.CreateGroup(c.Request.Context(), c.GetString("scimTenant"), &res)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusCreated, created)
+}
+
+func (h *SCIMHandler) GetGroup(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+
+	res, err := h.service.GetGroup(c.Request.Context(), c.GetString("scimTenant"), id)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusOK, res)
+}
+
+func (h *SCIMHandler) ListGroups(c *gin.Context) {
+	page := ParsePage(c.Query("startIndex"), c.Query("count"))
+	resources, total, err := h.service.ListGroups(c.Request.Context(), c.GetString("scimTenant"), c.Query("filter"), page)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+
+	for _, res := range resources {
+		res.Meta.Location = location(c, "Groups", res.ID)
+	}
+	writeList(c, resources, len(resources), total, page)
+}
+
+func (h *SCIMHandler) ReplaceGroup(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+	var res GroupResource
+	if !bind(c, &res) {
+		return
+	}
+
+	updated, err := h.service.ReplaceGroup(c.Request.Context(), c.GetString("scimTenant"), id, &res)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusOK, updated)
+}
+
+func (h *SCIMHandler) PatchGroup(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+	var patch PatchRequest
+	if !bind(c, &patch) {
+		return
+	}
+
+	updated, err := h.service.PatchGroup(c.Request.Context(), c.GetString("scimTenant"), id, &patch)
+	if err != nil {
+		abort(c, err)
+		return
+	}
+	h.writeGroup(c, http.StatusOK, updated)
+}
+
+func (h *SCIMHandler) DeleteGroup(c *gin.Context) {
+	id, ok := resourceID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteGroup(c.Request.Context(), c.GetString("scimTenant"), id); err != nil {
+		abort(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *SCIMHandler) writeUser(c *gin.Context, status int, res *UserResource) {
+	res.Meta.Location = location(c, "Users", res.ID)
+	c.Header("Location", res.Meta.Location)
+	c.Header("Content-Type", contentType)
+	c.JSON(status, res)
+}
+
+func (h *SCIMHandler) writeGroup(c *gin.Context, status int, res *GroupResource) {
+	res.Meta.Location = location(c, "Groups", res.ID)
+	c.Header("Location", res.Meta.Location)
+	c.Header("Content-Type", contentType)
+	c.JSON(status, res)
+}
+
+func writeList(c *gin.Context, resources interface{}, n, total int, page Page) {
+	c.Header("Content-Type", contentType)
+	c.JSON(http.StatusOK, ListResponse{
+		Schemas:      []string{ListResponseSchema},
+		TotalResults: total,
+		StartIndex:   page.StartIndex,
+		ItemsPerPage: n,
+		Resources:    resources,
+	})
+}
+
+// writeError répond au format d'erreur SCIM (RFC 7644 §3.12)
+func writeError(c *gin.Context, status int, scimType, detail string) {
+	body := gin.H{
+		"schemas": []string{ErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	c.Header("Content-Type", contentType)
+	c.AbortWithStatusJSON(status, body)
+}
+
+// abort traduit les erreurs du service en erreurs SCIM
+func abort(c *gin.Context, err error) {
+	msg := err.Error()
+	switch {
+	case errors.Is(err, ErrInvalidFilter):
+		writeError(c, http.StatusBadRequest, "invalidFilter", msg)
+	case errors.Is(err, ErrInvalidPath):
+		writeError(c, http.StatusBadRequest, "invalidPath", msg)
+	case errors.Is(err, ErrNoTarget):
+		writeError(c, http.StatusBadRequest, "noTarget", msg)
+	case errors.Is(err, ErrInvalidValue), errors.Is(err, apperror.ErrValidation):
+		writeError(c, http.StatusBadRequest, "invalidValue", msg)
+	case errors.Is(err, apperror.ErrConflict):
+		writeError(c, http.StatusConflict, "uniqueness", msg)
+	case errors.Is(err, apperror.ErrNotFound):
+		writeError(c, http.StatusNotFound, "", msg)
+	default:
+		writeError(c, http.StatusInternalServerError, "", "internal error")
+	}
+}
+
+func bind(c *gin.Context, v interface{}) bool {
+	if err := c.ShouldBindJSON(v); err != nil {
+		writeError(c, http.StatusBadRequest, "invalidSyntax", err.Error())
+		return false
+	}
+	return true
+}
+
+func resourceID(c *gin.Context) (int, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		writeError(c, http.StatusNotFound, "", "resource "+c.Param("id")+" not found")
+		return 0, false
+	}
+	return id, true
+}
+
+// location construit l'URL absolue d'une ressource à partir de la requête courante
+func location(c *gin.Context, resourceType, id string) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	path := c.Request.URL.Path
+	if i := strings.Index(path, "/scim/v2"); i >= 0 {
+		path = path[:i+len("/scim/v2")]
+	}
+	return scheme + "://" + c.Request.Host + path + "/" + resourceType + "/" + id
+}
@@ -0,0 +1,209 @@
+package scim
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// patchPath est un chemin PATCH décomposé : attr[filtre].sub (RFC 7644 §3.5.2)
+type patchPath struct {
+	attribute string
+	filter    *Condition
+	sub       string
+}
+
+func parsePatchPath(raw string) (*patchPath, error) {
+	p := &patchPath{}
+	raw = strings.TrimSpace(raw)
+
+	// Attributs d'extension : urn:...:Group:role
+	if strings.HasPrefix(raw, "urn:") {
+		i := strings.LastIndex(raw, ":")
+		p.attribute, p.sub = raw[:i], raw[i+1:]
+		return p, nil
+	}
+
+	if open := strings.IndexByte(raw, '['); open >= 0 {
+		end := strings.IndexByte(raw, ']')
+		if end < open {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, raw)
+		}
+		conditions, err := ParseFilter(raw[open+1:end], nil)
+		if err != nil || len(conditions) != 1 {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, raw)
+		}
+		p.attribute, p.filter = raw[:open], &conditions[0]
+		raw = raw[end+1:]
+		if raw != "" && !strings.HasPrefix(raw, ".") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidPath, raw)
+		}
+		p.sub = strings.TrimPrefix(raw, ".")
+		return p, nil
+	}
+
+	p.attribute, p.sub, _ = strings.Cut(raw, ".")
+	if p.attribute == "" {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidPath, raw)
+	}
+	return p, nil
+}
+
+// applyPatch applique une opération sur la représentation JSON générique d'une ressource
+func applyPatch(doc map[string]interface{}, op PatchOperation) error {
+	kind := strings.ToLower(op.Op)
+	if kind != "add" && kind != "replace" && kind != "remove" {
+		return fmt.Errorf("%w: unsupported patch operation %q", ErrInvalidValue, op.Op)
+	}
+
+	if op.Path == "" {
+		if kind == "remove" {
+			return fmt.Errorf("%w: remove requires a path", ErrNoTarget)
+		}
+		values, ok := op.Value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%w: patch without path requires an object value", ErrInvalidValue)
+		}
+		for key, value := range values {
+			if err := applyPatch(doc, PatchOperation{Op: op.Op, Path: key, Value: value}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	path, err := parsePatchPath(op.Path)
+	if err != nil {
+		return err
+	}
+	key := lookupKey(doc, path.attribute)
+
+	if path.filter != nil {
+		return patchFiltered(doc, key, path, kind, op.Value)
+	}
+
+	if path.sub != "" {
+		child, _ := doc[key].(map[string]interface{})
+		if child == nil {
+			if kind == "remove" {
+				return nil
+			}
+			child = map[string]interface{}{}
+			doc[key] = child
+		}
+		return applyPatch(child, PatchOperation{Op: op.Op, Path: path.sub, Value: op.Value})
+	}
+
+	switch kind {
+	case "remove":
+		if items, ok := op.Value.([]interface{}); ok {
+			doc[key] = withoutValues(doc[key], items)
+		} else {
+			delete(doc, key)
+		}
+	case "add":
+		if items, ok := op.Value.([]interface{}); ok {
+			existing, _ := doc[key].([]interface{})
+			doc[key] = append(withoutValues(existing, items), items...)
+		} else {
+			doc[key] = normalize(key, op.Value)
+		}
+	default:
+		doc[key] = normalize(key, op.Value)
+	}
+	return nil
+}
+
+// patchFiltered traite les chemins de la forme members[value eq "12"] ou emails[type eq "work"].value
+func patchFiltered(doc map[string]interface{}, key string, path *patchPath, kind string, value interface{}) error {
+	items, _ := doc[key].([]interface{})
+	var kept []interface{}
+	matched := false
+
+	for _, item := range items {
+		element, ok := item.(map[string]interface{})
+		if !ok || !matches(element, path.filter) {
+			kept = append(kept, item)
+			continue
+		}
+		matched = true
+
+		switch {
+		case kind == "remove" && path.sub == "":
+			continue
+		case kind == "remove":
+			delete(element, lookupKey(element, path.sub))
+		case path.sub == "":
+			if replacement, ok := value.(map[string]interface{}); ok {
+				element = replacement
+			}
+		default:
+			element[lookupKey(element, path.sub)] = value
+		}
+		kept = append(kept, element)
+	}
+
+	// Un add/replace sans élément correspondant crée l'élément (ex. le premier numéro « mobile »)
+	if !matched && kind != "remove" {
+		element := map[string]interface{}{path.filter.Attribute: path.filter.Value}
+		if path.sub != "" {
+			element[path.sub] = value
+		} else if replacement, ok := value.(map[string]interface{}); ok {
+			for k, v := range replacement {
+				element[k] = v
+			}
+		}
+		kept = append(kept, element)
+	}
+
+	doc[key] = kept
+	return nil
+}
+
+func matches(element map[string]interface{}, c *Condition) bool {
+	v, ok := element[lookupKey(element, c.Attribute)]
+	if !ok {
+		return false
+	}
+	return strings.EqualFold(fmt.Sprint(v), c.Value)
+}
+
+// withoutValues retire d'une liste multi-valuée les éléments dont la « value » figure dans items
+func withoutValues(current interface{}, items []interface{}) []interface{} {
+	existing, _ := current.([]interface{})
+	remove := map[string]bool{}
+	for _, item := range items {
+		if element, ok := item.(map[string]interface{}); ok {
+			remove[fmt.Sprint(element["value"])] = true
+		}
+	}
+
+	var kept []interface{}
+	for _, item := range existing {
+		if element, ok := item.(map[string]interface{}); ok && remove[fmt.Sprint(element["value"])] {
+			continue
+		}
+		kept = append(kept, item)
+	}
+	return kept
+}
+
+// lookupKey retrouve la clé existante sans tenir compte de la casse (les attributs SCIM y sont insensibles)
+func lookupKey(doc map[string]interface{}, name string) string {
+	for key := range doc {
+		if strings.EqualFold(key, name) {
+			return key
+		}
+	}
+	return name
+}
+
+// normalize convertit les booléens envoyés en chaîne (« False ») par certains IdP
+func normalize(key string, value interface{}) interface{} {
+	if s, ok := value.(string); ok && strings.EqualFold(key, "active") {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return value
+}
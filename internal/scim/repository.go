@@ -0,0 +1,495 @@
+package scim
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Colonnes SQL correspondant aux attributs filtrables
+var (
+	userFilterColumns = map[string]string{
+		"userName":     "LOWER(u.email) = LOWER(%s)",
+		"emails.value": "LOWER(u.email) = LOWER(%s)",
+		"externalId":   "s.external_id = %s",
+		"active":       "u.active = %s",
+	}
+	groupFilterColumns = map[string]string{
+		"displayName": "LOWER(g.display_name) = LOWER(%s)",
+		"externalId":  "g.external_id = %s",
+	}
+)
+
+// groupSelect lit les groupes avec le rôle que leur accorde la correspondance configurée par un
+// administrateur pour le tenant
+const groupSelect = `SELECT g.id, g.tenant, g.display_name, g.external_id, rm.role, g.created_at, g.updated_at
+	FROM groups g LEFT JOIN scim_role_mappings rm ON rm.tenant = g.tenant AND rm.group_name = g.display_name`
+
+// Membership rattache un utilisateur au tenant qui l'a provisionné
+type Membership struct {
+	UserID     int
+	ExternalID string
+}
+
+type SCIMRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewSCIMRepository(db *sql.DB) *SCIMRepository {
+	return &SCIMRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// startQuery ouvre le span d'une requête du dépôt
+func (r *SCIMRepository) startQuery(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracing.StartQuery(ctx, "SCIMRepository."+method, r.dialect.System(), operation)
+}
+
+func (r *SCIMRepository) SaveToken(ctx context.Context, tenant, tokenHash string) (err error) {
+	ctx, span := r.startQuery(ctx, "SaveToken", "INSERT scim_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx,
+		r.dialect.Rebind(`INSERT INTO scim_tokens (tenant, token_hash) VALUES ($1, $2)
+		ON CONFLICT (tenant) DO UPDATE SET token_hash = EXCLUDED.token_hash, created_at = CURRENT_TIMESTAMP`),
+		tenant, tokenHash)
+	if err != nil {
+		return fmt.Errorf("error saving scim token: %w", err)
+	}
+	return nil
+}
+
+func (r *SCIMRepository) DeleteToken(ctx context.Context, tenant string) (err error) {
+	ctx, span := r.startQuery(ctx, "DeleteToken", "DELETE scim_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM scim_tokens WHERE tenant = $1"), tenant)
+	if err != nil {
+		return fmt.Errorf("error deleting scim token: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("scim token %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *SCIMRepository) FindTenantByToken(ctx context.Context, tokenHash string) (_ string, err error) {
+	ctx, span := r.startQuery(ctx, "FindTenantByToken", "SELECT scim_tokens")
+	defer func() { tracing.End(span, err) }()
+
+	var tenant string
+	err = r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT tenant FROM scim_tokens WHERE token_hash = $1"), tokenHash).Scan(&tenant)
+	if err == sql.ErrNoRows {
+		return "", fmt.Errorf("scim token %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return "", err
+	}
+	return tenant, nil
+}
+
+func (r *SCIMRepository) SetExternalID(ctx context.Context, tenant string, userID int, externalID string) (err error) {
+	ctx, span := r.startQuery(ctx, "SetExternalID", "UPDATE scim_users")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx,
+		r.dialect.Rebind("UPDATE scim_users SET external_id = NULLIF($1, '') WHERE tenant = $2 AND user_id = $3"),
+		externalID, tenant, userID)
+	if err != nil {
+		return fmt.Errorf("error updating scim user: %w", database.MapError(err))
+	}
+	return nil
+}
+
+func (r *SCIMRepository) FindUser(ctx context.Context, tenant string, userID int) (_ *Membership, err error) {
+	ctx, span := r.startQuery(ctx, "FindUser", "SELECT scim_users")
+	defer func() { tracing.End(span, err) }()
+
+	m := Membership{UserID: userID}
+	var externalID sql.NullString
+	err = r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT external_id FROM scim_users WHERE tenant = $1 AND user_id = $2"), tenant, userID).
+		Scan(&externalID)
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("scim user %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m.ExternalID = externalID.String
+	return &m, nil
+}
+
+// ListUsers retourne une page des utilisateurs du tenant et le nombre total de résultats
+func (r *SCIMRepository) ListUsers(ctx context.Context, tenant string, conditions []Condition, page Page) (_ []Membership, _ int, err error) {
+	ctx, span := r.startQuery(ctx, "ListUsers", "SELECT scim_users")
+	defer func() { tracing.End(span, err) }()
+
+	where, args := whereClause("s.tenant = $1", tenant, conditions, userFilterColumns)
+	from := "FROM scim_users s JOIN users u ON u.id = s.user_id WHERE " + where
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT COUNT(*) "+from), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting scim users: %w", err)
+	}
+
+	args = append(args, page.Count, page.StartIndex-1)
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind(fmt.Sprintf("SELECT s.user_id, s.external_id %s ORDER BY s.user_id LIMIT $%d OFFSET $%d", from, len(args)-1, len(args))),
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing scim users: %w", err)
+	}
+	defer rows.Close()
+
+	var memberships []Membership
+	for rows.Next() {
+		var m Membership
+		var externalID sql.NullString
+		if err := rows.Scan(&m.UserID, &externalID); err != nil {
+			return nil, 0, err
+		}
+		m.ExternalID = externalID.String
+		memberships = append(memberships, m)
+	}
+	return memberships, total, rows.Err()
+}
+
+// CountUsers compte parmi userIDs ceux qui appartiennent au tenant
+func (r *SCIMRepository) CountUsers(ctx context.Context, tenant string, userIDs []int) (_ int, err error) {
+	ctx, span := r.startQuery(ctx, "CountUsers", "SELECT scim_users")
+	defer func() { tracing.End(span, err) }()
+
+	if len(userIDs) == 0 {
+		return 0, nil
+	}
+	args := []interface{}{tenant}
+	for _, id := range userIDs {
+		args = append(args, id)
+	}
+
+	var count int
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("SELECT COUNT(DISTINCT user_id) FROM scim_users WHERE tenant = $1 AND user_id IN "+database.InList(2, len(userIDs))),
+		args...).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("error counting scim users: %w", err)
+	}
+	return count, nil
+}
+
+func (r *SCIMRepository) CreateGroup(ctx context.Context, g *Group) (err error) {
+	ctx, span := r.startQuery(ctx, "CreateGroup", "INSERT groups")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		r.dialect.Rebind(`INSERT INTO groups (tenant, display_name, external_id) VALUES ($1, $2, NULLIF($3, ''))
+		RETURNING id, created_at, updated_at`),
+		g.Tenant, g.DisplayName, g.ExternalID).Scan(&g.ID, &g.CreatedAt, &g.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting group: %w", database.MapError(err))
+	}
+	if err := r.insertMembers(ctx, tx, g.ID, g.Members); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// UpdateGroup remplace les attributs et la liste des membres du groupe
+func (r *SCIMRepository) UpdateGroup(ctx context.Context, g *Group) (err error) {
+	ctx, span := r.startQuery(ctx, "UpdateGroup", "UPDATE groups")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowContext(ctx,
+		r.dialect.Rebind(`UPDATE groups SET display_name = $1, external_id = NULLIF($2, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND tenant = $4 RETURNING updated_at`),
+		g.DisplayName, g.ExternalID, g.ID, g.Tenant).Scan(&g.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("group %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("error updating group: %w", database.MapError(err))
+	}
+
+	if _, err := tx.ExecContext(ctx, r.dialect.Rebind("DELETE FROM group_members WHERE group_id = $1"), g.ID); err != nil {
+		return fmt.Errorf("error clearing group members: %w", err)
+	}
+	if err := r.insertMembers(ctx, tx, g.ID, g.Members); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *SCIMRepository) insertMembers(ctx context.Context, tx *sql.Tx, groupID int, userIDs []int) error {
+	for _, userID := range userIDs {
+		_, err := tx.ExecContext(ctx,
+			r.dialect.Rebind("INSERT INTO group_members (group_id, user_id) VALUES ($1, $2) ON CONFLICT DO NOTHING"),
+			groupID, userID)
+		if err != nil {
+			return fmt.Errorf("error inserting group members: %w", err)
+		}
+	}
+	return nil
+}
+
+func (r *SCIMRepository) FindGroup(ctx context.Context, tenant string, id int) (_ *Group, err error) {
+	ctx, span := r.startQuery(ctx, "FindGroup", "SELECT groups")
+	defer func() { tracing.End(span, err) }()
+
+	g, err := scanGroup(r.db.QueryRowContext(ctx,
+		r.dialect.Rebind(groupSelect+" WHERE g.tenant = $1 AND g.id = $2"),
+		tenant, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("group %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := r.loadMembers(ctx, []*Group{g}); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+func (r *SCIMRepository) ListGroups(ctx context.Context, tenant string, conditions []Condition, page Page) (_ []*Group, _ int, err error) {
+	ctx, span := r.startQuery(ctx, "ListGroups", "SELECT groups")
+	defer func() { tracing.End(span, err) }()
+
+	where, args := whereClause("g.tenant = $1", tenant, conditions, groupFilterColumns)
+
+	var total int
+	if err := r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT COUNT(*) FROM groups g WHERE "+where), args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("error counting groups: %w", err)
+	}
+
+	args = append(args, page.Count, page.StartIndex-1)
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind(fmt.Sprintf("%s WHERE %s ORDER BY g.id LIMIT $%d OFFSET $%d", groupSelect, where, len(args)-1, len(args))),
+		args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*Group
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, 0, err
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if err := r.loadMembers(ctx, groups); err != nil {
+		return nil, 0, err
+	}
+	return groups, total, nil
+}
+
+func (r *SCIMRepository) DeleteGroup(ctx context.Context, tenant string, id int) (err error) {
+	ctx, span := r.startQuery(ctx, "DeleteGroup", "DELETE groups")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM groups WHERE tenant = $1 AND id = $2"), tenant, id)
+	if err != nil {
+		return fmt.Errorf("error deleting group: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("group %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+// GroupsOfUser retourne les groupes du tenant dont l'utilisateur est membre (sans leurs membres)
+func (r *SCIMRepository) GroupsOfUser(ctx context.Context, tenant string, userID int) (_ []*Group, err error) {
+	ctx, span := r.startQuery(ctx, "GroupsOfUser", "SELECT groups")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind(groupSelect+` JOIN group_members m ON m.group_id = g.id
+		WHERE g.tenant = $1 AND m.user_id = $2 ORDER BY g.id`),
+		tenant, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing user groups: %w", err)
+	}
+	defer rows.Close()
+
+	var groups []*Group
+	for rows.Next() {
+		g, err := scanGroup(rows)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// IsInAdminGroup indique si l'un des groupes de l'utilisateur, tous tenants confondus, accorde le
+// rôle admin d'après la correspondance configurée pour son tenant
+func (r *SCIMRepository) IsInAdminGroup(ctx context.Context, userID int) (_ bool, err error) {
+	ctx, span := r.startQuery(ctx, "IsInAdminGroup", "SELECT group_members")
+	defer func() { tracing.End(span, err) }()
+
+	var admin bool
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind(`SELECT EXISTS (SELECT 1 FROM group_members m JOIN groups g ON g.id = m.group_id
+		JOIN scim_role_mappings rm ON rm.tenant = g.tenant AND rm.group_name = g.display_name
+		WHERE m.user_id = $1 AND rm.role = 'admin')`),
+		userID).Scan(&admin)
+	if err != nil {
+		return false, fmt.Errorf("error checking admin groups: %w", err)
+	}
+	return admin, nil
+}
+
+// GroupMembers retourne les membres de tous les groupes du tenant
+func (r *SCIMRepository) GroupMembers(ctx context.Context, tenant string) (_ []int, err error) {
+	ctx, span := r.startQuery(ctx, "GroupMembers", "SELECT group_members")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind(`SELECT DISTINCT m.user_id FROM group_members m JOIN groups g ON g.id = m.group_id
+		WHERE g.tenant = $1 ORDER BY m.user_id`),
+		tenant)
+	if err != nil {
+		return nil, fmt.Errorf("error listing group members: %w", err)
+	}
+	defer rows.Close()
+
+	var userIDs []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		userIDs = append(userIDs, id)
+	}
+	return userIDs, rows.Err()
+}
+
+// RoleMapping retourne la correspondance nom de groupe → rôle configurée pour le tenant
+func (r *SCIMRepository) RoleMapping(ctx context.Context, tenant string) (_ map[string]string, err error) {
+	ctx, span := r.startQuery(ctx, "RoleMapping", "SELECT scim_role_mappings")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind("SELECT group_name, role FROM scim_role_mappings WHERE tenant = $1"), tenant)
+	if err != nil {
+		return nil, fmt.Errorf("error listing scim role mappings: %w", err)
+	}
+	defer rows.Close()
+
+	mapping := map[string]string{}
+	for rows.Next() {
+		var group, role string
+		if err := rows.Scan(&group, &role); err != nil {
+			return nil, err
+		}
+		mapping[group] = role
+	}
+	return mapping, rows.Err()
+}
+
+// SaveRoleMapping remplace la correspondance des rôles du tenant
+func (r *SCIMRepository) SaveRoleMapping(ctx context.Context, tenant string, mapping map[string]string) (err error) {
+	ctx, span := r.startQuery(ctx, "SaveRoleMapping", "INSERT scim_role_mappings")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, r.dialect.Rebind("DELETE FROM scim_role_mappings WHERE tenant = $1"), tenant); err != nil {
+		return fmt.Errorf("error clearing scim role mappings: %w", err)
+	}
+	for group, role := range mapping {
+		_, err := tx.ExecContext(ctx,
+			r.dialect.Rebind("INSERT INTO scim_role_mappings (tenant, group_name, role) VALUES ($1, $2, $3)"),
+			tenant, group, role)
+		if err != nil {
+			return fmt.Errorf("error inserting scim role mapping: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *SCIMRepository) loadMembers(ctx context.Context, groups []*Group) error {
+	if len(groups) == 0 {
+		return nil
+	}
+	byID := make(map[int]*Group, len(groups))
+	ids := make([]interface{}, 0, len(groups))
+	for _, g := range groups {
+		byID[g.ID] = g
+		ids = append(ids, g.ID)
+	}
+
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind("SELECT group_id, user_id FROM group_members WHERE group_id IN "+database.InList(1, len(ids))+" ORDER BY user_id"), ids...)
+	if err != nil {
+		return fmt.Errorf("error listing group members: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupID, userID int
+		if err := rows.Scan(&groupID, &userID); err != nil {
+			return err
+		}
+		byID[groupID].Members = append(byID[groupID].Members, userID)
+	}
+	return rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGroup(row rowScanner) (*Group, error) {
+	var g Group
+	var externalID, role sql.NullString
+	if err := row.Scan(&g.ID, &g.Tenant, &g.DisplayName, &externalID, &role, &g.CreatedAt, &g.UpdatedAt); err != nil {
+		return nil, err
+	}
+	g.ExternalID = externalID.String
+	g.Role = role.String
+	return &g, nil
+}
+
+// whereClause ajoute les conditions du filtre à la restriction sur le tenant, en paramètres liés.
+// Les booléens sont liés comme tels : SQLite les stocke sous forme d'entiers.
+func whereClause(base, tenant string, conditions []Condition, columns map[string]string) (string, []interface{}) {
+	clauses := []string{base}
+	args := []interface{}{tenant}
+	for _, c := range conditions {
+		var value interface{} = c.Value
+		if b, err := strconv.ParseBool(c.Value); err == nil && c.Attribute == "active" {
+			value = b
+		}
+		args = append(args, value)
+		clauses = append(clauses, fmt.Sprintf(columns[c.Attribute], fmt.Sprintf("$%d", len(args))))
+	}
+	return strings.Join(clauses, " AND "), args
+}
@@ -0,0 +1,135 @@
+package scim
+
+import (
+	"strconv"
+	"time"
+)
+
+// URNs des schémas SCIM 2.0 (RFC 7643 / RFC 7644)
+const (
+	UserSchema          = "urn:ietf:params:scim:schemas:core:2.0:User"
+	GroupSchema         = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	ListResponseSchema  = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	PatchOpSchema       = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	ErrorSchema         = "urn:ietf:params:scim:api:messages:2.0:Error"
+	ServiceConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+
+	// GroupExtensionSchema porte, en lecture seule, le rôle applicatif accordé aux membres d'un groupe
+	GroupExtensionSchema = "urn:pathi14:params:scim:schemas:extension:authgo:2.0:Group"
+)
+
+const (
+	defaultCount = 100
+	maxCount     = 200
+)
+
+// Group est un groupe poussé par l'IdP d'un tenant. Role, accordé à ses membres, provient de la
+// correspondance configurée par un administrateur pour le tenant.
+type Group struct {
+	ID          int
+	Tenant      string
+	DisplayName string
+	ExternalID  string
+	Role        string
+	Members     []int
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// Member est la référence d'un utilisateur ou d'un groupe dans une ressource SCIM
+type Member struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+type Name struct {
+	Formatted  string `json:"formatted,omitempty"`
+	GivenName  string `json:"givenName,omitempty"`
+	FamilyName string `json:"familyName,omitempty"`
+}
+
+type MultiValued struct {
+	Value   string `json:"value"`
+	Type    string `json:"type,omitempty"`
+	Primary bool   `json:"primary,omitempty"`
+}
+
+type Meta struct {
+	ResourceType string     `json:"resourceType"`
+	Created      *time.Time `json:"created,omitempty"`
+	LastModified *time.Time `json:"lastModified,omitempty"`
+	Location     string     `json:"location,omitempty"`
+}
+
+type UserResource struct {
+	Schemas      []string      `json:"schemas"`
+	ID           string        `json:"id,omitempty"`
+	ExternalID   string        `json:"externalId,omitempty"`
+	UserName     string        `json:"userName"`
+	Name         *Name         `json:"name,omitempty"`
+	DisplayName  string        `json:"displayName,omitempty"`
+	Emails       []MultiValued `json:"emails,omitempty"`
+	PhoneNumbers []MultiValued `json:"phoneNumbers,omitempty"`
+	Active       *bool         `json:"active,omitempty"`
+	Groups       []Member      `json:"groups,omitempty"`
+	Meta         *Meta         `json:"meta,omitempty"`
+}
+
+type GroupExtension struct {
+	Role string `json:"role,omitempty"`
+}
+
+type GroupResource struct {
+	Schemas     []string        `json:"schemas"`
+	ID          string          `json:"id,omitempty"`
+	ExternalID  string          `json:"externalId,omitempty"`
+	DisplayName string          `json:"displayName"`
+	Members     []Member        `json:"members"`
+	Extension   *GroupExtension `json:"urn:pathi14:params:scim:schemas:extension:authgo:2.0:Group,omitempty"`
+	Meta        *Meta           `json:"meta,omitempty"`
+}
+
+type ListResponse struct {
+	Schemas      []string    `json:"schemas"`
+	TotalResults int         `json:"totalResults"`
+	StartIndex   int         `json:"startIndex"`
+	ItemsPerPage int         `json:"itemsPerPage"`
+	Resources    interface{} `json:"Resources"`
+}
+
+// PatchOperation est une opération d'une requête PATCH ; Value reste brute car sa forme dépend de Path
+type PatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+type PatchRequest struct {
+	Schemas    []string         `json:"schemas"`
+	Operations []PatchOperation `json:"Operations"`
+}
+
+// Page décrit la pagination demandée (startIndex commence à 1)
+type Page struct {
+	StartIndex int
+	Count      int
+}
+
+// ParsePage lit startIndex et count en appliquant les bornes de la RFC 7644 §3.4.2.4
+func ParsePage(startIndex, count string) Page {
+	p := Page{StartIndex: 1, Count: defaultCount}
+	if n, err := strconv.Atoi(startIndex); err == nil && n > 1 {
+		p.StartIndex = n
+	}
+	if n, err := strconv.Atoi(count); err == nil {
+		p.Count = n
+	}
+	if p.Count < 0 {
+		p.Count = 0
+	}
+	if p.Count > maxCount {
+		p.Count = maxCount
+	}
+	return p
+}
@@ -0,0 +1,568 @@
+package scim
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// TokenPrefix identifie les jetons SCIM dans les en-têtes Authorization
+const TokenPrefix = "scim_"
+
+var (
+	tenantPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,39}$`)
+
+	userFilterAttributes = map[string]string{
+		"username":     "userName",
+		"emails":       "emails.value",
+		"emails.value": "emails.value",
+		"externalid":   "externalId",
+		"active":       "active",
+	}
+	groupFilterAttributes = map[string]string{
+		"displayname": "displayName",
+		"externalid":  "externalId",
+	}
+)
+
+// UserDirectory regroupe les opérations du domaine utilisateur utilisées par le provisionnement
+type UserDirectory interface {
+	ProvisionUser(ctx context.Context, u user.User, membership user.TenantMembership) (int, error)
+	GetUserByID(ctx context.Context, id int) (*user.User, error)
+	FindUsers(ctx context.Context, ids []int) ([]user.User, error)
+	UpdateUser(ctx context.Context, u user.User) error
+	DeleteUser(ctx context.Context, id int) error
+	SetRole(ctx context.Context, userID int, role string) error
+}
+
+type SCIMService struct {
+	repo  *SCIMRepository
+	users UserDirectory
+}
+
+func NewSCIMService(repo *SCIMRepository, users UserDirectory) *SCIMService {
+	return &SCIMService{repo: repo, users: users}
+}
+
+// IssueToken génère (ou remplace) le jeton bearer du tenant ; il n'est retourné qu'une fois
+func (s *SCIMService) IssueToken(ctx context.Context, tenant string) (string, error) {
+	if !tenantPattern.MatchString(tenant) {
+		return "", fmt.Errorf("%w: tenant must match %s", apperror.ErrValidation, tenantPattern)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("internal error: %v", err)
+	}
+	token := TokenPrefix + hex.EncodeToString(b)
+
+	if err := s.repo.SaveToken(ctx, tenant, hashToken(token)); err != nil {
+		return "", fmt.Errorf("internal error: %v", err)
+	}
+	return token, nil
+}
+
+func (s *SCIMService) RevokeToken(ctx context.Context, tenant string) error {
+	if err := s.repo.DeleteToken(ctx, tenant); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: no scim token for tenant %s", apperror.ErrNotFound, tenant)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// Authenticate retourne le tenant associé au jeton bearer
+func (s *SCIMService) Authenticate(ctx context.Context, token string) (string, error) {
+	if !strings.HasPrefix(token, TokenPrefix) {
+		return "", fmt.Errorf("%w: invalid scim token", apperror.ErrUnauthorized)
+	}
+	tenant, err := s.repo.FindTenantByToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return "", fmt.Errorf("%w: invalid scim token", apperror.ErrUnauthorized)
+		}
+		return "", fmt.Errorf("internal error: %v", err)
+	}
+	return tenant, nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *SCIMService) CreateUser(ctx context.Context, tenant string, res *UserResource) (*UserResource, error) {
+	u, err := toUser(res)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := s.users.ProvisionUser(ctx, u, user.TenantMembership{Tenant: tenant, ExternalID: res.ExternalID})
+	if err != nil {
+		if errors.Is(err, user.ErrExternalIDInUse) {
+			return nil, uniqueness(err, "externalId")
+		}
+		return nil, err
+	}
+	return s.GetUser(ctx, tenant, id)
+}
+
+func (s *SCIMService) GetUser(ctx context.Context, tenant string, id int) (*UserResource, error) {
+	membership, err := s.membership(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	u, err := s.users.GetUserByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.repo.GroupsOfUser(ctx, tenant, id)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return userResource(u, membership, groups), nil
+}
+
+func (s *SCIMService) ListUsers(ctx context.Context, tenant, filter string, page Page) ([]*UserResource, int, error) {
+	conditions, err := ParseFilter(filter, userFilterAttributes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	memberships, total, err := s.repo.ListUsers(ctx, tenant, conditions, page)
+	if err != nil {
+		return nil, 0, fmt.Errorf("internal error: %v", err)
+	}
+	if len(memberships) == 0 {
+		return []*UserResource{}, total, nil
+	}
+
+	ids := make([]int, len(memberships))
+	for i, m := range memberships {
+		ids[i] = m.UserID
+	}
+	users, err := s.users.FindUsers(ctx, ids)
+	if err != nil {
+		return nil, 0, err
+	}
+	byID := make(map[int]*user.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	resources := make([]*UserResource, 0, len(memberships))
+	for i := range memberships {
+		u, ok := byID[memberships[i].UserID]
+		if !ok {
+			continue
+		}
+		groups, err := s.repo.GroupsOfUser(ctx, tenant, u.ID)
+		if err != nil {
+			return nil, 0, fmt.Errorf("internal error: %v", err)
+		}
+		resources = append(resources, userResource(u, &memberships[i], groups))
+	}
+	return resources, total, nil
+}
+
+func (s *SCIMService) ReplaceUser(ctx context.Context, tenant string, id int, res *UserResource) (*UserResource, error) {
+	if _, err := s.membership(ctx, tenant, id); err != nil {
+		return nil, err
+	}
+
+	u, err := toUser(res)
+	if err != nil {
+		return nil, err
+	}
+	u.ID = id
+	if err := s.users.UpdateUser(ctx, u); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetExternalID(ctx, tenant, id, res.ExternalID); err != nil {
+		return nil, uniqueness(err, "externalId")
+	}
+	return s.GetUser(ctx, tenant, id)
+}
+
+func (s *SCIMService) PatchUser(ctx context.Context, tenant string, id int, patch *PatchRequest) (*UserResource, error) {
+	current, err := s.GetUser(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched UserResource
+	if err := patchResource(current, patch, &patched); err != nil {
+		return nil, err
+	}
+	patched.Name = &Name{Formatted: patchedName(current, &patched)}
+	return s.ReplaceUser(ctx, tenant, id, &patched)
+}
+
+// patchedName retient l'attribut de nom effectivement modifié par le PATCH : name.formatted,
+// displayName ou givenName/familyName, dans cet ordre
+func patchedName(current, patched *UserResource) string {
+	switch {
+	case patched.Name != nil && patched.Name.Formatted != "" && patched.Name.Formatted != current.Name.Formatted:
+		return patched.Name.Formatted
+	case patched.DisplayName != "" && patched.DisplayName != current.DisplayName:
+		return patched.DisplayName
+	case patched.Name != nil && (patched.Name.GivenName != "" || patched.Name.FamilyName != ""):
+		return strings.TrimSpace(patched.Name.GivenName + " " + patched.Name.FamilyName)
+	}
+	return current.Name.Formatted
+}
+
+func (s *SCIMService) DeleteUser(ctx context.Context, tenant string, id int) error {
+	if _, err := s.membership(ctx, tenant, id); err != nil {
+		return err
+	}
+	return s.users.DeleteUser(ctx, id)
+}
+
+func (s *SCIMService) membership(ctx context.Context, tenant string, id int) (*Membership, error) {
+	m, err := s.repo.FindUser(ctx, tenant, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user %d does not exist", apperror.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return m, nil
+}
+
+func (s *SCIMService) CreateGroup(ctx context.Context, tenant string, res *GroupResource) (*GroupResource, error) {
+	g, err := s.toGroup(ctx, tenant, res)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateGroup(ctx, g); err != nil {
+		return nil, uniqueness(err, "displayName")
+	}
+	if g.Role != "" {
+		if err := s.syncRoles(ctx, g.Members); err != nil {
+			return nil, err
+		}
+	}
+	return s.groupResource(ctx, g)
+}
+
+func (s *SCIMService) GetGroup(ctx context.Context, tenant string, id int) (*GroupResource, error) {
+	g, err := s.findGroup(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.groupResource(ctx, g)
+}
+
+func (s *SCIMService) ListGroups(ctx context.Context, tenant, filter string, page Page) ([]*GroupResource, int, error) {
+	conditions, err := ParseFilter(filter, groupFilterAttributes)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	groups, total, err := s.repo.ListGroups(ctx, tenant, conditions, page)
+	if err != nil {
+		return nil, 0, fmt.Errorf("internal error: %v", err)
+	}
+
+	resources := make([]*GroupResource, 0, len(groups))
+	for _, g := range groups {
+		res, err := s.groupResource(ctx, g)
+		if err != nil {
+			return nil, 0, err
+		}
+		resources = append(resources, res)
+	}
+	return resources, total, nil
+}
+
+func (s *SCIMService) ReplaceGroup(ctx context.Context, tenant string, id int, res *GroupResource) (*GroupResource, error) {
+	previous, err := s.findGroup(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	g, err := s.toGroup(ctx, tenant, res)
+	if err != nil {
+		return nil, err
+	}
+	g.ID = id
+	if err := s.repo.UpdateGroup(ctx, g); err != nil {
+		return nil, uniqueness(err, "displayName")
+	}
+	g.CreatedAt = previous.CreatedAt
+
+	if previous.Role != "" || g.Role != "" {
+		if err := s.syncRoles(ctx, append(previous.Members, g.Members...)); err != nil {
+			return nil, err
+		}
+	}
+	return s.groupResource(ctx, g)
+}
+
+func (s *SCIMService) PatchGroup(ctx context.Context, tenant string, id int, patch *PatchRequest) (*GroupResource, error) {
+	current, err := s.GetGroup(ctx, tenant, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var patched GroupResource
+	if err := patchResource(current, patch, &patched); err != nil {
+		return nil, err
+	}
+	return s.ReplaceGroup(ctx, tenant, id, &patched)
+}
+
+func (s *SCIMService) DeleteGroup(ctx context.Context, tenant string, id int) error {
+	previous, err := s.findGroup(ctx, tenant, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.DeleteGroup(ctx, tenant, id); err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if previous.Role != "" {
+		return s.syncRoles(ctx, previous.Members)
+	}
+	return nil
+}
+
+func (s *SCIMService) findGroup(ctx context.Context, tenant string, id int) (*Group, error) {
+	g, err := s.repo.FindGroup(ctx, tenant, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, fmt.Errorf("%w: group %d does not exist", apperror.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return g, nil
+}
+
+// RoleMapping retourne les rôles accordés aux groupes du tenant, par nom de groupe
+func (s *SCIMService) RoleMapping(ctx context.Context, tenant string) (map[string]string, error) {
+	if !tenantPattern.MatchString(tenant) {
+		return nil, fmt.Errorf("%w: tenant must match %s", apperror.ErrValidation, tenantPattern)
+	}
+	mapping, err := s.repo.RoleMapping(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return mapping, nil
+}
+
+// SetRoleMapping remplace les rôles accordés aux groupes du tenant et recalcule le rôle de
+// leurs membres
+func (s *SCIMService) SetRoleMapping(ctx context.Context, tenant string, mapping map[string]string) error {
+	if !tenantPattern.MatchString(tenant) {
+		return fmt.Errorf("%w: tenant must match %s", apperror.ErrValidation, tenantPattern)
+	}
+	for group, role := range mapping {
+		if strings.TrimSpace(group) == "" {
+			return fmt.Errorf("%w: group name is required", apperror.ErrValidation)
+		}
+		if role != user.RoleUser && role != user.RoleAdmin {
+			return fmt.Errorf("%w: unknown role %q for group %q", apperror.ErrValidation, role, group)
+		}
+	}
+
+	if err := s.repo.SaveRoleMapping(ctx, tenant, mapping); err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	members, err := s.repo.GroupMembers(ctx, tenant)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return s.syncRoles(ctx, members)
+}
+
+// syncRoles recalcule le rôle des utilisateurs dont l'appartenance à un groupe porteur de rôle a changé
+func (s *SCIMService) syncRoles(ctx context.Context, userIDs []int) error {
+	seen := map[int]bool{}
+	for _, id := range userIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		admin, err := s.repo.IsInAdminGroup(ctx, id)
+		if err != nil {
+			return fmt.Errorf("internal error: %v", err)
+		}
+		role := user.RoleUser
+		if admin {
+			role = user.RoleAdmin
+		}
+		if err := s.users.SetRole(ctx, id, role); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// toUser convertit une ressource SCIM en utilisateur ; userName doit être une adresse email
+func toUser(res *UserResource) (user.User, error) {
+	if _, err := mail.ParseAddress(res.UserName); err != nil {
+		return user.User{}, fmt.Errorf("%w: userName must be an email address", apperror.ErrValidation)
+	}
+
+	u := user.User{Email: res.UserName, Active: res.Active == nil || *res.Active}
+	switch {
+	case res.Name != nil && res.Name.Formatted != "":
+		u.Name = res.Name.Formatted
+	case res.DisplayName != "":
+		u.Name = res.DisplayName
+	case res.Name != nil && (res.Name.GivenName != "" || res.Name.FamilyName != ""):
+		u.Name = strings.TrimSpace(res.Name.GivenName + " " + res.Name.FamilyName)
+	default:
+		u.Name, _, _ = strings.Cut(res.UserName, "@")
+	}
+	if len(u.Name) > 50 {
+		u.Name = u.Name[:50]
+	}
+
+	for _, phone := range res.PhoneNumbers {
+		if u.MobileNumber == "" || phone.Type == "mobile" || phone.Primary {
+			u.MobileNumber = phone.Value
+		}
+	}
+	return u, nil
+}
+
+func userResource(u *user.User, m *Membership, groups []*Group) *UserResource {
+	active := u.Active
+	res := &UserResource{
+		Schemas:     []string{UserSchema},
+		ID:          strconv.Itoa(u.ID),
+		ExternalID:  m.ExternalID,
+		UserName:    u.Email,
+		Name:        &Name{Formatted: u.Name},
+		DisplayName: u.Name,
+		Emails:      []MultiValued{{Value: u.Email, Type: "work", Primary: true}},
+		Active:      &active,
+		Meta:        &Meta{ResourceType: "User"},
+	}
+	if u.MobileNumber != "" {
+		res.PhoneNumbers = []MultiValued{{Value: u.MobileNumber, Type: "mobile"}}
+	}
+	for _, g := range groups {
+		res.Groups = append(res.Groups, Member{Value: strconv.Itoa(g.ID), Display: g.DisplayName})
+	}
+	return res
+}
+
+// toGroup valide la ressource : les membres doivent être des utilisateurs provisionnés par le même
+// tenant. Le rôle du groupe n'est pas lu dans la ressource mais dans la correspondance configurée
+// par un administrateur : le jeton d'un tenant ne peut pas s'accorder le rôle admin.
+func (s *SCIMService) toGroup(ctx context.Context, tenant string, res *GroupResource) (*Group, error) {
+	if strings.TrimSpace(res.DisplayName) == "" {
+		return nil, fmt.Errorf("%w: displayName is required", apperror.ErrValidation)
+	}
+
+	mapping, err := s.repo.RoleMapping(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	g := &Group{Tenant: tenant, DisplayName: res.DisplayName, ExternalID: res.ExternalID, Role: mapping[res.DisplayName]}
+
+	seen := map[int]bool{}
+	for _, member := range res.Members {
+		id, err := strconv.Atoi(member.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid member %q", apperror.ErrValidation, member.Value)
+		}
+		if !seen[id] {
+			seen[id] = true
+			g.Members = append(g.Members, id)
+		}
+	}
+	sort.Ints(g.Members)
+
+	if len(g.Members) > 0 {
+		count, err := s.repo.CountUsers(ctx, tenant, g.Members)
+		if err != nil {
+			return nil, fmt.Errorf("internal error: %v", err)
+		}
+		if count != len(g.Members) {
+			return nil, fmt.Errorf("%w: members must be users of this tenant", apperror.ErrValidation)
+		}
+	}
+	return g, nil
+}
+
+func (s *SCIMService) groupResource(ctx context.Context, g *Group) (*GroupResource, error) {
+	res := &GroupResource{
+		Schemas:     []string{GroupSchema},
+		ID:          strconv.Itoa(g.ID),
+		ExternalID:  g.ExternalID,
+		DisplayName: g.DisplayName,
+		Members:     []Member{},
+		Meta:        &Meta{ResourceType: "Group", Created: &g.CreatedAt, LastModified: &g.UpdatedAt},
+	}
+	if g.Role != "" {
+		res.Schemas = append(res.Schemas, GroupExtensionSchema)
+		res.Extension = &GroupExtension{Role: g.Role}
+	}
+
+	if len(g.Members) > 0 {
+		users, err := s.users.FindUsers(ctx, g.Members)
+		if err != nil {
+			return nil, err
+		}
+		for _, u := range users {
+			res.Members = append(res.Members, Member{Value: strconv.Itoa(u.ID), Display: u.Name})
+		}
+	}
+	return res, nil
+}
+
+// patchResource applique les opérations PATCH sur la représentation JSON de current et décode le résultat dans out
+func patchResource(current interface{}, patch *PatchRequest, out interface{}) error {
+	if len(patch.Operations) == 0 {
+		return fmt.Errorf("%w: no patch operations", ErrInvalidValue)
+	}
+
+	raw, err := json.Marshal(current)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(raw, &doc); err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+
+	for _, op := range patch.Operations {
+		if err := applyPatch(doc, op); err != nil {
+			return err
+		}
+	}
+
+	raw, err = json.Marshal(doc)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if err := json.Unmarshal(raw, out); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	return nil
+}
+
+// uniqueness traduit une violation de contrainte d'unicité en conflit SCIM
+func uniqueness(err error, attribute string) error {
+	if errors.Is(err, apperror.ErrConflict) {
+		return fmt.Errorf("%w: %s must be unique", apperror.ErrConflict, attribute)
+	}
+	return fmt.Errorf("internal error: %v", err)
+}
@@ -0,0 +1,31 @@
+package social
+
+import (
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+)
+
+// NewProviderConfigs retourne la configuration de chaque fournisseur activé dans la section
+// social de la configuration, déjà validée, dans l'ordre de social.providers
+func NewProviderConfigs(c config.Social) []ProviderConfig {
+	var configs []ProviderConfig
+	for _, name := range c.Names() {
+		p := c.Provider[name]
+		configs = append(configs, ProviderConfig{
+			Name:         name,
+			Type:         p.Type,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  p.RedirectURL,
+			Scopes:       strings.Fields(strings.ReplaceAll(p.Scopes, ",", " ")),
+			TrustEmail:   p.TrustEmail,
+			IssuerURL:    p.IssuerURL,
+			AuthURL:      p.AuthURL,
+			TokenURL:     p.TokenURL,
+			UserInfoURL:  p.UserInfoURL,
+			EmailsURL:    p.EmailsURL,
+		})
+	}
+	return configs
+}
@@ -0,0 +1,79 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// githubProvider gère les fournisseurs OAuth2 sans ID token, dont l'identité
+// est lue sur un endpoint « userinfo » à la manière de l'API GitHub
+type githubProvider struct {
+	cfg    ProviderConfig
+	client *http.Client
+}
+
+func (p *githubProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *githubProvider) TrustsEmail() bool {
+	return p.cfg.TrustEmail
+}
+
+func (p *githubProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	return buildAuthURL(p.cfg.AuthURL, p.cfg, state, codeChallenge, nil)
+}
+
+func (p *githubProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	tok, err := exchangeCode(ctx, p.client, p.cfg, p.cfg.TokenURL, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile struct {
+		ID    json.Number `json:"id"`
+		Login string      `json:"login"`
+		Name  string      `json:"name"`
+		Email string      `json:"email"`
+	}
+	if err := getJSON(ctx, p.client, p.cfg.UserInfoURL, tok.AccessToken, &profile); err != nil {
+		return nil, fmt.Errorf("failed to fetch user profile: %w", err)
+	}
+	if profile.ID == "" {
+		return nil, fmt.Errorf("user profile has no id")
+	}
+
+	identity := &Identity{
+		Provider: p.cfg.Name,
+		Subject:  profile.ID.String(),
+		Name:     profile.Name,
+	}
+	if identity.Name == "" {
+		identity.Name = profile.Login
+	}
+
+	// L'email du profil public n'est pas garanti vérifié : on consulte la liste des emails
+	if p.cfg.EmailsURL != "" {
+		var emails []struct {
+			Email    string `json:"email"`
+			Primary  bool   `json:"primary"`
+			Verified bool   `json:"verified"`
+		}
+		if err := getJSON(ctx, p.client, p.cfg.EmailsURL, tok.AccessToken, &emails); err != nil {
+			return nil, fmt.Errorf("failed to fetch user emails: %w", err)
+		}
+		for _, e := range emails {
+			if e.Primary {
+				identity.Email = e.Email
+				identity.EmailVerified = e.Verified
+				break
+			}
+		}
+	} else {
+		identity.Email = profile.Email
+	}
+
+	return identity, nil
+}
@@ -0,0 +1,126 @@
+package social
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+const stateCookie = "social_login_state"
+
+type SocialHandler struct {
+	service *SocialService
+}
+
+func NewSocialHandler(service *SocialService) *SocialHandler {
+	return &SocialHandler{service: service}
+}
+
+func (h *SocialHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": h.service.Providers()})
+}
+
+func (h *SocialHandler) Login(c *gin.Context) {
+	redirectURL, ok := h.begin(c, 0)
+	if !ok {
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectURL)
+}
+
+// Link démarre, pour l'utilisateur connecté, le rattachement d'une identité externe à son compte.
+// L'URL est retournée en JSON car l'appel est fait avec un jeton, hors navigation.
+func (h *SocialHandler) Link(c *gin.Context) {
+	redirectURL, ok := h.begin(c, c.GetInt("userID"))
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"authorization_url": redirectURL})
+}
+
+func (h *SocialHandler) begin(c *gin.Context, linkUserID int) (string, bool) {
+	redirectURL, state, err := h.service.Begin(c.Request.Context(), c.Param("provider"), linkUserID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "social.provider_unknown")
+			return "", false
+		}
+
+		problem.Abort(c, http.StatusBadGateway, problem.CodeProviderError, "social.provider_unavailable")
+		return "", false
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(stateCookie, state, int(stateLifetime.Seconds()), "/", "", isSecure(c), true)
+	return redirectURL, true
+}
+
+func (h *SocialHandler) Callback(c *gin.Context) {
+	if providerErr := c.Query("error"); providerErr != "" {
+		problem.Send(c, problem.New(http.StatusUnauthorized, problem.CodeProviderError,
+			"social.provider_denied").With("provider_error", providerErr))
+		return
+	}
+
+	state, _ := c.Cookie(stateCookie)
+	c.SetCookie(stateCookie, "", -1, "/", "", isSecure(c), true)
+
+	result, err := h.service.Complete(c.Request.Context(),
+		c.Param("provider"), c.Query("code"), c.Query("state"), state)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "social.provider_unknown")
+			return
+		}
+
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			problem.Abort(c, http.StatusUnauthorized, problem.CodeProviderError, "social.provider_auth_failed")
+			return
+		}
+
+		if errors.Is(err, ErrLinkRequired) {
+			problem.Abort(c, http.StatusConflict, problem.CodeLinkRequired, "social.link_required")
+			return
+		}
+
+		if errors.Is(err, user.ErrIdentityLinked) {
+			problem.Abort(c, http.StatusConflict, problem.CodeIdentityLinked, "user.identity_linked")
+			return
+		}
+
+		if errors.Is(err, user.ErrEmailInUse) {
+			problem.Abort(c, http.StatusConflict, problem.CodeEmailInUse, "user.email_in_use")
+			return
+		}
+
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "error.internal")
+		return
+	}
+
+	if result.LinkedUserID != 0 {
+		c.JSON(http.StatusOK, gin.H{"message": i18n.Localize(c, "social.identity_linked")})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      i18n.Localize(c, "user.login.success"),
+		"token":        result.AccessToken,
+		"refreshToken": result.RefreshToken,
+	})
+}
+
+func isSecure(c *gin.Context) bool {
+	return c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+}
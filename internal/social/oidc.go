@@ -0,0 +1,256 @@
+package social
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+type discoveryDocument struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// jwksRefreshInterval est le délai minimal entre deux chargements du JWKS : un kid inconnu
+// (rotation ou jeton forgé) ne recharge pas les clés plus souvent
+const jwksRefreshInterval = time.Minute
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// oidcProvider implémente la découverte OIDC et la vérification de l'ID token (RS256)
+type oidcProvider struct {
+	cfg    ProviderConfig
+	client *http.Client
+
+	mu        sync.Mutex
+	discovery *discoveryDocument
+	keys      map[string]*rsa.PublicKey
+	// keysFetched est la date du dernier chargement du JWKS ; keysLoading, non nil pendant un
+	// chargement, est fermé à sa fin
+	keysFetched time.Time
+	keysLoading chan struct{}
+}
+
+func (p *oidcProvider) Name() string {
+	return p.cfg.Name
+}
+
+func (p *oidcProvider) TrustsEmail() bool {
+	return p.cfg.TrustEmail
+}
+
+func (p *oidcProvider) AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return buildAuthURL(doc.AuthorizationEndpoint, p.cfg, state, codeChallenge, url.Values{"nonce": {nonce}})
+}
+
+func (p *oidcProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error) {
+	doc, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tok, err := exchangeCode(ctx, p.client, p.cfg, doc.TokenEndpoint, code, codeVerifier)
+	if err != nil {
+		return nil, err
+	}
+	if tok.IDToken == "" {
+		return nil, fmt.Errorf("token response does not contain an id_token")
+	}
+
+	claims, err := p.verifyIDToken(ctx, doc, tok.IDToken, nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	identity := &Identity{Provider: p.cfg.Name}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.Name, _ = claims["name"].(string)
+	switch v := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = v
+	case string:
+		identity.EmailVerified = v == "true"
+	}
+
+	if identity.Subject == "" {
+		return nil, fmt.Errorf("id_token has no subject")
+	}
+	return identity, nil
+}
+
+func (p *oidcProvider) verifyIDToken(ctx context.Context, doc *discoveryDocument, raw, nonce string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(raw, claims, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		kid, _ := token.Header["kid"].(string)
+		return p.publicKey(ctx, doc, kid)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid id_token: %w", err)
+	}
+
+	if iss, _ := claims["iss"].(string); iss != doc.Issuer {
+		return nil, fmt.Errorf("invalid id_token: unexpected issuer %q", iss)
+	}
+	if !claims.VerifyAudience(p.cfg.ClientID, true) {
+		return nil, fmt.Errorf("invalid id_token: audience mismatch")
+	}
+	if got, _ := claims["nonce"].(string); got != nonce {
+		return nil, fmt.Errorf("invalid id_token: nonce mismatch")
+	}
+	return claims, nil
+}
+
+// discover retourne les métadonnées du fournisseur, chargées au premier appel. Le chargement se
+// fait hors du verrou : un émetteur lent ne bloque pas les autres appels, dont publicKey.
+func (p *oidcProvider) discover(ctx context.Context) (*discoveryDocument, error) {
+	p.mu.Lock()
+	discovery := p.discovery
+	p.mu.Unlock()
+	if discovery != nil {
+		return discovery, nil
+	}
+
+	endpoint := strings.TrimSuffix(p.cfg.IssuerURL, "/") + "/.well-known/openid-configuration"
+	var doc discoveryDocument
+	if err := getJSON(ctx, p.client, endpoint, "", &doc); err != nil {
+		return nil, fmt.Errorf("oidc discovery failed: %w", err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != strings.TrimSuffix(p.cfg.IssuerURL, "/") {
+		return nil, fmt.Errorf("oidc discovery failed: issuer %q does not match %q", doc.Issuer, p.cfg.IssuerURL)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.JWKSURI == "" {
+		return nil, fmt.Errorf("oidc discovery failed: incomplete provider metadata")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	// Des appels concurrents ont pu charger le document en même temps : le premier est conservé
+	if p.discovery == nil {
+		p.discovery = &doc
+	}
+	return p.discovery, nil
+}
+
+// publicKey retourne la clé correspondant au kid, en rechargeant le JWKS si elle est inconnue
+// (rotation). Le JWKS est chargé hors du verrou, une seule fois à la fois, et au plus une fois
+// par jwksRefreshInterval une fois des clés connues.
+func (p *oidcProvider) publicKey(ctx context.Context, doc *discoveryDocument, kid string) (*rsa.PublicKey, error) {
+	for {
+		p.mu.Lock()
+		if key, ok := p.findKey(kid); ok {
+			p.mu.Unlock()
+			return key, nil
+		}
+		if loading := p.keysLoading; loading != nil {
+			// Un chargement est en cours : la clé sera cherchée dans son résultat
+			p.mu.Unlock()
+			select {
+			case <-loading:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		if p.keys != nil && time.Since(p.keysFetched) < jwksRefreshInterval {
+			p.mu.Unlock()
+			return nil, fmt.Errorf("no signing key found for kid %q", kid)
+		}
+		loading := make(chan struct{})
+		p.keysLoading = loading
+		p.mu.Unlock()
+
+		keys, err := p.fetchKeys(ctx, doc)
+
+		p.mu.Lock()
+		if err == nil {
+			p.keys = keys
+		}
+		if p.keys != nil {
+			// Un échec de rechargement est lui aussi espacé, tant que des clés sont connues
+			p.keysFetched = time.Now()
+		}
+		p.keysLoading = nil
+		close(loading)
+		p.mu.Unlock()
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// findKey cherche la clé du kid ; p.mu doit être verrouillé
+func (p *oidcProvider) findKey(kid string) (*rsa.PublicKey, bool) {
+	if key, ok := p.keys[kid]; ok {
+		return key, true
+	}
+	// Un fournisseur avec une seule clé peut omettre le kid
+	if kid == "" && len(p.keys) == 1 {
+		for _, only := range p.keys {
+			return only, true
+		}
+	}
+	return nil, false
+}
+
+func (p *oidcProvider) fetchKeys(ctx context.Context, doc *discoveryDocument) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := getJSON(ctx, p.client, doc.JWKSURI, "", &set); err != nil {
+		return nil, fmt.Errorf("failed to fetch jwks: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") {
+			continue
+		}
+		key, err := parseRSAKey(k)
+		if err != nil {
+			return nil, err
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func parseRSAKey(k jsonWebKey) (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwk modulus: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid jwk exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(n),
+		E: int(new(big.Int).SetBytes(e).Int64()),
+	}, nil
+}
@@ -0,0 +1,169 @@
+package social
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Identity est l'identité renvoyée par un fournisseur externe après authentification
+type Identity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// Provider représente un fournisseur d'identité amont (OIDC ou OAuth2 à la GitHub)
+type Provider interface {
+	Name() string
+	// TrustsEmail indique si un email vérifié par ce fournisseur suffit à rattacher
+	// automatiquement l'identité au compte local de même email
+	TrustsEmail() bool
+	// AuthCodeURL construit l'URL de redirection vers le fournisseur
+	AuthCodeURL(ctx context.Context, state, nonce, codeChallenge string) (string, error)
+	// Exchange échange le code d'autorisation et retourne l'identité vérifiée
+	Exchange(ctx context.Context, code, codeVerifier, nonce string) (*Identity, error)
+}
+
+type ProviderConfig struct {
+	Name         string
+	Type         string // "oidc" ou "github"
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	TrustEmail   bool
+
+	// OIDC
+	IssuerURL string
+
+	// OAuth2 (GitHub et compatibles)
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	EmailsURL   string
+}
+
+// NewProvider instancie le fournisseur correspondant au type configuré
+func NewProvider(cfg ProviderConfig, client *http.Client) (Provider, error) {
+	if cfg.ClientID == "" || cfg.RedirectURL == "" {
+		return nil, fmt.Errorf("provider %s: client id and redirect url are required", cfg.Name)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	switch cfg.Type {
+	case "oidc":
+		if cfg.IssuerURL == "" {
+			return nil, fmt.Errorf("provider %s: issuer url is required", cfg.Name)
+		}
+		return &oidcProvider{cfg: cfg, client: client}, nil
+	case "github":
+		if cfg.AuthURL == "" || cfg.TokenURL == "" || cfg.UserInfoURL == "" {
+			return nil, fmt.Errorf("provider %s: auth, token and userinfo urls are required", cfg.Name)
+		}
+		return &githubProvider{cfg: cfg, client: client}, nil
+	default:
+		return nil, fmt.Errorf("provider %s: unsupported type %q", cfg.Name, cfg.Type)
+	}
+}
+
+func buildAuthURL(endpoint string, cfg ProviderConfig, state, codeChallenge string, extra url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid authorization endpoint: %w", err)
+	}
+
+	q := u.Query()
+	q.Set("response_type", "code")
+	q.Set("client_id", cfg.ClientID)
+	q.Set("redirect_uri", cfg.RedirectURL)
+	q.Set("scope", strings.Join(cfg.Scopes, " "))
+	q.Set("state", state)
+	q.Set("code_challenge", codeChallenge)
+	q.Set("code_challenge_method", "S256")
+	for k, v := range extra {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	IDToken     string `json:"id_token"`
+	Error       string `json:"error"`
+	ErrorDesc   string `json:"error_description"`
+}
+
+// exchangeCode effectue l'appel au token endpoint (RFC 6749 §4.1.3 avec PKCE)
+func exchangeCode(ctx context.Context, client *http.Client, cfg ProviderConfig, tokenURL, code, codeVerifier string) (*tokenResponse, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {cfg.RedirectURL},
+		"client_id":     {cfg.ClientID},
+		"client_secret": {cfg.ClientSecret},
+		"code_verifier": {codeVerifier},
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	var tok tokenResponse
+	if err := doJSON(client, req, &tok); err != nil {
+		return nil, fmt.Errorf("token exchange failed: %w", err)
+	}
+	if tok.Error != "" {
+		return nil, fmt.Errorf("token exchange failed: %s %s", tok.Error, tok.ErrorDesc)
+	}
+	if tok.AccessToken == "" {
+		return nil, fmt.Errorf("token exchange failed: no access token returned")
+	}
+	return &tok, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, endpoint, accessToken string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if accessToken != "" {
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+	}
+	return doJSON(client, req, out)
+}
+
+func doJSON(client *http.Client, req *http.Request, out any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 400 && resp.StatusCode != http.StatusBadRequest {
+		return fmt.Errorf("%s %s: unexpected status %d", req.Method, req.URL.Redacted(), resp.StatusCode)
+	}
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("%s %s: invalid json response: %w", req.Method, req.URL.Redacted(), err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package social
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// UserAccounts regroupe les opérations du domaine utilisateur nécessaires à la connexion sociale
+type UserAccounts interface {
+	FindByEmail(ctx context.Context, email string) (*user.User, error)
+	FindIdentity(ctx context.Context, provider, subject string) (*user.Identity, error)
+	CreateExternalUser(ctx context.Context, name, email, provider, subject string) (*user.User, error)
+	LinkIdentity(ctx context.Context, userID int, provider, subject, email string) error
+	IssueTokens(ctx context.Context, userID int) (string, string, error)
+}
+
+// Result décrit l'issue d'un callback : des jetons pour une connexion, ou l'ID du
+// compte auquel l'identité a été rattachée
+type Result struct {
+	AccessToken  string
+	RefreshToken string
+	LinkedUserID int
+}
+
+// ErrLinkRequired signale qu'un compte local existe déjà pour l'email de l'identité externe et
+// que l'utilisateur doit s'y connecter pour la lier
+var ErrLinkRequired = user.ErrLinkRequired
+
+type SocialService struct {
+	users       UserAccounts
+	providers   map[string]Provider
+	stateSecret []byte
+}
+
+// NewSocialService construit le service ; stateSecret signe l'état conservé dans le cookie
+// pendant l'aller-retour chez le fournisseur
+func NewSocialService(users UserAccounts, providers []Provider, stateSecret string) *SocialService {
+	byName := make(map[string]Provider, len(providers))
+	for _, p := range providers {
+		byName[p.Name()] = p
+	}
+	return &SocialService{users: users, providers: byName, stateSecret: []byte(stateSecret)}
+}
+
+func (s *SocialService) Providers() []string {
+	names := make([]string, 0, len(s.providers))
+	for name := range s.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Begin prépare la redirection vers le fournisseur et retourne l'état signé à conserver côté client.
+// linkUserID vaut 0 pour une connexion, ou l'ID du compte connecté qui souhaite lier l'identité.
+func (s *SocialService) Begin(ctx context.Context, providerName string, linkUserID int) (string, string, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return "", "", fmt.Errorf("%w: unknown provider %s", apperror.ErrNotFound, providerName)
+	}
+
+	state, err := newLoginState(providerName, linkUserID)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: %v", err)
+	}
+
+	redirectURL, err := provider.AuthCodeURL(ctx, state.State, state.Nonce, state.codeChallenge())
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: %v", err)
+	}
+
+	signed, err := state.sign(s.stateSecret)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: failed to sign login state: %v", err)
+	}
+	return redirectURL, signed, nil
+}
+
+// Complete traite le callback du fournisseur : vérification de l'état, échange du code, puis
+// rattachement de l'identité au compte connecté ou connexion (avec création éventuelle du compte)
+func (s *SocialService) Complete(ctx context.Context, providerName, code, stateParam, signedState string) (*Result, error) {
+	provider, ok := s.providers[providerName]
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown provider %s", apperror.ErrNotFound, providerName)
+	}
+	if code == "" {
+		return nil, apperror.Localize(apperror.ErrValidation, "validation.authorization_code_required", "authorization code is required")
+	}
+
+	state, err := parseLoginState(signedState, s.stateSecret)
+	if err != nil || state.Provider != providerName || state.State != stateParam {
+		return nil, fmt.Errorf("%w: invalid or expired login state", apperror.ErrUnauthorized)
+	}
+
+	identity, err := provider.Exchange(ctx, code, state.CodeVerifier, state.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", apperror.ErrUnauthorized, err)
+	}
+
+	if state.LinkUserID != 0 {
+		if err := s.users.LinkIdentity(ctx, state.LinkUserID, identity.Provider, identity.Subject, identity.Email); err != nil {
+			return nil, err
+		}
+		return &Result{LinkedUserID: state.LinkUserID}, nil
+	}
+
+	userID, err := s.resolveUser(ctx, provider, identity)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, refreshToken, err := s.users.IssueTokens(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{AccessToken: accessToken, RefreshToken: refreshToken}, nil
+}
+
+func (s *SocialService) resolveUser(ctx context.Context, provider Provider, identity *Identity) (int, error) {
+	linked, err := s.users.FindIdentity(ctx, identity.Provider, identity.Subject)
+	if err != nil {
+		return 0, err
+	}
+	if linked != nil {
+		return linked.UserID, nil
+	}
+
+	if identity.Email == "" {
+		return 0, apperror.Localize(apperror.ErrValidation, "validation.provider_email_missing", "provider did not return an email address")
+	}
+
+	existing, err := s.users.FindByEmail(ctx, identity.Email)
+	if err != nil {
+		return 0, fmt.Errorf("internal error: %v", err)
+	}
+
+	if existing != nil {
+		// Sans confiance explicite dans le fournisseur, l'utilisateur doit prouver qu'il possède
+		// le compte local en s'y connectant puis en liant l'identité depuis /me/identities
+		if !provider.TrustsEmail() || !identity.EmailVerified {
+			return 0, fmt.Errorf("%w: an account already exists for %s", ErrLinkRequired, identity.Email)
+		}
+		if err := s.users.LinkIdentity(ctx, existing.ID, identity.Provider, identity.Subject, identity.Email); err != nil {
+			return 0, err
+		}
+		return existing.ID, nil
+	}
+
+	name := identity.Name
+	if len(name) < 2 {
+		name, _, _ = strings.Cut(identity.Email, "@")
+	}
+	created, err := s.users.CreateExternalUser(ctx, name, identity.Email, identity.Provider, identity.Subject)
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
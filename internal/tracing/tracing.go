@@ -0,0 +1,122 @@
+// Package tracing configure OpenTelemetry : export des spans (OTLP ou fichier local),
+// propagation du contexte de trace W3C, et fonctions utilitaires pour instrumenter les
+// handlers, les services, les dépôts et l'envoi des emails.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentation est le nom de la bibliothèque d'instrumentation porté par chaque span
+const instrumentation = "github.com/pathi14/AuthentificationGO"
+
+// Setup installe le fournisseur de traces global selon cfg et retourne la fonction qui vide les
+// spans en attente à l'arrêt. La propagation W3C (traceparent, baggage) est active même sans
+// exportateur, pour ne pas rompre la trace des services appelés.
+func Setup(ctx context.Context, cfg config.Tracing) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	closeFile := func() error { return nil }
+	switch cfg.Exporter {
+	case "none":
+		return func(context.Context) error { return nil }, nil
+	case "otlp":
+		var opts []otlptracehttp.Option
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+		}
+		var err error
+		if exporter, err = otlptracehttp.New(ctx, opts...); err != nil {
+			return nil, fmt.Errorf("error creating OTLP exporter: %w", err)
+		}
+	case "stdout":
+		var w io.Writer = os.Stdout
+		if cfg.File != "" {
+			f, err := os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+			if err != nil {
+				return nil, fmt.Errorf("error opening trace file: %w", err)
+			}
+			w, closeFile = f, f.Close
+		}
+		var err error
+		if exporter, err = stdouttrace.New(stdouttrace.WithWriter(w)); err != nil {
+			return nil, fmt.Errorf("error creating stdout exporter: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", cfg.Exporter)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceName(cfg.ServiceName),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("error describing the service: %w", err)
+	}
+
+	provider := NewProvider(exporter, res, cfg.SampleRatio)
+	otel.SetTracerProvider(provider)
+	return func(ctx context.Context) error {
+		return errors.Join(provider.Shutdown(ctx), closeFile())
+	}, nil
+}
+
+// NewProvider construit un fournisseur qui exporte par lots vers exporter. Les requêtes qui
+// arrivent avec une trace suivent la décision d'échantillonnage de l'appelant ; les autres sont
+// retenues dans la proportion ratio.
+func NewProvider(exporter sdktrace.SpanExporter, res *resource.Resource, ratio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+}
+
+// Tracer retourne le traceur de l'application, issu du fournisseur global
+func Tracer() trace.Tracer {
+	return otel.Tracer(instrumentation)
+}
+
+// Start ouvre un span enfant de celui porté par ctx
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ferme span en le marquant en erreur si err n'est pas nil. Avec un résultat nommé :
+//
+//	ctx, span := tracing.Start(ctx, "UserService.Login")
+//	defer func() { tracing.End(span, err) }()
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartQuery ouvre le span d'une requête SQL ; system est le moteur (postgres, sqlite3) et
+// operation une description courte de la requête (« SELECT users »)
+func StartQuery(ctx context.Context, name, system, operation string) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.DBSystemKey.String(system),
+			semconv.DBOperationName(operation),
+		))
+}
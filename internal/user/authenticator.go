@@ -0,0 +1,150 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+)
+
+// AuthResult décrit l'utilisateur reconnu par un Authenticator. Un authentificateur local
+// renseigne UserID ; un annuaire externe renseigne l'identité (Provider, Subject) et les
+// attributs servant au provisionnement du compte.
+type AuthResult struct {
+	UserID   int
+	Provider string
+	Subject  string
+	Email    string
+	Name     string
+	Role     string
+	// Tenant est le tenant de la source (IdP SAML) : un compte existant de même email n'est
+	// rattaché à l'identité que s'il appartient à ce tenant
+	Tenant string
+	// MobileNumber est recopié sur le compte lorsqu'il est fourni par la source externe
+	MobileNumber string
+}
+
+// Authenticator vérifie un couple email / mot de passe. Il retourne une erreur enveloppant
+// apperror.ErrNotFound lorsqu'il ne connaît pas l'utilisateur, afin que le suivant de la chaîne
+// soit essayé, et apperror.ErrUnauthorized lorsque le mot de passe est refusé.
+type Authenticator interface {
+	Name() string
+	Authenticate(ctx context.Context, email, password string) (*AuthResult, error)
+}
+
+// LocalAuthenticator vérifie le mot de passe stocké (bcrypt) dans le UserStore
+type LocalAuthenticator struct {
+	repo UserStore
+}
+
+func NewLocalAuthenticator(repo UserStore) *LocalAuthenticator {
+	return &LocalAuthenticator{repo: repo}
+}
+
+func (a *LocalAuthenticator) Name() string {
+	return "local"
+}
+
+func (a *LocalAuthenticator) Authenticate(ctx context.Context, email, password string) (*AuthResult, error) {
+	u, err := a.repo.Login(ctx, email, password)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthResult{UserID: u.ID, Email: u.Email, Name: u.Name}, nil
+}
+
+// authenticate essaie chaque authentificateur dans l'ordre. Un mot de passe refusé par l'un
+// n'arrête pas la chaîne : un compte local et un compte d'annuaire peuvent partager un email.
+func (s *UserService) authenticate(ctx context.Context, email, password string) (int, error) {
+	var lastErr error = fmt.Errorf("user %w", apperror.ErrNotFound)
+
+	for _, a := range s.authenticators {
+		result, err := a.Authenticate(ctx, email, password)
+		if err != nil {
+			if errors.Is(err, apperror.ErrNotFound) {
+				continue
+			}
+			if errors.Is(err, apperror.ErrUnauthorized) {
+				lastErr = err
+				continue
+			}
+			return 0, fmt.Errorf("%s authenticator: %w", a.Name(), err)
+		}
+
+		if result.UserID != 0 {
+			return result.UserID, nil
+		}
+		return s.ProvisionExternal(ctx, result)
+	}
+
+	return 0, lastErr
+}
+
+// ProvisionExternal retrouve ou crée (just-in-time) le compte d'un utilisateur authentifié par
+// une source externe configurée par l'administrateur (annuaire, IdP SAML), et synchronise son rôle.
+// Un compte existant n'est jamais rattaché sur la seule foi de son email : l'inscription publique
+// ne vérifie pas les adresses, et un compte pré-enregistré à l'email d'un membre de l'annuaire
+// récupérerait son rôle. ErrLinkRequired est alors retourné, sauf pour un compte provisionné par
+// le tenant de la source : l'IdP d'un tenant ne peut ouvrir que les comptes de ce tenant.
+func (s *UserService) ProvisionExternal(ctx context.Context, result *AuthResult) (int, error) {
+	identity, err := s.repo.FindIdentity(ctx, result.Provider, result.Subject)
+	if err != nil {
+		return 0, err
+	}
+
+	var userID int
+	if identity != nil {
+		userID = identity.UserID
+	} else {
+		existing, err := s.repo.GetByEmail(ctx, result.Email)
+		if err != nil {
+			return 0, err
+		}
+		if existing != nil {
+			userID = existing.ID
+			if err := s.linkExisting(ctx, userID, result); err != nil {
+				return 0, err
+			}
+		} else {
+			name := result.Name
+			if len(name) < 2 {
+				name, _, _ = strings.Cut(result.Email, "@")
+			}
+			created, err := s.CreateExternalUser(ctx, name, result.Email, result.Provider, result.Subject)
+			if err != nil {
+				return 0, err
+			}
+			userID = created.ID
+		}
+	}
+
+	if result.Role != "" {
+		if err := s.SetRole(ctx, userID, result.Role); err != nil {
+			return 0, err
+		}
+	}
+	if result.MobileNumber != "" {
+		if err := s.repo.SetMobileNumber(ctx, userID, result.MobileNumber); err != nil {
+			return 0, err
+		}
+	}
+	return userID, nil
+}
+
+// linkExisting rattache l'identité externe au compte userID de même email, s'il a été provisionné
+// par le tenant de la source
+func (s *UserService) linkExisting(ctx context.Context, userID int, result *AuthResult) error {
+	member := false
+	if result.Tenant != "" {
+		var err error
+		if member, err = s.repo.IsTenantMember(ctx, result.Tenant, userID); err != nil {
+			return err
+		}
+	}
+	if !member {
+		return fmt.Errorf("%w: an account already exists for %s", ErrLinkRequired, result.Email)
+	}
+	return s.LinkIdentity(ctx, userID, result.Provider, result.Subject, result.Email)
+}
@@ -0,0 +1,88 @@
+package user
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+// Conflits que les domaines appelants (connexion sociale, SCIM) doivent distinguer
+var (
+	ErrEmailInUse      = fmt.Errorf("%w: email already in use", apperror.ErrConflict)
+	ErrIdentityLinked  = fmt.Errorf("%w: identity already linked to another account", apperror.ErrConflict)
+	ErrLastLoginMethod = fmt.Errorf("%w: last login method, cannot remove the only way to sign in", apperror.ErrConflict)
+	// ErrLinkRequired signale qu'un compte existe déjà pour l'email d'une identité externe et
+	// que l'utilisateur doit s'y connecter pour la lier
+	ErrLinkRequired = fmt.Errorf("%w: link required", apperror.ErrConflict)
+	// ErrExternalIDInUse signale un externalId déjà attribué à un autre compte du tenant
+	ErrExternalIDInUse = fmt.Errorf("%w: external id already in use", apperror.ErrConflict)
+)
+
+// errRefreshReused signale un refresh token déjà consommé, compté à part dans les métriques : un
+// rejeu peut trahir un jeton volé
+var errRefreshReused = fmt.Errorf("%w: refresh token already used", apperror.ErrUnauthorized)
+
+// errorResponse associe une catégorie d'erreur à son statut HTTP, à son code stable et à la clé
+// de son message par défaut. Une clé vide renvoie le message de l'erreur elle-même, traduit s'il
+// porte une clé (apperror.Localized) : réservé aux erreurs de validation.
+type errorResponse struct {
+	kind   error
+	status int
+	code   string
+	key    string
+}
+
+// errorResponses est parcouru dans l'ordre : les erreurs précises sont reconnues avant la
+// catégorie qu'elles enveloppent (ErrLocked avant ErrUnauthorized, ErrEmailInUse avant ErrConflict)
+var errorResponses = []errorResponse{
+	{apperror.ErrValidation, http.StatusBadRequest, problem.CodeValidationFailed, ""},
+	{apperror.ErrLocked, http.StatusLocked, problem.CodeAccountLocked, "user.account_locked"},
+	{apperror.ErrUnauthorized, http.StatusUnauthorized, problem.CodeUnauthorized, "error.unauthorized"},
+	{oauth.ErrConsentRequired, http.StatusForbidden, problem.CodeConsentRequired, "error.consent_required"},
+	{apperror.ErrNotFound, http.StatusNotFound, problem.CodeNotFound, "error.not_found"},
+	{ErrEmailInUse, http.StatusConflict, problem.CodeEmailInUse, "user.email_in_use"},
+	{ErrIdentityLinked, http.StatusConflict, problem.CodeIdentityLinked, "user.identity_linked"},
+	{ErrLastLoginMethod, http.StatusConflict, problem.CodeLastLoginMethod, "user.last_login_method"},
+	{ErrLinkRequired, http.StatusConflict, problem.CodeLinkRequired, "user.link_required"},
+	{apperror.ErrConflict, http.StatusConflict, problem.CodeConflict, "error.conflict"},
+}
+
+// detail remplace, pour un handler donné, le code et la clé du message renvoyés pour une catégorie
+type detail struct {
+	code string
+	key  string
+}
+
+type details map[error]detail
+
+// writeError traduit une erreur du service en problème RFC 7807. Les erreurs qui n'entrent dans
+// aucune catégorie sont journalisées avec l'identifiant de corrélation et masquées derrière
+// la clé fallback (500) : leur texte n'est jamais renvoyé au client.
+func writeError(c *gin.Context, err error, fallback string, custom details) {
+	for _, r := range errorResponses {
+		if !errors.Is(err, r.kind) {
+			continue
+		}
+		code, key := r.code, r.key
+		if d, ok := custom[r.kind]; ok {
+			code, key = d.code, d.key
+		}
+		if key == "" {
+			p := problem.FromError(r.status, code, err)
+			p.Errors = problem.FieldErrors(err)
+			problem.Send(c, p)
+			return
+		}
+		problem.Abort(c, r.status, code, key)
+		return
+	}
+
+	logging.FromContext(c.Request.Context()).Error("request failed", "method", c.Request.Method, "route", c.FullPath(), "error", err)
+	problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, fallback)
+}
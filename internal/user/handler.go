@@ -1,10 +1,16 @@
 package user
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
 )
 
 type UserHandler struct {
@@ -18,32 +24,19 @@ func NewUserHandler(service *UserService) *UserHandler {
 func (h *UserHandler) Register(c *gin.Context) {
 	var u User
 	if err := c.ShouldBindJSON(&u); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Données d'entrée invalides",
-			"details": err.Error(),
-		})
+		problem.InvalidInput(c, err)
 		return
 	}
 
-	err := h.service.Create(u)
+	err := h.service.Create(c.Request.Context(), u)
 	if err != nil {
-		if strings.Contains(err.Error(), "validation error") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		if strings.Contains(err.Error(), "email already in use") {
-			c.JSON(http.StatusConflict, gin.H{"error": "Cet email est déjà utilisé"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err, "error.internal", nil)
 		return
 	}
 
 	u.Password = ""
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Utilisateur enregistré avec succès",
+		"message": i18n.Localize(c, "user.register.success"),
 		"user":    u,
 	})
 }
@@ -52,35 +45,27 @@ func (h *UserHandler) Login(c *gin.Context) {
 	var credentials struct {
 		Email    string `json:"email" binding:"required,email"`
 		Password string `json:"password" binding:"required,min=8"`
+		ClientID string `json:"client_id"`
+		Scope    string `json:"scope"`
 	}
 
 	if err := c.ShouldBindJSON(&credentials); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Données d'entrée invalides",
-			"details": err.Error(),
-		})
+		problem.InvalidInput(c, err)
 		return
 	}
 
-	accessToken, refreshToken, err := h.service.Login(credentials.Email, credentials.Password)
+	accessToken, refreshToken, err := h.service.Login(c.Request.Context(), credentials.Email, credentials.Password,
+		credentials.ClientID, oauth.ParseScope(credentials.Scope))
 	if err != nil {
-
-		if strings.Contains(err.Error(), "validation error") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		if strings.Contains(err.Error(), "authentication error") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Email ou mot de passe incorrect"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Une erreur interne est survenue"})
+		writeError(c, err, "error.internal", details{
+			apperror.ErrUnauthorized: {problem.CodeInvalidCredentials, "user.login.invalid_credentials"},
+			oauth.ErrConsentRequired: {problem.CodeConsentRequired, "user.login.consent_required"},
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":      "Connexion réussie",
+		"message":      i18n.Localize(c, "user.login.success"),
 		"token":        accessToken,
 		"refreshToken": refreshToken,
 	})
@@ -92,35 +77,19 @@ func (h *UserHandler) ForgotPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Données d'entrée invalides",
-			"details": err.Error(),
-		})
+		problem.InvalidInput(c, err)
 		return
 	}
 
-	token, err := h.service.SendPasswordResetToken(request.Email)
-	if err != nil {
-		if strings.Contains(err.Error(), "user not found") {
-			c.JSON(http.StatusOK, gin.H{
-				"message": "Si votre email est enregistré, vous recevrez un lien de réinitialisation.",
-			})
-			return
-		}
-
-		if strings.Contains(err.Error(), "validation error") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Une erreur est survenue lors de l'envoi de l'email"})
+	// Le lien n'est remis que par email, et la réponse ne dit pas si l'adresse est connue
+	err := h.service.SendPasswordResetToken(c.Request.Context(), request.Email, i18n.FromContext(c))
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		writeError(c, err, "user.forgot_password.failed", nil)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Les instructions pour la mise à jour de votre password ont été envoyés à votre email",
-		"email":   request.Email,
-		"token":   token,
+		"message": i18n.Localize(c, "user.forgot_password.generic"),
 	})
 }
 
@@ -131,44 +100,56 @@ func (h *UserHandler) ResetPassword(c *gin.Context) {
 	}
 
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{
-			"error":   "Données d'entrée invalides",
-			"details": err.Error(),
-		})
+		problem.InvalidInput(c, err)
 		return
 	}
 
-	err := h.service.ResetPassword(request.Token, request.NewPassword)
+	err := h.service.ResetPassword(c.Request.Context(), request.Token, request.NewPassword)
 	if err != nil {
-		if strings.Contains(err.Error(), "validation error") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
+		writeError(c, err, "user.reset_password.failed", details{
+			apperror.ErrUnauthorized: {problem.CodeInvalidToken, "auth.token_invalid_or_expired"},
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ReportLogin traite le lien « ce n'était pas moi » d'une alerte de connexion : les sessions du
+// compte sont révoquées et un lien de réinitialisation du mot de passe est envoyé
+func (h *UserHandler) ReportLogin(c *gin.Context) {
+	var request struct {
+		Token string `json:"token" binding:"required"`
+	}
 
-		if strings.Contains(err.Error(), "authentication error") ||
-			strings.Contains(err.Error(), "invalid or expired token") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide ou expiré"})
-			return
-		}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
 
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Une erreur système est survenue"})
+	if err := h.service.ReportLogin(c.Request.Context(), request.Token, i18n.FromContext(c)); err != nil {
+		writeError(c, err, "user.login_report.failed", details{
+			apperror.ErrUnauthorized: {problem.CodeInvalidToken, "auth.token_invalid_or_expired"},
+		})
 		return
 	}
 
-	c.Status(http.StatusNoContent)
+	c.JSON(http.StatusOK, gin.H{"message": i18n.Localize(c, "user.login_report.done")})
 }
 
 func (h *UserHandler) Logout(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token manquant"})
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "auth.token_missing")
 		return
 	}
 
 	token = strings.TrimPrefix(token, "Bearer ")
 
-	if err := h.service.Logout(token); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Déconnexion échouée"})
+	if err := h.service.Logout(c.Request.Context(), token); err != nil {
+		writeError(c, err, "user.logout.failed", details{
+			apperror.ErrUnauthorized: {problem.CodeInvalidToken, "auth.token_invalid"},
+		})
 		return
 	}
 
@@ -178,27 +159,52 @@ func (h *UserHandler) Logout(c *gin.Context) {
 func (h *UserHandler) Profile(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Non autorisé"})
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "error.unauthorized")
 		return
 	}
 
-	user, err := h.service.GetUserByID(userID.(int))
+	user, err := h.service.GetUserByID(c.Request.Context(), userID.(int))
 	if err != nil {
-		if strings.Contains(err.Error(), "not found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Utilisateur non trouvé"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Une erreur est survenue lors de la récupération du profil"})
+		writeError(c, err, "user.profile.failed", details{
+			apperror.ErrNotFound: {problem.CodeNotFound, "user.not_found"},
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":     "Profil de l'utilisateur",
+		"message":     i18n.Localize(c, "user.profile"),
 		"email":       user.Email,
 		"name":        user.Name,
 		"age":         user.Age,
 		"phoneNumber": user.MobileNumber,
+		"role":        user.Role,
+		"locale":      user.Locale,
+	})
+}
+
+// UpdateLocale enregistre la langue préférée de l'utilisateur connecté. Elle s'applique aux
+// jetons émis ensuite et aux emails ; une langue vide revient à la négociation par Accept-Language.
+func (h *UserHandler) UpdateLocale(c *gin.Context) {
+	var request struct {
+		Locale string `json:"locale" binding:"omitempty,oneof=en fr de"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+
+	if err := h.service.SetLocale(c.Request.Context(), c.GetInt("userID"), request.Locale); err != nil {
+		writeError(c, err, "user.locale.failed", details{
+			apperror.ErrNotFound: {problem.CodeNotFound, "user.not_found"},
+		})
+		return
+	}
+
+	c.Set(i18n.ContextKey, request.Locale)
+	c.JSON(http.StatusOK, gin.H{
+		"message": i18n.Localize(c, "user.locale.updated"),
+		"locale":  request.Locale,
 	})
 }
 
@@ -207,32 +213,83 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 	token := c.GetHeader("Authorization")
 
 	if token == "" {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Token manquant"})
+		problem.Abort(c, http.StatusUnauthorized, problem.CodeUnauthorized, "auth.token_missing")
 		return
 	}
 
 	token = strings.TrimPrefix(token, "Bearer ")
 
-	accessToken, refreshToken, err := h.service.refreshToken(token)
+	accessToken, refreshToken, err := h.service.refreshToken(c.Request.Context(), token)
 	if err != nil {
-
-		if strings.Contains(err.Error(), "validation error") {
-			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
-			return
-		}
-
-		if strings.Contains(err.Error(), "authentication error") {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Token invalide ou expiré"})
-			return
-		}
-
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Une erreur interne est survenue"})
+		writeError(c, err, "error.internal", details{
+			apperror.ErrUnauthorized: {problem.CodeInvalidToken, "auth.token_invalid_or_expired"},
+			oauth.ErrConsentRequired: {problem.CodeConsentRequired, "user.refresh.consent_revoked"},
+		})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":      "Token rafraîchi avec succès",
+		"message":      i18n.Localize(c, "user.refresh.success"),
 		"token":        accessToken,
 		"refreshToken": refreshToken,
 	})
 }
+
+// Logins retourne l'historique des connexions du compte connecté, de la plus récente à la plus
+// ancienne. Paramètres : before (ID) et limit.
+func (h *UserHandler) Logins(c *gin.Context) {
+	var filter LoginFilter
+	var err error
+	if raw := c.Query("before"); raw != "" {
+		filter.BeforeID, err = strconv.ParseInt(raw, 10, 64)
+	}
+	if raw := c.Query("limit"); raw != "" && err == nil {
+		filter.Limit, err = strconv.Atoi(raw)
+	}
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "user.logins.invalid_filter")
+		return
+	}
+
+	logins, err := h.service.ListLogins(c.Request.Context(), c.GetInt("userID"), filter)
+	if err != nil {
+		writeError(c, err, "user.logins.failed", nil)
+		return
+	}
+
+	response := gin.H{"logins": logins}
+	if filter.Limit == 0 {
+		filter.Limit = defaultLoginLimit
+	}
+	if len(logins) == filter.Limit {
+		response["next_before"] = logins[len(logins)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *UserHandler) ListIdentities(c *gin.Context) {
+	identities, err := h.service.ListIdentities(c.Request.Context(), c.GetInt("userID"))
+	if err != nil {
+		writeError(c, err, "user.identities.failed", nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"identities": identities})
+}
+
+func (h *UserHandler) UnlinkIdentity(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "user.identity.invalid_id")
+		return
+	}
+
+	if err := h.service.UnlinkIdentity(c.Request.Context(), c.GetInt("userID"), id); err != nil {
+		writeError(c, err, "user.identity.unlink_failed", details{
+			apperror.ErrNotFound: {problem.CodeNotFound, "user.identity.not_found"},
+		})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
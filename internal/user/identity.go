@@ -0,0 +1,36 @@
+package user
+
+import (
+	"strconv"
+	"time"
+)
+
+// PasswordProvider désigne la méthode de connexion locale par email et mot de passe
+const PasswordProvider = "password"
+
+// Identity est une méthode de connexion rattachée à un compte : mot de passe local
+// ou identité chez un fournisseur externe (provider + subject)
+type Identity struct {
+	ID        int       `json:"id"`
+	UserID    int       `json:"-"`
+	Provider  string    `json:"provider"`
+	Subject   string    `json:"subject"`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TenantMembership rattache un compte provisionné par SCIM au tenant qui l'a créé. ExternalID,
+// l'identifiant du compte chez l'IdP du tenant, est unique dans le tenant.
+type TenantMembership struct {
+	Tenant     string
+	ExternalID string
+}
+
+func passwordIdentity(userID int, email string) Identity {
+	return Identity{
+		UserID:   userID,
+		Provider: PasswordProvider,
+		Subject:  strconv.Itoa(userID),
+		Email:    email,
+	}
+}
@@ -0,0 +1,283 @@
+package user
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+)
+
+const (
+	defaultLoginLimit = 50
+	maxLoginLimit     = 200
+
+	// loginReportPurpose distingue les jetons « ce n'était pas moi » des autres jetons signés
+	// avec le même secret
+	loginReportPurpose = "login_report"
+)
+
+// LoginAttempt est une tentative de connexion par mot de passe enregistrée dans l'historique du
+// compte. Device est l'empreinte du navigateur (voir deviceFingerprint) et Network le réseau de
+// l'adresse IP ; NewDevice et NewNetwork signalent une connexion réussie depuis un appareil ou
+// un réseau jamais vus lors des connexions réussies précédentes. ReportedAt est renseigné
+// lorsque l'utilisateur l'a signalée comme frauduleuse.
+type LoginAttempt struct {
+	ID         int64      `json:"id"`
+	UserID     int        `json:"-"`
+	Time       time.Time  `json:"time"`
+	Success    bool       `json:"success"`
+	Reason     string     `json:"reason,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	Network    string     `json:"network,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	Device     string     `json:"device"`
+	NewDevice  bool       `json:"new_device"`
+	NewNetwork bool       `json:"new_network"`
+	ReportedAt *time.Time `json:"reported_at,omitempty"`
+}
+
+// unfamiliar indique une connexion réussie depuis un appareil ou un réseau inconnus du compte
+func (a *LoginAttempt) unfamiliar() bool {
+	return a.NewDevice || a.NewNetwork
+}
+
+// LoginFilter pagine l'historique des connexions, de la plus récente à la plus ancienne
+type LoginFilter struct {
+	BeforeID int64
+	Limit    int
+}
+
+// versionNumbers retire les numéros de version du user agent
+var versionNumbers = regexp.MustCompile(`[0-9][0-9._]*`)
+
+// deviceFingerprint est l'empreinte du navigateur : le user agent sans ses numéros de version,
+// pour qu'une simple mise à jour ne passe pas pour un nouvel appareil
+func deviceFingerprint(userAgent string) string {
+	normalized := strings.ToLower(versionNumbers.ReplaceAllString(userAgent, ""))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:16])
+}
+
+// networkOf retourne le réseau de l'adresse ip : /24 en IPv4, /48 en IPv6. Une adresse
+// illisible donne une chaîne vide.
+func networkOf(ip string) string {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+	bits := 48
+	if addr.Is4() {
+		bits = 24
+	}
+	prefix, err := addr.Prefix(bits)
+	if err != nil {
+		return ""
+	}
+	return prefix.String()
+}
+
+// recordLogin ajoute une tentative de connexion à l'historique du compte userID, avec l'origine
+// de la requête ; reason est vide pour une connexion réussie. Un échec d'écriture est journalisé
+// sans interrompre la connexion.
+func (s *UserService) recordLogin(ctx context.Context, userID int, reason string) *LoginAttempt {
+	source := audit.SourceFrom(ctx)
+	attempt := &LoginAttempt{
+		UserID:    userID,
+		Time:      time.Now(),
+		Success:   reason == "",
+		Reason:    reason,
+		IP:        source.IP,
+		Network:   networkOf(source.IP),
+		UserAgent: source.UserAgent,
+		Device:    deviceFingerprint(source.UserAgent),
+	}
+	if err := s.repo.RecordLogin(ctx, attempt); err != nil {
+		logging.FromContext(ctx).Error("error recording login attempt", "user_id", userID, "error", err)
+		return nil
+	}
+	return attempt
+}
+
+// sendLoginAlert prévient l'utilisateur d'une connexion depuis un appareil ou un réseau
+// inconnus. L'email porte un lien « ce n'était pas moi » (ReportLogin). Un échec d'envoi est
+// journalisé sans interrompre la connexion.
+func (s *UserService) sendLoginAlert(ctx context.Context, attempt *LoginAttempt) {
+	logger := logging.FromContext(ctx).With("user_id", attempt.UserID, "login_id", attempt.ID)
+	if err := s.loginAlert(ctx, attempt); err != nil {
+		logger.Error("error sending login alert", "error", err)
+		return
+	}
+	logger.Info("login alert sent", "new_device", attempt.NewDevice, "new_network", attempt.NewNetwork)
+	metrics.LoginAlerts.WithLabelValues("sent").Inc()
+}
+
+func (s *UserService) loginAlert(ctx context.Context, attempt *LoginAttempt) error {
+	user, err := s.repo.FindByID(ctx, attempt.UserID)
+	if err != nil {
+		return err
+	}
+	token, err := s.generateLoginReportToken(attempt.UserID, attempt.ID)
+	if err != nil {
+		return err
+	}
+
+	device := attempt.UserAgent
+	if device == "" {
+		device = "?"
+	}
+	email, err := i18n.RenderEmail(user.Locale, "login_alert", map[string]string{
+		"Name":   user.Name,
+		"Time":   attempt.Time.UTC().Format("2006-01-02 15:04 MST"),
+		"IP":     attempt.IP,
+		"Device": device,
+		"Link":   s.auth.LoginReportURL + "?token=" + token,
+	})
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mail.Message{To: user.Email, Subject: email.Subject, Body: email.Body})
+}
+
+// generateLoginReportToken signe le jeton du lien « ce n'était pas moi » de la connexion
+// loginID. Il ne porte pas de claim user_id : il ne peut pas servir de jeton d'accès.
+func (s *UserService) generateLoginReportToken(userID int, loginID int64) (string, error) {
+	claims := jwt.MapClaims{
+		"sub":      strconv.Itoa(userID),
+		"login_id": loginID,
+		"purpose":  loginReportPurpose,
+		"exp":      time.Now().Add(s.auth.LoginReportTTL).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(s.auth.JWTSecret))
+}
+
+// parseLoginReportToken retourne le compte, la connexion signalée et l'expiration du jeton
+func (s *UserService) parseLoginReportToken(token string) (int, int64, time.Time, error) {
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(s.auth.JWTSecret), nil
+	})
+	if err != nil || !parsed.Valid {
+		return 0, 0, time.Time{}, fmt.Errorf("%w: invalid token", apperror.ErrUnauthorized)
+	}
+
+	claims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || claims["purpose"] != loginReportPurpose {
+		return 0, 0, time.Time{}, fmt.Errorf("%w: invalid token structure", apperror.ErrUnauthorized)
+	}
+	sub, _ := claims["sub"].(string)
+	userID, err := strconv.Atoi(sub)
+	loginID, okLogin := claims["login_id"].(float64)
+	exp, okExp := claims["exp"].(float64)
+	if err != nil || !okLogin || !okExp {
+		return 0, 0, time.Time{}, fmt.Errorf("%w: invalid token structure", apperror.ErrUnauthorized)
+	}
+	return userID, int64(loginID), time.Unix(int64(exp), 0), nil
+}
+
+// ListLogins retourne l'historique des connexions du compte. Sans limite, 50 tentatives sont
+// retournées, 200 au plus.
+func (s *UserService) ListLogins(ctx context.Context, userID int, filter LoginFilter) ([]LoginAttempt, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultLoginLimit
+	case filter.Limit < 0 || filter.Limit > maxLoginLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", apperror.ErrValidation, maxLoginLimit)
+	}
+
+	logins, err := s.repo.ListLogins(ctx, userID, filter)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return logins, nil
+}
+
+// ReportLogin traite le lien « ce n'était pas moi » d'une alerte de connexion : la connexion est
+// marquée comme signalée, toutes les sessions du compte sont révoquées et le mot de passe,
+// peut-être connu de l'intrus, est remplacé par un secret que personne ne connaît. Un lien de
+// réinitialisation est envoyé dans la langue préférée du compte, ou à défaut dans locale. Le
+// lien de l'alerte ne sert qu'une fois.
+func (s *UserService) ReportLogin(ctx context.Context, token, locale string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ReportLogin")
+	defer func() { tracing.End(span, err) }()
+	var userID int
+	defer func() { s.record(ctx, audit.ActionLoginReported, userID, err) }()
+
+	if token == "" {
+		return apperror.Localize(apperror.ErrValidation, "validation.token_required", "token is required")
+	}
+
+	userID, loginID, expiration, err := s.parseLoginReportToken(token)
+	if err != nil {
+		return err
+	}
+	// Le lien ne sert qu'une fois : il est consommé avant la révocation des sessions
+	first, err := s.blacklist.Claim(ctx, token, expiration)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if !first {
+		return fmt.Errorf("%w: token already used or expired", apperror.ErrUnauthorized)
+	}
+
+	if err := s.repo.ReportLogin(ctx, userID, loginID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			// Compte supprimé depuis l'envoi de l'alerte
+			return fmt.Errorf("%w: login %d does not exist", apperror.ErrUnauthorized, loginID)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if err := s.blacklist.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("internal error: failed to load user: %v", err)
+	}
+	unusable, err := randomSecret()
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	hashed, err := hashPassword(ctx, unusable)
+	if err != nil {
+		return fmt.Errorf("internal error: failed to hash password: %v", err)
+	}
+	if err := s.repo.ResetPassword(ctx, user.Email, hashed); err != nil {
+		return fmt.Errorf("internal error: failed to update password: %v", err)
+	}
+
+	logger := logging.FromContext(ctx)
+	logger.Warn("login reported as fraudulent", "user_id", userID, "login_id", loginID)
+	metrics.LoginAlerts.WithLabelValues("reported").Inc()
+
+	resetToken, err := s.generateResetToken(user.Email)
+	if err != nil {
+		return fmt.Errorf("internal error: failed to generate reset token: %v", err)
+	}
+	if user.Locale != "" {
+		locale = user.Locale
+	}
+	if err := s.sendResetEmail(ctx, user, resetToken, locale); err != nil {
+		// Les sessions sont déjà révoquées : l'utilisateur peut redemander un lien
+		logger.Error("error sending password reset after login report", "user_id", userID, "error", err)
+	}
+	return nil
+}
@@ -0,0 +1,388 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+)
+
+// MemoryUserStore est une implémentation de UserStore en mémoire, utilisée par les tests et
+// les déploiements sans base de données. Elle reproduit les contraintes du schéma Postgres :
+// email unique, couple (provider, subject) unique, suppression en cascade des identités et de
+// l'historique des connexions.
+type MemoryUserStore struct {
+	mu             sync.Mutex
+	users          map[int]*memoryUser
+	identities     map[int]*Identity
+	logins         []LoginAttempt
+	nextUserID     int
+	nextIdentityID int
+	nextLoginID    int64
+}
+
+type memoryUser struct {
+	user       User
+	password   string
+	membership *TenantMembership
+}
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{
+		users:      make(map[int]*memoryUser),
+		identities: make(map[int]*Identity),
+	}
+}
+
+var _ UserStore = (*MemoryUserStore)(nil)
+
+func (s *MemoryUserStore) Create(_ context.Context, user User, identity Identity) (int, error) {
+	return s.create(user, identity, nil)
+}
+
+func (s *MemoryUserStore) CreateInTenant(_ context.Context, user User, identity Identity, membership TenantMembership) (int, error) {
+	return s.create(user, identity, &membership)
+}
+
+func (s *MemoryUserStore) create(user User, identity Identity, membership *TenantMembership) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.emailTaken(user.Email, 0) {
+		return 0, fmt.Errorf("error inserting user: %w: email %s", apperror.ErrConflict, user.Email)
+	}
+	if identity.Provider != PasswordProvider && s.findIdentity(identity.Provider, identity.Subject) != nil {
+		return 0, fmt.Errorf("error inserting identity: %w: %s identity %s", apperror.ErrConflict, identity.Provider, identity.Subject)
+	}
+	if membership != nil && membership.ExternalID != "" {
+		for _, u := range s.users {
+			if u.membership != nil && *u.membership == *membership {
+				return 0, ErrExternalIDInUse
+			}
+		}
+	}
+
+	s.nextUserID++
+	id := s.nextUserID
+	user.ID = id
+	user.Role = RoleUser
+	user.Active = true
+	password := user.Password
+	user.Password = ""
+	s.users[id] = &memoryUser{user: user, password: password, membership: membership}
+
+	if identity.Provider == PasswordProvider {
+		identity = passwordIdentity(id, user.Email)
+	}
+	identity.UserID = id
+	s.insertIdentity(identity)
+	return id, nil
+}
+
+func (s *MemoryUserStore) Login(ctx context.Context, email, password string) (*User, error) {
+	s.mu.Lock()
+	u := s.byEmail(email)
+	var hashedPassword string
+	if u != nil {
+		hashedPassword = u.password
+	}
+	s.mu.Unlock()
+
+	if u == nil {
+		return nil, fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	if err := comparePassword(ctx, hashedPassword, password); err != nil {
+		return nil, fmt.Errorf("%w: invalid password", apperror.ErrUnauthorized)
+	}
+	return &User{ID: u.user.ID, Name: u.user.Name, Email: u.user.Email}, nil
+}
+
+func (s *MemoryUserStore) GetByEmail(_ context.Context, email string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.byEmail(email)
+	if u == nil {
+		return nil, nil
+	}
+	return &User{ID: u.user.ID, Name: u.user.Name, Email: u.user.Email, Locale: u.user.Locale}, nil
+}
+
+func (s *MemoryUserStore) FindByID(_ context.Context, id int) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[id]
+	if !ok {
+		return nil, fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	user := u.user
+	return &user, nil
+}
+
+func (s *MemoryUserStore) FindByIDs(_ context.Context, ids []int) ([]User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seen := make(map[int]bool, len(ids))
+	var users []User
+	for _, id := range ids {
+		if u, ok := s.users[id]; ok && !seen[id] {
+			seen[id] = true
+			users = append(users, u.user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+func (s *MemoryUserStore) Update(_ context.Context, user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[user.ID]
+	if !ok {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	if s.emailTaken(user.Email, user.ID) {
+		return fmt.Errorf("error updating user: %w: email %s", apperror.ErrConflict, user.Email)
+	}
+	u.user.Name = user.Name
+	u.user.Email = user.Email
+	u.user.MobileNumber = user.MobileNumber
+	u.user.Active = user.Active
+	return nil
+}
+
+func (s *MemoryUserStore) Delete(_ context.Context, id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[id]; !ok {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	delete(s.users, id)
+	for identityID, identity := range s.identities {
+		if identity.UserID == id {
+			delete(s.identities, identityID)
+		}
+	}
+	s.logins = slices.DeleteFunc(s.logins, func(a LoginAttempt) bool { return a.UserID == id })
+	return nil
+}
+
+func (s *MemoryUserStore) SetRole(_ context.Context, userID int, role string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	u.user.Role = role
+	return nil
+}
+
+func (s *MemoryUserStore) SetMobileNumber(_ context.Context, userID int, mobileNumber string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u, ok := s.users[userID]; ok {
+		u.user.MobileNumber = mobileNumber
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) SetLocale(_ context.Context, userID int, locale string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	if !ok {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	u.user.Locale = locale
+	return nil
+}
+
+func (s *MemoryUserStore) ResetPassword(_ context.Context, email, hashedPassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if u := s.byEmail(email); u != nil {
+		u.password = hashedPassword
+	}
+	return nil
+}
+
+// CreateIdentity ignore silencieusement une identité déjà rattachée, comme le ON CONFLICT
+// DO NOTHING de la version Postgres
+func (s *MemoryUserStore) CreateIdentity(_ context.Context, identity Identity) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[identity.UserID]; !ok {
+		return errors.New("error inserting identity: user does not exist")
+	}
+	if s.findIdentity(identity.Provider, identity.Subject) != nil {
+		return nil
+	}
+	s.insertIdentity(identity)
+	return nil
+}
+
+func (s *MemoryUserStore) FindIdentity(_ context.Context, provider, subject string) (*Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity := s.findIdentity(provider, subject)
+	if identity == nil {
+		return nil, nil
+	}
+	i := *identity
+	return &i, nil
+}
+
+func (s *MemoryUserStore) IsTenantMember(_ context.Context, tenant string, userID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u, ok := s.users[userID]
+	return ok && u.membership != nil && u.membership.Tenant == tenant, nil
+}
+
+func (s *MemoryUserStore) ListIdentities(_ context.Context, userID int) ([]Identity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identities := []Identity{}
+	for _, identity := range s.identities {
+		if identity.UserID == userID {
+			identities = append(identities, *identity)
+		}
+	}
+	sort.Slice(identities, func(i, j int) bool { return identities[i].ID < identities[j].ID })
+	return identities, nil
+}
+
+func (s *MemoryUserStore) DeleteIdentity(_ context.Context, userID, identityID int, unusablePassword string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	identity, ok := s.identities[identityID]
+	if !ok || identity.UserID != userID {
+		return fmt.Errorf("identity %w", apperror.ErrNotFound)
+	}
+	count := 0
+	for _, i := range s.identities {
+		if i.UserID == userID {
+			count++
+		}
+	}
+	if count <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	delete(s.identities, identityID)
+	if identity.Provider == PasswordProvider {
+		s.users[userID].password = unusablePassword
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) RecordLogin(_ context.Context, attempt *LoginAttempt) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[attempt.UserID]; !ok {
+		return errors.New("error inserting login attempt: user does not exist")
+	}
+	if attempt.Success {
+		var total, sameDevice, sameNetwork int
+		for _, a := range s.logins {
+			if a.UserID != attempt.UserID || !a.Success {
+				continue
+			}
+			total++
+			if a.Device == attempt.Device {
+				sameDevice++
+			}
+			if a.Network == attempt.Network {
+				sameNetwork++
+			}
+		}
+		attempt.NewDevice = total > 0 && sameDevice == 0
+		attempt.NewNetwork = total > 0 && sameNetwork == 0
+	}
+
+	s.nextLoginID++
+	attempt.ID = s.nextLoginID
+	s.logins = append(s.logins, *attempt)
+	return nil
+}
+
+func (s *MemoryUserStore) ListLogins(_ context.Context, userID int, filter LoginFilter) ([]LoginAttempt, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logins := []LoginAttempt{}
+	for i := len(s.logins) - 1; i >= 0 && len(logins) < filter.Limit; i-- {
+		a := s.logins[i]
+		if a.UserID == userID && (filter.BeforeID == 0 || a.ID < filter.BeforeID) {
+			logins = append(logins, a)
+		}
+	}
+	return logins, nil
+}
+
+func (s *MemoryUserStore) ReportLogin(_ context.Context, userID int, loginID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.logins {
+		if a := &s.logins[i]; a.ID == loginID && a.UserID == userID {
+			if a.ReportedAt == nil {
+				now := time.Now()
+				a.ReportedAt = &now
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("login attempt %w", apperror.ErrNotFound)
+}
+
+// byEmail compare les emails à l'identique, comme la contrainte UNIQUE de la table users
+func (s *MemoryUserStore) byEmail(email string) *memoryUser {
+	for _, u := range s.users {
+		if u.user.Email == email {
+			return u
+		}
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) emailTaken(email string, exceptID int) bool {
+	u := s.byEmail(email)
+	return u != nil && u.user.ID != exceptID
+}
+
+func (s *MemoryUserStore) findIdentity(provider, subject string) *Identity {
+	for _, identity := range s.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity
+		}
+	}
+	return nil
+}
+
+func (s *MemoryUserStore) insertIdentity(identity Identity) {
+	s.nextIdentityID++
+	identity.ID = s.nextIdentityID
+	identity.CreatedAt = time.Now()
+	s.identities[identity.ID] = &identity
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"context"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// hashPassword hache un mot de passe avec bcrypt ; la durée est mesurée, son coût étant
+// volontairement élevé
+func hashPassword(ctx context.Context, password string) (string, error) {
+	_, span := tracing.Start(ctx, "bcrypt.Hash")
+	defer span.End()
+	defer metrics.ObserveHashing("hash", time.Now())
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hashed), err
+}
+
+// comparePassword vérifie un mot de passe contre son empreinte bcrypt
+func comparePassword(ctx context.Context, hashed, password string) error {
+	_, span := tracing.Start(ctx, "bcrypt.Compare")
+	defer span.End()
+	defer metrics.ObserveHashing("verify", time.Now())
+	return bcrypt.CompareHashAndPassword([]byte(hashed), []byte(password))
+}
@@ -1,61 +1,132 @@
 package user
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
-	"golang.org/x/crypto/bcrypt"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"github.com/pathi14/AuthentificationGO/internal/webhook"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type UserRepository struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect database.Dialect
 }
 
 func NewUserRepository(db *sql.DB) *UserRepository {
-	return &UserRepository{db: db}
+	return &UserRepository{db: db, dialect: database.DialectOf(db)}
 }
 
-func (r *UserRepository) Create(user User) error {
-	fmt.Println("Attempting to create user:", user.Email)
+// Create insère l'utilisateur et sa première méthode de connexion dans une même transaction,
+// qui publie aussi webhook.EventUserRegistered. Une identité dont le Provider est
+// PasswordProvider reçoit l'ID du nouvel utilisateur comme sujet.
+func (r *UserRepository) Create(ctx context.Context, user User, identity Identity) (_ int, err error) {
+	ctx, span := r.startQuery(ctx, "Create", "INSERT users")
+	defer func() { tracing.End(span, err) }()
 
-	_, err := r.db.Exec(
-		"INSERT INTO users (name, age, mobile_number, email, password) VALUES ($1, $2, $3, $4, $5)",
-		user.Name, user.Age, user.MobileNumber, user.Email, user.Password)
+	return r.create(ctx, user, identity, nil)
+}
+
+// CreateInTenant crée le compte comme Create et le rattache, dans la même transaction, au tenant
+// SCIM qui le provisionne. Un externalId déjà pris dans le tenant retourne ErrExternalIDInUse.
+func (r *UserRepository) CreateInTenant(ctx context.Context, user User, identity Identity, membership TenantMembership) (_ int, err error) {
+	ctx, span := r.startQuery(ctx, "CreateInTenant", "INSERT users")
+	defer func() { tracing.End(span, err) }()
+
+	return r.create(ctx, user, identity, &membership)
+}
 
+func (r *UserRepository) create(ctx context.Context, user User, identity Identity, membership *TenantMembership) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
-		fmt.Println("Error inserting user:", err)
-		return fmt.Errorf("error inserting user: %w", err)
+		return 0, fmt.Errorf("error starting transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	fmt.Println("User created successfully:", user.Email)
-	return nil
+	var id int
+	err = tx.QueryRowContext(ctx,
+		r.dialect.Rebind("INSERT INTO users (name, age, mobile_number, email, password, locale) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id"),
+		user.Name, user.Age, user.MobileNumber, user.Email, user.Password, user.Locale).Scan(&id)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting user: %w", database.MapError(err))
+	}
+
+	if identity.Provider == PasswordProvider {
+		identity = passwordIdentity(id, user.Email)
+	}
+	_, err = tx.ExecContext(ctx,
+		r.dialect.Rebind("INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4)"),
+		id, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return 0, fmt.Errorf("error inserting identity: %w", database.MapError(err))
+	}
+
+	if membership != nil {
+		_, err = tx.ExecContext(ctx,
+			r.dialect.Rebind("INSERT INTO scim_users (tenant, user_id, external_id) VALUES ($1, $2, NULLIF($3, ''))"),
+			membership.Tenant, id, membership.ExternalID)
+		if errors.Is(database.MapError(err), apperror.ErrConflict) {
+			return 0, ErrExternalIDInUse
+		}
+		if err != nil {
+			return 0, fmt.Errorf("error inserting tenant membership: %w", err)
+		}
+	}
+
+	if err := webhook.Enqueue(ctx, tx, r.dialect, webhook.EventUserRegistered, webhook.UserData{UserID: id, Email: user.Email}); err != nil {
+		return 0, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing user creation: %w", err)
+	}
+
+	logging.FromContext(ctx).Debug("user inserted", "user_id", id, "email", user.Email, "provider", identity.Provider)
+	return id, nil
+}
+
+// startQuery ouvre le span d'une requête du dépôt
+func (r *UserRepository) startQuery(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracing.StartQuery(ctx, "UserRepository."+method, r.dialect.System(), operation)
 }
 
-func (r *UserRepository) Login(email, password string) (*User, error) {
+func (r *UserRepository) Login(ctx context.Context, email, password string) (_ *User, err error) {
+	ctx, span := r.startQuery(ctx, "Login", "SELECT users")
+	defer func() { tracing.End(span, err) }()
+
 	var u User
 	var hashedPassword string
-	err := r.db.QueryRow("SELECT id, name, email, password FROM users WHERE email = $1", email).
+	err = r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT id, name, email, password FROM users WHERE email = $1"), email).
 		Scan(&u.ID, &u.Name, &u.Email, &hashedPassword)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, fmt.Errorf("user %w", apperror.ErrNotFound)
 	}
 	if err != nil {
 		return nil, err
 	}
 
-	err = bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	err = comparePassword(ctx, hashedPassword, password)
 	if err != nil {
-		return nil, fmt.Errorf("invalid password")
+		return nil, fmt.Errorf("%w: invalid password", apperror.ErrUnauthorized)
 	}
 
 	return &u, nil
 }
 
-func (r *UserRepository) GetByEmail(email string) (*User, error) {
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (_ *User, err error) {
+	ctx, span := r.startQuery(ctx, "GetByEmail", "SELECT users")
+	defer func() { tracing.End(span, err) }()
+
 	var u User
-	err := r.db.QueryRow("SELECT id, name, email FROM users WHERE email = $1", email).
-		Scan(&u.ID, &u.Name, &u.Email)
+	err = r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT id, name, email, locale FROM users WHERE email = $1"), email).
+		Scan(&u.ID, &u.Name, &u.Email, &u.Locale)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -65,23 +136,403 @@ func (r *UserRepository) GetByEmail(email string) (*User, error) {
 	return &u, nil
 }
 
-func (r *UserRepository) FindByID(id int) (*User, error) {
+func (r *UserRepository) FindByID(ctx context.Context, id int) (_ *User, err error) {
+	ctx, span := r.startQuery(ctx, "FindByID", "SELECT users")
+	defer func() { tracing.End(span, err) }()
+
 	var user User
-	query := "SELECT id, name, age, mobile_number, email FROM users WHERE id = $1"
-	err := r.db.QueryRow(query, id).Scan(&user.ID, &user.Name, &user.Age, &user.MobileNumber, &user.Email)
+	query := "SELECT id, name, age, mobile_number, email, role, active, locale FROM users WHERE id = $1"
+	err = r.db.QueryRowContext(ctx, r.dialect.Rebind(query), id).Scan(&user.ID, &user.Name, &user.Age, &user.MobileNumber, &user.Email, &user.Role, &user.Active, &user.Locale)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("user not found")
+			return nil, fmt.Errorf("user %w", apperror.ErrNotFound)
 		}
 		return nil, err
 	}
 	return &user, nil
 }
 
-func (r *UserRepository) ResetPassword(email, hashedPassword string) error {
+// FindByIDs charge plusieurs utilisateurs en une requête ; les IDs inconnus sont ignorés
+func (r *UserRepository) FindByIDs(ctx context.Context, ids []int) (_ []User, err error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	ctx, span := r.startQuery(ctx, "FindByIDs", "SELECT users")
+	defer func() { tracing.End(span, err) }()
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind("SELECT id, name, age, mobile_number, email, role, active, locale FROM users WHERE id IN "+database.InList(1, len(ids))+" ORDER BY id"),
+		args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []User
+	for rows.Next() {
+		var u User
+		if err := rows.Scan(&u.ID, &u.Name, &u.Age, &u.MobileNumber, &u.Email, &u.Role, &u.Active, &u.Locale); err != nil {
+			return nil, err
+		}
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+// Update remplace le nom, l'email, le numéro de mobile et l'état du compte. La désactivation
+// d'un compte actif est publiée (webhook.EventUserDisabled) dans la même transaction.
+func (r *UserRepository) Update(ctx context.Context, user User) (err error) {
+	ctx, span := r.startQuery(ctx, "Update", "UPDATE users")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasActive bool
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT active FROM users WHERE id = $1"+r.forUpdate()), user.ID).Scan(&wasActive)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("error loading user: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		r.dialect.Rebind("UPDATE users SET name = $1, email = $2, mobile_number = $3, active = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $5"),
+		user.Name, user.Email, user.MobileNumber, user.Active, user.ID)
+	if err != nil {
+		return fmt.Errorf("error updating user: %w", database.MapError(err))
+	}
+	if wasActive && !user.Active {
+		if err := webhook.Enqueue(ctx, tx, r.dialect, webhook.EventUserDisabled, webhook.UserData{UserID: user.ID, Email: user.Email}); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Delete supprime le compte et publie webhook.EventUserDeleted dans la même transaction
+func (r *UserRepository) Delete(ctx context.Context, id int) (err error) {
+	ctx, span := r.startQuery(ctx, "Delete", "DELETE users")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var email string
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind("DELETE FROM users WHERE id = $1 RETURNING email"), id).Scan(&email)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return fmt.Errorf("error deleting user: %w", err)
+	}
+	if err := webhook.Enqueue(ctx, tx, r.dialect, webhook.EventUserDeleted, webhook.UserData{UserID: id, Email: email}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// forUpdate verrouille sous PostgreSQL la ligne lue avant sa modification. SQLite n'a pas de
+// verrou de ligne : la transaction y détient déjà le verrou d'écriture.
+func (r *UserRepository) forUpdate() string {
+	if r.dialect == database.Postgres {
+		return " FOR UPDATE"
+	}
+	return ""
+}
+
+func (r *UserRepository) SetRole(ctx context.Context, userID int, role string) (err error) {
+	ctx, span := r.startQuery(ctx, "SetRole", "UPDATE users")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE users SET role = $1 WHERE id = $2"), role, userID)
+	if err != nil {
+		return fmt.Errorf("error updating role: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+func (r *UserRepository) SetMobileNumber(ctx context.Context, userID int, mobileNumber string) (err error) {
+	ctx, span := r.startQuery(ctx, "SetMobileNumber", "UPDATE users")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE users SET mobile_number = $1 WHERE id = $2"), mobileNumber, userID)
+	if err != nil {
+		return fmt.Errorf("error updating mobile number: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) SetLocale(ctx context.Context, userID int, locale string) (err error) {
+	ctx, span := r.startQuery(ctx, "SetLocale", "UPDATE users")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("UPDATE users SET locale = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2"), locale, userID)
+	if err != nil {
+		return fmt.Errorf("error updating locale: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+// ResetPassword remplace le mot de passe du compte email et publie
+// webhook.EventUserPasswordChanged dans la même transaction ; un email inconnu est ignoré
+func (r *UserRepository) ResetPassword(ctx context.Context, email, hashedPassword string) (err error) {
+	ctx, span := r.startQuery(ctx, "ResetPassword", "UPDATE users")
+	defer func() { tracing.End(span, err) }()
 
-	_, err := r.db.Exec("UPDATE users SET password = $1 WHERE email = $2", hashedPassword, email)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var id int
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind("UPDATE users SET password = $1 WHERE email = $2 RETURNING id"), hashedPassword, email).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := webhook.Enqueue(ctx, tx, r.dialect, webhook.EventUserPasswordChanged, webhook.UserData{UserID: id, Email: email}); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *UserRepository) CreateIdentity(ctx context.Context, identity Identity) (err error) {
+	ctx, span := r.startQuery(ctx, "CreateIdentity", "INSERT user_identities")
+	defer func() { tracing.End(span, err) }()
+
+	_, err = r.db.ExecContext(ctx,
+		r.dialect.Rebind("INSERT INTO user_identities (user_id, provider, subject, email) VALUES ($1, $2, $3, $4) ON CONFLICT (provider, subject) DO NOTHING"),
+		identity.UserID, identity.Provider, identity.Subject, identity.Email)
+	if err != nil {
+		return fmt.Errorf("error inserting identity: %w", err)
+	}
+	return nil
+}
+
+func (r *UserRepository) FindIdentity(ctx context.Context, provider, subject string) (_ *Identity, err error) {
+	ctx, span := r.startQuery(ctx, "FindIdentity", "SELECT user_identities")
+	defer func() { tracing.End(span, err) }()
 
-	return err
+	var i Identity
+	var email sql.NullString
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("SELECT id, user_id, provider, subject, email, created_at FROM user_identities WHERE provider = $1 AND subject = $2"),
+		provider, subject).
+		Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &email, &i.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	i.Email = email.String
+	return &i, nil
+}
+
+func (r *UserRepository) IsTenantMember(ctx context.Context, tenant string, userID int) (_ bool, err error) {
+	ctx, span := r.startQuery(ctx, "IsTenantMember", "SELECT scim_users")
+	defer func() { tracing.End(span, err) }()
+
+	var member bool
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("SELECT EXISTS (SELECT 1 FROM scim_users WHERE tenant = $1 AND user_id = $2)"),
+		tenant, userID).Scan(&member)
+	if err != nil {
+		return false, fmt.Errorf("error checking tenant membership: %w", err)
+	}
+	return member, nil
+}
+
+func (r *UserRepository) ListIdentities(ctx context.Context, userID int) (_ []Identity, err error) {
+	ctx, span := r.startQuery(ctx, "ListIdentities", "SELECT user_identities")
+	defer func() { tracing.End(span, err) }()
+
+	rows, err := r.db.QueryContext(ctx,
+		r.dialect.Rebind("SELECT id, user_id, provider, subject, email, created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at"),
+		userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing identities: %w", err)
+	}
+	defer rows.Close()
+
+	identities := []Identity{}
+	for rows.Next() {
+		var i Identity
+		var email sql.NullString
+		if err := rows.Scan(&i.ID, &i.UserID, &i.Provider, &i.Subject, &email, &i.CreatedAt); err != nil {
+			return nil, err
+		}
+		i.Email = email.String
+		identities = append(identities, i)
+	}
+	return identities, rows.Err()
+}
+
+// DeleteIdentity supprime une méthode de connexion sauf s'il s'agit de la dernière du compte.
+// La vérification et la suppression sont faites sous verrou pour éviter deux suppressions concurrentes.
+// Supprimer l'identité mot de passe remplace aussi le hash par unusablePassword et publie
+// webhook.EventUserPasswordChanged dans la même transaction.
+func (r *UserRepository) DeleteIdentity(ctx context.Context, userID, identityID int, unusablePassword string) (err error) {
+	ctx, span := r.startQuery(ctx, "DeleteIdentity", "DELETE user_identities")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// SQLite n'a pas de verrou de ligne : la transaction y détient déjà le verrou d'écriture
+	if r.dialect == database.Postgres {
+		if _, err := tx.ExecContext(ctx, "SELECT id FROM users WHERE id = $1 FOR UPDATE", userID); err != nil {
+			return fmt.Errorf("error locking user: %w", err)
+		}
+	}
 
+	var count int
+	if err := tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT COUNT(*) FROM user_identities WHERE user_id = $1"), userID).Scan(&count); err != nil {
+		return fmt.Errorf("error counting identities: %w", err)
+	}
+
+	var provider string
+	err = tx.QueryRowContext(ctx, r.dialect.Rebind("SELECT provider FROM user_identities WHERE id = $1 AND user_id = $2"), identityID, userID).Scan(&provider)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("identity %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return err
+	}
+	if count <= 1 {
+		return ErrLastLoginMethod
+	}
+
+	if _, err := tx.ExecContext(ctx, r.dialect.Rebind("DELETE FROM user_identities WHERE id = $1"), identityID); err != nil {
+		return fmt.Errorf("error deleting identity: %w", err)
+	}
+	if provider == PasswordProvider {
+		var email string
+		err := tx.QueryRowContext(ctx, r.dialect.Rebind("UPDATE users SET password = $1 WHERE id = $2 RETURNING email"), unusablePassword, userID).Scan(&email)
+		if err != nil {
+			return fmt.Errorf("error clearing password: %w", err)
+		}
+		if err := webhook.Enqueue(ctx, tx, r.dialect, webhook.EventUserPasswordChanged, webhook.UserData{UserID: userID, Email: email}); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// RecordLogin insère une tentative de connexion. Pour une connexion réussie, l'appareil et le
+// réseau sont comparés, dans la même transaction, à ceux des connexions réussies précédentes.
+func (r *UserRepository) RecordLogin(ctx context.Context, attempt *LoginAttempt) (err error) {
+	ctx, span := r.startQuery(ctx, "RecordLogin", "INSERT login_attempts")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if attempt.Success {
+		var total, sameDevice, sameNetwork int
+		err = tx.QueryRowContext(ctx,
+			r.dialect.Rebind("SELECT COUNT(*), COUNT(CASE WHEN device = $2 THEN 1 END), COUNT(CASE WHEN network = $3 THEN 1 END) "+
+				"FROM login_attempts WHERE user_id = $1 AND success = $4"),
+			attempt.UserID, attempt.Device, attempt.Network, true).Scan(&total, &sameDevice, &sameNetwork)
+		if err != nil {
+			return fmt.Errorf("error loading login history: %w", err)
+		}
+		attempt.NewDevice = total > 0 && sameDevice == 0
+		attempt.NewNetwork = total > 0 && sameNetwork == 0
+	}
+
+	err = tx.QueryRowContext(ctx,
+		r.dialect.Rebind("INSERT INTO login_attempts (user_id, attempted_at, success, reason, ip, network, user_agent, device, new_device, new_network) "+
+			"VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id"),
+		attempt.UserID, attempt.Time.UTC(), attempt.Success, attempt.Reason, attempt.IP, attempt.Network,
+		attempt.UserAgent, attempt.Device, attempt.NewDevice, attempt.NewNetwork).Scan(&attempt.ID)
+	if err != nil {
+		return fmt.Errorf("error inserting login attempt: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListLogins retourne les tentatives de connexion du compte, de la plus récente à la plus
+// ancienne
+func (r *UserRepository) ListLogins(ctx context.Context, userID int, filter LoginFilter) (_ []LoginAttempt, err error) {
+	ctx, span := r.startQuery(ctx, "ListLogins", "SELECT login_attempts")
+	defer func() { tracing.End(span, err) }()
+
+	query := "SELECT id, user_id, attempted_at, success, reason, ip, network, user_agent, device, new_device, new_network, reported_at " +
+		"FROM login_attempts WHERE user_id = $1"
+	args := []any{userID}
+	if filter.BeforeID != 0 {
+		args = append(args, filter.BeforeID)
+		query += " AND id < $2"
+	}
+	args = append(args, filter.Limit)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d", len(args))
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	logins := []LoginAttempt{}
+	for rows.Next() {
+		var a LoginAttempt
+		var reportedAt sql.NullTime
+		err := rows.Scan(&a.ID, &a.UserID, &a.Time, &a.Success, &a.Reason, &a.IP, &a.Network,
+			&a.UserAgent, &a.Device, &a.NewDevice, &a.NewNetwork, &reportedAt)
+		if err != nil {
+			return nil, err
+		}
+		if reportedAt.Valid {
+			a.ReportedAt = &reportedAt.Time
+		}
+		logins = append(logins, a)
+	}
+	return logins, rows.Err()
+}
+
+// ReportLogin marque la connexion loginID du compte comme signalée ; un second signalement
+// conserve la date du premier
+func (r *UserRepository) ReportLogin(ctx context.Context, userID int, loginID int64) (err error) {
+	ctx, span := r.startQuery(ctx, "ReportLogin", "UPDATE login_attempts")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx,
+		r.dialect.Rebind("UPDATE login_attempts SET reported_at = COALESCE(reported_at, $1) WHERE id = $2 AND user_id = $3"),
+		time.Now().UTC(), loginID, userID)
+	if err != nil {
+		return fmt.Errorf("error reporting login: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("login attempt %w", apperror.ErrNotFound)
+	}
+	return nil
 }
@@ -1,91 +1,231 @@
 package user
 
 import (
-	"database/sql"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
 	"fmt"
-	"os"
-	"strings"
+	netmail "net/mail"
 	"time"
 
 	"github.com/google/uuid"
 
 	"github.com/dgrijalva/jwt-go"
-	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
-	"github.com/pathi14/AuthentificationGO/internal/middleware"
-	"golang.org/x/crypto/bcrypt"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type UserService struct {
-	repo        *UserRepository
-	tokenExpiry time.Duration
+	repo           UserStore
+	blacklist      blacklist.TokenStore
+	oauth          *oauth.OAuthService
+	authenticators []Authenticator
+	auth           config.Auth
+	mailer         mail.Mailer
+	audit          *audit.AuditService
 }
 
-func NewUserService(repo *UserRepository) *UserService {
+// NewUserService construit le service. auth fournit le secret et la durée de vie des jetons,
+// mailer envoie les liens de réinitialisation et auditService reçoit les opérations sensibles ;
+// sans authentificateur explicite, seuls les mots de passe locaux sont vérifiés.
+func NewUserService(repo UserStore, blacklist blacklist.TokenStore, oauthService *oauth.OAuthService, auth config.Auth, mailer mail.Mailer, auditService *audit.AuditService, authenticators ...Authenticator) *UserService {
+	if len(authenticators) == 0 {
+		authenticators = []Authenticator{NewLocalAuthenticator(repo)}
+	}
 	return &UserService{
-		repo:        repo,
-		tokenExpiry: 15 * time.Minute,
+		repo:           repo,
+		blacklist:      blacklist,
+		oauth:          oauthService,
+		authenticators: authenticators,
+		auth:           auth,
+		mailer:         mailer,
+		audit:          auditService,
 	}
 }
 
-func (s *UserService) Create(u User) error {
+// record ajoute au journal d'audit l'opération action sur le compte subjectID ; elle a échoué
+// si err n'est pas nil
+func (s *UserService) record(ctx context.Context, action string, subjectID int, err error) {
+	s.audit.Record(ctx, audit.Event{Action: action, SubjectID: subjectID}, err)
+}
+
+func (s *UserService) Create(ctx context.Context, u User) error {
+	_, err := s.create(ctx, u, Identity{Provider: PasswordProvider}, nil)
+	return err
+}
+
+// create valide et enregistre un nouveau compte ; membership, s'il est fourni, le rattache au
+// tenant SCIM qui le provisionne dans la même transaction
+func (s *UserService) create(ctx context.Context, u User, identity Identity, membership *TenantMembership) (id int, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.create", attribute.String("auth.provider", identity.Provider))
+	defer func() { tracing.End(span, err) }()
+	defer func() { s.record(ctx, audit.ActionUserCreate, id, err) }()
+
 	if err := u.Validate(); err != nil {
-		return fmt.Errorf("validation error: %w", err)
+		return 0, &apperror.Localized{Kind: apperror.ErrValidation, Key: "request.invalid_input", Text: err.Error(), Cause: err}
 	}
 
-	existingUser, err := s.repo.GetByEmail(u.Email)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
-		return fmt.Errorf("internal error: %v", err)
+	existingUser, err := s.repo.GetByEmail(ctx, u.Email)
+	if err != nil {
+		return 0, fmt.Errorf("internal error: %v", err)
 	}
 	if existingUser != nil {
-		return fmt.Errorf("email already in use")
+		return 0, ErrEmailInUse
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(u.Password), bcrypt.DefaultCost)
+	hashedPassword, err := hashPassword(ctx, u.Password)
 	if err != nil {
-		return fmt.Errorf("error hashing password: %v", err)
+		return 0, fmt.Errorf("error hashing password: %v", err)
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
 
-	if err := s.repo.Create(u); err != nil {
-		if strings.Contains(err.Error(), "duplicate") || strings.Contains(err.Error(), "unique") {
-			return fmt.Errorf("email already in use")
+	if membership != nil {
+		id, err = s.repo.CreateInTenant(ctx, u, identity, *membership)
+	} else {
+		id, err = s.repo.Create(ctx, u, identity)
+	}
+	if err != nil {
+		if errors.Is(err, ErrExternalIDInUse) {
+			return 0, err
 		}
-		return fmt.Errorf("internal error: %v", err)
+		if errors.Is(err, apperror.ErrConflict) {
+			return 0, ErrEmailInUse
+		}
+		return 0, fmt.Errorf("internal error: %v", err)
 	}
-	return nil
+	logging.FromContext(ctx).Info("account created", "user_id", id, "email", u.Email, "provider", identity.Provider)
+	metrics.Registrations.WithLabelValues(identity.Provider).Inc()
+	return id, nil
 }
 
-func (s *UserService) Login(email, password string) (string, string, error) {
+// Login authentifie l'utilisateur. clientID est vide pour une connexion de première partie ;
+// pour un client tiers, seuls les scopes consentis par l'utilisateur sont accordés.
+func (s *UserService) Login(ctx context.Context, email, password, clientID string, scopes []string) (_, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Login")
+	defer func() { tracing.End(span, err) }()
+
 	if email == "" {
-		return "", "", fmt.Errorf("validation error: email is required")
+		err := apperror.Localize(apperror.ErrValidation, "validation.email_required", "email is required")
+		s.loginFailed(ctx, 0, metrics.ReasonInvalidRequest, err)
+		return "", "", err
 	}
 	if password == "" {
-		return "", "", fmt.Errorf("validation error: password is required")
+		err := apperror.Localize(apperror.ErrValidation, "validation.password_required", "password is required")
+		s.loginFailed(ctx, 0, metrics.ReasonInvalidRequest, err)
+		return "", "", err
 	}
 
-	user, err := s.repo.Login(email, password)
+	logger := logging.FromContext(ctx)
+	userID, err := s.authenticate(ctx, email, password)
 	if err != nil {
-		if err == sql.ErrNoRows || strings.Contains(err.Error(), "user not found") {
-			return "", "", fmt.Errorf("authentication error: user not found")
+		logger.Warn("login failed", "email", email, "error", err)
+		if errors.Is(err, apperror.ErrNotFound) {
+			err := fmt.Errorf("%w: user not found", apperror.ErrUnauthorized)
+			s.loginFailed(ctx, 0, metrics.ReasonUnknownUser, err)
+			return "", "", err
 		}
-		if strings.Contains(err.Error(), "invalid password") || strings.Contains(err.Error(), "password does not match") {
-			return "", "", fmt.Errorf("authentication error: invalid credentials")
+		if errors.Is(err, apperror.ErrUnauthorized) {
+			// Le compte visé est retrouvé pour que l'échec figure dans son propre journal
+			var subjectID int
+			if u, _ := s.repo.GetByEmail(ctx, email); u != nil {
+				subjectID = u.ID
+			}
+			err := fmt.Errorf("%w: invalid credentials", apperror.ErrUnauthorized)
+			s.loginFailed(ctx, subjectID, metrics.ReasonInvalidCredentials, err)
+			return "", "", err
 		}
-		return "", "", fmt.Errorf("internal error: %v", err)
+		if errors.Is(err, ErrLinkRequired) {
+			s.loginFailed(ctx, 0, metrics.ReasonLinkRequired, err)
+			return "", "", err
+		}
+		err = fmt.Errorf("internal error: %v", err)
+		s.loginFailed(ctx, 0, metrics.ReasonInternal, err)
+		return "", "", err
 	}
 
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		return "", "", fmt.Errorf("internal error: JWT_SECRET not configured")
+	granted, err := s.oauth.GrantedScopes(ctx, userID, clientID, scopes)
+	if err != nil {
+		s.loginFailed(ctx, userID, scopeFailure(err), err)
+		return "", "", err
 	}
 
-	accessToken, err := s.generateToken(int(user.ID), 2*time.Hour)
+	accessToken, refreshToken, err := s.issueTokens(ctx, userID, clientID, granted)
+	if err != nil {
+		logger.Warn("login refused", "user_id", userID, "error", err)
+		s.loginFailed(ctx, userID, issueFailure(err), err)
+		return "", "", err
+	}
+	logger.Info("login succeeded", "user_id", userID, "client_id", clientID)
+	metrics.LoginSucceeded()
+	s.record(ctx, audit.ActionLogin, userID, nil)
+	if attempt := s.recordLogin(ctx, userID, ""); attempt != nil && attempt.unfamiliar() {
+		s.sendLoginAlert(ctx, attempt)
+	}
+	return accessToken, refreshToken, nil
+}
+
+// loginFailed compte une connexion refusée et l'enregistre dans le journal d'audit avec le
+// même motif que les métriques, ainsi que dans l'historique du compte visé s'il est connu
+func (s *UserService) loginFailed(ctx context.Context, subjectID int, reason string, err error) {
+	metrics.LoginFailed(reason)
+	s.audit.Record(ctx, audit.Event{Action: audit.ActionLogin, SubjectID: subjectID, Reason: reason}, err)
+	if subjectID != 0 {
+		s.recordLogin(ctx, subjectID, reason)
+	}
+}
+
+// scopeFailure est le motif d'échec, pour les métriques, d'une erreur de GrantedScopes : consentement
+// absent, scope ou client inconnus, ou erreur interne
+func scopeFailure(err error) string {
+	if errors.Is(err, oauth.ErrConsentRequired) {
+		return metrics.ReasonConsentRequired
+	}
+	if errors.Is(err, apperror.ErrValidation) {
+		return metrics.ReasonInvalidRequest
+	}
+	return metrics.ReasonInternal
+}
+
+// issueFailure est le motif d'échec, pour les métriques, d'une erreur de issueTokens
+func issueFailure(err error) string {
+	if errors.Is(err, apperror.ErrLocked) {
+		return metrics.ReasonAccountLocked
+	}
+	return metrics.ReasonInternal
+}
+
+func (s *UserService) issueTokens(ctx context.Context, userID int, clientID string, scopes []string) (string, string, error) {
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: failed to load user: %v", err)
+	}
+	if !user.Active {
+		return "", "", fmt.Errorf("%w: %w", apperror.ErrUnauthorized, apperror.ErrLocked)
+	}
+	if user.Role != RoleAdmin {
+		scopes = oauth.Without(scopes, oauth.AdminScopes)
+	}
+	generation, err := s.blacklist.SessionGeneration(ctx, userID)
+	if err != nil {
+		return "", "", fmt.Errorf("internal error: %v", err)
+	}
+
+	accessToken, err := s.generateToken(user, clientID, scopes, generation, s.auth.AccessTokenTTL)
 	if err != nil {
 		return "", "", fmt.Errorf("internal error: failed to generate access token")
 	}
 
-	newRefreshToken, err := s.generateToken(int(user.ID), 7*24*time.Hour)
+	newRefreshToken, err := s.generateToken(user, clientID, scopes, generation, s.auth.RefreshTokenTTL)
 	if err != nil {
 		return "", "", fmt.Errorf("internal error: failed to generate refresh token")
 	}
@@ -93,113 +233,429 @@ func (s *UserService) Login(email, password string) (string, string, error) {
 	return accessToken, newRefreshToken, nil
 }
 
-func (s *UserService) Logout(tokenString string) error {
-	db, err := database.ConnectDB()
+// IssueTokens émet un couple de jetons de première partie pour un utilisateur déjà authentifié
+// par un autre moyen (fournisseur d'identité externe par exemple) ; c'est une connexion pour le
+// journal d'audit
+func (s *UserService) IssueTokens(ctx context.Context, userID int) (_, _ string, err error) {
+	defer func() { s.record(ctx, audit.ActionLogin, userID, err) }()
+	return s.issueTokens(ctx, userID, "", oauth.AllScopes)
+}
+
+func (s *UserService) FindByEmail(ctx context.Context, email string) (*User, error) {
+	return s.repo.GetByEmail(ctx, email)
+}
+
+// CreateExternalUser crée un compte sans mot de passe utilisable, rattaché dès sa création
+// à l'identité externe (provider, subject)
+func (s *UserService) CreateExternalUser(ctx context.Context, name, email, provider, subject string) (*User, error) {
+	if len(name) > 50 {
+		name = name[:50]
+	}
+
+	randomPassword, err := randomSecret()
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+
+	u := User{Name: name, Email: email, Password: randomPassword}
+	id, err := s.create(ctx, u, Identity{Provider: provider, Subject: subject, Email: email}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	u.ID = id
+	u.Password = ""
+	return &u, nil
+}
+
+// ProvisionUser crée un compte poussé par un système tiers (SCIM), rattaché au tenant de
+// membership. Le mot de passe est aléatoire : l'utilisateur se connecte par SSO ou définit son
+// mot de passe via « mot de passe oublié ».
+func (s *UserService) ProvisionUser(ctx context.Context, u User, membership TenantMembership) (int, error) {
+	randomPassword, err := randomSecret()
 	if err != nil {
-		return fmt.Errorf("erreur de connexion à la base de données : %v", err)
+		return 0, fmt.Errorf("internal error: %v", err)
 	}
-	defer db.Close()
+	u.Password = randomPassword
+
+	id, err := s.create(ctx, u, Identity{Provider: PasswordProvider}, &membership)
+	if err != nil {
+		return 0, err
+	}
+	if !u.Active {
+		u.ID = id
+		return id, s.UpdateUser(ctx, u)
+	}
+	return id, nil
+}
+
+// UpdateUser remplace le nom, l'email, le mobile et l'état actif d'un compte existant
+func (s *UserService) UpdateUser(ctx context.Context, u User) (err error) {
+	defer func() { s.record(ctx, audit.ActionUserUpdate, u.ID, err) }()
+
+	if len(u.Name) < 2 || len(u.Name) > 50 {
+		return apperror.Localize(apperror.ErrValidation, "validation.name_length", "name must contain between 2 and 50 characters")
+	}
+	if _, err := netmail.ParseAddress(u.Email); err != nil {
+		return apperror.Localize(apperror.ErrValidation, "validation.email_invalid", "invalid email")
+	}
+
+	existing, err := s.repo.GetByEmail(ctx, u.Email)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if existing != nil && existing.ID != u.ID {
+		return ErrEmailInUse
+	}
+
+	// Un compte actif qui est désactivé compte comme un verrouillage et perd ses sessions
+	locking := false
+	if !u.Active {
+		if previous, err := s.repo.FindByID(ctx, u.ID); err == nil && previous.Active {
+			locking = true
+		}
+	}
+
+	if err := s.repo.Update(ctx, u); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: user with ID %d does not exist", apperror.ErrNotFound, u.ID)
+		}
+		if errors.Is(err, apperror.ErrConflict) {
+			return ErrEmailInUse
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if locking {
+		if err := s.blacklist.RevokeAll(ctx, u.ID); err != nil {
+			return fmt.Errorf("internal error: %v", err)
+		}
+		metrics.Lockouts.Inc()
+		s.record(ctx, audit.ActionUserDisable, u.ID, nil)
+	}
+	return nil
+}
+
+func (s *UserService) DeleteUser(ctx context.Context, id int) (err error) {
+	defer func() { s.record(ctx, audit.ActionUserDelete, id, err) }()
+
+	if err := s.repo.Delete(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: user with ID %d does not exist", apperror.ErrNotFound, id)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+func (s *UserService) FindUsers(ctx context.Context, ids []int) ([]User, error) {
+	users, err := s.repo.FindByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return users, nil
+}
+
+// SetLocale enregistre la langue préférée de l'utilisateur ; une chaîne vide revient à la
+// négociation par Accept-Language
+func (s *UserService) SetLocale(ctx context.Context, userID int, locale string) error {
+	if locale != "" && !i18n.Supported(locale) {
+		return apperror.Localize(apperror.ErrValidation, "validation.locale_unsupported",
+			fmt.Sprintf("unsupported locale %q", locale), locale)
+	}
+	if err := s.repo.SetLocale(ctx, userID, locale); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: user with ID %d does not exist", apperror.ErrNotFound, userID)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// SetActive active ou désactive un compte ; un compte désactivé ne peut plus obtenir de jetons,
+// et ceux déjà émis sont révoqués
+func (s *UserService) SetActive(ctx context.Context, userID int, active bool) (err error) {
+	action := audit.ActionUserEnable
+	if !active {
+		action = audit.ActionUserDisable
+	}
+	defer func() { s.record(ctx, action, userID, err) }()
+
+	u, err := s.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	wasActive := u.Active
+	u.Active = active
+	if err := s.repo.Update(ctx, *u); err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if !active {
+		if err := s.blacklist.RevokeAll(ctx, userID); err != nil {
+			return fmt.Errorf("internal error: %v", err)
+		}
+	}
+	if wasActive && !active {
+		metrics.Lockouts.Inc()
+	}
+	return nil
+}
+
+// SetRole change le rôle d'un compte. Un rôle inchangé (resynchronisation SCIM) n'est pas
+// enregistré dans le journal d'audit.
+func (s *UserService) SetRole(ctx context.Context, userID int, role string) (err error) {
+	if role != RoleUser && role != RoleAdmin {
+		return apperror.Localize(apperror.ErrValidation, "validation.role_unknown", fmt.Sprintf("unknown role %q", role), role)
+	}
+	if u, err := s.repo.FindByID(ctx, userID); err == nil && u.Role == role {
+		return nil
+	}
+	defer func() { s.record(ctx, audit.ActionUserRoleChange, userID, err) }()
+
+	if err := s.repo.SetRole(ctx, userID, role); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: user with ID %d does not exist", apperror.ErrNotFound, userID)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+func (s *UserService) FindIdentity(ctx context.Context, provider, subject string) (*Identity, error) {
+	identity, err := s.repo.FindIdentity(ctx, provider, subject)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return identity, nil
+}
+
+func (s *UserService) ListIdentities(ctx context.Context, userID int) ([]Identity, error) {
+	identities, err := s.repo.ListIdentities(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return identities, nil
+}
+
+// LinkIdentity rattache une identité externe à un compte existant. Une identité déjà
+// rattachée à un autre compte n'est jamais déplacée.
+func (s *UserService) LinkIdentity(ctx context.Context, userID int, provider, subject, email string) (err error) {
+	existing, err := s.repo.FindIdentity(ctx, provider, subject)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if existing != nil && existing.UserID == userID {
+		return nil
+	}
+	defer func() { s.record(ctx, audit.ActionIdentityLink, userID, err) }()
+
+	if existing != nil {
+		return ErrIdentityLinked
+	}
+
+	identity := Identity{UserID: userID, Provider: provider, Subject: subject, Email: email}
+	if err := s.repo.CreateIdentity(ctx, identity); err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// UnlinkIdentity retire une méthode de connexion, sauf la dernière du compte
+func (s *UserService) UnlinkIdentity(ctx context.Context, userID, identityID int) (err error) {
+	defer func() { s.record(ctx, audit.ActionIdentityUnlink, userID, err) }()
+
+	unusable, err := randomSecret()
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+	hashed, err := hashPassword(ctx, unusable)
+	if err != nil {
+		return fmt.Errorf("internal error: %v", err)
+	}
+
+	if err := s.repo.DeleteIdentity(ctx, userID, identityID, hashed); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: identity %d does not exist", apperror.ErrNotFound, identityID)
+		}
+		if errors.Is(err, ErrLastLoginMethod) {
+			return err
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// randomSecret produit un mot de passe aléatoire que personne ne connaît
+func randomSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (s *UserService) Logout(ctx context.Context, tokenString string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.Logout")
+	defer func() { tracing.End(span, err) }()
 
 	token, _, err := new(jwt.Parser).ParseUnverified(tokenString, jwt.MapClaims{})
 	if err != nil {
-		return errors.New("invalid token")
+		return fmt.Errorf("%w: invalid token", apperror.ErrUnauthorized)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return errors.New("malformed token")
+		return fmt.Errorf("%w: malformed token", apperror.ErrUnauthorized)
 	}
 
-	expirationTime := time.Unix(int64(claims["exp"].(float64)), 0)
+	exp, ok := claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("%w: malformed token", apperror.ErrUnauthorized)
+	}
 
-	return middleware.AddToBlacklist(db, tokenString, expirationTime)
+	userID, _ := claims["user_id"].(float64)
+	err = s.blacklist.Add(ctx, tokenString, time.Unix(int64(exp), 0))
+	s.record(ctx, audit.ActionLogout, int(userID), err)
+	return err
 }
 
-func (s *UserService) ResetPassword(token, newPassword string) error {
-	db, err := database.ConnectDB()
-	if err != nil {
-		return fmt.Errorf("erreur de connexion à la base de données : %v", err)
-	}
-	defer db.Close()
+// ResetPassword remplace le mot de passe avec un lien de réinitialisation, qui ne sert qu'une fois,
+// et révoque toutes les sessions du compte
+func (s *UserService) ResetPassword(ctx context.Context, token, newPassword string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.ResetPassword")
+	defer func() { tracing.End(span, err) }()
+	var userID int
+	defer func() { s.record(ctx, audit.ActionPasswordReset, userID, err) }()
 
 	if token == "" {
-		return fmt.Errorf("validation error: token is required")
+		return apperror.Localize(apperror.ErrValidation, "validation.token_required", "token is required")
 	}
 	if newPassword == "" {
-		return fmt.Errorf("validation error: new password is required")
+		return apperror.Localize(apperror.ErrValidation, "validation.new_password_required", "new password is required")
 	}
 	if len(newPassword) < 8 {
-		return fmt.Errorf("validation error: password must be at least 8 characters long")
+		return apperror.Localize(apperror.ErrValidation, "validation.password_length", "password must be at least 8 characters long")
 	}
 
-	if middleware.IsTokenBlacklisted(token) {
-		return fmt.Errorf("authentication error: token already used or expired")
+	email, err := s.ValidateResetToken(token)
+	if err != nil {
+		return err
 	}
 
-	email, err := s.ValidateResetToken(token)
+	// Le lien ne sert qu'une fois : il est consommé avant le changement de mot de passe
+	first, err := s.blacklist.Claim(ctx, token, time.Now().Add(s.auth.ResetTokenTTL))
 	if err != nil {
-		return fmt.Errorf("authentication error: %v", err)
+		return fmt.Errorf("internal error: %v", err)
+	}
+	if !first {
+		return fmt.Errorf("%w: token already used or expired", apperror.ErrUnauthorized)
+	}
+
+	if userID, err = s.setPassword(ctx, email, newPassword); err != nil {
+		return err
+	}
+	// Les sessions et clés d'API ouvertes avec l'ancien mot de passe sont révoquées
+	if err := s.blacklist.RevokeAll(ctx, userID); err != nil {
+		return fmt.Errorf("internal error: %v", err)
 	}
+	logging.FromContext(ctx).Info("password reset", "email", email)
+	metrics.PasswordResets.WithLabelValues("completed").Inc()
+	return nil
+}
+
+// SetPassword remplace le mot de passe d'un compte sans passer par le lien de réinitialisation
+// (outil d'administration)
+func (s *UserService) SetPassword(ctx context.Context, email, newPassword string) (err error) {
+	var userID int
+	defer func() { s.record(ctx, audit.ActionPasswordChange, userID, err) }()
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	if len(newPassword) < 8 {
+		return apperror.Localize(apperror.ErrValidation, "validation.password_length", "password must be at least 8 characters long")
+	}
+	userID, err = s.setPassword(ctx, email, newPassword)
+	return err
+}
+
+// setPassword remplace le mot de passe et retourne l'ID du compte, connu même en cas d'échec
+// de l'écriture
+func (s *UserService) setPassword(ctx context.Context, email, newPassword string) (int, error) {
+	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
-		return fmt.Errorf("internal error: failed to hash password: %v", err)
+		return 0, fmt.Errorf("internal error: failed to load user: %v", err)
+	}
+	if user == nil {
+		return 0, fmt.Errorf("user %w", apperror.ErrNotFound)
 	}
 
-	err = s.repo.ResetPassword(email, string(hashedPassword))
+	hashedPassword, err := hashPassword(ctx, newPassword)
 	if err != nil {
-		return fmt.Errorf("internal error: failed to update password: %v", err)
+		return user.ID, fmt.Errorf("internal error: failed to hash password: %v", err)
+	}
+
+	if err := s.repo.ResetPassword(ctx, email, hashedPassword); err != nil {
+		return user.ID, fmt.Errorf("internal error: failed to update password: %v", err)
 	}
 
-	expirationTime := time.Now().Add(s.tokenExpiry)
-	return middleware.AddToBlacklist(db, token, expirationTime)
+	// Définir un mot de passe (re)crée la méthode de connexion locale
+	if err := s.repo.CreateIdentity(ctx, passwordIdentity(user.ID, email)); err != nil {
+		return user.ID, fmt.Errorf("internal error: %v", err)
+	}
+	return user.ID, nil
 }
 
-func (s *UserService) SendPasswordResetToken(email string) (string, error) {
+// SendPasswordResetToken envoie le lien de réinitialisation dans la langue préférée du compte,
+// ou à défaut dans locale, la langue de la requête. Le jeton n'est remis que par email : seul le
+// titulaire de la boîte aux lettres peut réinitialiser le mot de passe.
+func (s *UserService) SendPasswordResetToken(ctx context.Context, email, locale string) (err error) {
+	ctx, span := tracing.Start(ctx, "UserService.SendPasswordResetToken")
+	defer func() { tracing.End(span, err) }()
+	var userID int
+	defer func() { s.record(ctx, audit.ActionPasswordResetRequest, userID, err) }()
+
 	if email == "" {
-		return "", fmt.Errorf("validation error: email is required")
+		return apperror.Localize(apperror.ErrValidation, "validation.email_required", "email is required")
 	}
 
-	user, err := s.repo.GetByEmail(email)
+	user, err := s.repo.GetByEmail(ctx, email)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return "", fmt.Errorf("user not found")
-		}
-		return "", fmt.Errorf("internal error: %v", err)
+		return fmt.Errorf("internal error: %v", err)
 	}
 
 	if user == nil {
-		return "", fmt.Errorf("user not found")
+		return fmt.Errorf("user %w", apperror.ErrNotFound)
 	}
+	userID = user.ID
 
-	resetToken, err := generateResetToken(user.Email)
+	resetToken, err := s.generateResetToken(user.Email)
 	if err != nil {
-		return "", fmt.Errorf("internal error: failed to generate reset token: %v", err)
+		return fmt.Errorf("internal error: failed to generate reset token: %v", err)
 	}
 
-	err = sendResetEmail(user.Email, resetToken)
+	if user.Locale != "" {
+		locale = user.Locale
+	}
+	err = s.sendResetEmail(ctx, user, resetToken, locale)
 	if err != nil {
-		return "", fmt.Errorf("internal error: failed to send reset email: %v", err)
+		return fmt.Errorf("internal error: failed to send reset email: %v", err)
 	}
+	logging.FromContext(ctx).Info("password reset link sent", "user_id", user.ID)
+	metrics.PasswordResets.WithLabelValues("requested").Inc()
 
-	return resetToken, nil
+	return nil
 }
 
-func generateResetToken(email string) (string, error) {
+func (s *UserService) generateResetToken(email string) (string, error) {
 	if email == "" {
 		return "", errors.New("email cannot be empty")
 	}
 
-	secretKey := []byte(os.Getenv("JWT_SECRET"))
-
 	claims := jwt.MapClaims{
 		"email": email,
-		"exp":   time.Now().Add(15 * time.Minute).Unix(),
+		"exp":   time.Now().Add(s.auth.ResetTokenTTL).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 
-	tokenString, err := token.SignedString(secretKey)
+	tokenString, err := token.SignedString([]byte(s.auth.JWTSecret))
 	if err != nil {
 		return "", fmt.Errorf("failed to generate token: %v", err)
 	}
@@ -209,135 +665,173 @@ func generateResetToken(email string) (string, error) {
 
 func (s *UserService) ValidateResetToken(token string) (string, error) {
 	if token == "" {
-		return "", fmt.Errorf("authentication error: token is required")
+		return "", fmt.Errorf("%w: token is required", apperror.ErrUnauthorized)
 	}
 
-	secretKey := []byte(os.Getenv("JWT_SECRET"))
-
 	parsedToken, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
 
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return secretKey, nil
+		return []byte(s.auth.JWTSecret), nil
 	})
 
 	if err != nil {
-		return "", fmt.Errorf("authentication error: invalid token: %v", err)
+		return "", fmt.Errorf("%w: invalid token: %v", apperror.ErrUnauthorized, err)
 	}
 
 	if claims, ok := parsedToken.Claims.(jwt.MapClaims); ok && parsedToken.Valid {
 		if email, ok := claims["email"].(string); ok {
 			return email, nil
 		}
-		return "", fmt.Errorf("authentication error: invalid token structure")
+		return "", fmt.Errorf("%w: invalid token structure", apperror.ErrUnauthorized)
 	}
-	return "", fmt.Errorf("authentication error: invalid token")
+	return "", fmt.Errorf("%w: invalid token", apperror.ErrUnauthorized)
 }
 
-func sendResetEmail(email, token string) error {
-	if email == "" || token == "" {
+func (s *UserService) sendResetEmail(ctx context.Context, user *User, token, locale string) error {
+	if user.Email == "" || token == "" {
 		return errors.New("email and token are required")
 	}
 
-	fmt.Printf("To: %s\n", email)
-	fmt.Printf("Subject: Password Reset Request\n")
-	fmt.Printf("Body: Click the link below to reset your password:\n")
-	fmt.Printf("https://go-auth-api-latest.onrender.com/44df37e7-fe2a-404f-917b-399f5c5ffd12/reset-password?token=%s\n", token)
-	return nil
+	email, err := i18n.RenderEmail(locale, "password_reset", map[string]string{
+		"Name": user.Name,
+		"Link": s.auth.ResetURL + "?token=" + token,
+	})
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mail.Message{To: user.Email, Subject: email.Subject, Body: email.Body})
 }
 
-func (s *UserService) GetUserByID(id int) (*User, error) {
+func (s *UserService) GetUserByID(ctx context.Context, id int) (*User, error) {
 	if id <= 0 {
-		return nil, fmt.Errorf("validation error: invalid user ID")
+		return nil, apperror.Localize(apperror.ErrValidation, "validation.user_id_invalid", "invalid user ID")
 	}
 
-	user, err := s.repo.FindByID(id)
+	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, fmt.Errorf("not found: user with ID %d does not exist", id)
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, fmt.Errorf("%w: user with ID %d does not exist", apperror.ErrNotFound, id)
 		}
 		return nil, fmt.Errorf("internal error: %v", err)
 	}
 
-	if user == nil {
-		return nil, fmt.Errorf("not found: user with ID %d does not exist", id)
-	}
-
 	return user, nil
 }
 
-func (s *UserService) refreshToken(refreshToken string) (string, string, error) {
-
-	if refreshToken == "" {
-		return "", "", fmt.Errorf("validation error: refresh token is required")
+// refreshToken consomme un refresh token et émet un nouveau couple de jetons
+func (s *UserService) refreshToken(ctx context.Context, refreshToken string) (_, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "UserService.RefreshToken")
+	defer func() { tracing.End(span, err) }()
+
+	userID, accessToken, newRefreshToken, err := s.refresh(ctx, refreshToken)
+	var reason string
+	switch {
+	case err == nil:
+		metrics.RefreshSucceeded()
+	case errors.Is(err, apperror.ErrValidation):
+		reason = metrics.ReasonInvalidRequest
+	case errors.Is(err, errRefreshReused):
+		reason = metrics.ReasonTokenReused
+	case errors.Is(err, apperror.ErrLocked):
+		reason = metrics.ReasonAccountLocked
+	case errors.Is(err, apperror.ErrUnauthorized):
+		reason = metrics.ReasonInvalidToken
+	default:
+		reason = metrics.ReasonInternal
+	}
+	if err != nil {
+		metrics.RefreshFailed(reason)
 	}
+	s.audit.Record(ctx, audit.Event{Action: audit.ActionTokenRefresh, SubjectID: userID, Reason: reason}, err)
+	return accessToken, newRefreshToken, err
+}
 
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		return "", "", fmt.Errorf("internal error: JWT_SECRET not configured")
+func (s *UserService) refresh(ctx context.Context, refreshToken string) (int, string, string, error) {
+	if refreshToken == "" {
+		return 0, "", "", apperror.Localize(apperror.ErrValidation, "validation.refresh_token_required", "refresh token is required")
 	}
 
 	token, err := jwt.Parse(refreshToken, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(secretKey), nil
+		return []byte(s.auth.JWTSecret), nil
 	})
 
 	if err != nil || !token.Valid {
-		return "", "", fmt.Errorf("authentication error: invalid refresh token")
+		return 0, "", "", fmt.Errorf("%w: invalid refresh token", apperror.ErrUnauthorized)
 	}
 
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return "", "", fmt.Errorf("authentication error: invalid token claims")
+		return 0, "", "", fmt.Errorf("%w: invalid token claims", apperror.ErrUnauthorized)
 	}
 
 	userID, ok := claims["user_id"].(float64)
 	if !ok {
-		return "", "", fmt.Errorf("authentication error: invalid user_id")
+		return 0, "", "", fmt.Errorf("%w: invalid user_id", apperror.ErrUnauthorized)
 	}
 
 	expiration, ok := claims["exp"].(float64)
 	if !ok || time.Now().Unix() > int64(expiration) {
-		return "", "", fmt.Errorf("authentication error: refresh token expired")
+		return int(userID), "", "", fmt.Errorf("%w: refresh token expired", apperror.ErrUnauthorized)
 	}
 
-	accessToken, err := s.generateToken(int(userID), 2*time.Hour)
+	// Les sessions du compte ont pu être révoquées depuis l'émission du refresh token
+	generation, err := s.blacklist.SessionGeneration(ctx, int(userID))
 	if err != nil {
-		return "", "", fmt.Errorf("internal error: failed to generate access token")
+		return int(userID), "", "", fmt.Errorf("internal error: %v", err)
+	}
+	if gen, _ := claims["gen"].(float64); int(gen) < generation {
+		return int(userID), "", "", fmt.Errorf("%w: session revoked", apperror.ErrUnauthorized)
 	}
 
-	newRefreshToken, err := s.generateToken(int(userID), 7*24*time.Hour)
+	// Un refresh token ne sert qu'une fois : il est consommé avant l'émission des nouveaux jetons
+	first, err := s.blacklist.Claim(ctx, refreshToken, time.Unix(int64(expiration), 0))
 	if err != nil {
-		return "", "", fmt.Errorf("internal error: failed to generate refresh token")
+		return int(userID), "", "", fmt.Errorf("internal error: %v", err)
+	}
+	if !first {
+		return int(userID), "", "", errRefreshReused
 	}
 
-	db, err := database.ConnectDB()
+	// Les scopes sont recalculés pour tenir compte d'un consentement révoqué entre-temps
+	clientID, _ := claims["client_id"].(string)
+	scope, _ := claims["scope"].(string)
+	granted, err := s.oauth.GrantedScopes(ctx, int(userID), clientID, oauth.ParseScope(scope))
 	if err != nil {
-		return "", "", fmt.Errorf("erreur de connexion à la base de données : %v", err)
+		return int(userID), "", "", err
 	}
-	defer db.Close()
 
-	expirationTime := time.Now().Add(s.tokenExpiry)
-	middleware.AddToBlacklist(db, refreshToken, expirationTime)
+	accessToken, newRefreshToken, err := s.issueTokens(ctx, int(userID), clientID, granted)
+	if err != nil {
+		return int(userID), "", "", err
+	}
 
-	return accessToken, newRefreshToken, nil
+	return int(userID), accessToken, newRefreshToken, nil
 }
 
-func (s *UserService) generateToken(userID int, duration time.Duration) (string, error) {
-	secretKey := os.Getenv("JWT_SECRET")
-	if secretKey == "" {
-		return "", fmt.Errorf("internal error: JWT_SECRET not configured")
-	}
-
+// generateToken signe un jeton pour l'utilisateur. Sa langue préférée est portée par le claim
+// « locale », qui prime sur Accept-Language pour les réponses aux requêtes authentifiées ;
+// « gen » est la génération de ses sessions, pour les révoquer toutes d'un coup.
+func (s *UserService) generateToken(user *User, clientID string, scopes []string, generation int, duration time.Duration) (string, error) {
 	claims := jwt.MapClaims{
-		"user_id": userID,
+		"user_id": user.ID,
 		"id":      uuid.New().String(),
 		"exp":     time.Now().Add(duration).Unix(),
+		"scope":   oauth.FormatScope(scopes),
+		"gen":     generation,
+	}
+	if clientID != "" {
+		claims["client_id"] = clientID
+	}
+	if user.Locale != "" {
+		claims["locale"] = user.Locale
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secretKey))
+	return token.SignedString([]byte(s.auth.JWTSecret))
 }
@@ -0,0 +1,49 @@
+package user
+
+import "context"
+
+// UserStore est la persistance des comptes, de leurs méthodes de connexion et de leur historique
+// de connexions utilisée par UserService. UserRepository l'implémente sur Postgres,
+// MemoryUserStore en mémoire.
+//
+// Les implémentations respectent les mêmes conventions d'erreur : apperror.ErrNotFound pour
+// un compte ou une identité inconnus, apperror.ErrConflict lorsqu'un email ou une identité est
+// déjà pris et pour la dernière méthode de connexion, apperror.ErrUnauthorized pour un mot de
+// passe refusé. GetByEmail et FindIdentity retournent nil, nil lorsqu'aucune ligne ne correspond.
+//
+// RecordLogin attribue son ID à la tentative et, pour une connexion réussie, renseigne
+// NewDevice et NewNetwork d'après les connexions réussies précédentes du compte ; la première
+// connexion d'un compte n'est jamais inhabituelle.
+//
+// CreateInTenant crée un compte provisionné par SCIM et le rattache à son tenant dans la même
+// transaction ; un externalId déjà pris dans le tenant retourne ErrExternalIDInUse.
+// IsTenantMember indique si le compte a été provisionné par le tenant.
+//
+// UserRepository publie en outre les événements d'identité dans l'outbox des webhooks, dans la
+// transaction de la modification ; MemoryUserStore n'en publie pas.
+type UserStore interface {
+	Create(ctx context.Context, user User, identity Identity) (int, error)
+	CreateInTenant(ctx context.Context, user User, identity Identity, membership TenantMembership) (int, error)
+	Login(ctx context.Context, email, password string) (*User, error)
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	FindByID(ctx context.Context, id int) (*User, error)
+	FindByIDs(ctx context.Context, ids []int) ([]User, error)
+	Update(ctx context.Context, user User) error
+	Delete(ctx context.Context, id int) error
+	SetRole(ctx context.Context, userID int, role string) error
+	SetMobileNumber(ctx context.Context, userID int, mobileNumber string) error
+	SetLocale(ctx context.Context, userID int, locale string) error
+	ResetPassword(ctx context.Context, email, hashedPassword string) error
+
+	CreateIdentity(ctx context.Context, identity Identity) error
+	FindIdentity(ctx context.Context, provider, subject string) (*Identity, error)
+	ListIdentities(ctx context.Context, userID int) ([]Identity, error)
+	DeleteIdentity(ctx context.Context, userID, identityID int, unusablePassword string) error
+	IsTenantMember(ctx context.Context, tenant string, userID int) (bool, error)
+
+	RecordLogin(ctx context.Context, attempt *LoginAttempt) error
+	ListLogins(ctx context.Context, userID int, filter LoginFilter) ([]LoginAttempt, error)
+	ReportLogin(ctx context.Context, userID int, loginID int64) error
+}
+
+var _ UserStore = (*UserRepository)(nil)
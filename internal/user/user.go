@@ -9,8 +9,17 @@ type User struct {
 	MobileNumber string `json:"mobile_number"`
 	Email        string `json:"email" binding:"required,email"`
 	Password     string `json:"password,omitempty" binding:"required,min=8"`
+	Locale       string `json:"locale,omitempty" binding:"omitempty,oneof=en fr de"`
+	Role         string `json:"-"`
+	Active       bool   `json:"-"`
 }
 
+// Rôles attribués aux utilisateurs
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 func (u *User) Validate() error {
 	validate := validator.New()
 	return validate.Struct(u)
@@ -0,0 +1,188 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	// dispatchBatchSize borne les événements répartis et les livraisons envoyées par passage
+	dispatchBatchSize = 100
+	// dispatchConcurrency borne les envois simultanés, pour qu'un endpoint lent ne retarde
+	// pas tous les autres
+	dispatchConcurrency = 8
+	maxRetryDelay       = 6 * time.Hour
+)
+
+// En-têtes des requêtes de livraison
+const (
+	HeaderEventID   = "X-Webhook-ID"
+	HeaderEventType = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Sign calcule l'en-tête X-Webhook-Signature : « t=<horodatage Unix>,v1=<HMAC-SHA256> », le HMAC
+// portant sur l'horodatage, un point et le corps de la requête. Le destinataire recalcule le
+// HMAC avec le secret de l'endpoint et refuse un horodatage trop ancien (rejeu).
+func Sign(secret string, timestamp time.Time, payload []byte) string {
+	t := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(t + "."))
+	mac.Write(payload)
+	return "t=" + t + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Dispatcher répartit les événements de l'outbox entre les endpoints abonnés puis livre
+// chaque livraison due. Plusieurs instances peuvent tourner ensemble : les lignes traitées
+// sont réservées (voir WebhookRepository.ClaimDue). Un événement peut être livré plus d'une
+// fois, jamais perdu.
+type Dispatcher struct {
+	repo   *WebhookRepository
+	cfg    config.Webhook
+	client *http.Client
+}
+
+func NewDispatcher(repo *WebhookRepository, cfg config.Webhook) *Dispatcher {
+	return &Dispatcher{repo: repo, cfg: cfg, client: &http.Client{Timeout: cfg.Timeout}}
+}
+
+// Run traite l'outbox toutes les PollInterval jusqu'à l'annulation de ctx
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.cfg.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := d.RunOnce(ctx); err != nil && ctx.Err() == nil {
+			slog.ErrorContext(ctx, "error dispatching webhooks", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce répartit les nouveaux événements puis envoie les livraisons dues, et attend leur
+// résultat
+func (d *Dispatcher) RunOnce(ctx context.Context) error {
+	if _, err := d.repo.FanOut(ctx, time.Now(), dispatchBatchSize); err != nil {
+		return err
+	}
+
+	// La réservation couvre le délai de réponse de chaque envoi, même en file derrière les autres
+	lease := d.cfg.Timeout * (dispatchBatchSize/dispatchConcurrency + 2)
+	deliveries, err := d.repo.ClaimDue(ctx, time.Now(), lease, dispatchBatchSize)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, dispatchConcurrency)
+	for _, delivery := range deliveries {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			d.deliver(ctx, delivery)
+		}()
+	}
+	wg.Wait()
+	return nil
+}
+
+// deliver envoie une livraison et en enregistre le résultat : livrée sur une réponse 2xx,
+// sinon retentée après un délai doublé à chaque échec, ou classée en lettre morte après
+// MaxAttempts tentatives
+func (d *Dispatcher) deliver(ctx context.Context, delivery Delivery) {
+	ctx, span := tracing.Start(ctx, "Dispatcher.deliver",
+		attribute.Int64("webhook.delivery_id", delivery.ID),
+		attribute.String("webhook.event_type", delivery.EventType))
+	attempt, err := d.send(ctx, delivery)
+	tracing.End(span, err)
+	if ctx.Err() != nil {
+		// Envoi interrompu par l'arrêt : la livraison redeviendra due à la fin de sa réservation
+		return
+	}
+
+	status, result := StatusDelivered, StatusDelivered
+	next := attempt.Time
+	if err != nil {
+		attempt.Error = err.Error()
+		status, result = StatusPending, "failed"
+		next = attempt.Time.Add(d.retryDelay(delivery.Attempts + 1))
+		if delivery.Attempts+1 >= d.cfg.MaxAttempts {
+			status, result = StatusDead, StatusDead
+		}
+	}
+	metrics.WebhookAttempts.WithLabelValues(result).Inc()
+
+	logger := slog.With("delivery_id", delivery.ID, "endpoint_id", delivery.EndpointID, "event_type", delivery.EventType, "attempt", delivery.Attempts+1)
+	switch status {
+	case StatusPending:
+		logger.WarnContext(ctx, "webhook delivery failed", "retry_at", next, "error", err)
+	case StatusDead:
+		logger.ErrorContext(ctx, "webhook delivery dead-lettered", "error", err)
+	}
+
+	// Le résultat est noté même si l'arrêt commence entre-temps
+	if err := d.repo.RecordAttempt(context.WithoutCancel(ctx), delivery.ID, attempt, status, next); err != nil {
+		logger.ErrorContext(ctx, "error recording webhook attempt", "error", err)
+	}
+}
+
+func (d *Dispatcher) send(ctx context.Context, delivery Delivery) (Attempt, error) {
+	attempt := Attempt{Time: time.Now()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return attempt, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "AuthentificationGO-Webhook/1")
+	req.Header.Set(HeaderEventID, delivery.EventID)
+	req.Header.Set(HeaderEventType, delivery.EventType)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(delivery.ID, 10))
+	req.Header.Set(HeaderSignature, Sign(delivery.secret, attempt.Time, delivery.payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		attempt.DurationMS = time.Since(attempt.Time).Milliseconds()
+		return attempt, err
+	}
+	// Le corps est lu, dans une limite raisonnable, pour réutiliser la connexion
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	resp.Body.Close()
+
+	attempt.StatusCode = resp.StatusCode
+	attempt.DurationMS = time.Since(attempt.Time).Milliseconds()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return attempt, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return attempt, nil
+}
+
+// retryDelay est le délai après la tentative n : RetryBase, puis doublé, six heures au plus
+func (d *Dispatcher) retryDelay(n int) time.Duration {
+	delay := d.cfg.RetryBase
+	for i := 1; i < n && delay < maxRetryDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxRetryDelay)
+}
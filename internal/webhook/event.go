@@ -0,0 +1,69 @@
+// Package webhook notifie les services tiers des événements d'identité (inscription,
+// changement de mot de passe, désactivation, suppression). Les événements sont écrits dans la
+// table outbox_events par la transaction qui modifie le compte : ils ne sont publiés que si la
+// modification est validée, et ne sont jamais perdus si elle l'est. Le Dispatcher les livre
+// ensuite, signés, à chaque endpoint abonné.
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+)
+
+// Types d'événements publiés
+const (
+	EventUserRegistered      = "user.registered"
+	EventUserPasswordChanged = "user.password_changed"
+	EventUserDisabled        = "user.disabled"
+	EventUserDeleted         = "user.deleted"
+)
+
+// EventTypes liste les types auxquels un endpoint peut s'abonner
+var EventTypes = []string{EventUserRegistered, EventUserPasswordChanged, EventUserDisabled, EventUserDeleted}
+
+// Event est le corps JSON envoyé aux endpoints. ID est unique et identique d'une tentative à
+// l'autre : les destinataires s'en servent pour ignorer les livraisons répétées.
+type Event struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// UserData décrit le compte concerné par un événement
+type UserData struct {
+	UserID int    `json:"user_id"`
+	Email  string `json:"email"`
+}
+
+// Enqueue ajoute un événement à l'outbox dans la transaction tx de l'appelant
+func Enqueue(ctx context.Context, tx *sql.Tx, dialect database.Dialect, eventType string, data any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding event data: %w", err)
+	}
+	event := Event{
+		ID:        uuid.NewString(),
+		Type:      eventType,
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+		Data:      body,
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("error encoding event: %w", err)
+	}
+
+	_, err = tx.ExecContext(ctx,
+		dialect.Rebind("INSERT INTO outbox_events (event_id, type, payload, created_at) VALUES ($1, $2, $3, $4)"),
+		event.ID, event.Type, string(payload), event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("error inserting outbox event: %w", err)
+	}
+	return nil
+}
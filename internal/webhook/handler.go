@@ -0,0 +1,158 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+type WebhookHandler struct {
+	service *WebhookService
+}
+
+func NewWebhookHandler(service *WebhookService) *WebhookHandler {
+	return &WebhookHandler{service: service}
+}
+
+func (h *WebhookHandler) Create(c *gin.Context) {
+	var request struct {
+		URL    string   `json:"url" binding:"required"`
+		Events []string `json:"events"`
+	}
+
+	if err := c.ShouldBindJSON(&request); err != nil {
+		problem.InvalidInput(c, err)
+		return
+	}
+
+	endpoint, err := h.service.CreateEndpoint(c.Request.Context(), request.URL, request.Events)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "webhook.create_failed")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  i18n.Localize(c, "webhook.created"),
+		"endpoint": endpoint,
+	})
+}
+
+func (h *WebhookHandler) List(c *gin.Context) {
+	endpoints, err := h.service.ListEndpoints(c.Request.Context())
+	if err != nil {
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "webhook.list_failed")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"endpoints": endpoints})
+}
+
+func (h *WebhookHandler) Delete(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "webhook.invalid_id")
+		return
+	}
+
+	if err := h.service.DeleteEndpoint(c.Request.Context(), id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "webhook.not_found")
+			return
+		}
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "webhook.delete_failed")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Deliveries retourne le journal des livraisons d'un endpoint, de la plus récente à la plus
+// ancienne. Paramètres : status (pending, delivered ou dead), before (ID) et limit.
+func (h *WebhookHandler) Deliveries(c *gin.Context) {
+	filter := DeliveryFilter{Status: c.Query("status")}
+	var err error
+	filter.EndpointID, err = strconv.Atoi(c.Param("id"))
+	if raw := c.Query("before"); raw != "" && err == nil {
+		filter.BeforeID, err = strconv.ParseInt(raw, 10, 64)
+	}
+	if raw := c.Query("limit"); raw != "" && err == nil {
+		filter.Limit, err = strconv.Atoi(raw)
+	}
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "webhook.invalid_filter")
+		return
+	}
+
+	deliveries, err := h.service.ListDeliveries(c.Request.Context(), filter)
+	if err != nil {
+		if errors.Is(err, apperror.ErrValidation) {
+			problem.Validation(c, err)
+			return
+		}
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "webhook.list_failed")
+		return
+	}
+
+	response := gin.H{"deliveries": deliveries}
+	if filter.Limit == 0 {
+		filter.Limit = defaultLimit
+	}
+	if len(deliveries) == filter.Limit {
+		response["next_before"] = deliveries[len(deliveries)-1].ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// Delivery retourne une livraison et l'historique de ses tentatives
+func (h *WebhookHandler) Delivery(c *gin.Context) {
+	id, ok := deliveryID(c)
+	if !ok {
+		return
+	}
+
+	delivery, err := h.service.GetDelivery(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "webhook.delivery_not_found")
+			return
+		}
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "webhook.list_failed")
+		return
+	}
+	c.JSON(http.StatusOK, delivery)
+}
+
+// Redeliver relance une livraison (lettre morte comprise) ; elle est envoyée au prochain
+// passage du dispatcher
+func (h *WebhookHandler) Redeliver(c *gin.Context) {
+	id, ok := deliveryID(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.Redeliver(c.Request.Context(), id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			problem.Abort(c, http.StatusNotFound, problem.CodeNotFound, "webhook.delivery_not_found")
+			return
+		}
+		problem.Abort(c, http.StatusInternalServerError, problem.CodeInternal, "webhook.redeliver_failed")
+		return
+	}
+	c.JSON(http.StatusAccepted, gin.H{"message": i18n.Localize(c, "webhook.redelivery_scheduled")})
+}
+
+func deliveryID(c *gin.Context) (int64, bool) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		problem.Abort(c, http.StatusBadRequest, problem.CodeInvalidRequest, "webhook.invalid_id")
+		return 0, false
+	}
+	return id, true
+}
@@ -0,0 +1,431 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Endpoint est une URL abonnée aux événements. Events vide vaut abonnement à tous les types.
+// Le secret de signature n'est retourné qu'à la création.
+type Endpoint struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// subscribes indique si l'endpoint reçoit les événements de type eventType
+func (e Endpoint) subscribes(eventType string) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, t := range e.Events {
+		if t == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// États d'une livraison
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusDead      = "dead"
+)
+
+// Delivery est l'envoi d'un événement à un endpoint. NextAttemptAt n'est renseigné que pour une
+// livraison en attente ; Log, l'historique des tentatives, que pour une livraison lue seule.
+type Delivery struct {
+	ID            int64      `json:"id"`
+	EndpointID    int        `json:"endpoint_id"`
+	EventID       string     `json:"event_id"`
+	EventType     string     `json:"event_type"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastError     string     `json:"last_error,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Log           []Attempt  `json:"log,omitempty"`
+
+	// Renseignés pour l'envoi par ClaimDue
+	url     string
+	secret  string
+	payload []byte
+}
+
+// Attempt est une tentative de livraison. StatusCode est absent si l'endpoint n'a pas répondu.
+type Attempt struct {
+	Time       time.Time `json:"time"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+// DeliveryFilter restreint la liste des livraisons d'un endpoint, de la plus récente à la plus
+// ancienne ; BeforeID reprend la liste après le dernier ID de la page précédente
+type DeliveryFilter struct {
+	EndpointID int
+	Status     string
+	BeforeID   int64
+	Limit      int
+}
+
+type WebhookRepository struct {
+	db      *sql.DB
+	dialect database.Dialect
+}
+
+func NewWebhookRepository(db *sql.DB) *WebhookRepository {
+	return &WebhookRepository{db: db, dialect: database.DialectOf(db)}
+}
+
+// startQuery ouvre le span d'une requête du dépôt
+func (r *WebhookRepository) startQuery(ctx context.Context, method, operation string) (context.Context, trace.Span) {
+	return tracing.StartQuery(ctx, "WebhookRepository."+method, r.dialect.System(), operation)
+}
+
+// skipLocked est ajouté aux sélections de lignes à traiter : sous PostgreSQL, deux instances
+// ne prennent pas les mêmes lignes. Sous SQLite, la transaction détient déjà le verrou d'écriture.
+func (r *WebhookRepository) skipLocked() string {
+	if r.dialect == database.Postgres {
+		return " FOR UPDATE SKIP LOCKED"
+	}
+	return ""
+}
+
+func (r *WebhookRepository) CreateEndpoint(ctx context.Context, endpoint *Endpoint) (err error) {
+	ctx, span := r.startQuery(ctx, "CreateEndpoint", "INSERT webhook_endpoints")
+	defer func() { tracing.End(span, err) }()
+
+	endpoint.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	err = r.db.QueryRowContext(ctx,
+		r.dialect.Rebind("INSERT INTO webhook_endpoints (url, secret, events, created_at) VALUES ($1, $2, $3, $4) RETURNING id"),
+		endpoint.URL, endpoint.Secret, strings.Join(endpoint.Events, ","), endpoint.CreatedAt).Scan(&endpoint.ID)
+	if err != nil {
+		return fmt.Errorf("error inserting webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+// ListEndpoints retourne les endpoints sans leur secret
+func (r *WebhookRepository) ListEndpoints(ctx context.Context) (_ []Endpoint, err error) {
+	ctx, span := r.startQuery(ctx, "ListEndpoints", "SELECT webhook_endpoints")
+	defer func() { tracing.End(span, err) }()
+
+	endpoints, err := r.endpoints(ctx, r.db)
+	if err != nil {
+		return nil, err
+	}
+	for i := range endpoints {
+		endpoints[i].Secret = ""
+	}
+	return endpoints, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func (r *WebhookRepository) endpoints(ctx context.Context, q queryer) ([]Endpoint, error) {
+	rows, err := q.QueryContext(ctx, "SELECT id, url, secret, events, created_at FROM webhook_endpoints ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		var e Endpoint
+		var events string
+		if err := rows.Scan(&e.ID, &e.URL, &e.Secret, &events, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Events = []string{}
+		if events != "" {
+			e.Events = strings.Split(events, ",")
+		}
+		e.CreatedAt = e.CreatedAt.UTC()
+		endpoints = append(endpoints, e)
+	}
+	return endpoints, rows.Err()
+}
+
+// DeleteEndpoint supprime l'endpoint et l'historique de ses livraisons
+func (r *WebhookRepository) DeleteEndpoint(ctx context.Context, id int) (err error) {
+	ctx, span := r.startQuery(ctx, "DeleteEndpoint", "DELETE webhook_endpoints")
+	defer func() { tracing.End(span, err) }()
+
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind("DELETE FROM webhook_endpoints WHERE id = $1"), id)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook endpoint: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook endpoint %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+// FanOut prend au plus limit événements de l'outbox pas encore répartis et crée, dans la même
+// transaction, une livraison due à now pour chaque endpoint abonné. Il retourne le nombre
+// d'événements répartis.
+func (r *WebhookRepository) FanOut(ctx context.Context, now time.Time, limit int) (_ int, err error) {
+	ctx, span := r.startQuery(ctx, "FanOut", "UPDATE outbox_events")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, r.dialect.Rebind(
+		"SELECT id, type FROM outbox_events WHERE dispatched_at IS NULL ORDER BY id LIMIT $1"+r.skipLocked()), limit)
+	if err != nil {
+		return 0, fmt.Errorf("error reading outbox: %w", err)
+	}
+	type pending struct {
+		id        int64
+		eventType string
+	}
+	var events []pending
+	for rows.Next() {
+		var p pending
+		if err := rows.Scan(&p.id, &p.eventType); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		events = append(events, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		return 0, nil
+	}
+
+	endpoints, err := r.endpoints(ctx, tx)
+	if err != nil {
+		return 0, err
+	}
+	now = now.UTC().Truncate(time.Microsecond)
+	for _, event := range events {
+		for _, endpoint := range endpoints {
+			if !endpoint.subscribes(event.eventType) {
+				continue
+			}
+			_, err := tx.ExecContext(ctx, r.dialect.Rebind(
+				"INSERT INTO webhook_deliveries (endpoint_id, event_id, status, next_attempt_at, created_at, updated_at) "+
+					"VALUES ($1, $2, $3, $4, $4, $4) ON CONFLICT (endpoint_id, event_id) DO NOTHING"),
+				endpoint.ID, event.id, StatusPending, now)
+			if err != nil {
+				return 0, fmt.Errorf("error inserting webhook delivery: %w", err)
+			}
+		}
+		if _, err := tx.ExecContext(ctx, r.dialect.Rebind("UPDATE outbox_events SET dispatched_at = $1 WHERE id = $2"), now, event.id); err != nil {
+			return 0, fmt.Errorf("error marking outbox event: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error committing fan-out: %w", err)
+	}
+	return len(events), nil
+}
+
+// ClaimDue réserve au plus limit livraisons en attente dues à now : elles ne redeviennent dues
+// qu'à now+lease, au cas où l'instance qui les envoie s'arrêterait avant d'en noter le résultat
+func (r *WebhookRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) (_ []Delivery, err error) {
+	ctx, span := r.startQuery(ctx, "ClaimDue", "UPDATE webhook_deliveries")
+	defer func() { tracing.End(span, err) }()
+
+	now = now.UTC().Truncate(time.Microsecond)
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(
+		"UPDATE webhook_deliveries SET next_attempt_at = $1 WHERE id IN ("+
+			"SELECT id FROM webhook_deliveries WHERE status = $2 AND next_attempt_at <= $3 ORDER BY next_attempt_at LIMIT $4"+r.skipLocked()+
+			") RETURNING id"),
+		now.Add(lease), StatusPending, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	var ids []any
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(ids) == 0 {
+		return nil, err
+	}
+
+	rows, err = r.db.QueryContext(ctx, r.dialect.Rebind(
+		"SELECT d.id, d.endpoint_id, d.attempts, o.event_id, o.type, o.payload, e.url, e.secret "+
+			"FROM webhook_deliveries d JOIN outbox_events o ON o.id = d.event_id JOIN webhook_endpoints e ON e.id = d.endpoint_id "+
+			"WHERE d.id IN "+database.InList(1, len(ids))+" ORDER BY d.id"), ids...)
+	if err != nil {
+		return nil, fmt.Errorf("error loading webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var d Delivery
+		var payload string
+		if err := rows.Scan(&d.ID, &d.EndpointID, &d.Attempts, &d.EventID, &d.EventType, &payload, &d.url, &d.secret); err != nil {
+			return nil, err
+		}
+		d.Status = StatusPending
+		d.payload = []byte(payload)
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// RecordAttempt ajoute la tentative à l'historique et passe la livraison à status ; une
+// livraison encore en attente sera retentée à next
+func (r *WebhookRepository) RecordAttempt(ctx context.Context, deliveryID int64, attempt Attempt, status string, next time.Time) (err error) {
+	ctx, span := r.startQuery(ctx, "RecordAttempt", "INSERT webhook_attempts")
+	defer func() { tracing.End(span, err) }()
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	attempt.Time = attempt.Time.UTC().Truncate(time.Microsecond)
+	_, err = tx.ExecContext(ctx, r.dialect.Rebind(
+		"INSERT INTO webhook_attempts (delivery_id, attempted_at, status_code, error, duration_ms) VALUES ($1, $2, $3, $4, $5)"),
+		deliveryID, attempt.Time, attempt.StatusCode, attempt.Error, attempt.DurationMS)
+	if err != nil {
+		return fmt.Errorf("error inserting webhook attempt: %w", err)
+	}
+	_, err = tx.ExecContext(ctx, r.dialect.Rebind(
+		"UPDATE webhook_deliveries SET status = $1, attempts = attempts + 1, next_attempt_at = $2, last_error = $3, updated_at = $4 WHERE id = $5"),
+		status, next.UTC().Truncate(time.Microsecond), attempt.Error, time.Now().UTC().Truncate(time.Microsecond), deliveryID)
+	if err != nil {
+		return fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	return tx.Commit()
+}
+
+const deliveryColumns = "d.id, d.endpoint_id, o.event_id, o.type, d.status, d.attempts, d.next_attempt_at, d.last_error, d.created_at, d.updated_at"
+
+const deliveryFrom = " FROM webhook_deliveries d JOIN outbox_events o ON o.id = d.event_id"
+
+func (r *WebhookRepository) ListDeliveries(ctx context.Context, filter DeliveryFilter) (_ []Delivery, err error) {
+	ctx, span := r.startQuery(ctx, "ListDeliveries", "SELECT webhook_deliveries")
+	defer func() { tracing.End(span, err) }()
+
+	args := []any{filter.EndpointID}
+	query := "SELECT " + deliveryColumns + deliveryFrom + " WHERE d.endpoint_id = $1"
+	if filter.Status != "" {
+		args = append(args, filter.Status)
+		query += " AND d.status = $" + strconv.Itoa(len(args))
+	}
+	if filter.BeforeID != 0 {
+		args = append(args, filter.BeforeID)
+		query += " AND d.id < $" + strconv.Itoa(len(args))
+	}
+	args = append(args, filter.Limit)
+	query += " ORDER BY d.id DESC LIMIT $" + strconv.Itoa(len(args))
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(query), args...)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
+
+// FindDelivery retourne la livraison et l'historique de ses tentatives
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id int64) (_ *Delivery, err error) {
+	ctx, span := r.startQuery(ctx, "FindDelivery", "SELECT webhook_deliveries")
+	defer func() { tracing.End(span, err) }()
+
+	d, err := scanDelivery(r.db.QueryRowContext(ctx, r.dialect.Rebind("SELECT "+deliveryColumns+deliveryFrom+" WHERE d.id = $1"), id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("webhook delivery %w", apperror.ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.dialect.Rebind(
+		"SELECT attempted_at, status_code, error, duration_ms FROM webhook_attempts WHERE delivery_id = $1 ORDER BY id"), id)
+	if err != nil {
+		return nil, fmt.Errorf("error listing webhook attempts: %w", err)
+	}
+	defer rows.Close()
+
+	d.Log = []Attempt{}
+	for rows.Next() {
+		var a Attempt
+		if err := rows.Scan(&a.Time, &a.StatusCode, &a.Error, &a.DurationMS); err != nil {
+			return nil, err
+		}
+		a.Time = a.Time.UTC()
+		d.Log = append(d.Log, a)
+	}
+	return d, rows.Err()
+}
+
+// Redeliver remet une livraison en attente, due à now, pour un nouveau cycle de tentatives
+func (r *WebhookRepository) Redeliver(ctx context.Context, id int64, now time.Time) (err error) {
+	ctx, span := r.startQuery(ctx, "Redeliver", "UPDATE webhook_deliveries")
+	defer func() { tracing.End(span, err) }()
+
+	now = now.UTC().Truncate(time.Microsecond)
+	res, err := r.db.ExecContext(ctx, r.dialect.Rebind(
+		"UPDATE webhook_deliveries SET status = $1, attempts = 0, next_attempt_at = $2, updated_at = $2 WHERE id = $3"),
+		StatusPending, now, id)
+	if err != nil {
+		return fmt.Errorf("error rescheduling webhook delivery: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("webhook delivery %w", apperror.ErrNotFound)
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(row rowScanner) (*Delivery, error) {
+	var d Delivery
+	var next time.Time
+	if err := row.Scan(&d.ID, &d.EndpointID, &d.EventID, &d.EventType, &d.Status, &d.Attempts, &next, &d.LastError, &d.CreatedAt, &d.UpdatedAt); err != nil {
+		return nil, err
+	}
+	if d.Status == StatusPending {
+		next = next.UTC()
+		d.NextAttemptAt = &next
+	}
+	d.CreatedAt, d.UpdatedAt = d.CreatedAt.UTC(), d.UpdatedAt.UTC()
+	return &d, nil
+}
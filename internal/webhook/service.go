@@ -0,0 +1,118 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+)
+
+const (
+	defaultLimit = 50
+	maxLimit     = 200
+)
+
+type WebhookService struct {
+	repo *WebhookRepository
+}
+
+func NewWebhookService(repo *WebhookRepository) *WebhookService {
+	return &WebhookService{repo: repo}
+}
+
+// CreateEndpoint enregistre une URL http(s) abonnée à events (vide : tous les types). Le secret
+// de signature est généré et retourné une seule fois.
+func (s *WebhookService) CreateEndpoint(ctx context.Context, rawURL string, events []string) (*Endpoint, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("%w: url must be an absolute http or https URL", apperror.ErrValidation)
+	}
+	for _, t := range events {
+		if !slices.Contains(EventTypes, t) {
+			return nil, fmt.Errorf("%w: unknown event type %q", apperror.ErrValidation, t)
+		}
+	}
+	if events == nil {
+		events = []string{}
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	endpoint := &Endpoint{URL: u.String(), Events: events, Secret: hex.EncodeToString(secret)}
+	if err := s.repo.CreateEndpoint(ctx, endpoint); err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return endpoint, nil
+}
+
+func (s *WebhookService) ListEndpoints(ctx context.Context) ([]Endpoint, error) {
+	endpoints, err := s.repo.ListEndpoints(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return endpoints, nil
+}
+
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, id int) error {
+	if err := s.repo.DeleteEndpoint(ctx, id); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: webhook endpoint %d does not exist", apperror.ErrNotFound, id)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
+
+// ListDeliveries retourne le journal des livraisons d'un endpoint. Sans limite, 50 livraisons
+// sont retournées, 200 au plus.
+func (s *WebhookService) ListDeliveries(ctx context.Context, filter DeliveryFilter) ([]Delivery, error) {
+	switch {
+	case filter.Limit == 0:
+		filter.Limit = defaultLimit
+	case filter.Limit < 0 || filter.Limit > maxLimit:
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", apperror.ErrValidation, maxLimit)
+	}
+	switch filter.Status {
+	case "", StatusPending, StatusDelivered, StatusDead:
+	default:
+		return nil, fmt.Errorf("%w: unknown status %q", apperror.ErrValidation, filter.Status)
+	}
+
+	deliveries, err := s.repo.ListDeliveries(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return deliveries, nil
+}
+
+// GetDelivery retourne une livraison avec l'historique de ses tentatives
+func (s *WebhookService) GetDelivery(ctx context.Context, id int64) (*Delivery, error) {
+	d, err := s.repo.FindDelivery(ctx, id)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, fmt.Errorf("%w: webhook delivery %d does not exist", apperror.ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("internal error: %v", err)
+	}
+	return d, nil
+}
+
+// Redeliver relance une livraison, typiquement classée en lettre morte une fois l'endpoint
+// réparé ; elle repart pour un cycle complet de tentatives
+func (s *WebhookService) Redeliver(ctx context.Context, id int64) error {
+	if err := s.repo.Redeliver(ctx, id, time.Now()); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return fmt.Errorf("%w: webhook delivery %d does not exist", apperror.ErrNotFound, id)
+		}
+		return fmt.Errorf("internal error: %v", err)
+	}
+	return nil
+}
@@ -1,7 +1,11 @@
 package main
 
-import "github.com/pathi14/AuthentificationGO/cmd/api"
+import (
+	"os"
+
+	"github.com/pathi14/AuthentificationGO/cmd/cli"
+)
 
 func main() {
-	api.Run()
+	os.Exit(cli.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
 }
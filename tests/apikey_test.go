@@ -0,0 +1,214 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// newAPIKeyTestRouter ajoute les routes de clés d'API au routeur de compte, tout étant
+// stocké dans db (PostgreSQL ou SQLite)
+func newAPIKeyTestRouter(db *sql.DB) *gin.Engine {
+	tokens := blacklist.NewBlacklistStore(db)
+	userService := user.NewUserService(user.NewUserRepository(db), tokens, oauth.NewOAuthService(oauth.NewOAuthRepository(db)), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	apiKeyService := apikey.NewAPIKeyService(apikey.NewAPIKeyRepository(db), userService, tokens)
+	apiKeyHandler := apikey.NewAPIKeyHandler(apiKeyService)
+
+	r := newUserTestRouter(userService, tokens, apiKeyService)
+	keys := r.Group("/api-keys", middleware.JWTAuth(testAuth.JWTSecret, tokens, apiKeyService))
+	keys.POST("", apiKeyHandler.Create)
+	keys.GET("", apiKeyHandler.List)
+	keys.DELETE("/:id", apiKeyHandler.Revoke)
+	return r
+}
+
+func registerAndLogin(t *testing.T, r *gin.Engine, email string) string {
+	t.Helper()
+
+	payload, _ := json.Marshal(map[string]string{
+		"name":     "ApiKeyUser",
+		"email":    email,
+		"password": "password123",
+	})
+	req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	payload, _ = json.Marshal(map[string]string{
+		"email":    email,
+		"password": "password123",
+	})
+	req, _ = http.NewRequest("POST", "/login", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Connexion impossible : %d, Détails : %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Token
+}
+
+func TestAPIKeyLifecycle(t *testing.T) {
+	testAPIKeyLifecycle(t, newAPIKeyTestRouter(testDB(t)))
+}
+
+func testAPIKeyLifecycle(t *testing.T, r *gin.Engine) {
+	token := registerAndLogin(t, r, "apikey@example.com")
+
+	payload, _ := json.Marshal(map[string]any{
+		"name":   "cli",
+		"scopes": []string{"profile:read"},
+	})
+	req, _ := http.NewRequest("POST", "/api-keys", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var created struct {
+		Key    string `json:"key"`
+		APIKey struct {
+			ID int `json:"id"`
+		} `json:"api_key"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	req, _ = http.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/api-keys/%d", created.APIKey.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusNoContent {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusNoContent, w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+created.Key)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+}
+
+func TestAPIKeyFollowsAccountState(t *testing.T) {
+	db := newSQLiteDB(t)
+	tokens := blacklist.NewBlacklistStore(db)
+	userService := user.NewUserService(user.NewUserRepository(db), tokens, oauth.NewOAuthService(oauth.NewOAuthRepository(db)), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	apiKeyService := apikey.NewAPIKeyService(apikey.NewAPIKeyRepository(db), userService, tokens)
+
+	r := newUserTestRouter(userService, tokens, apiKeyService)
+	r.GET("/admin", middleware.JWTAuth(testAuth.JWTSecret, tokens, apiKeyService), middleware.RequireScope(oauth.ScopeAdmin),
+		func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	token := registerAndLogin(t, r, "keyowner@example.com")
+	owner, _ := user.NewUserRepository(db).GetByEmail(context.Background(), "keyowner@example.com")
+	if err := userService.SetRole(context.Background(), owner.ID, user.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	key, _, err := apiKeyService.Create(context.Background(), owner.ID, "ops", nil, oauth.AllScopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(path, credential string) int {
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+credential)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := get("/admin", key); code != http.StatusNoContent {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusNoContent, code)
+	}
+
+	// Rétrogradé, le compte perd le scope admin de ses clés
+	if err := userService.SetRole(context.Background(), owner.ID, user.RoleUser); err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/admin", key); code != http.StatusForbidden {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusForbidden, code)
+	}
+	if code := get("/me", key); code != http.StatusOK {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusOK, code)
+	}
+
+	// Désactivé, le compte ne peut plus utiliser ni ses jetons ni ses clés
+	if err := userService.SetActive(context.Background(), owner.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if code := get("/me", token); code != http.StatusUnauthorized {
+		t.Errorf("Jeton — Attendu : %d, Reçu : %d", http.StatusUnauthorized, code)
+	}
+	if code := get("/me", key); code != http.StatusUnauthorized {
+		t.Errorf("Clé d'API — Attendu : %d, Reçu : %d", http.StatusUnauthorized, code)
+	}
+}
+
+func TestAPIKeyUnverifiable(t *testing.T) {
+	db := newSQLiteDB(t)
+	tokens := blacklist.NewBlacklistStore(db)
+	userService := user.NewUserService(user.NewUserRepository(db), tokens, oauth.NewOAuthService(oauth.NewOAuthRepository(db)), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	apiKeyService := apikey.NewAPIKeyService(apikey.NewAPIKeyRepository(db), userService, tokens)
+	r := newUserTestRouter(userService, tokens, apiKeyService)
+
+	registerAndLogin(t, r, "unverifiable@example.com")
+	owner, _ := user.NewUserRepository(db).GetByEmail(context.Background(), "unverifiable@example.com")
+	key, _, err := apiKeyService.Create(context.Background(), owner.ID, "ops", nil, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	get := func(credential string) int {
+		req, _ := http.NewRequest("GET", "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+credential)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Une clé au secret erroné est refusée
+	if code := get(key[:len(key)-4] + "0000"); code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusUnauthorized, code)
+	}
+
+	// Base indisponible : la clé ne peut pas être vérifiée, ce qui n'est pas un refus
+	db.Close()
+	if code := get(key); code != http.StatusServiceUnavailable {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusServiceUnavailable, code)
+	}
+}
@@ -0,0 +1,240 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// runExporter lance x et retourne la fonction qui l'arrête après avoir vidé sa file
+func runExporter(x *audit.Exporter) (stop func()) {
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		x.Run(ctx)
+	}()
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+func TestAuditFormatCEF(t *testing.T) {
+	e := audit.Event{
+		ID:        42,
+		Time:      time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+		Action:    audit.ActionLogin,
+		Outcome:   audit.OutcomeFailure,
+		Reason:    "invalid_credentials",
+		ActorType: audit.ActorAnonymous,
+		SubjectID: 7,
+		IP:        "10.0.0.1",
+		UserAgent: "curl/8.0 a=b\\c\nd",
+		Hash:      "abc",
+	}
+
+	expected := `CEF:0|AuthentificationGO|AuthentificationGO|1|login|login failure|5|` +
+		`externalId=42 rt=1790856000000 act=login outcome=failure reason=invalid_credentials ` +
+		`cs1Label=actorType cs1=anonymous duid=7 src=10.0.0.1 requestClientApplication=curl/8.0 a\=b\\c\nd ` +
+		`cs3Label=hash cs3=abc`
+	if got := string(audit.FormatCEF(e)); got != expected {
+		t.Errorf("Attendu : %s\nReçu : %s", expected, got)
+	}
+}
+
+func TestAuditExportFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	sink, err := audit.OpenFileSink(path, audit.FormatJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+	x := audit.NewExporter("test-file", sink, 16)
+	stop := runExporter(x)
+
+	service := audit.NewAuditService(audit.NewMemoryStore(), x)
+	for i := 1; i <= 3; i++ {
+		service.Record(context.Background(), audit.Event{Action: audit.ActionLogin, SubjectID: i}, nil)
+	}
+	// L'arrêt écrit les événements encore en file
+	stop()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var prev string
+	lines := 0
+	for scanner := bufio.NewScanner(f); scanner.Scan(); lines++ {
+		var e audit.Event
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("Ligne %d illisible : %v", lines+1, err)
+		}
+		if e.ID != int64(lines+1) || e.PrevHash != prev || e.Hash == "" {
+			t.Errorf("Les événements doivent être exportés dans l'ordre, chaînés : %+v", e)
+		}
+		prev = e.Hash
+	}
+	if lines != 3 {
+		t.Errorf("Attendu : 3 lignes, Reçu : %d", lines)
+	}
+}
+
+func TestAuditExportSyslogUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	x := audit.NewExporter("test-udp", audit.NewSyslogSink("udp", conn.LocalAddr().String(), audit.FormatCEF), 16)
+	stop := runExporter(x)
+	defer stop()
+
+	service := audit.NewAuditService(audit.NewMemoryStore(), x)
+	service.Record(context.Background(), audit.Event{Action: audit.ActionLogin, SubjectID: 3}, errors.New("boom"))
+
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// authpriv (10) × 8 + warning (4)
+	if !strings.HasPrefix(msg, "<84>1 ") || !strings.Contains(msg, " authentificationgo "+strconv.Itoa(os.Getpid())+" login - CEF:0|") {
+		t.Errorf("Message RFC 5424 inattendu : %s", msg)
+	}
+}
+
+func TestAuditExportSyslogTCPRetry(t *testing.T) {
+	// Le collecteur n'écoute pas encore : les premières tentatives échouent
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	errorsBefore := testutil.ToFloat64(metrics.AuditExportErrors.WithLabelValues("test-tcp"))
+	x := audit.NewExporter("test-tcp", audit.NewSyslogSink("tcp", addr, audit.FormatJSON), 16)
+	stop := runExporter(x)
+	defer stop()
+
+	service := audit.NewAuditService(audit.NewMemoryStore(), x)
+	start := time.Now()
+	service.Record(context.Background(), audit.Event{Action: audit.ActionLogout, SubjectID: 5}, nil)
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("L'enregistrement ne doit pas attendre le collecteur : %v", elapsed)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for testutil.ToFloat64(metrics.AuditExportErrors.WithLabelValues("test-tcp")) == errorsBefore {
+		if time.Now().After(deadline) {
+			t.Fatal("L'échec de connexion doit être compté")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	l, err = net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("port %s repris entre-temps : %v", addr, err)
+	}
+	defer l.Close()
+	l.(*net.TCPListener).SetDeadline(time.Now().Add(5 * time.Second))
+	conn, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+	// Trame RFC 6587 : longueur, espace, message
+	r := bufio.NewReader(conn)
+	length, err := r.ReadString(' ')
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(length))
+	if err != nil {
+		t.Fatalf("Longueur de trame illisible : %q", length)
+	}
+	msg := make([]byte, n)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(msg), "<86>1 ") || !strings.Contains(string(msg), `"action":"logout"`) {
+		t.Errorf("Message inattendu après reconnexion : %s", msg)
+	}
+}
+
+// blockingSink n'accepte aucune écriture avant la fermeture de release
+type blockingSink struct {
+	release chan struct{}
+}
+
+func (s *blockingSink) Write(ctx context.Context, events []audit.Event) (int, error) {
+	select {
+	case <-s.release:
+		return len(events), nil
+	case <-ctx.Done():
+		return 0, ctx.Err()
+	}
+}
+
+func (s *blockingSink) Close() error { return nil }
+
+func TestAuditExportBackpressure(t *testing.T) {
+	sink := &blockingSink{release: make(chan struct{})}
+	x := audit.NewExporter("test-backpressure", sink, 2)
+	stop := runExporter(x)
+
+	dropped := func() float64 {
+		return testutil.ToFloat64(metrics.AuditExports.WithLabelValues("test-backpressure", "dropped"))
+	}
+	exported := func() float64 {
+		return testutil.ToFloat64(metrics.AuditExports.WithLabelValues("test-backpressure", "exported"))
+	}
+	droppedBefore, exportedBefore := dropped(), exported()
+
+	service := audit.NewAuditService(audit.NewMemoryStore(), x)
+	start := time.Now()
+	for i := 1; i <= 10; i++ {
+		service.Record(context.Background(), audit.Event{Action: audit.ActionLogin, SubjectID: i}, nil)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Une destination bloquée ne doit pas ralentir l'enregistrement : %v", elapsed)
+	}
+
+	// Deux événements en file, et au plus trois dans le lot en cours d'écriture
+	if lost := dropped() - droppedBefore; lost < 5 || lost > 8 {
+		t.Errorf("Attendu : entre 5 et 8 événements abandonnés, Reçu : %v", lost)
+	}
+
+	close(sink.release)
+	stop()
+	if total := dropped() - droppedBefore + exported() - exportedBefore; total != 10 {
+		t.Errorf("Chaque événement doit être exporté ou compté comme abandonné, Reçu : %v", total)
+	}
+
+	events, _ := service.List(context.Background(), audit.Filter{})
+	if len(events) != 10 {
+		t.Errorf("Le journal en base doit rester complet, Reçu : %d événements", len(events))
+	}
+}
@@ -0,0 +1,191 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// newAuditTestRouter ajoute au routeur de compte la vue personnelle et les routes
+// d'administration du journal, le tout stocké dans db
+func newAuditTestRouter(db *sql.DB) (*gin.Engine, *user.UserService) {
+	tokens := blacklist.NewBlacklistStore(db)
+	auditService := audit.NewAuditService(audit.NewAuditStore(db))
+	userService := user.NewUserService(user.NewUserRepository(db), tokens, oauth.NewOAuthService(nil), testAuth, testMailer, auditService)
+	auditHandler := audit.NewAuditHandler(auditService)
+
+	r := newUserTestRouter(userService, tokens, nil)
+	protected := r.Group("/", middleware.JWTAuth(testAuth.JWTSecret, tokens, nil))
+	protected.GET("/me/audit-events", middleware.RequireScope(oauth.ScopeProfileRead), auditHandler.Mine)
+	admin := protected.Group("/audit", middleware.RequireScope(oauth.ScopeAdmin))
+	admin.GET("/events", auditHandler.List)
+	admin.GET("/verify", auditHandler.Verify)
+	return r, userService
+}
+
+func getAudit(t *testing.T, r *gin.Engine, path, token string, target any) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET %s — Attendu : %d, Reçu : %d (%s)", path, http.StatusOK, w.Code, w.Body.String())
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), target); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuditLog(t *testing.T) {
+	db := newSQLiteDB(t)
+	r, userService := newAuditTestRouter(db)
+
+	if w := postJSON(r, "/login", map[string]string{"email": "audited@example.com", "password": "wrong-password"}, nil); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Connexion d'un compte inconnu — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	postJSON(r, "/register", map[string]string{"name": "Audited", "email": "audited@example.com", "password": "password123"}, nil)
+	w := postJSON(r, "/login", map[string]string{"email": "audited@example.com", "password": "wrong-password"},
+		map[string]string{"User-Agent": "audit-test/1.0", "X-Request-ID": "req-audit-1"})
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("Mauvais mot de passe — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	token := registerAndLogin(t, r, "audited@example.com")
+
+	t.Run("own events", func(t *testing.T) {
+		var page struct{ Events []audit.Event }
+		getAudit(t, r, "/me/audit-events", token, &page)
+
+		// Du plus récent au plus ancien ; la connexion au compte encore inconnu n'y figure pas
+		expected := []struct{ action, outcome string }{
+			{audit.ActionLogin, audit.OutcomeSuccess},
+			{audit.ActionLogin, audit.OutcomeFailure},
+			{audit.ActionUserCreate, audit.OutcomeSuccess},
+		}
+		if len(page.Events) != len(expected) {
+			t.Fatalf("Attendu : %d événements, Reçu : %+v", len(expected), page.Events)
+		}
+		for i, e := range expected {
+			if got := page.Events[i]; got.Action != e.action || got.Outcome != e.outcome {
+				t.Errorf("Événement %d — Attendu : %s/%s, Reçu : %s/%s", i, e.action, e.outcome, got.Action, got.Outcome)
+			}
+		}
+
+		failed := page.Events[1]
+		if failed.Reason != "invalid_credentials" || failed.UserAgent != "audit-test/1.0" || failed.RequestID != "req-audit-1" || failed.ActorType != audit.ActorAnonymous {
+			t.Errorf("Origine de l'échec mal enregistrée : %+v", failed)
+		}
+	})
+
+	t.Run("admin search", func(t *testing.T) {
+		ctx := context.Background()
+		registerAndLogin(t, r, "audit-admin@example.com")
+		adminUser, _ := userService.FindByEmail(ctx, "audit-admin@example.com")
+		if err := userService.SetRole(ctx, adminUser.ID, user.RoleAdmin); err != nil {
+			t.Fatal(err)
+		}
+		admin := registerAndLogin(t, r, "audit-admin@example.com")
+
+		// L'administrateur désactive le compte
+		audited, _ := userService.FindByEmail(ctx, "audited@example.com")
+		if err := userService.SetActive(audit.WithActor(ctx, audit.UserActor(adminUser.ID)), audited.ID, false); err != nil {
+			t.Fatal(err)
+		}
+
+		var page struct {
+			Events     []audit.Event
+			NextBefore int64 `json:"next_before"`
+		}
+		getAudit(t, r, "/audit/events?subject_id="+strconv.Itoa(audited.ID)+"&action="+audit.ActionUserDisable, admin, &page)
+		if len(page.Events) != 1 || page.Events[0].ActorID != strconv.Itoa(adminUser.ID) || page.Events[0].ActorType != audit.ActorUser {
+			t.Fatalf("La désactivation doit être attribuée à l'administrateur : %+v", page.Events)
+		}
+
+		getAudit(t, r, "/audit/events?outcome=failure&limit=2", admin, &page)
+		if len(page.Events) != 2 || page.NextBefore != page.Events[1].ID {
+			t.Errorf("Attendu : une page de 2 échecs avec le curseur suivant, Reçu : %+v", page)
+		}
+
+		// Les sessions du compte désactivé sont révoquées : un autre compte sans le rôle admin
+		reader := registerAndLogin(t, r, "audit-reader@example.com")
+		req, _ := http.NewRequest("GET", "/audit/events", nil)
+		req.Header.Set("Authorization", "Bearer "+reader)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusForbidden {
+			t.Errorf("Un utilisateur sans scope admin ne doit pas lire tout le journal — Attendu : %d, Reçu : %d", http.StatusForbidden, w.Code)
+		}
+	})
+
+	t.Run("tampering", func(t *testing.T) {
+		admin := registerAndLogin(t, r, "audit-admin@example.com")
+		var v audit.Verification
+		getAudit(t, r, "/audit/verify", admin, &v)
+		if !v.Valid || v.Events == 0 || v.Head == "" {
+			t.Fatalf("Le journal intact doit être valide : %+v", v)
+		}
+
+		if _, err := db.Exec("UPDATE audit_events SET outcome = 'success' WHERE id = 3"); err == nil {
+			t.Fatal("Les lignes du journal ne doivent pas pouvoir être modifiées")
+		}
+		if _, err := db.Exec("DELETE FROM audit_events WHERE id = 3"); err == nil {
+			t.Fatal("Les lignes du journal ne doivent pas pouvoir être supprimées")
+		}
+
+		// Sans les triggers, la modification est détectée par la chaîne d'empreintes
+		if _, err := db.Exec("DROP TRIGGER audit_events_no_update"); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec("UPDATE audit_events SET outcome = 'success' WHERE id = 3"); err != nil {
+			t.Fatal(err)
+		}
+		getAudit(t, r, "/audit/verify", admin, &v)
+		if v.Valid || v.BrokenAt != 3 {
+			t.Errorf("Attendu : chaîne rompue à l'événement 3, Reçu : %+v", v)
+		}
+	})
+}
+
+func TestAuditChainConcurrentAppends(t *testing.T) {
+	for name, store := range map[string]audit.EventStore{
+		"memory": audit.NewMemoryStore(),
+		"sqlite": audit.NewAuditStore(newSQLiteDB(t)),
+	} {
+		t.Run(name, func(t *testing.T) {
+			service := audit.NewAuditService(store)
+			ctx := audit.WithSource(context.Background(), audit.Source{IP: "10.0.0.1", RequestID: "concurrent"})
+
+			var wg sync.WaitGroup
+			for i := 1; i <= 20; i++ {
+				wg.Add(1)
+				go func(id int) {
+					defer wg.Done()
+					service.Record(ctx, audit.Event{Action: audit.ActionLogin, SubjectID: id}, nil)
+				}(i)
+			}
+			wg.Wait()
+
+			v, err := service.Verify(context.Background())
+			if err != nil || !v.Valid || v.Events != 20 {
+				t.Errorf("Attendu : 20 événements chaînés, Reçu : %+v (%v)", v, err)
+			}
+			events, err := service.List(context.Background(), audit.Filter{SubjectID: 7})
+			if err != nil || len(events) != 1 || events[0].IP != "10.0.0.1" || events[0].RequestID != "concurrent" {
+				t.Errorf("L'origine doit être reprise du contexte : %+v (%v)", events, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func authorizedRequest(method, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	return w
+}
+
+func TestLogoutRevokesToken(t *testing.T) {
+	token := registerAndLogin(t, testRouter, "logout@example.com")
+
+	if w := authorizedRequest("GET", "/me", token); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusOK, w.Code)
+	}
+	if w := authorizedRequest("POST", "/logout", token); w.Code != http.StatusNoContent {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	if w := authorizedRequest("GET", "/me", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestRefreshTokenIsSingleUse(t *testing.T) {
+	registerAndLogin(t, testRouter, "refresh@example.com")
+
+	payload, _ := json.Marshal(map[string]string{"email": "refresh@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	var response struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+
+	if w := authorizedRequest("POST", "/refresh", response.RefreshToken); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := authorizedRequest("POST", "/refresh", response.RefreshToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("réutilisation — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,158 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/cmd/cli"
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// runCLI exécute une sous-commande et retourne son code de sortie, sa sortie et ses erreurs
+func runCLI(stdin string, args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	code := cli.Run(args, strings.NewReader(stdin), &stdout, &stderr)
+	return code, stdout.String(), stderr.String()
+}
+
+// newCLIDatabase configure les commandes sur une base SQLite migrée et retourne son chemin
+func newCLIDatabase(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "cli.db")
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("SQLITE_PATH", path)
+	if code, out, errOut := runCLI("", "migrate", "up"); code != 0 || !strings.Contains(out, "migration(s) applied") {
+		t.Fatalf("migrate up a échoué (%d) : %s%s", code, out, errOut)
+	}
+	return path
+}
+
+func TestCLIUserCommands(t *testing.T) {
+	path := newCLIDatabase(t)
+
+	code, out, errOut := runCLI("password123\n", "user", "create", "-email", "root@example.com", "-name", "Root", "-role", "admin")
+	if code != 0 || !strings.Contains(out, "role admin") {
+		t.Fatalf("Création de l'administrateur impossible (%d) : %s%s", code, out, errOut)
+	}
+
+	db, err := database.ConnectSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	repo := user.NewUserRepository(db)
+
+	if _, err := repo.Login(context.Background(), "root@example.com", "password123"); err != nil {
+		t.Errorf("Le mot de passe lu sur l'entrée standard doit être enregistré : %v", err)
+	}
+	admin, _ := repo.GetByEmail(context.Background(), "root@example.com")
+	if u, _ := repo.FindByID(context.Background(), admin.ID); u.Role != user.RoleAdmin || !u.Active {
+		t.Errorf("Compte inattendu : %+v", u)
+	}
+
+	if code, _, errOut := runCLI("", "user", "disable", "-email", "root@example.com"); code != 0 {
+		t.Fatalf("Désactivation impossible (%d) : %s", code, errOut)
+	}
+	if u, _ := repo.FindByID(context.Background(), admin.ID); u.Active {
+		t.Error("Le compte doit être désactivé")
+	}
+	if generation, _ := blacklist.NewBlacklistStore(db).SessionGeneration(context.Background(), admin.ID); generation == 0 {
+		t.Error("La désactivation doit révoquer les sessions du compte")
+	}
+
+	if code, _, errOut := runCLI("", "user", "reset-password", "-email", "root@example.com", "-password", "newpassword"); code != 0 {
+		t.Fatalf("Réinitialisation impossible (%d) : %s", code, errOut)
+	}
+	if _, err := repo.Login(context.Background(), "root@example.com", "newpassword"); err != nil {
+		t.Errorf("Le nouveau mot de passe doit être accepté : %v", err)
+	}
+
+	if code, _, _ := runCLI("", "user", "set-role", "-email", "root@example.com", "-role", "owner"); code != 1 {
+		t.Errorf("Un rôle inconnu doit être refusé, code %d", code)
+	}
+	if code, _, errOut := runCLI("", "user", "set-role", "-email", "ghost@example.com", "-role", "admin"); code != 1 || !strings.Contains(errOut, "not found") {
+		t.Errorf("Un compte inconnu doit être signalé (%d) : %s", code, errOut)
+	}
+	if code, _, errOut := runCLI("", "user", "create", "-name", "Root"); code != 2 || !strings.Contains(errOut, "-email is required") {
+		t.Errorf("Une option obligatoire manquante est une erreur d'usage (%d) : %s", code, errOut)
+	}
+}
+
+func TestCLIKeysAndTokens(t *testing.T) {
+	path := newCLIDatabase(t)
+	runCLI("", "user", "create", "-email", "keys@example.com", "-name", "Keys", "-password", "password123")
+
+	db, err := database.ConnectSQLite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	owner, _ := user.NewUserRepository(db).GetByEmail(context.Background(), "keys@example.com")
+	users := user.NewUserService(user.NewUserRepository(db), blacklist.NewBlacklistStore(db), oauth.NewOAuthService(oauth.NewOAuthRepository(db)), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	keys := apikey.NewAPIKeyService(apikey.NewAPIKeyRepository(db), users, blacklist.NewBlacklistStore(db))
+	oldKey, created, err := keys.Create(context.Background(), owner.ID, "ci", []string{oauth.ScopeProfileRead}, oauth.AllScopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, out, errOut := runCLI("", "keys", "list", "-email", "keys@example.com")
+	if code != 0 || !strings.Contains(out, "ci") || !strings.Contains(out, created.Prefix) {
+		t.Fatalf("Liste des clés inattendue (%d) : %s%s", code, out, errOut)
+	}
+
+	code, out, errOut = runCLI("", "keys", "rotate", "-email", "keys@example.com", "-id", strconv.Itoa(created.ID))
+	if code != 0 {
+		t.Fatalf("Rotation impossible (%d) : %s", code, errOut)
+	}
+	newKey := strings.TrimSpace(out[strings.LastIndex(strings.TrimSpace(out), "\n")+1:])
+	if _, _, err := keys.Authenticate(context.Background(), oldKey); err == nil {
+		t.Error("L'ancienne clé doit être révoquée")
+	}
+	if _, scopes, err := keys.Authenticate(context.Background(), newKey); err != nil || len(scopes) != 1 || scopes[0] != oauth.ScopeProfileRead {
+		t.Errorf("La nouvelle clé doit reprendre les scopes de l'ancienne : %v %v", scopes, err)
+	}
+	if code, _, _ := runCLI("", "keys", "rotate", "-email", "keys@example.com", "-id", strconv.Itoa(created.ID)); code != 1 {
+		t.Errorf("Une clé révoquée ne peut pas être remplacée, code %d", code)
+	}
+
+	store := blacklist.NewBlacklistStore(db)
+	store.Add(context.Background(), "expired-token", time.Now().Add(-time.Minute))
+	store.Add(context.Background(), "live-token", time.Now().Add(time.Hour))
+	if code, out, _ := runCLI("", "tokens", "purge-expired"); code != 0 || !strings.Contains(out, "1 expired token(s) purged") {
+		t.Errorf("Purge inattendue (%d) : %s", code, out)
+	}
+}
+
+func TestCLIConfigCheck(t *testing.T) {
+	t.Setenv("JWT_SECRET", strongSecret)
+	t.Setenv("DB_DRIVER", "sqlite")
+
+	code, out, errOut := runCLI("", "config", "check", "-port", "9000")
+	if code != 0 || !strings.Contains(out, "configuration OK") {
+		t.Fatalf("Configuration refusée (%d) : %s", code, errOut)
+	}
+	if !strings.Contains(out, "9000") || strings.Contains(out, strongSecret) {
+		t.Errorf("La configuration affichée doit refléter les options et masquer les secrets :\n%s", out)
+	}
+
+	t.Setenv("JWT_SECRET", "short")
+	if code, _, errOut := runCLI("", "config", "check"); code != 1 || !strings.Contains(errOut, "is too weak") {
+		t.Errorf("Un secret trop court doit être signalé (%d) : %s", code, errOut)
+	}
+
+	if code, _, errOut := runCLI("", "unknown"); code != 2 || !strings.Contains(errOut, "user create") {
+		t.Errorf("Une commande inconnue doit afficher l'aide (%d) : %s", code, errOut)
+	}
+}
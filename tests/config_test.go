@@ -0,0 +1,221 @@
+package tests
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/ldapauth"
+	"github.com/pathi14/AuthentificationGO/internal/social"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+const strongSecret = "0123456789abcdef0123456789abcdef"
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestConfigPrecedence(t *testing.T) {
+	file := writeConfigFile(t, "config.yaml", `
+server:
+  port: 9000
+  route_prefix: /api
+auth:
+  jwt_secret: `+strongSecret+`
+  access_token_ttl: 30m
+database:
+  driver: sqlite
+  sqlite_path: /tmp/auth.db
+  max_open_conns: 4
+`)
+	t.Setenv("PORT", "9100")
+	t.Setenv("REFRESH_TOKEN_TTL", "48h")
+
+	cfg, err := config.Load([]string{"-config", file, "-port", "9200"})
+	if err != nil {
+		t.Fatalf("Configuration refusée : %v", err)
+	}
+
+	if cfg.Server.Port != 9200 {
+		t.Errorf("L'option de ligne de commande doit primer : port %d", cfg.Server.Port)
+	}
+	if cfg.Auth.AccessTokenTTL != 30*time.Minute || cfg.Auth.RefreshTokenTTL != 48*time.Hour {
+		t.Errorf("Durées inattendues : %v / %v", cfg.Auth.AccessTokenTTL, cfg.Auth.RefreshTokenTTL)
+	}
+	if cfg.Auth.ResetTokenTTL != 15*time.Minute {
+		t.Errorf("Les valeurs par défaut doivent s'appliquer aux réglages absents : %v", cfg.Auth.ResetTokenTTL)
+	}
+	if cfg.Database.MaxOpenConns != 4 || cfg.Database.MaxIdleConns != 4 {
+		t.Errorf("Le nombre de connexions inactives est borné par max_open_conns : %d / %d",
+			cfg.Database.MaxOpenConns, cfg.Database.MaxIdleConns)
+	}
+	if cfg.Auth.ResetURL != "https://go-auth-api-latest.onrender.com/api/reset-password" {
+		t.Errorf("Le lien de réinitialisation doit suivre le préfixe des routes : %s", cfg.Auth.ResetURL)
+	}
+}
+
+func TestConfigTOMLAndSecretFile(t *testing.T) {
+	file := writeConfigFile(t, "config.toml", `
+default_locale = "en"
+
+[database]
+driver = "postgres"
+host = "db.internal"
+user = "auth"
+name = "auth"
+auto_migrate = false
+`)
+	secretFile := writeConfigFile(t, "jwt_secret", strongSecret+"\n")
+	t.Setenv("CONFIG_FILE", file)
+	t.Setenv("JWT_SECRET_FILE", secretFile)
+
+	cfg, err := config.Load(nil)
+	if err != nil {
+		t.Fatalf("Configuration refusée : %v", err)
+	}
+	if cfg.Auth.JWTSecret != strongSecret {
+		t.Errorf("Le secret doit être lu dans JWT_SECRET_FILE, sans le saut de ligne final : %q", cfg.Auth.JWTSecret)
+	}
+	if cfg.Database.Host != "db.internal" || cfg.Database.AutoMigrate || cfg.DefaultLocale != "en" {
+		t.Errorf("Fichier TOML mal appliqué : %+v", cfg)
+	}
+
+	t.Setenv("JWT_SECRET", strongSecret)
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "JWT_SECRET_FILE") {
+		t.Errorf("JWT_SECRET et JWT_SECRET_FILE ne peuvent pas être définis ensemble : %v", err)
+	}
+}
+
+func TestConfigValidation(t *testing.T) {
+	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("DB_DRIVER", "mysql")
+
+	_, err := config.Load([]string{"-port", "70000", "-audit-syslog", "udp://localhost"})
+	if err == nil {
+		t.Fatal("Une configuration invalide doit être refusée")
+	}
+	for _, expected := range []string{
+		"auth.jwt_secret (JWT_SECRET): is too weak",
+		"server.port (PORT): must be between 1 and 65535",
+		`database.driver (DB_DRIVER): unsupported driver "mysql"`,
+		"audit.syslog (AUDIT_SYSLOG): must be udp://host:port or tcp://host:port",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Toutes les erreurs doivent être signalées ensemble, %q manque dans :\n%v", expected, err)
+		}
+	}
+
+	file := writeConfigFile(t, "config.yaml", "auth:\n  jwt_secrte: typo\n")
+	if _, err := config.Load([]string{"-config", file}); err == nil || !strings.Contains(err.Error(), `unknown setting "auth.jwt_secrte"`) {
+		t.Errorf("Une clé inconnue doit être signalée : %v", err)
+	}
+
+	t.Setenv("ACCESS_TOKEN_TTL", "2 hours")
+	if _, err := config.Load(nil); err == nil || !strings.Contains(err.Error(), "ACCESS_TOKEN_TTL") {
+		t.Errorf("Une durée mal formée doit être signalée avec sa variable : %v", err)
+	}
+}
+
+func TestConfigIdentitySources(t *testing.T) {
+	file := writeConfigFile(t, "config.yaml", `
+auth:
+  jwt_secret: `+strongSecret+`
+database:
+  driver: sqlite
+ldap:
+  url: ldaps://ldap.example.com
+  bind_dn: cn=service,dc=example,dc=com
+  base_dn: ou=people,dc=example,dc=com
+  role_mapping: admins=admin, staff=user
+social:
+  redirect_base_url: https://api.example.com/auth/
+  google:
+    client_id: google-client
+  acme:
+    issuer: https://sso.acme.example
+    client_id: acme-client
+    trust_email: true
+`)
+	t.Setenv("LDAP_BIND_PASSWORD_FILE", writeConfigFile(t, "ldap_password", "bind-secret\n"))
+	t.Setenv("SOCIAL_GITHUB_CLIENT_ID", "github-client")
+	t.Setenv("SOCIAL_GITHUB_CLIENT_SECRET", "github-secret")
+
+	cfg, err := config.Load([]string{"-config", file, "-social-providers", "google,github,acme"})
+	if err != nil {
+		t.Fatalf("Configuration refusée : %v", err)
+	}
+
+	ldapConfig, err := ldapauth.NewConfig(cfg.LDAP)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ldapConfig.BindPassword != "bind-secret" || ldapConfig.GroupBaseDN != ldapConfig.BaseDN || ldapConfig.Timeout != 10*time.Second {
+		t.Errorf("Configuration LDAP inattendue : %+v", ldapConfig)
+	}
+	if len(ldapConfig.RoleMapping) != 2 || ldapConfig.RoleMapping[1] != (ldapauth.RoleMapping{Group: "staff", Role: user.RoleUser}) {
+		t.Errorf("Correspondance des groupes inattendue : %+v", ldapConfig.RoleMapping)
+	}
+
+	providers := social.NewProviderConfigs(cfg.Social)
+	if len(providers) != 3 || providers[0].Name != "google" || providers[1].Name != "github" || providers[2].Name != "acme" {
+		t.Fatalf("Les fournisseurs doivent suivre l'ordre de social.providers : %+v", providers)
+	}
+	if google := providers[0]; google.IssuerURL != "https://accounts.google.com" || google.ClientID != "google-client" ||
+		google.RedirectURL != "https://api.example.com/auth/google/callback" {
+		t.Errorf("Valeurs par défaut de Google mal appliquées : %+v", google)
+	}
+	if github := providers[1]; github.Type != "github" || github.ClientSecret != "github-secret" || len(github.Scopes) != 2 {
+		t.Errorf("Variables SOCIAL_GITHUB_* mal appliquées : %+v", github)
+	}
+	if acme := providers[2]; acme.Type != "oidc" || !acme.TrustEmail || acme.IssuerURL != "https://sso.acme.example" {
+		t.Errorf("Un fournisseur inconnu est un fournisseur OIDC : %+v", acme)
+	}
+
+	var out strings.Builder
+	if err := cfg.Write(&out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "github-secret") || strings.Contains(out.String(), "bind-secret") {
+		t.Errorf("Les secrets ne doivent pas être affichés :\n%s", out.String())
+	}
+
+	if _, err := config.Load([]string{"-config", file, "-social-providers", "github"}); err == nil || !strings.Contains(err.Error(), `unknown setting "social.acme.client_id"`) {
+		t.Errorf("Les réglages d'un fournisseur non activé doivent être signalés : %v", err)
+	}
+}
+
+func TestConfigIdentitySourcesValidation(t *testing.T) {
+	t.Setenv("JWT_SECRET", strongSecret)
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("LDAP_URL", "ldap.example.com")
+	t.Setenv("LDAP_ROLE_MAPPING", "admins=root")
+	t.Setenv("SAML_SP_BASE_URL", "https://api.example.com/saml")
+	t.Setenv("SOCIAL_PROVIDERS", "github")
+
+	_, err := config.Load(nil)
+	if err == nil {
+		t.Fatal("Une configuration invalide doit être refusée")
+	}
+	for _, expected := range []string{
+		"ldap.url (LDAP_URL): must be ldap://host[:port] or ldaps://host[:port]",
+		"ldap.base_dn (LDAP_BASE_DN): is required with ldap.url",
+		`ldap.role_mapping (LDAP_ROLE_MAPPING): invalid entry "admins=root": unknown role "root"`,
+		"saml.sp_cert_file (SAML_SP_CERT_FILE): is required with saml.sp_base_url",
+		"social.github.client_id (SOCIAL_GITHUB_CLIENT_ID): is required",
+		"social.github.redirect_url (SOCIAL_GITHUB_REDIRECT_URL): is required when social.redirect_base_url is not set",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q manque dans :\n%v", expected, err)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+func TestRepositoryErrorsAreTyped(t *testing.T) {
+	repo := user.NewUserRepository(newSQLiteDB(t))
+
+	if _, err := repo.Create(context.Background(), user.User{Name: "First", Email: "typed@example.com", Password: "x"}, user.Identity{Provider: user.PasswordProvider}); err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	_, err := repo.Create(context.Background(), user.User{Name: "Again", Email: "typed@example.com", Password: "x"}, user.Identity{Provider: user.PasswordProvider})
+	if !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("Attendu : ErrConflict, Reçu : %v", err)
+	}
+
+	if _, err := repo.FindByID(context.Background(), 999); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("Attendu : ErrNotFound, Reçu : %v", err)
+	}
+	if _, err := repo.Login(context.Background(), "typed@example.com", "wrong-password"); !errors.Is(err, apperror.ErrUnauthorized) {
+		t.Errorf("Attendu : ErrUnauthorized, Reçu : %v", err)
+	}
+}
+
+func TestSetRoleUnknownUser(t *testing.T) {
+	stores := map[string]user.UserStore{
+		"sqlite":  user.NewUserRepository(newSQLiteDB(t)),
+		"mémoire": user.NewMemoryUserStore(),
+	}
+	for name, store := range stores {
+		if err := store.SetRole(context.Background(), 999, user.RoleAdmin); !errors.Is(err, apperror.ErrNotFound) {
+			t.Errorf("%s — Attendu : ErrNotFound, Reçu : %v", name, err)
+		}
+	}
+}
+
+func TestLoginDisabledAccount(t *testing.T) {
+	registerAndLogin(t, testRouter, "disabled@example.com")
+
+	u, err := testUsers.GetByEmail(context.Background(), "disabled@example.com")
+	if err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	u.Active = false
+	if err := testUsers.Update(context.Background(), *u); err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+
+	payload, _ := json.Marshal(map[string]string{"email": "disabled@example.com", "password": "password123"})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	if w.Code != http.StatusLocked {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusLocked, w.Code, w.Body.String())
+	}
+}
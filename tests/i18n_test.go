@@ -0,0 +1,132 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/pathi14/AuthentificationGO/internal/i18n"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+func TestCatalogsHaveSameKeys(t *testing.T) {
+	reference := i18n.Keys(i18n.French)
+	for _, locale := range i18n.Locales() {
+		if keys := i18n.Keys(locale); !reflect.DeepEqual(keys, reference) {
+			t.Errorf("Le catalogue %q n'a pas les mêmes clés que le catalogue français (%d/%d clés)", locale, len(keys), len(reference))
+		}
+	}
+}
+
+func TestNegotiateLocale(t *testing.T) {
+	cases := map[string]string{
+		"de-CH, en;q=0.8":    i18n.German,
+		"en-US,en;q=0.9":     i18n.English,
+		"es-ES, fr;q=0.5":    i18n.French,
+		"ja":                 i18n.Default(),
+		"":                   i18n.Default(),
+		"not a header;;q=xx": i18n.Default(),
+	}
+	for header, expected := range cases {
+		if locale := i18n.Negotiate(header); locale != expected {
+			t.Errorf("Accept-Language %q — Attendu : %q, Reçu : %q", header, expected, locale)
+		}
+	}
+}
+
+func TestProblemFollowsAcceptLanguage(t *testing.T) {
+	credentials := map[string]string{"email": "nobody@example.com", "password": "password123"}
+
+	cases := map[string]struct{ title, detail string }{
+		"de-DE,de;q=0.9": {"Ungültige Anmeldedaten", "E-Mail-Adresse oder Passwort falsch"},
+		"en":             {"Invalid credentials", "Incorrect email or password"},
+		"":               {"Identifiants invalides", "Email ou mot de passe incorrect"},
+	}
+	for header, expected := range cases {
+		w := postJSON(testRouter, "/login", credentials, map[string]string{"Accept-Language": header})
+		body := decodeProblem(t, w)
+		if body["code"] != problem.CodeInvalidCredentials || body["title"] != expected.title || body["detail"] != expected.detail {
+			t.Errorf("Accept-Language %q — problème inattendu : %v", header, body)
+		}
+	}
+}
+
+func TestLocalizedFieldErrors(t *testing.T) {
+	w := postJSON(testRouter, "/register", map[string]string{"name": "P", "email": "p@example.com", "password": "password123"},
+		map[string]string{"Accept-Language": "en"})
+	body := decodeProblem(t, w)
+
+	errs, _ := body["errors"].([]interface{})
+	if len(errs) != 1 {
+		t.Fatalf("Attendu : une erreur sur le champ name, Reçu : %v", body)
+	}
+	fe := errs[0].(map[string]interface{})
+	if fe["field"] != "name" || fe["detail"] != "Must be at least 2 characters long" {
+		t.Errorf("Détail du champ non traduit : %v", fe)
+	}
+}
+
+func TestUserLocaleOverridesAcceptLanguage(t *testing.T) {
+	token := registerAndLogin(t, testRouter, "locale@example.com")
+
+	payload, _ := json.Marshal(map[string]string{"locale": "de"})
+	req, _ := http.NewRequest("PUT", "/me/locale", bytes.NewBuffer(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "Bevorzugte Sprache aktualisiert") {
+		t.Fatalf("Mise à jour de la langue impossible : %d, Détails : %s", w.Code, w.Body.String())
+	}
+
+	// La préférence est portée par les jetons émis après la mise à jour
+	token = registerAndLogin(t, testRouter, "locale@example.com")
+	req, _ = http.NewRequest("GET", "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept-Language", "en")
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+
+	var profile map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &profile)
+	if profile["locale"] != i18n.German || profile["message"] != "Benutzerprofil" {
+		t.Errorf("La langue de l'utilisateur doit primer sur Accept-Language : %v", profile)
+	}
+
+	// Une langue inconnue est refusée avec le détail par champ
+	payload, _ = json.Marshal(map[string]string{"locale": "xx"})
+	req, _ = http.NewRequest("PUT", "/me/locale", bytes.NewBuffer(payload))
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	testRouter.ServeHTTP(w, req)
+	if body := decodeProblem(t, w); w.Code != http.StatusBadRequest || body["code"] != problem.CodeInvalidRequest {
+		t.Errorf("Attendu : %d, Reçu : %d %v", http.StatusBadRequest, w.Code, body)
+	}
+}
+
+func TestRenderEmail(t *testing.T) {
+	data := map[string]string{"Name": "Ada", "Link": "https://example.com/reset?token=abc"}
+	subjects := map[string]bool{}
+	for _, locale := range i18n.Locales() {
+		email, err := i18n.RenderEmail(locale, "password_reset", data)
+		if err != nil {
+			t.Fatalf("%s : %v", locale, err)
+		}
+		if !strings.Contains(email.Body, "Ada") || !strings.Contains(email.Body, data["Link"]) {
+			t.Errorf("%s — le corps doit reprendre le nom et le lien : %q", locale, email.Body)
+		}
+		subjects[email.Subject] = true
+	}
+	if len(subjects) != len(i18n.Locales()) {
+		t.Errorf("Chaque langue doit avoir son propre sujet : %v", subjects)
+	}
+
+	if _, err := i18n.RenderEmail("it", "password_reset", data); err != nil {
+		t.Errorf("Une langue inconnue doit se rabattre sur la langue par défaut : %v", err)
+	}
+}
@@ -0,0 +1,190 @@
+package tests
+
+import (
+	"net"
+	"strings"
+	"sync"
+	"testing"
+
+	ber "github.com/go-asn1-ber/asn1-ber"
+)
+
+// ldapStub est un annuaire LDAPv3 minimal exécuté dans le processus de test. Il ne gère que
+// les opérations utilisées par ldapauth : bind simple, recherche avec filtres and/or/not,
+// égalité et présence, et unbind.
+type ldapStub struct {
+	listener net.Listener
+	mu       sync.Mutex
+	entries  map[string]ldapStubEntry
+}
+
+type ldapStubEntry struct {
+	password   string
+	attributes map[string][]string
+}
+
+const (
+	ldapBindRequest        = 0
+	ldapBindResponse       = 1
+	ldapUnbindRequest      = 2
+	ldapSearchRequest      = 3
+	ldapSearchResultEntry  = 4
+	ldapSearchResultDone   = 5
+	ldapResultSuccess      = 0
+	ldapResultInvalidCreds = 49
+)
+
+func newLDAPStub(t *testing.T) *ldapStub {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Impossible de démarrer l'annuaire de test : %v", err)
+	}
+
+	s := &ldapStub{listener: listener, entries: map[string]ldapStubEntry{}}
+	t.Cleanup(func() { listener.Close() })
+	go s.serve()
+	return s
+}
+
+func (s *ldapStub) url() string {
+	return "ldap://" + s.listener.Addr().String()
+}
+
+func (s *ldapStub) add(dn, password string, attributes map[string][]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[strings.ToLower(dn)] = ldapStubEntry{password: password, attributes: attributes}
+}
+
+func (s *ldapStub) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *ldapStub) handle(conn net.Conn) {
+	defer conn.Close()
+
+	for {
+		packet, err := ber.ReadPacket(conn)
+		if err != nil || len(packet.Children) < 2 {
+			return
+		}
+		messageID := packet.Children[0].Value.(int64)
+		op := packet.Children[1]
+
+		switch op.Tag {
+		case ldapBindRequest:
+			dn := op.Children[1].Data.String()
+			password := op.Children[2].Data.String()
+			code := int64(ldapResultSuccess)
+			s.mu.Lock()
+			entry, ok := s.entries[strings.ToLower(dn)]
+			s.mu.Unlock()
+			if !ok || entry.password != password {
+				code = ldapResultInvalidCreds
+			}
+			conn.Write(ldapResult(messageID, ldapBindResponse, code).Bytes())
+
+		case ldapSearchRequest:
+			base := strings.ToLower(op.Children[0].Data.String())
+			filter := op.Children[6]
+
+			s.mu.Lock()
+			for dn, entry := range s.entries {
+				if !strings.HasSuffix(dn, base) || !ldapStubMatch(filter, entry.attributes) {
+					continue
+				}
+				conn.Write(ldapSearchEntry(messageID, dn, entry.attributes).Bytes())
+			}
+			s.mu.Unlock()
+			conn.Write(ldapResult(messageID, ldapSearchResultDone, ldapResultSuccess).Bytes())
+
+		case ldapUnbindRequest:
+			return
+		}
+	}
+}
+
+func ldapStubMatch(filter *ber.Packet, attributes map[string][]string) bool {
+	switch filter.Tag {
+	case 0: // and
+		for _, child := range filter.Children {
+			if !ldapStubMatch(child, attributes) {
+				return false
+			}
+		}
+		return true
+	case 1: // or
+		for _, child := range filter.Children {
+			if ldapStubMatch(child, attributes) {
+				return true
+			}
+		}
+		return false
+	case 2: // not
+		return !ldapStubMatch(filter.Children[0], attributes)
+	case 3: // equalityMatch
+		name := filter.Children[0].Data.String()
+		value := filter.Children[1].Data.String()
+		for attr, values := range attributes {
+			if !strings.EqualFold(attr, name) {
+				continue
+			}
+			for _, v := range values {
+				if strings.EqualFold(v, value) {
+					return true
+				}
+			}
+		}
+		return false
+	case 7: // present
+		for attr := range attributes {
+			if strings.EqualFold(attr, filter.Data.String()) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
+
+func ldapEnvelope(messageID int64, op *ber.Packet) *ber.Packet {
+	envelope := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "LDAP Message")
+	envelope.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagInteger, messageID, "MessageID"))
+	envelope.AppendChild(op)
+	return envelope
+}
+
+func ldapResult(messageID int64, tag ber.Tag, code int64) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, tag, nil, "Result")
+	op.AppendChild(ber.NewInteger(ber.ClassUniversal, ber.TypePrimitive, ber.TagEnumerated, code, "resultCode"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "matchedDN"))
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, "", "diagnosticMessage"))
+	return ldapEnvelope(messageID, op)
+}
+
+func ldapSearchEntry(messageID int64, dn string, attributes map[string][]string) *ber.Packet {
+	op := ber.Encode(ber.ClassApplication, ber.TypeConstructed, ldapSearchResultEntry, nil, "Search Result Entry")
+	op.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, dn, "objectName"))
+
+	list := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attributes")
+	for name, values := range attributes {
+		attr := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSequence, nil, "attribute")
+		attr.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, name, "type"))
+		set := ber.Encode(ber.ClassUniversal, ber.TypeConstructed, ber.TagSet, nil, "vals")
+		for _, v := range values {
+			set.AppendChild(ber.NewString(ber.ClassUniversal, ber.TypePrimitive, ber.TagOctetString, v, "value"))
+		}
+		attr.AppendChild(set)
+		list.AppendChild(attr)
+	}
+	op.AppendChild(list)
+	return ldapEnvelope(messageID, op)
+}
@@ -0,0 +1,148 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/ldapauth"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+func newStaffDirectory(t *testing.T) (*ldapStub, ldapauth.Config) {
+	t.Helper()
+
+	dir := newLDAPStub(t)
+	dir.add("cn=service,dc=example,dc=com", "service-secret", map[string][]string{"objectClass": {"person"}})
+	dir.add("uid=alice,ou=people,dc=example,dc=com", "alice-password", map[string][]string{
+		"objectClass": {"person"},
+		"mail":        {"alice.staff@example.com"},
+		"cn":          {"Alice Staff"},
+	})
+	dir.add("cn=admins,ou=groups,dc=example,dc=com", "", map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"admins"},
+		"member":      {"uid=alice,ou=people,dc=example,dc=com"},
+	})
+
+	return dir, ldapauth.Config{
+		URL:            dir.url(),
+		BindDN:         "cn=service,dc=example,dc=com",
+		BindPassword:   "service-secret",
+		BaseDN:         "ou=people,dc=example,dc=com",
+		UserFilter:     "(&(objectClass=person)(mail=%s))",
+		EmailAttribute: "mail",
+		NameAttribute:  "cn",
+		GroupBaseDN:    "ou=groups,dc=example,dc=com",
+		GroupFilter:    "(&(objectClass=groupOfNames)(member=%s))",
+		RoleMapping:    []ldapauth.RoleMapping{{Group: "admins", Role: user.RoleAdmin}},
+		DefaultRole:    user.RoleUser,
+		Timeout:        5 * time.Second,
+	}
+}
+
+func TestLDAPAuthenticator(t *testing.T) {
+	_, cfg := newStaffDirectory(t)
+	authenticator := ldapauth.NewAuthenticator(cfg)
+
+	result, err := authenticator.Authenticate(context.Background(), "alice.staff@example.com", "alice-password")
+	if err != nil {
+		t.Fatalf("Authentification refusée : %v", err)
+	}
+	if result.Role != user.RoleAdmin || result.Name != "Alice Staff" || result.Subject != "uid=alice,ou=people,dc=example,dc=com" {
+		t.Errorf("Résultat inattendu : %+v", result)
+	}
+
+	cases := map[string]struct {
+		email, password string
+		expected        error
+	}{
+		"mauvais mot de passe": {"alice.staff@example.com", "wrong-password", apperror.ErrUnauthorized},
+		"mot de passe vide":    {"alice.staff@example.com", "", apperror.ErrUnauthorized},
+		"utilisateur inconnu":  {"nobody@example.com", "whatever123", apperror.ErrNotFound},
+		"injection de filtre":  {"*)(mail=*", "alice-password", apperror.ErrNotFound},
+	}
+	for name, tc := range cases {
+		_, err := authenticator.Authenticate(context.Background(), tc.email, tc.password)
+		if !errors.Is(err, tc.expected) {
+			t.Errorf("%s — Attendu : %v, Reçu : %v", name, tc.expected, err)
+		}
+	}
+}
+
+func TestLDAPLoginProvisionsUser(t *testing.T) {
+	_, cfg := newStaffDirectory(t)
+
+	users := user.NewMemoryUserStore()
+	userService := user.NewUserService(users, blacklist.NewMemoryStore(), oauth.NewOAuthService(nil), testAuth, testMailer, audit.NewAuditService(audit.NewMemoryStore()),
+		user.NewLocalAuthenticator(users), ldapauth.NewAuthenticator(cfg))
+	userHandler := user.NewUserHandler(userService)
+
+	r := gin.Default()
+	r.POST("/login", userHandler.Login)
+
+	login := func(password string) int {
+		payload, _ := json.Marshal(map[string]string{"email": "alice.staff@example.com", "password": password})
+		req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	for i := 0; i < 2; i++ {
+		if code := login("alice-password"); code != http.StatusOK {
+			t.Fatalf("Attendu : %d, Reçu : %d", http.StatusOK, code)
+		}
+	}
+	if code := login("wrong-password"); code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusUnauthorized, code)
+	}
+
+	account, _ := users.GetByEmail(context.Background(), "alice.staff@example.com")
+	if account == nil {
+		t.Fatalf("Le compte alice.staff@example.com aurait dû être provisionné")
+	}
+	if account, _ = users.FindByID(context.Background(), account.ID); account.Role != user.RoleAdmin {
+		t.Errorf("Attendu : rôle %q, Reçu : %q", user.RoleAdmin, account.Role)
+	}
+}
+
+func TestLDAPLoginDoesNotTakeOverRegisteredEmail(t *testing.T) {
+	_, cfg := newStaffDirectory(t)
+
+	users := user.NewMemoryUserStore()
+	userService := user.NewUserService(users, blacklist.NewMemoryStore(), oauth.NewOAuthService(nil), testAuth, testMailer, audit.NewAuditService(audit.NewMemoryStore()),
+		user.NewLocalAuthenticator(users), ldapauth.NewAuthenticator(cfg))
+	r := newUserTestRouter(userService, blacklist.NewMemoryStore(), nil)
+
+	// Un inconnu enregistre l'email d'un membre de l'annuaire avant sa première connexion
+	registerAndLogin(t, r, "alice.staff@example.com")
+
+	w := postJSON(r, "/login", map[string]string{"email": "alice.staff@example.com", "password": "alice-password"}, nil)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if body := decodeProblem(t, w); body["code"] != problem.CodeLinkRequired {
+		t.Errorf("Attendu : code %q, Reçu : %v", problem.CodeLinkRequired, body["code"])
+	}
+
+	account, _ := users.GetByEmail(context.Background(), "alice.staff@example.com")
+	if account, _ = users.FindByID(context.Background(), account.ID); account.Role != user.RoleUser {
+		t.Errorf("Le rôle de l'annuaire ne doit pas être recopié — Attendu : %q, Reçu : %q", user.RoleUser, account.Role)
+	}
+	if identities, _ := users.ListIdentities(context.Background(), account.ID); len(identities) != 1 {
+		t.Errorf("Attendu : 1 identité, Reçu : %d", len(identities))
+	}
+}
@@ -0,0 +1,354 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/apikey"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+const (
+	firefox = "Mozilla/5.0 (X11; Linux x86_64; rv:120.0) Gecko/20100101 Firefox/120.0"
+	chrome  = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+)
+
+// mailbox conserve les emails écrits par un LogMailer
+type mailbox struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (m *mailbox) Write(p []byte) (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.buf.Write(p)
+}
+
+// take retourne les emails reçus depuis le dernier appel
+func (m *mailbox) take() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	defer m.buf.Reset()
+	return m.buf.String()
+}
+
+var tokenParam = regexp.MustCompile(`\?token=([A-Za-z0-9._-]+)`)
+
+// linkToken extrait le jeton du lien contenu dans un email
+func linkToken(t *testing.T, email string) string {
+	t.Helper()
+
+	m := tokenParam.FindStringSubmatch(email)
+	if m == nil {
+		t.Fatalf("Aucun lien dans l'email : %q", email)
+	}
+	return m[1]
+}
+
+// newLoginHistoryRouter monte les routes de compte sur des stockages en mémoire, avec une boîte
+// aux lettres pour lire les emails envoyés
+func newLoginHistoryRouter(users user.UserStore, tokens blacklist.TokenStore) (*gin.Engine, *mailbox) {
+	box := &mailbox{}
+	userService := user.NewUserService(users, tokens, oauth.NewOAuthService(nil), testAuth, mail.NewLogMailer(box), audit.NewAuditService(audit.NewMemoryStore()))
+	return newUserTestRouter(userService, tokens, nil), box
+}
+
+// loginFrom se connecte depuis l'adresse ip avec le navigateur userAgent
+func loginFrom(r *gin.Engine, email, password, ip, userAgent string) (*httptest.ResponseRecorder, string, string) {
+	payload, _ := json.Marshal(map[string]string{"email": email, "password": password})
+	req, _ := http.NewRequest("POST", "/login", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.RemoteAddr = net.JoinHostPort(ip, "52000")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refreshToken"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response.Token, response.RefreshToken
+}
+
+func listLogins(t *testing.T, r *gin.Engine, token, query string) ([]user.LoginAttempt, int64) {
+	t.Helper()
+
+	w := webhookRequest(r, "GET", "/me/logins"+query, token)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	var response struct {
+		Logins     []user.LoginAttempt
+		NextBefore int64 `json:"next_before"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return response.Logins, response.NextBefore
+}
+
+func TestLoginHistoryAndAlerts(t *testing.T) {
+	r, box := newLoginHistoryRouter(user.NewMemoryUserStore(), blacklist.NewMemoryStore())
+	postJSON(r, "/register", map[string]string{"name": "Ada", "email": "ada@example.com", "password": "password123"}, nil)
+
+	// La première connexion du compte n'est jamais inhabituelle
+	if w, _, _ := loginFrom(r, "ada@example.com", "password123", "203.0.113.5", firefox); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusOK, w.Code)
+	}
+	if mails := box.take(); mails != "" {
+		t.Errorf("Aucune alerte attendue pour la première connexion : %q", mails)
+	}
+
+	if w, _, _ := loginFrom(r, "ada@example.com", "wrong-password", "192.0.2.1", chrome); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	// Même navigateur mis à jour, même réseau : pas d'alerte, et l'échec précédent ne compte pas
+	_, token, _ := loginFrom(r, "ada@example.com", "password123", "203.0.113.77", strings.ReplaceAll(firefox, "120.0", "121.0"))
+	if mails := box.take(); mails != "" {
+		t.Errorf("Aucune alerte attendue pour un appareil connu : %q", mails)
+	}
+
+	loginFrom(r, "ada@example.com", "password123", "203.0.113.5", chrome)
+	if mails := box.take(); !strings.Contains(mails, "To: ada@example.com") || !strings.Contains(mails, chrome) || !strings.Contains(mails, testAuth.LoginReportURL+"?token=") {
+		t.Errorf("Alerte attendue pour un nouvel appareil : %q", mails)
+	}
+	loginFrom(r, "ada@example.com", "password123", "2001:db8:1::1", firefox)
+	if mails := box.take(); !strings.Contains(mails, "2001:db8:1::1") {
+		t.Errorf("Alerte attendue pour un nouveau réseau : %q", mails)
+	}
+
+	logins, _ := listLogins(t, r, token, "")
+	if len(logins) != 5 {
+		t.Fatalf("Attendu : 5 tentatives, Reçu : %d", len(logins))
+	}
+	latest, newDevice, failed := logins[0], logins[1], logins[3]
+	if !latest.Success || latest.NewDevice || !latest.NewNetwork || latest.Network != "2001:db8:1::/48" {
+		t.Errorf("Nouveau réseau attendu : %+v", latest)
+	}
+	if !newDevice.NewDevice || newDevice.NewNetwork || newDevice.IP != "203.0.113.5" || newDevice.Network != "203.0.113.0/24" {
+		t.Errorf("Nouvel appareil attendu : %+v", newDevice)
+	}
+	if failed.Success || failed.Reason != "invalid_credentials" || failed.UserAgent != chrome || failed.NewDevice {
+		t.Errorf("Échec de connexion attendu : %+v", failed)
+	}
+
+	page, next := listLogins(t, r, token, "?limit=2")
+	if len(page) != 2 || next != page[1].ID {
+		t.Fatalf("Pagination inattendue : %d tentatives, next_before %d", len(page), next)
+	}
+	if page, _ = listLogins(t, r, token, "?limit=2&before="+strconv.FormatInt(next, 10)); len(page) != 2 || page[0].ID != next-1 {
+		t.Errorf("Page suivante inattendue : %+v", page)
+	}
+	if w := webhookRequest(r, "GET", "/me/logins?limit=500", token); w.Code != http.StatusBadRequest {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestReportLogin(t *testing.T) {
+	r, box := newLoginHistoryRouter(user.NewMemoryUserStore(), blacklist.NewMemoryStore())
+	postJSON(r, "/register", map[string]string{"name": "Ada", "email": "ada@example.com", "password": "password123"}, nil)
+
+	_, token, refreshToken := loginFrom(r, "ada@example.com", "password123", "203.0.113.5", firefox)
+	_, intruderToken, _ := loginFrom(r, "ada@example.com", "password123", "198.51.100.9", chrome)
+	reportToken := linkToken(t, box.take())
+
+	// Le jeton du lien n'est pas un jeton d'accès, et inversement
+	if w := webhookRequest(r, "GET", "/me", reportToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("Jeton de signalement utilisé comme jeton d'accès — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := postJSON(r, "/logins/report", map[string]string{"token": token}, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Jeton d'accès utilisé comme lien — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+
+	if w := postJSON(r, "/logins/report", map[string]string{"token": reportToken}, nil); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	resetMail := box.take()
+	if !strings.Contains(resetMail, testAuth.ResetURL+"?token=") {
+		t.Fatalf("Un lien de réinitialisation doit être envoyé : %q", resetMail)
+	}
+
+	// Toutes les sessions sont révoquées et l'ancien mot de passe ne fonctionne plus
+	for name, tok := range map[string]string{"utilisateur": token, "intrus": intruderToken} {
+		if w := webhookRequest(r, "GET", "/me", tok); w.Code != http.StatusUnauthorized {
+			t.Errorf("Session %s — Attendu : %d, Reçu : %d", name, http.StatusUnauthorized, w.Code)
+		}
+	}
+	if w := postJSON(r, "/refresh", nil, map[string]string{"Authorization": "Bearer " + refreshToken}); w.Code != http.StatusUnauthorized {
+		t.Errorf("Refresh token révoqué — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	if w, _, _ := loginFrom(r, "ada@example.com", "password123", "198.51.100.9", chrome); w.Code != http.StatusUnauthorized {
+		t.Errorf("Ancien mot de passe — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := postJSON(r, "/logins/report", map[string]string{"token": reportToken}, nil); w.Code != http.StatusUnauthorized {
+		t.Errorf("Lien déjà utilisé — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+
+	// Le nouveau mot de passe ouvre une nouvelle session, qui voit la connexion signalée
+	reset := map[string]string{"token": linkToken(t, resetMail), "new_password": "a-brand-new-password"}
+	if w := postJSON(r, "/reset-password", reset, nil); w.Code != http.StatusNoContent {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusNoContent, w.Code, w.Body.String())
+	}
+	_, token, _ = loginFrom(r, "ada@example.com", "a-brand-new-password", "203.0.113.5", firefox)
+	logins, _ := listLogins(t, r, token, "")
+	var reported []int64
+	for _, l := range logins {
+		if l.ReportedAt != nil {
+			reported = append(reported, l.ID)
+		}
+	}
+	if len(reported) != 1 || reported[0] != 2 {
+		t.Errorf("Seule la connexion de l'intrus doit être signalée : %v", reported)
+	}
+}
+
+func TestLoginHistorySQLite(t *testing.T) {
+	db := newSQLiteDB(t)
+	users, tokens := user.NewUserRepository(db), blacklist.NewBlacklistStore(db)
+	r, box := newLoginHistoryRouter(users, tokens)
+	postJSON(r, "/register", map[string]string{"name": "Ada", "email": "ada@example.com", "password": "password123"}, nil)
+
+	loginFrom(r, "ada@example.com", "password123", "203.0.113.5", firefox)
+	loginFrom(r, "ada@example.com", "wrong-password", "203.0.113.5", firefox)
+	_, token, _ := loginFrom(r, "ada@example.com", "password123", "198.51.100.9", firefox)
+	reportToken := linkToken(t, box.take())
+
+	logins, _ := listLogins(t, r, token, "")
+	if len(logins) != 3 || !logins[0].NewNetwork || logins[0].NewDevice || logins[1].Success || logins[1].NewNetwork {
+		t.Fatalf("Historique inattendu : %+v", logins)
+	}
+
+	if w := postJSON(r, "/logins/report", map[string]string{"token": reportToken}, nil); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	if w := webhookRequest(r, "GET", "/me", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	if generation, err := tokens.SessionGeneration(context.Background(), 1); err != nil || generation != 1 {
+		t.Errorf("Génération attendue : 1, Reçu : %d (%v)", generation, err)
+	}
+
+	// Les tentatives disparaissent avec le compte
+	if err := users.Delete(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	var remaining int
+	db.QueryRow("SELECT COUNT(*) FROM login_attempts").Scan(&remaining)
+	if remaining != 0 {
+		t.Errorf("Attendu : 0 tentative, Reçu : %d", remaining)
+	}
+}
+
+func TestReportLoginRevokesAPIKeys(t *testing.T) {
+	db := newSQLiteDB(t)
+	tokens := blacklist.NewBlacklistStore(db)
+	box := &mailbox{}
+	userService := user.NewUserService(user.NewUserRepository(db), tokens, oauth.NewOAuthService(nil), testAuth, mail.NewLogMailer(box), audit.NewAuditService(audit.NewMemoryStore()))
+	apiKeyService := apikey.NewAPIKeyService(apikey.NewAPIKeyRepository(db), userService, tokens)
+	r := newUserTestRouter(userService, tokens, apiKeyService)
+
+	postJSON(r, "/register", map[string]string{"name": "Ada", "email": "ada@example.com", "password": "password123"}, nil)
+	loginFrom(r, "ada@example.com", "password123", "203.0.113.5", firefox)
+	loginFrom(r, "ada@example.com", "password123", "198.51.100.9", chrome)
+	reportToken := linkToken(t, box.take())
+
+	// Une clé créée par l'intrus avant le signalement
+	key, _, err := apiKeyService.Create(context.Background(), 1, "intruder", nil, oauth.AllScopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := webhookRequest(r, "GET", "/me", key); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusOK, w.Code)
+	}
+
+	// Le lien ne sert qu'une fois, même présenté par deux requêtes simultanées
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			codes[i] = postJSON(r, "/logins/report", map[string]string{"token": reportToken}, nil).Code
+		}()
+	}
+	wg.Wait()
+	if !(codes[0] == http.StatusOK && codes[1] == http.StatusUnauthorized) && !(codes[0] == http.StatusUnauthorized && codes[1] == http.StatusOK) {
+		t.Fatalf("Attendu : un signalement accepté et un refusé, Reçu : %v", codes)
+	}
+
+	if w := webhookRequest(r, "GET", "/me", key); w.Code != http.StatusUnauthorized {
+		t.Errorf("Clé antérieure au signalement — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	if _, _, err := apiKeyService.Rotate(context.Background(), 1, 1); err == nil {
+		t.Error("Une clé révoquée avec les sessions ne peut pas être remplacée")
+	}
+	fresh, _, err := apiKeyService.Create(context.Background(), 1, "ci", nil, oauth.AllScopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := webhookRequest(r, "GET", "/me", fresh); w.Code != http.StatusOK {
+		t.Errorf("Clé postérieure au signalement — Attendu : %d, Reçu : %d", http.StatusOK, w.Code)
+	}
+}
+
+func TestForgotPasswordOnlyMailsToken(t *testing.T) {
+	r, box := newLoginHistoryRouter(user.NewMemoryUserStore(), blacklist.NewMemoryStore())
+	postJSON(r, "/register", map[string]string{"name": "Ada", "email": "ada@example.com", "password": "password123"}, nil)
+
+	known := postJSON(r, "/forgot-password", map[string]string{"email": "ada@example.com"}, nil)
+	unknown := postJSON(r, "/forgot-password", map[string]string{"email": "nobody@example.com"}, nil)
+	if known.Code != http.StatusOK || unknown.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d et %d", http.StatusOK, known.Code, unknown.Code)
+	}
+	if known.Body.String() != unknown.Body.String() {
+		t.Errorf("La réponse ne doit pas révéler si l'adresse est connue : %s / %s", known.Body.String(), unknown.Body.String())
+	}
+	token := linkToken(t, box.take())
+	if strings.Contains(known.Body.String(), token) {
+		t.Errorf("Le jeton de réinitialisation ne doit être remis que par email : %s", known.Body.String())
+	}
+}
+
+func TestResetPasswordIsSingleUseAndRevokesSessions(t *testing.T) {
+	r, box := newLoginHistoryRouter(user.NewMemoryUserStore(), blacklist.NewMemoryStore())
+	postJSON(r, "/register", map[string]string{"name": "Ada", "email": "ada@example.com", "password": "password123"}, nil)
+	_, token, _ := loginFrom(r, "ada@example.com", "password123", "203.0.113.5", firefox)
+	box.take()
+
+	postJSON(r, "/forgot-password", map[string]string{"email": "ada@example.com"}, nil)
+	resetToken := linkToken(t, box.take())
+
+	// Deux requêtes simultanées avec le même lien : une seule change le mot de passe
+	codes := make([]int, 2)
+	var wg sync.WaitGroup
+	for i := range codes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			reset := map[string]string{"token": resetToken, "new_password": "new-password-" + strconv.Itoa(i)}
+			codes[i] = postJSON(r, "/reset-password", reset, nil).Code
+		}()
+	}
+	wg.Wait()
+	if !(codes[0] == http.StatusNoContent && codes[1] == http.StatusUnauthorized) && !(codes[0] == http.StatusUnauthorized && codes[1] == http.StatusNoContent) {
+		t.Fatalf("Attendu : une réinitialisation acceptée et une refusée, Reçu : %v", codes)
+	}
+
+	if w := webhookRequest(r, "GET", "/me", token); w.Code != http.StatusUnauthorized {
+		t.Errorf("Session antérieure à la réinitialisation — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+}
@@ -0,0 +1,90 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/apperror"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+func TestMemoryUserStoreUniqueEmail(t *testing.T) {
+	store := user.NewMemoryUserStore()
+
+	first, err := store.Create(context.Background(), user.User{Name: "First", Email: "unique@example.com"}, user.Identity{Provider: user.PasswordProvider})
+	if err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	_, err = store.Create(context.Background(), user.User{Name: "Second", Email: "unique@example.com"}, user.Identity{Provider: user.PasswordProvider})
+	if !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("Attendu : ErrConflict, Reçu : %v", err)
+	}
+
+	second, _ := store.Create(context.Background(), user.User{Name: "Second", Email: "other@example.com"}, user.Identity{Provider: user.PasswordProvider})
+	err = store.Update(context.Background(), user.User{ID: second, Name: "Second", Email: "unique@example.com", Active: true})
+	if !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("Attendu : ErrConflict à la mise à jour, Reçu : %v", err)
+	}
+
+	u, _ := store.FindByID(context.Background(), first)
+	if u.Role != user.RoleUser || !u.Active {
+		t.Errorf("Attendu : compte actif de rôle %q, Reçu : %+v", user.RoleUser, u)
+	}
+}
+
+func TestMemoryUserStoreIdentities(t *testing.T) {
+	store := user.NewMemoryUserStore()
+	id, _ := store.Create(context.Background(), user.User{Name: "Linked", Email: "linked@example.com"}, user.Identity{Provider: user.PasswordProvider})
+
+	external := user.Identity{UserID: id, Provider: "github", Subject: "42"}
+	for i := 0; i < 2; i++ {
+		if err := store.CreateIdentity(context.Background(), external); err != nil {
+			t.Fatalf("Erreur inattendue : %v", err)
+		}
+	}
+
+	identities, _ := store.ListIdentities(context.Background(), id)
+	if len(identities) != 2 {
+		t.Fatalf("Attendu : 2 identités, Reçu : %d", len(identities))
+	}
+
+	if err := store.DeleteIdentity(context.Background(), id, identities[0].ID, "unusable"); err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	if _, err := store.Login(context.Background(), "linked@example.com", "anything"); err == nil {
+		t.Errorf("Le mot de passe aurait dû être rendu inutilisable")
+	}
+	if err := store.DeleteIdentity(context.Background(), id, identities[1].ID, "unusable"); !errors.Is(err, apperror.ErrConflict) {
+		t.Errorf("Attendu : ErrConflict, Reçu : %v", err)
+	}
+
+	if err := store.Delete(context.Background(), id); err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	if identity, _ := store.FindIdentity(context.Background(), "github", "42"); identity != nil {
+		t.Errorf("Les identités auraient dû être supprimées avec le compte")
+	}
+}
+
+func TestMemoryTokenStoreClaim(t *testing.T) {
+	store := blacklist.NewMemoryStore()
+	ctx := context.Background()
+
+	if first, _ := store.Claim(ctx, "refresh-token", time.Now().Add(time.Hour)); !first {
+		t.Errorf("Le premier Claim devrait réussir")
+	}
+	if again, _ := store.Claim(ctx, "refresh-token", time.Now().Add(time.Hour)); again {
+		t.Errorf("Un jeton déjà réclamé ne doit pas pouvoir l'être de nouveau")
+	}
+
+	store.Add(ctx, "expired-token", time.Now().Add(-time.Minute))
+	if revoked, _ := store.Contains(ctx, "expired-token"); revoked {
+		t.Errorf("Un jeton expiré ne doit plus être signalé comme révoqué")
+	}
+	if purged, _ := store.PurgeExpired(ctx); purged != 1 {
+		t.Errorf("Attendu : 1 jeton purgé, Reçu : %d", purged)
+	}
+}
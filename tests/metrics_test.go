@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/metrics"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAuthenticationMetrics(t *testing.T) {
+	users := user.NewMemoryUserStore()
+	service := newMemoryUserService(users, blacklist.NewMemoryStore())
+	ctx := context.Background()
+
+	count := func(result, reason string) float64 {
+		return testutil.ToFloat64(metrics.Logins.WithLabelValues(result, reason))
+	}
+	registrations := testutil.ToFloat64(metrics.Registrations.WithLabelValues(user.PasswordProvider))
+	succeeded := count(metrics.ResultSuccess, "")
+	invalid := count(metrics.ResultFailure, metrics.ReasonInvalidCredentials)
+	unknown := count(metrics.ResultFailure, metrics.ReasonUnknownUser)
+	locked := count(metrics.ResultFailure, metrics.ReasonAccountLocked)
+	lockouts := testutil.ToFloat64(metrics.Lockouts)
+
+	if err := service.Create(ctx, user.User{Name: "Metrics", Email: "metrics@example.com", Password: "password123"}); err != nil {
+		t.Fatal(err)
+	}
+	service.Login(ctx, "metrics@example.com", "password123", "", nil)
+	service.Login(ctx, "metrics@example.com", "wrong-password", "", nil)
+	service.Login(ctx, "ghost@example.com", "password123", "", nil)
+
+	account, _ := service.FindByEmail(ctx, "metrics@example.com")
+	if err := service.SetActive(ctx, account.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	service.SetActive(ctx, account.ID, false)
+	service.Login(ctx, "metrics@example.com", "password123", "", nil)
+
+	checks := []struct {
+		name          string
+		before, after float64
+	}{
+		{"registrations", registrations, testutil.ToFloat64(metrics.Registrations.WithLabelValues(user.PasswordProvider))},
+		{"success", succeeded, count(metrics.ResultSuccess, "")},
+		{"invalid_credentials", invalid, count(metrics.ResultFailure, metrics.ReasonInvalidCredentials)},
+		{"unknown_user", unknown, count(metrics.ResultFailure, metrics.ReasonUnknownUser)},
+		{"account_locked", locked, count(metrics.ResultFailure, metrics.ReasonAccountLocked)},
+		// Désactiver un compte déjà désactivé n'est pas un nouveau verrouillage
+		{"lockouts", lockouts, testutil.ToFloat64(metrics.Lockouts)},
+	}
+	for _, c := range checks {
+		if c.after-c.before != 1 {
+			t.Errorf("%s : Attendu +1, Reçu %+v", c.name, c.after-c.before)
+		}
+	}
+}
+
+func TestConsentRequiredMetrics(t *testing.T) {
+	db := newSQLiteDB(t)
+	oauthService := oauth.NewOAuthService(oauth.NewOAuthRepository(db))
+	service := user.NewUserService(user.NewUserRepository(db), blacklist.NewBlacklistStore(db), oauthService, testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	ctx := context.Background()
+
+	count := func(reason string) float64 {
+		return testutil.ToFloat64(metrics.Logins.WithLabelValues(metrics.ResultFailure, reason))
+	}
+	consent, internal := count(metrics.ReasonConsentRequired), count(metrics.ReasonInternal)
+
+	if err := service.Create(ctx, user.User{Name: "Consent", Email: "consent@example.com", Password: "password123"}); err != nil {
+		t.Fatal(err)
+	}
+	account, _ := service.FindByEmail(ctx, "consent@example.com")
+	client, err := oauthService.RegisterClient(ctx, account.ID, "Third party", []string{oauth.ScopeProfileRead})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Un client tiers que l'utilisateur n'a pas approuvé n'est pas une erreur interne
+	if _, _, err := service.Login(ctx, "consent@example.com", "password123", client.ClientID, nil); !errors.Is(err, oauth.ErrConsentRequired) {
+		t.Fatalf("Attendu : %v, Reçu : %v", oauth.ErrConsentRequired, err)
+	}
+	if got := count(metrics.ReasonConsentRequired) - consent; got != 1 {
+		t.Errorf("consent_required : Attendu +1, Reçu %+v", got)
+	}
+	if got := count(metrics.ReasonInternal) - internal; got != 0 {
+		t.Errorf("internal_error : Attendu +0, Reçu %+v", got)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	r := gin.New()
+	r.GET("/metrics", metrics.Handler())
+	r.Use(middleware.Metrics())
+	r.GET("/users/:id", func(c *gin.Context) { c.Status(http.StatusNoContent) })
+
+	for _, path := range []string{"/users/42", "/users/43", "/unknown/path"} {
+		req, _ := http.NewRequest("GET", path, nil)
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	tokens := blacklist.NewMemoryStore()
+	tokens.Add(context.Background(), "revoked-token", time.Now().Add(time.Hour))
+	if err := metrics.RegisterBlacklistSize(tokens.Count); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	body, _ := io.ReadAll(w.Body)
+	out := string(body)
+
+	for _, expected := range []string{
+		`authgo_http_request_duration_seconds_count{method="GET",route="/users/:id",status="204"}`,
+		`route="unmatched",status="404"`,
+		"authgo_blacklisted_tokens 1",
+		"go_goroutines",
+	} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("%q manque dans /metrics", expected)
+		}
+	}
+	if strings.Contains(out, "/users/42") || strings.Contains(out, `route="/metrics"`) {
+		t.Error("Les labels doivent porter le modèle de route, et la collecte ne doit pas être mesurée")
+	}
+}
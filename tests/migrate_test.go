@@ -0,0 +1,99 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"testing/fstest"
+
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0002_add_status.up.sql":     {Data: []byte("ALTER TABLE t ADD COLUMN status TEXT;")},
+		"m/0002_add_status.down.sql":   {Data: []byte("ALTER TABLE t DROP COLUMN status;")},
+		"m/0001_create_t.up.sql":       {Data: []byte("CREATE TABLE t (id INT);")},
+		"m/0001_create_t.down.sql":     {Data: []byte("DROP TABLE t;")},
+		"m/0010_create_other.up.sql":   {Data: []byte("CREATE TABLE o (id INT);")},
+		"m/0010_create_other.down.sql": {Data: []byte("DROP TABLE o;")},
+	}
+
+	migrations, err := database.LoadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("Erreur de chargement : %v", err)
+	}
+	if len(migrations) != 3 || migrations[0].Version != 1 || migrations[1].Version != 2 || migrations[2].Version != 10 {
+		t.Fatalf("Ordre inattendu : %+v", migrations)
+	}
+	if migrations[1].Name != "add_status" || !strings.Contains(migrations[1].Down, "DROP COLUMN status") {
+		t.Errorf("Migration inattendue : %+v", migrations[1])
+	}
+
+	cases := map[string]fstest.MapFS{
+		"script down manquant": {"m/0001_create_t.up.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		"nom invalide":         {"m/create_t.sql": {Data: []byte("CREATE TABLE t (id INT);")}},
+		"noms en conflit": {
+			"m/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"m/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+	}
+	for name, fsys := range cases {
+		if _, err := database.LoadMigrations(fsys, "m"); err == nil {
+			t.Errorf("%s — Attendu : une erreur", name)
+		}
+	}
+}
+
+func TestMigratorUpDownStatus(t *testing.T) {
+	db := testDB(t)
+
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Erreur de chargement des migrations : %v", err)
+	}
+	ctx := context.Background()
+
+	// ConnectTestDB a déjà tout appliqué : plusieurs Up concurrents ne font rien
+	var wg sync.WaitGroup
+	errs := make(chan error, 3)
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			applied, err := migrator.Up(ctx)
+			if err == nil && len(applied) != 0 {
+				err = fmt.Errorf("%d migration(s) réappliquée(s)", len(applied))
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("Up concurrent inattendu : %v", err)
+		}
+	}
+
+	reverted, err := migrator.Down(ctx, 1)
+	if err != nil || len(reverted) != 1 {
+		t.Fatalf("Down : %v (%d annulée(s))", err, len(reverted))
+	}
+
+	statuses, err := migrator.Status(ctx)
+	if err != nil {
+		t.Fatalf("Status : %v", err)
+	}
+	last := statuses[len(statuses)-1]
+	if last.Version != reverted[0].Version || last.AppliedAt != nil {
+		t.Errorf("Attendu : migration %d en attente, Reçu : %+v", reverted[0].Version, last)
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil || len(applied) != 1 || applied[0].Version != reverted[0].Version {
+		t.Errorf("Up : %v (%d appliquée(s))", err, len(applied))
+	}
+}
@@ -0,0 +1,117 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+)
+
+func decodeProblem(t *testing.T, w *httptest.ResponseRecorder) map[string]interface{} {
+	t.Helper()
+
+	if ct := w.Header().Get("Content-Type"); ct != problem.ContentType {
+		t.Errorf("Attendu : Content-Type %q, Reçu : %q", problem.ContentType, ct)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Corps illisible : %v (%s)", err, w.Body.String())
+	}
+	return body
+}
+
+func postJSON(r *gin.Engine, path string, payload interface{}, headers map[string]string) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(payload)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestProblemFieldErrors(t *testing.T) {
+	w := postJSON(testRouter, "/register", map[string]string{"name": "P", "email": "not-an-email"}, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusBadRequest, w.Code)
+	}
+
+	body := decodeProblem(t, w)
+	if body["code"] != problem.CodeInvalidRequest || body["status"] != float64(http.StatusBadRequest) || body["instance"] != "/register" {
+		t.Errorf("Problème inattendu : %v", body)
+	}
+	if body["correlation_id"] == "" || body["correlation_id"] != w.Header().Get(problem.CorrelationHeader) {
+		t.Errorf("L'identifiant de corrélation doit être renvoyé dans le corps et l'en-tête : %v", body)
+	}
+
+	fields := map[string]string{}
+	for _, e := range body["errors"].([]interface{}) {
+		fe := e.(map[string]interface{})
+		fields[fe["field"].(string)] = fe["code"].(string)
+	}
+	expected := map[string]string{"name": "min", "email": "email", "password": "required"}
+	for field, code := range expected {
+		if fields[field] != code {
+			t.Errorf("%s — Attendu : %q, Reçu : %q (%v)", field, code, fields[field], fields)
+		}
+	}
+}
+
+func TestProblemCodes(t *testing.T) {
+	registerAndLogin(t, testRouter, "problem@example.com")
+
+	cases := []struct {
+		name, path string
+		payload    map[string]string
+		status     int
+		code       string
+	}{
+		{"email déjà utilisé", "/register",
+			map[string]string{"name": "Again", "email": "problem@example.com", "password": "password123"},
+			http.StatusConflict, problem.CodeEmailInUse},
+		{"mauvais mot de passe", "/login",
+			map[string]string{"email": "problem@example.com", "password": "wrong-password"},
+			http.StatusUnauthorized, problem.CodeInvalidCredentials},
+	}
+	for _, tc := range cases {
+		w := postJSON(testRouter, tc.path, tc.payload, map[string]string{problem.CorrelationHeader: "client-trace-42"})
+		body := decodeProblem(t, w)
+		if w.Code != tc.status || body["code"] != tc.code {
+			t.Errorf("%s — Attendu : %d %s, Reçu : %d %v", tc.name, tc.status, tc.code, w.Code, body)
+		}
+		if body["correlation_id"] != "client-trace-42" {
+			t.Errorf("%s — L'identifiant fourni par le client doit être conservé : %v", tc.name, body["correlation_id"])
+		}
+	}
+}
+
+func TestProblemHidesTokenErrors(t *testing.T) {
+	w := authorizedRequest("GET", "/me", "not-a-jwt")
+	body := decodeProblem(t, w)
+	if w.Code != http.StatusUnauthorized || body["code"] != problem.CodeInvalidToken || body["detail"] != "Token invalide" {
+		t.Errorf("Le détail ne doit pas reprendre l'erreur du parseur JWT : %d %v", w.Code, body)
+	}
+}
+
+func TestProblemExtensions(t *testing.T) {
+	r := gin.New()
+	r.Use(middleware.RequestID(logging.Discard()))
+	r.GET("/scoped", middleware.RequireScope("admin"), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	req, _ := http.NewRequest("GET", "/scoped", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	body := decodeProblem(t, w)
+	if w.Code != http.StatusForbidden || body["code"] != problem.CodeInsufficientScope || body["required_scope"] != "admin" {
+		t.Errorf("Problème inattendu : %d %v", w.Code, body)
+	}
+}
@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/health"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+)
+
+func newHealthRouter(checks *health.Registry) *gin.Engine {
+	r := gin.New()
+	r.GET("/livez", checks.Live)
+	r.GET("/readyz", checks.Ready)
+	return r
+}
+
+type readiness struct {
+	Status string          `json:"status"`
+	Reason string          `json:"reason"`
+	Checks []health.Result `json:"checks"`
+}
+
+func getReadiness(t *testing.T, r *gin.Engine, path string) (int, readiness) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var body readiness
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("Réponse JSON attendue : %s", w.Body.String())
+	}
+	return w.Code, body
+}
+
+func TestReadinessChecks(t *testing.T) {
+	db := newSQLiteDB(t)
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checks := health.NewRegistry(100 * time.Millisecond)
+	checks.Register("database", health.Database(db))
+	checks.Register("migrations", health.Migrations(migrator))
+	checks.Register("mailer", health.Mailer(testMailer))
+	checks.Register("signing_key", health.SigningKey(testAuth.JWTSecret))
+	r := newHealthRouter(checks)
+
+	code, body := getReadiness(t, r, "/readyz")
+	if code != http.StatusOK || body.Status != health.StatusOK || body.Checks != nil {
+		t.Fatalf("Service prêt attendu, sans détail : %d %+v", code, body)
+	}
+
+	code, body = getReadiness(t, r, "/readyz?verbose")
+	if code != http.StatusOK || len(body.Checks) != 4 || body.Checks[0].Name != "database" {
+		t.Errorf("Le mode verbeux doit détailler chaque contrôle : %+v", body)
+	}
+
+	// Une dépendance lente est abandonnée à l'échéance et rend le service indisponible
+	checks.Register("slow", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	code, body = getReadiness(t, r, "/readyz?verbose")
+	if code != http.StatusServiceUnavailable || body.Status != health.StatusFail {
+		t.Fatalf("Attendu : %d, Reçu : %d %+v", http.StatusServiceUnavailable, code, body)
+	}
+	last := body.Checks[len(body.Checks)-1]
+	if last.Name != "slow" || last.Status != health.StatusFail || !strings.Contains(last.Error, "deadline exceeded") {
+		t.Errorf("Le contrôle en échec doit être signalé avec sa cause : %+v", last)
+	}
+
+	// Base fermée : le ping échoue, le processus reste vivant
+	db.Close()
+	if code, _ := getReadiness(t, r, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("Une base indisponible doit faire échouer /readyz, Reçu : %d", code)
+	}
+	if code, body := getReadiness(t, r, "/livez"); code != http.StatusOK || body.Status != health.StatusOK {
+		t.Errorf("/livez ne dépend pas des contrôles : %d %+v", code, body)
+	}
+}
+
+func TestReadinessPendingMigrations(t *testing.T) {
+	db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "empty.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Up(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrator.Down(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	err = health.Migrations(migrator)(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "1 pending migration(s)") {
+		t.Errorf("Une migration en attente doit être signalée : %v", err)
+	}
+}
+
+func TestReadinessFailsWhileDraining(t *testing.T) {
+	checks := health.NewRegistry(time.Second)
+	checks.Register("ok", func(context.Context) error { return nil })
+	r := newHealthRouter(checks)
+
+	checks.Drain()
+	code, body := getReadiness(t, r, "/readyz")
+	if code != http.StatusServiceUnavailable || body.Reason != "shutting down" {
+		t.Errorf("/readyz doit échouer pendant l'arrêt : %d %+v", code, body)
+	}
+	if code, _ := getReadiness(t, r, "/livez"); code != http.StatusOK {
+		t.Errorf("/livez doit répondre pendant l'arrêt, Reçu : %d", code)
+	}
+}
+
+// fakeSMTP est un serveur SMTP minimal qui retourne le message reçu par DATA
+func fakeSMTP(t *testing.T) (config.Mail, <-chan string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	messages := make(chan string, 1)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, messages)
+		}
+	}()
+
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	cfg := config.Default().Mail
+	cfg.SMTPHost = host
+	cfg.SMTPPort, _ = strconv.Atoi(port)
+	return cfg, messages
+}
+
+func serveSMTP(conn net.Conn, messages chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 fake ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch verb := strings.ToUpper(strings.Fields(line + " x")[0]); verb {
+		case "EHLO", "HELO", "MAIL", "RCPT", "NOOP":
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := reader.ReadString('\n')
+				if err != nil || l == ".\r\n" {
+					break
+				}
+				data.WriteString(l)
+			}
+			messages <- data.String()
+			reply("250 queued")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func TestSMTPMailer(t *testing.T) {
+	cfg, messages := fakeSMTP(t)
+	mailer := mail.New(cfg, nil)
+
+	if err := health.Mailer(mailer)(context.Background()); err != nil {
+		t.Fatalf("Le serveur SMTP doit être joignable : %v", err)
+	}
+
+	err := mailer.Send(context.Background(), mail.Message{To: "ada@example.com", Subject: "Réinitialisation", Body: "Bonjour\nAda"})
+	if err != nil {
+		t.Fatalf("Envoi impossible : %v", err)
+	}
+	data := <-messages
+	for _, expected := range []string{"To: ada@example.com", "Subject: =?utf-8?q?R=C3=A9initialisation?=", "Bonjour\r\nAda"} {
+		if !strings.Contains(data, expected) {
+			t.Errorf("%q manque dans le message :\n%s", expected, data)
+		}
+	}
+
+	cfg.SMTPPort = 1
+	var opErr *net.OpError
+	if err := mail.New(cfg, nil).Check(context.Background()); !errors.As(err, &opErr) {
+		t.Errorf("Un serveur SMTP injoignable doit être signalé : %v", err)
+	}
+}
@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/base64"
+	"encoding/xml"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/crewjam/saml"
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/samlauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+func newSAMLKeyPair(t *testing.T, commonName string) (*rsa.PrivateKey, *x509.Certificate) {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Erreur de génération de clé : %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Erreur de génération de certificat : %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return key, cert
+}
+
+// spMetadataProvider sert à l'IdP de test les métadonnées publiées par notre SP
+type spMetadataProvider struct {
+	metadata *saml.EntityDescriptor
+}
+
+func (p *spMetadataProvider) GetServiceProvider(r *http.Request, serviceProviderID string) (*saml.EntityDescriptor, error) {
+	if p.metadata == nil || p.metadata.EntityID != serviceProviderID {
+		return nil, os.ErrNotExist
+	}
+	return p.metadata, nil
+}
+
+type samlFixture struct {
+	db     *sql.DB
+	router *gin.Engine
+	idp    *saml.IdentityProvider
+	sps    *spMetadataProvider
+}
+
+func newSAMLFixture(t *testing.T, db *sql.DB) *samlFixture {
+	t.Helper()
+
+	db.Exec("DELETE FROM users WHERE email = 'saml.user@acme.test'")
+
+	idpKey, idpCert := newSAMLKeyPair(t, "idp.acme.test")
+	spKey, spCert := newSAMLKeyPair(t, "sp.localhost")
+
+	f := &samlFixture{db: db, sps: &spMetadataProvider{}}
+	f.idp = &saml.IdentityProvider{
+		Key:                     idpKey,
+		Certificate:             idpCert,
+		MetadataURL:             url.URL{Scheme: "https", Host: "idp.acme.test", Path: "/metadata"},
+		SSOURL:                  url.URL{Scheme: "https", Host: "idp.acme.test", Path: "/sso"},
+		ServiceProviderProvider: f.sps,
+	}
+	idpMetadata, err := xml.Marshal(f.idp.Metadata())
+	if err != nil {
+		t.Fatalf("Erreur de sérialisation des métadonnées IdP : %v", err)
+	}
+
+	userService := user.NewUserService(user.NewUserRepository(db), blacklist.NewBlacklistStore(db), oauth.NewOAuthService(oauth.NewOAuthRepository(db)), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	samlService := samlauth.NewSAMLService(samlauth.NewSAMLRepository(db), userService, samlauth.SPConfig{
+		BaseURL:     url.URL{Scheme: "http", Host: "localhost", Path: "/saml"},
+		Key:         spKey,
+		Certificate: spCert,
+		StateSecret: []byte(testAuth.JWTSecret),
+	})
+
+	mapping := samlauth.DefaultAttributeMapping
+	mapping.Role = "groups"
+	mapping.RoleValues = map[string]string{"acme-admins": user.RoleAdmin}
+	if _, err := samlService.SaveConnection(t.Context(), "acme", string(idpMetadata), "", &mapping); err != nil {
+		t.Fatalf("Erreur d'enregistrement de la connexion SAML : %v", err)
+	}
+
+	handler := samlauth.NewSAMLHandler(samlService)
+	f.router = gin.Default()
+	f.router.GET("/saml/:tenant/metadata", handler.Metadata)
+	f.router.GET("/saml/:tenant/login", handler.Login)
+	f.router.POST("/saml/:tenant/acs", handler.ACS)
+
+	req, _ := http.NewRequest("GET", "/saml/acme/metadata", nil)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusOK, w.Code)
+	}
+	f.sps.metadata = &saml.EntityDescriptor{}
+	if err := xml.Unmarshal(w.Body.Bytes(), f.sps.metadata); err != nil {
+		t.Fatalf("Métadonnées SP invalides : %v", err)
+	}
+	return f
+}
+
+// samlResponse joue le parcours SP-initié : redirection vers l'IdP puis réponse signée de l'IdP
+func (f *samlFixture) samlResponse(t *testing.T) (string, []*http.Cookie) {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/saml/acme/login", nil)
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusFound, w.Code)
+	}
+
+	idpReq, _ := http.NewRequest("GET", w.Header().Get("Location"), nil)
+	authnRequest, err := saml.NewIdpAuthnRequest(f.idp, idpReq)
+	if err != nil {
+		t.Fatalf("AuthnRequest illisible : %v", err)
+	}
+	if err := authnRequest.Validate(); err != nil {
+		t.Fatalf("AuthnRequest refusée par l'IdP : %v", err)
+	}
+
+	session := &saml.Session{
+		NameID:       "saml.user@acme.test",
+		NameIDFormat: string(saml.EmailAddressNameIDFormat),
+		CustomAttributes: []saml.Attribute{
+			{Name: "email", Values: []saml.AttributeValue{{Type: "xs:string", Value: "saml.user@acme.test"}}},
+			{Name: "displayName", Values: []saml.AttributeValue{{Type: "xs:string", Value: "Saml User"}}},
+			{Name: "groups", Values: []saml.AttributeValue{{Type: "xs:string", Value: "acme-admins"}}},
+		},
+	}
+	if err := (saml.DefaultAssertionMaker{}).MakeAssertion(authnRequest, session); err != nil {
+		t.Fatalf("Erreur de génération de l'assertion : %v", err)
+	}
+	form, err := authnRequest.PostBinding()
+	if err != nil {
+		t.Fatalf("Erreur de génération de la réponse : %v", err)
+	}
+	return form.SAMLResponse, w.Result().Cookies()
+}
+
+func (f *samlFixture) postACS(samlResponse string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	form := url.Values{"SAMLResponse": {samlResponse}}
+	req, _ := http.NewRequest("POST", "/saml/acme/acs", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, c := range cookies {
+		req.AddCookie(c)
+	}
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestSAMLLoginProvisionsUser(t *testing.T) {
+	testSAMLLoginProvisionsUser(t, newSAMLFixture(t, testDB(t)))
+}
+
+func testSAMLLoginProvisionsUser(t *testing.T, f *samlFixture) {
+
+	for i := 0; i < 2; i++ {
+		response, cookies := f.samlResponse(t)
+		w := f.postACS(response, cookies)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+		}
+		if !strings.Contains(w.Body.String(), "refreshToken") {
+			t.Errorf("Jetons absents de la réponse : %s", w.Body.String())
+		}
+	}
+}
+
+func TestSAMLRejectsInvalidResponses(t *testing.T) {
+	f := newSAMLFixture(t, testDB(t))
+
+	response, cookies := f.samlResponse(t)
+	raw, _ := base64.StdEncoding.DecodeString(response)
+	tampered := base64.StdEncoding.EncodeToString([]byte(strings.Replace(string(raw), "http://localhost/saml/acme/acs", "http://localhost/saml/other/acs", 1)))
+
+	cases := map[string]struct {
+		response string
+		cookies  []*http.Cookie
+	}{
+		"réponse altérée": {tampered, cookies},
+		"état absent":     {response, nil},
+	}
+	for name, tc := range cases {
+		if w := f.postACS(tc.response, tc.cookies); w.Code != http.StatusUnauthorized {
+			t.Errorf("%s — Attendu : %d, Reçu : %d", name, http.StatusUnauthorized, w.Code)
+		}
+	}
+
+	// Une réponse valide ne peut pas être rejouée pour une autre requête
+	_, otherCookies := f.samlResponse(t)
+	if w := f.postACS(response, otherCookies); w.Code != http.StatusUnauthorized {
+		t.Errorf("rejeu — Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+}
+
+func TestSAMLLoginOnlyLinksTenantAccounts(t *testing.T) {
+	testSAMLLoginOnlyLinksTenantAccounts(t, newSAMLFixture(t, testDB(t)))
+}
+
+func testSAMLLoginOnlyLinksTenantAccounts(t *testing.T, f *samlFixture) {
+
+	// Un compte inscrit hors du tenant avec l'email de l'assertion ne peut pas être ouvert par l'IdP
+	var userID int
+	err := f.db.QueryRow(
+		"INSERT INTO users (name, age, mobile_number, email, password) VALUES ('Local User', 30, '', 'saml.user@acme.test', 'x') RETURNING id").Scan(&userID)
+	if err != nil {
+		t.Fatalf("Erreur lors de la création du compte : %v", err)
+	}
+
+	response, cookies := f.samlResponse(t)
+	w := f.postACS(response, cookies)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusConflict, w.Code, w.Body.String())
+	}
+	var role string
+	f.db.QueryRow("SELECT role FROM users WHERE id = $1", userID).Scan(&role)
+	if role != user.RoleUser {
+		t.Errorf("Attendu : rôle %q, Reçu : %q", user.RoleUser, role)
+	}
+
+	// Provisionné par le tenant, le même compte est rattaché à l'identité SAML
+	if _, err := f.db.Exec("INSERT INTO scim_users (tenant, user_id) VALUES ('acme', $1)", userID); err != nil {
+		t.Fatalf("Erreur lors du rattachement au tenant : %v", err)
+	}
+	response, cookies = f.samlResponse(t)
+	if w := f.postACS(response, cookies); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	f.db.QueryRow("SELECT role FROM users WHERE id = $1", userID).Scan(&role)
+	if role != user.RoleAdmin {
+		t.Errorf("Attendu : rôle %q, Reçu : %q", user.RoleAdmin, role)
+	}
+}
@@ -0,0 +1,230 @@
+package tests
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/scim"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+func newSCIMTestRouter(t *testing.T, db *sql.DB) (*gin.Engine, string) {
+	t.Helper()
+
+	db.Exec("DELETE FROM users WHERE email LIKE '%@scim.test'")
+	db.Exec("DELETE FROM groups WHERE tenant = 'scim-test'")
+	db.Exec("DELETE FROM scim_role_mappings WHERE tenant = 'scim-test'")
+
+	userService := user.NewUserService(user.NewUserRepository(db), blacklist.NewBlacklistStore(db), oauth.NewOAuthService(oauth.NewOAuthRepository(db)), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	scimService := scim.NewSCIMService(scim.NewSCIMRepository(db), userService)
+	token, err := scimService.IssueToken(t.Context(), "scim-test")
+	if err != nil {
+		t.Fatalf("Erreur de création du jeton SCIM : %v", err)
+	}
+
+	h := scim.NewSCIMHandler(scimService)
+	r := gin.Default()
+	v2 := r.Group("/scim/v2", h.Authenticate)
+	v2.GET("/Users", h.ListUsers)
+	v2.POST("/Users", h.CreateUser)
+	v2.GET("/Users/:id", h.GetUser)
+	v2.PUT("/Users/:id", h.ReplaceUser)
+	v2.PATCH("/Users/:id", h.PatchUser)
+	v2.DELETE("/Users/:id", h.DeleteUser)
+	v2.POST("/Groups", h.CreateGroup)
+	v2.GET("/Groups", h.ListGroups)
+	v2.PATCH("/Groups/:id", h.PatchGroup)
+	v2.DELETE("/Groups/:id", h.DeleteGroup)
+	r.PUT("/scim/roles/:tenant", h.SetRoleMapping)
+	return r, token
+}
+
+func scimRequest(r *gin.Engine, token, method, path string, body interface{}) (*httptest.ResponseRecorder, map[string]interface{}) {
+	var payload []byte
+	if body != nil {
+		payload, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/scim+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	return w, response
+}
+
+func TestSCIMFilterParsing(t *testing.T) {
+	allowed := map[string]string{"username": "userName", "active": "active"}
+
+	conditions, err := scim.ParseFilter(`userName eq "Alice@Example.com" and active eq true`, allowed)
+	if err != nil || len(conditions) != 2 {
+		t.Fatalf("Filtre refusé : %v", err)
+	}
+	if conditions[0].Attribute != "userName" || conditions[0].Value != "Alice@Example.com" || conditions[1].Value != "true" {
+		t.Errorf("Conditions inattendues : %+v", conditions)
+	}
+
+	for _, filter := range []string{
+		`userName co "alice"`,
+		`password eq "x"`,
+		`userName eq "alice`,
+		`userName eq "a" or active eq true`,
+		`userName eq`,
+	} {
+		if _, err := scim.ParseFilter(filter, allowed); !errors.Is(err, scim.ErrInvalidFilter) {
+			t.Errorf("%s — Attendu : invalid filter, Reçu : %v", filter, err)
+		}
+	}
+}
+
+func TestSCIMRejectsInvalidToken(t *testing.T) {
+	r, _ := newSCIMTestRouter(t, testDB(t))
+
+	w, body := scimRequest(r, "scim_not-a-real-token", "GET", "/scim/v2/Users", nil)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusUnauthorized, w.Code)
+	}
+	if body["status"] != "401" {
+		t.Errorf("Format d'erreur SCIM attendu, Reçu : %s", w.Body.String())
+	}
+}
+
+func TestSCIMUserLifecycle(t *testing.T) {
+	testSCIMUserLifecycle(t, testDB(t))
+}
+
+func testSCIMUserLifecycle(t *testing.T, db *sql.DB) {
+	r, token := newSCIMTestRouter(t, db)
+
+	w, created := scimRequest(r, token, "POST", "/scim/v2/Users", map[string]interface{}{
+		"schemas":      []string{scim.UserSchema},
+		"userName":     "jane@scim.test",
+		"externalId":   "00u-jane",
+		"name":         map[string]string{"givenName": "Jane", "familyName": "Doe"},
+		"phoneNumbers": []map[string]string{{"value": "+33600000000", "type": "mobile"}},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusCreated, w.Code, w.Body.String())
+	}
+	id, _ := created["id"].(string)
+	if created["displayName"] != "Jane Doe" || created["active"] != true {
+		t.Errorf("Ressource inattendue : %s", w.Body.String())
+	}
+
+	if w, _ := scimRequest(r, token, "POST", "/scim/v2/Users", map[string]interface{}{"userName": "jane@scim.test"}); w.Code != http.StatusConflict {
+		t.Errorf("doublon — Attendu : %d, Reçu : %d", http.StatusConflict, w.Code)
+	}
+	// Le compte n'est pas créé lorsque son rattachement au tenant échoue
+	if w, _ := scimRequest(r, token, "POST", "/scim/v2/Users", map[string]interface{}{"userName": "john@scim.test", "externalId": "00u-jane"}); w.Code != http.StatusConflict {
+		t.Errorf("externalId en double — Attendu : %d, Reçu : %d", http.StatusConflict, w.Code)
+	}
+	var orphans int
+	db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'john@scim.test'").Scan(&orphans)
+	if orphans != 0 {
+		t.Errorf("Attendu : aucun compte orphelin, Reçu : %d", orphans)
+	}
+	if w, body := scimRequest(r, token, "GET", `/scim/v2/Users?filter=userName+co+"jane"`, nil); w.Code != http.StatusBadRequest || body["scimType"] != "invalidFilter" {
+		t.Errorf("filtre invalide — Attendu : %d invalidFilter, Reçu : %d (%s)", http.StatusBadRequest, w.Code, w.Body.String())
+	}
+
+	w, list := scimRequest(r, token, "GET", `/scim/v2/Users?filter=userName+eq+"JANE@scim.test"&count=10`, nil)
+	if w.Code != http.StatusOK || list["totalResults"] != float64(1) {
+		t.Fatalf("Attendu : 1 résultat, Reçu : %d (%s)", w.Code, w.Body.String())
+	}
+
+	// Désactivation façon Azure AD : op en majuscule et booléen sous forme de chaîne
+	w, patched := scimRequest(r, token, "PATCH", "/scim/v2/Users/"+id, map[string]interface{}{
+		"schemas": []string{scim.PatchOpSchema},
+		"Operations": []map[string]interface{}{
+			{"op": "Replace", "path": "active", "value": "False"},
+			{"op": "replace", "path": "displayName", "value": "Jane Smith"},
+		},
+	})
+	if w.Code != http.StatusOK || patched["active"] != false || patched["displayName"] != "Jane Smith" {
+		t.Fatalf("PATCH inattendu : %d (%s)", w.Code, w.Body.String())
+	}
+
+	if w, _ := scimRequest(r, token, "DELETE", "/scim/v2/Users/"+id, nil); w.Code != http.StatusNoContent {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusNoContent, w.Code)
+	}
+	if w, _ := scimRequest(r, token, "GET", "/scim/v2/Users/"+id, nil); w.Code != http.StatusNotFound {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusNotFound, w.Code)
+	}
+}
+
+func TestSCIMGroupsDriveRole(t *testing.T) {
+	testSCIMGroupsDriveRole(t, testDB(t))
+}
+
+func testSCIMGroupsDriveRole(t *testing.T, db *sql.DB) {
+	r, token := newSCIMTestRouter(t, db)
+
+	_, member := scimRequest(r, token, "POST", "/scim/v2/Users", map[string]interface{}{"userName": "ops@scim.test", "displayName": "Ops User"})
+	memberID, _ := member["id"].(string)
+
+	w, group := scimRequest(r, token, "POST", "/scim/v2/Groups", map[string]interface{}{
+		"schemas":                 []string{scim.GroupSchema, scim.GroupExtensionSchema},
+		"displayName":             "Administrators",
+		"members":                 []map[string]string{{"value": memberID}},
+		scim.GroupExtensionSchema: map[string]string{"role": user.RoleAdmin},
+	})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusCreated, w.Code, w.Body.String())
+	}
+	groupID, _ := group["id"].(string)
+
+	role := func() string {
+		var role string
+		db.QueryRow("SELECT role FROM users WHERE email = 'ops@scim.test'").Scan(&role)
+		return role
+	}
+	// Le rôle demandé par le client SCIM est ignoré : seul un administrateur l'accorde
+	if got := role(); got != user.RoleUser {
+		t.Errorf("rôle choisi par le client — Attendu : %q, Reçu : %q", user.RoleUser, got)
+	}
+	if _, ok := group[scim.GroupExtensionSchema]; ok {
+		t.Errorf("Le groupe ne doit porter aucun rôle : %s", w.Body.String())
+	}
+
+	payload, _ := json.Marshal(map[string]interface{}{"groups": map[string]string{"Administrators": user.RoleAdmin}})
+	req, _ := http.NewRequest("PUT", "/scim/roles/scim-test", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := role(); got != user.RoleAdmin {
+		t.Errorf("Attendu : %q, Reçu : %q", user.RoleAdmin, got)
+	}
+
+	w, _ = scimRequest(r, token, "PATCH", "/scim/v2/Groups/"+groupID, map[string]interface{}{
+		"schemas":    []string{scim.PatchOpSchema},
+		"Operations": []map[string]interface{}{{"op": "remove", "path": `members[value eq "` + memberID + `"]`}},
+	})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+	if got := role(); got != user.RoleUser {
+		t.Errorf("Attendu : %q, Reçu : %q", user.RoleUser, got)
+	}
+
+	w, _ = scimRequest(r, token, "POST", "/scim/v2/Groups", map[string]interface{}{
+		"displayName": "Outsiders",
+		"members":     []map[string]string{{"value": "999999"}},
+	})
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("membre d'un autre tenant — Attendu : %d, Reçu : %d", http.StatusBadRequest, w.Code)
+	}
+}
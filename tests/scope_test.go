@@ -0,0 +1,53 @@
+package tests
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+)
+
+func TestRequireScope(t *testing.T) {
+	r := gin.Default()
+	r.GET("/scoped", func(c *gin.Context) {
+		c.Set("scopes", oauth.ParseScope(c.Query("scope")))
+		c.Next()
+	}, middleware.RequireScope(oauth.ScopeProfileRead), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	cases := []struct {
+		scope    string
+		expected int
+	}{
+		{"profile:read", http.StatusOK},
+		{"api_keys:read profile:read", http.StatusOK},
+		{"api_keys:read", http.StatusForbidden},
+		{"", http.StatusForbidden},
+	}
+
+	for _, tc := range cases {
+		req, _ := http.NewRequest("GET", "/scoped?scope="+url.QueryEscape(tc.scope), nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if w.Code != tc.expected {
+			t.Errorf("scope %q — Attendu : %d, Reçu : %d", tc.scope, tc.expected, w.Code)
+		}
+	}
+}
+
+func TestGrantedScopesIntersection(t *testing.T) {
+	granted := oauth.Intersect([]string{"profile:read", "api_keys:write", "profile:read"}, []string{"profile:read"})
+	if len(granted) != 1 || granted[0] != oauth.ScopeProfileRead {
+		t.Errorf("Attendu : [profile:read], Reçu : %v", granted)
+	}
+
+	if err := oauth.ValidateScopes([]string{"admin:everything"}); err == nil {
+		t.Errorf("Un scope inconnu devrait être refusé")
+	}
+}
@@ -0,0 +1,197 @@
+package tests
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/server"
+)
+
+// startServer sert handler sur un port libre et retourne son adresse, la fonction d'arrêt et
+// le résultat de server.Serve
+func startServer(t *testing.T, cfg config.Server, handler http.Handler) (string, context.CancelFunc, <-chan error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- server.Serve(ctx, ln, cfg, handler) }()
+	t.Cleanup(cancel)
+	return ln.Addr().String(), cancel, done
+}
+
+func TestGracefulShutdownDrainsRequests(t *testing.T) {
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+	addr, shutdown, done := startServer(t, config.Default().Server, handler)
+
+	type result struct {
+		body string
+		err  error
+	}
+	response := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + addr + "/slow")
+		if err != nil {
+			response <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		response <- result{string(body), err}
+	}()
+
+	<-started
+	shutdown()
+
+	if r := <-response; r.err != nil || r.body != "done" {
+		t.Errorf("La requête en cours doit se terminer malgré l'arrêt : %q %v", r.body, r.err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Arrêt propre attendu : %v", err)
+	}
+	if _, err := http.Get("http://" + addr + "/slow"); err == nil {
+		t.Error("Le serveur arrêté ne doit plus accepter de connexion")
+	}
+}
+
+func TestShutdownDeadline(t *testing.T) {
+	cfg := config.Default().Server
+	cfg.ShutdownTimeout = 50 * time.Millisecond
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+	addr, shutdown, done := startServer(t, cfg, handler)
+
+	go http.Get("http://" + addr + "/stuck")
+	<-started
+	shutdown()
+
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Le dépassement du délai d'arrêt doit être signalé")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("L'arrêt doit respecter shutdown_timeout")
+	}
+}
+
+// writeCertificate écrit un certificat autosigné pour 127.0.0.1 avec le numéro de série donné
+func writeCertificate(t *testing.T, certFile, keyFile string, serial int64) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "127.0.0.1"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTLSCertificateHotReload(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Default().Server
+	cfg.TLSCertFile = filepath.Join(dir, "cert.pem")
+	cfg.TLSKeyFile = filepath.Join(dir, "key.pem")
+	cfg.TLSReloadInterval = 20 * time.Millisecond
+	writeCertificate(t, cfg.TLSCertFile, cfg.TLSKeyFile, 1)
+
+	addr, _, _ := startServer(t, cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	serial := func() int64 {
+		conn, err := tls.Dial("tcp", addr, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			t.Fatalf("Connexion TLS impossible : %v", err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+	}
+	if s := serial(); s != 1 {
+		t.Fatalf("Certificat initial attendu, Reçu : série %d", s)
+	}
+
+	// Date de modification explicite : certains systèmes de fichiers ont une résolution grossière
+	writeCertificate(t, cfg.TLSCertFile, cfg.TLSKeyFile, 2)
+	later := time.Now().Add(time.Second)
+	os.Chtimes(cfg.TLSCertFile, later, later)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for serial() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("Le certificat renouvelé doit être servi sans redémarrage")
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+
+	// Un fichier invalide est ignoré : l'ancien certificat reste servi
+	os.WriteFile(cfg.TLSKeyFile, []byte("not a key"), 0o600)
+	later = later.Add(time.Second)
+	os.Chtimes(cfg.TLSKeyFile, later, later)
+	time.Sleep(100 * time.Millisecond)
+	if s := serial(); s != 2 {
+		t.Errorf("Le dernier certificat valide doit être conservé, Reçu : série %d", s)
+	}
+}
+
+func TestServerConfigValidation(t *testing.T) {
+	t.Setenv("JWT_SECRET", strongSecret)
+	t.Setenv("DB_DRIVER", "sqlite")
+
+	_, err := config.Load([]string{"-tls-cert", "/nonexistent/cert.pem", "-write-timeout", "0s"})
+	if err == nil {
+		t.Fatal("Une configuration serveur invalide doit être refusée")
+	}
+	for _, expected := range []string{"server.tls_cert_file", "server.write_timeout (HTTP_WRITE_TIMEOUT): must be positive"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q manque dans :\n%v", expected, err)
+		}
+	}
+}
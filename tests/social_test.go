@@ -0,0 +1,351 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/problem"
+	"github.com/pathi14/AuthentificationGO/internal/social"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// mockOIDCProvider simule un fournisseur OpenID Connect : découverte, JWKS,
+// endpoint d'autorisation (qui accepte immédiatement) et token endpoint avec PKCE
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+
+	mu       sync.Mutex
+	subject  string
+	email    string
+	verified bool
+	pending  map[string]url.Values
+	// kid est l'identifiant de clé annoncé dans les ID tokens ; jwksFetches compte les
+	// chargements du JWKS
+	kid         string
+	jwksFetches int
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Erreur lors de la génération de la clé RSA : %v", err)
+	}
+
+	m := &mockOIDCProvider{key: key, pending: map[string]url.Values{}, kid: "test-key"}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", m.discovery)
+	mux.HandleFunc("/jwks", m.jwks)
+	mux.HandleFunc("/authorize", m.authorize)
+	mux.HandleFunc("/token", m.token)
+	m.server = httptest.NewServer(mux)
+	t.Cleanup(m.server.Close)
+	return m
+}
+
+func (m *mockOIDCProvider) setUser(subject, email string, verified bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.subject, m.email, m.verified = subject, email, verified
+}
+
+func (m *mockOIDCProvider) discovery(w http.ResponseWriter, r *http.Request) {
+	json.NewEncoder(w).Encode(map[string]string{
+		"issuer":                 m.server.URL,
+		"authorization_endpoint": m.server.URL + "/authorize",
+		"token_endpoint":         m.server.URL + "/token",
+		"jwks_uri":               m.server.URL + "/jwks",
+	})
+}
+
+func (m *mockOIDCProvider) jwks(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	m.jwksFetches++
+	m.mu.Unlock()
+
+	json.NewEncoder(w).Encode(map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"kid": "test-key",
+			"use": "sig",
+			"n":   base64.RawURLEncoding.EncodeToString(m.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(m.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (m *mockOIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	code := "code-" + q.Get("state")
+
+	m.mu.Lock()
+	m.pending[code] = q
+	m.mu.Unlock()
+
+	redirect, _ := url.Parse(q.Get("redirect_uri"))
+	redirect.RawQuery = url.Values{"code": {code}, "state": {q.Get("state")}}.Encode()
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+func (m *mockOIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	r.ParseForm()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	auth, ok := m.pending[r.PostForm.Get("code")]
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != auth.Get("code_challenge") {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+		return
+	}
+	delete(m.pending, r.PostForm.Get("code"))
+
+	idToken := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            m.server.URL,
+		"aud":            auth.Get("client_id"),
+		"sub":            m.subject,
+		"email":          m.email,
+		"email_verified": m.verified,
+		"name":           "Mock User",
+		"nonce":          auth.Get("nonce"),
+		"exp":            time.Now().Add(time.Minute).Unix(),
+		"iat":            time.Now().Unix(),
+	})
+	idToken.Header["kid"] = m.kid
+	signed, _ := idToken.SignedString(m.key)
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"access_token": "mock-access-token",
+		"token_type":   "Bearer",
+		"id_token":     signed,
+	})
+}
+
+// newSocialTestRouter monte la connexion sociale et le rattachement d'identités sur des
+// stockages en mémoire propres au test
+func newSocialTestRouter(t *testing.T, mock *mockOIDCProvider) (*gin.Engine, *user.MemoryUserStore) {
+	t.Helper()
+
+	provider, err := social.NewProvider(social.ProviderConfig{
+		Name:         "mock",
+		Type:         "oidc",
+		IssuerURL:    mock.server.URL,
+		ClientID:     "test-client",
+		ClientSecret: "test-secret",
+		RedirectURL:  "http://localhost/auth/mock/callback",
+		Scopes:       []string{"openid", "email", "profile"},
+	}, nil)
+	if err != nil {
+		t.Fatalf("Erreur de configuration du fournisseur : %v", err)
+	}
+
+	users := user.NewMemoryUserStore()
+	tokens := blacklist.NewMemoryStore()
+	userService := newMemoryUserService(users, tokens)
+	socialHandler := social.NewSocialHandler(social.NewSocialService(userService, []social.Provider{provider}, testAuth.JWTSecret))
+	userHandler := user.NewUserHandler(userService)
+
+	r := newUserTestRouter(userService, tokens, nil)
+	r.GET("/auth/:provider/login", socialHandler.Login)
+	r.GET("/auth/:provider/callback", socialHandler.Callback)
+
+	me := r.Group("/me", middleware.JWTAuth(testAuth.JWTSecret, tokens, nil))
+	me.GET("/identities", userHandler.ListIdentities)
+	me.POST("/identities/:provider/link", socialHandler.Link)
+	me.DELETE("/identities/:id", userHandler.UnlinkIdentity)
+	return r, users
+}
+
+// socialLogin joue le parcours complet du navigateur : redirection, autorisation, callback
+func socialLogin(t *testing.T, r *gin.Engine) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, _ := http.NewRequest("GET", "/auth/mock/login", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusFound {
+		t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusFound, w.Code, w.Body.String())
+	}
+
+	return completeAuthorization(t, r, w.Header().Get("Location"), w.Result().Cookies())
+}
+
+// completeAuthorization suit l'URL d'autorisation du fournisseur puis rejoue le callback avec le cookie d'état
+func completeAuthorization(t *testing.T, r *gin.Engine, authorizationURL string, cookies []*http.Cookie) *httptest.ResponseRecorder {
+	t.Helper()
+
+	client := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+	resp, err := client.Get(authorizationURL)
+	if err != nil {
+		t.Fatalf("Erreur lors de l'appel au fournisseur : %v", err)
+	}
+	resp.Body.Close()
+	callback, _ := url.Parse(resp.Header.Get("Location"))
+
+	req, _ := http.NewRequest("GET", "/auth/mock/callback?"+callback.RawQuery, nil)
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestSocialLoginCreatesAndReusesAccount(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	r, users := newSocialTestRouter(t, mock)
+
+	mock.setUser("mock-subject-1", "social@example.com", true)
+
+	for i := 0; i < 2; i++ {
+		w := socialLogin(t, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusOK, w.Code, w.Body.String())
+		}
+	}
+
+	account, _ := users.GetByEmail(context.Background(), "social@example.com")
+	if account == nil {
+		t.Fatalf("Le compte social@example.com aurait dû être créé")
+	}
+	if identities, _ := users.ListIdentities(context.Background(), account.ID); len(identities) != 1 {
+		t.Errorf("Attendu : 1 identité, Reçu : %d", len(identities))
+	}
+}
+
+func TestSocialLoginExistingEmailRequiresLink(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	r, _ := newSocialTestRouter(t, mock)
+
+	registerAndLogin(t, r, "local-owner@example.com")
+	mock.setUser("mock-subject-2", "local-owner@example.com", true)
+
+	w := socialLogin(t, r)
+	if w.Code != http.StatusConflict {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+	if body := decodeProblem(t, w); body["code"] != problem.CodeLinkRequired {
+		t.Errorf("Attendu : code %q, Reçu : %v", problem.CodeLinkRequired, body["code"])
+	}
+}
+
+func TestLinkAndUnlinkIdentity(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	r, _ := newSocialTestRouter(t, mock)
+
+	token := registerAndLogin(t, r, "linker@example.com")
+	mock.setUser("mock-subject-3", "someone-else@example.com", true)
+
+	// Démarrage du rattachement depuis le compte connecté
+	req, _ := http.NewRequest("POST", "/me/identities/mock/link", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var link struct {
+		AuthorizationURL string `json:"authorization_url"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &link)
+	w = completeAuthorization(t, r, link.AuthorizationURL, w.Result().Cookies())
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var identities struct {
+		Identities []struct {
+			ID       int    `json:"id"`
+			Provider string `json:"provider"`
+		} `json:"identities"`
+	}
+	req, _ = http.NewRequest("GET", "/me/identities", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	json.Unmarshal(w.Body.Bytes(), &identities)
+	if len(identities.Identities) != 2 {
+		t.Fatalf("Attendu : 2 identités, Reçu : %s", w.Body.String())
+	}
+
+	// La connexion via le fournisseur ouvre désormais le compte local
+	w = socialLogin(t, r)
+	if w.Code != http.StatusOK {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	unlink := func(id int) int {
+		req, _ := http.NewRequest("DELETE", fmt.Sprintf("/me/identities/%d", id), nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := unlink(identities.Identities[0].ID); code != http.StatusNoContent {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusNoContent, code)
+	}
+	if code := unlink(identities.Identities[1].ID); code != http.StatusConflict {
+		t.Errorf("La dernière méthode de connexion ne doit pas pouvoir être supprimée — Attendu : %d, Reçu : %d", http.StatusConflict, code)
+	}
+}
+
+func TestSocialLoginRejectsForgedState(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	r, _ := newSocialTestRouter(t, mock)
+
+	req, _ := http.NewRequest("GET", "/auth/mock/callback?code=forged&state=forged", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusUnauthorized, w.Code, w.Body.String())
+	}
+}
+
+func TestSocialLoginThrottlesJWKSRefresh(t *testing.T) {
+	mock := newMockOIDCProvider(t)
+	r, _ := newSocialTestRouter(t, mock)
+
+	mock.setUser("mock-subject-4", "throttled@example.com", true)
+	if w := socialLogin(t, r); w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Des jetons signés avec une clé inconnue ne rechargent pas le JWKS à chaque fois
+	mock.mu.Lock()
+	mock.kid = "forged-key"
+	mock.mu.Unlock()
+	for i := 0; i < 3; i++ {
+		if w := socialLogin(t, r); w.Code == http.StatusOK {
+			t.Errorf("Un ID token signé avec une clé inconnue doit être refusé")
+		}
+	}
+
+	mock.mu.Lock()
+	defer mock.mu.Unlock()
+	if mock.jwksFetches != 1 {
+		t.Errorf("Attendu : 1 chargement du JWKS, Reçu : %d", mock.jwksFetches)
+	}
+}
@@ -0,0 +1,171 @@
+package tests
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+)
+
+// newSQLiteDB crée une base SQLite migrée dans le répertoire temporaire du test
+func newSQLiteDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := database.ConnectSQLite(filepath.Join(t.TempDir(), "auth.db"))
+	if err != nil {
+		t.Fatalf("Erreur lors de l'ouverture de la base SQLite : %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("Erreur lors de la migration SQLite : %v", err)
+	}
+	return db
+}
+
+func TestSQLiteBackend(t *testing.T) {
+	db := newSQLiteDB(t)
+	if dialect := database.DialectOf(db); dialect != database.SQLite {
+		t.Fatalf("Attendu : dialecte %q, Reçu : %q", database.SQLite, dialect)
+	}
+	r := newAPIKeyTestRouter(db)
+
+	t.Run("api keys", func(t *testing.T) {
+		testAPIKeyLifecycle(t, r)
+	})
+
+	t.Run("duplicate email", func(t *testing.T) {
+		payload, _ := json.Marshal(map[string]string{"name": "Again", "email": "apikey@example.com", "password": "password123"})
+		req, _ := http.NewRequest("POST", "/register", bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != http.StatusConflict {
+			t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusConflict, w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("logout", func(t *testing.T) {
+		token := registerAndLogin(t, r, "sqlite-logout@example.com")
+		for _, step := range []struct {
+			method, path string
+			expected     int
+		}{
+			{"GET", "/me", http.StatusOK},
+			{"POST", "/logout", http.StatusNoContent},
+			{"GET", "/me", http.StatusUnauthorized},
+		} {
+			req, _ := http.NewRequest(step.method, step.path, nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			if w.Code != step.expected {
+				t.Fatalf("%s %s — Attendu : %d, Reçu : %d (%s)", step.method, step.path, step.expected, w.Code, w.Body.String())
+			}
+		}
+	})
+}
+
+func TestSQLiteSCIM(t *testing.T) {
+	t.Run("users", func(t *testing.T) {
+		testSCIMUserLifecycle(t, newSQLiteDB(t))
+	})
+	t.Run("groups", func(t *testing.T) {
+		testSCIMGroupsDriveRole(t, newSQLiteDB(t))
+	})
+}
+
+func TestSQLiteSAML(t *testing.T) {
+	t.Run("provisioning", func(t *testing.T) {
+		testSAMLLoginProvisionsUser(t, newSAMLFixture(t, newSQLiteDB(t)))
+	})
+	t.Run("tenant accounts", func(t *testing.T) {
+		testSAMLLoginOnlyLinksTenantAccounts(t, newSAMLFixture(t, newSQLiteDB(t)))
+	})
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	repo := user.NewUserRepository(newSQLiteDB(t))
+
+	first, err := repo.Create(context.Background(), user.User{Name: "First", Email: "first@example.com", Password: "x"}, user.Identity{Provider: user.PasswordProvider})
+	if err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	second, _ := repo.Create(context.Background(), user.User{Name: "Second", Email: "second@example.com", Password: "x"}, user.Identity{Provider: user.PasswordProvider})
+
+	users, err := repo.FindByIDs(context.Background(), []int{second, 999, first})
+	if err != nil || len(users) != 2 || users[0].ID != first || !users[0].Active || users[0].Role != user.RoleUser {
+		t.Errorf("FindByIDs — Reçu : %+v, %v", users, err)
+	}
+
+	if err := repo.CreateIdentity(context.Background(), user.Identity{UserID: first, Provider: "github", Subject: "7"}); err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	identities, _ := repo.ListIdentities(context.Background(), first)
+	if len(identities) != 2 {
+		t.Fatalf("Attendu : 2 identités, Reçu : %d", len(identities))
+	}
+	if err := repo.DeleteIdentity(context.Background(), first, identities[0].ID, "unusable"); err != nil {
+		t.Errorf("Erreur inattendue : %v", err)
+	}
+	if err := repo.DeleteIdentity(context.Background(), first, identities[1].ID, "unusable"); err == nil {
+		t.Errorf("La dernière méthode de connexion ne doit pas pouvoir être supprimée")
+	}
+
+	if err := repo.Delete(context.Background(), first); err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	if identity, _ := repo.FindIdentity(context.Background(), "github", "7"); identity != nil {
+		t.Errorf("Les identités auraient dû être supprimées en cascade")
+	}
+}
+
+func TestSQLiteBlacklistStore(t *testing.T) {
+	store := blacklist.NewBlacklistStore(newSQLiteDB(t))
+	ctx := context.Background()
+
+	if first, err := store.Claim(ctx, "token", time.Now().Add(time.Hour)); err != nil || !first {
+		t.Fatalf("Le premier Claim devrait réussir : %v", err)
+	}
+	if again, _ := store.Claim(ctx, "token", time.Now().Add(time.Hour)); again {
+		t.Errorf("Un jeton déjà réclamé ne doit pas pouvoir l'être de nouveau")
+	}
+	if revoked, _ := store.Contains(ctx, "token"); !revoked {
+		t.Errorf("Le jeton devrait être révoqué")
+	}
+
+	store.Add(ctx, "expired", time.Now().Add(-time.Minute))
+	if revoked, _ := store.Contains(ctx, "expired"); revoked {
+		t.Errorf("Un jeton expiré ne doit plus être signalé comme révoqué")
+	}
+	if purged, err := store.PurgeExpired(ctx); err != nil || purged != 1 {
+		t.Errorf("Attendu : 1 jeton purgé, Reçu : %d (%v)", purged, err)
+	}
+}
+
+func TestSQLiteMigrationsRollBack(t *testing.T) {
+	db := newSQLiteDB(t)
+	migrator, err := database.NewMigrator(db)
+	if err != nil {
+		t.Fatalf("Erreur inattendue : %v", err)
+	}
+	ctx := context.Background()
+
+	statuses, _ := migrator.Status(ctx)
+	reverted, err := migrator.Down(ctx, len(statuses))
+	if err != nil || len(reverted) != len(statuses) {
+		t.Fatalf("Down — Attendu : %d migrations annulées, Reçu : %d (%v)", len(statuses), len(reverted), err)
+	}
+	if applied, err := migrator.Up(ctx); err != nil || len(applied) != len(statuses) {
+		t.Fatalf("Up — Attendu : %d migrations, Reçu : %d (%v)", len(statuses), len(applied), err)
+	}
+}
@@ -1,16 +1,48 @@
 package tests
 
 import (
+	"database/sql"
+	"io"
 	"os"
+	"sync"
+	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/config"
 	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/mail"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
 	"github.com/pathi14/AuthentificationGO/internal/user"
 )
 
+// testRouter expose les routes de compte sur des stockages en mémoire : les tests qui
+// l'utilisent n'ont pas besoin de base de données
 var testRouter *gin.Engine
 
-//var db *database.DB
+// testUsers est le stockage derrière testRouter, pour préparer ou vérifier des comptes
+var testUsers *user.MemoryUserStore
+
+// testAuth est la configuration des jetons partagée par les services et middlewares de test
+var testAuth = func() config.Auth {
+	auth := config.Default().Auth
+	auth.JWTSecret = "test_jwt_secret_with_at_least_32_bytes"
+	auth.ResetURL = "https://auth.example.com/reset-password"
+	auth.LoginReportURL = "https://auth.example.com/logins/report"
+	return auth
+}()
+
+// testMailer ne fait qu'écrire les emails, sans les afficher
+var testMailer = mail.NewLogMailer(io.Discard)
+
+var (
+	testDBOnce sync.Once
+	testDBConn *sql.DB
+	testDBErr  error
+)
 
 func init() {
 	os.Setenv("TEST_DB_HOST", "localhost")
@@ -19,23 +51,49 @@ func init() {
 	os.Setenv("TEST_DB_PASSWORD", "test_secret")
 	os.Setenv("TEST_DB_NAME", "authentificationgo_test")
 
-	db, err := database.ConnectTestDB()
-	if err != nil {
-		panic("Erreur lors de la connexion à la DB de test : " + err.Error())
-	}
+	testUsers = user.NewMemoryUserStore()
+	tokens := blacklist.NewMemoryStore()
+	testRouter = newUserTestRouter(newMemoryUserService(testUsers, tokens), tokens, nil)
+}
 
-	userRepo := user.NewUserRepository(db)
-	userService := user.NewUserService(userRepo)
+// newMemoryUserService construit un UserService sans base de données. Le service OAuth n'a
+// pas de dépôt : seules les connexions de première partie (sans client_id) sont possibles.
+func newMemoryUserService(users user.UserStore, tokens blacklist.TokenStore) *user.UserService {
+	return user.NewUserService(users, tokens, oauth.NewOAuthService(nil), testAuth, testMailer, audit.NewAuditService(audit.NewMemoryStore()))
+}
+
+// newUserTestRouter monte l'inscription, la connexion et les routes protégées du compte.
+// keys peut être nil lorsque les clés d'API ne sont pas testées.
+func newUserTestRouter(userService *user.UserService, tokens blacklist.TokenStore, keys middleware.APIKeyAuthenticator) *gin.Engine {
 	userHandler := user.NewUserHandler(userService)
 
-	testRouter = gin.Default()
-	testRouter.POST("/login", userHandler.Login)
-	testRouter.POST("/register", userHandler.Register)
+	r := gin.Default()
+	r.Use(middleware.RequestID(logging.Discard()))
+	r.POST("/login", userHandler.Login)
+	r.POST("/register", userHandler.Register)
+	r.POST("/forgot-password", userHandler.ForgotPassword)
+	r.POST("/reset-password", userHandler.ResetPassword)
+	r.POST("/logins/report", userHandler.ReportLogin)
+
+	protected := r.Group("/", middleware.JWTAuth(testAuth.JWTSecret, tokens, keys))
+	protected.POST("/logout", userHandler.Logout)
+	protected.POST("/refresh", userHandler.RefreshToken)
+	protected.GET("/me", middleware.RequireScope(oauth.ScopeProfileRead), userHandler.Profile)
+	protected.GET("/me/logins", middleware.RequireScope(oauth.ScopeProfileRead), userHandler.Logins)
+	protected.PUT("/me/locale", middleware.RequireScope(oauth.ScopeProfileWrite), userHandler.UpdateLocale)
+	return r
 }
 
-// func TestMain(m *testing.M) {
-// 	code := m.Run()
-// 	db.Exec("DELETE FROM users")
-// 	db.Close()
-// 	os.Exit(code)
-// }
+// testDB retourne la base Postgres de test, migrée, partagée par tous les tests. Les tests
+// qui en dépendent sont ignorés lorsqu'elle n'est pas joignable.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	testDBOnce.Do(func() {
+		testDBConn, testDBErr = database.ConnectTestDB()
+	})
+	if testDBErr != nil {
+		t.Skipf("DB de test indisponible : %v", testDBErr)
+	}
+	return testDBConn
+}
@@ -0,0 +1,136 @@
+package tests
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/logging"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/tracing"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recordSpans installe un fournisseur qui conserve les spans en mémoire, le temps du test
+func recordSpans(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+
+	if _, err := tracing.Setup(context.Background(), config.Default().Tracing); err != nil {
+		t.Fatal(err)
+	}
+	exporter := tracetest.NewInMemoryExporter()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return exporter
+}
+
+func TestLoginTrace(t *testing.T) {
+	exporter := recordSpans(t)
+
+	repo := user.NewUserRepository(newSQLiteDB(t))
+	service := user.NewUserService(repo, blacklist.NewMemoryStore(), oauth.NewOAuthService(nil), testAuth, testMailer, audit.NewAuditService(audit.NewMemoryStore()), user.NewLocalAuthenticator(repo))
+	if err := service.Create(context.Background(), user.User{Name: "Traced", Email: "traced@example.com", Password: "password123"}); err != nil {
+		t.Fatal(err)
+	}
+	exporter.Reset()
+
+	r := gin.New()
+	r.Use(middleware.RequestID(logging.Discard()), middleware.Tracing())
+	r.POST("/login", user.NewUserHandler(service).Login)
+
+	w := postJSON(r, "/login", map[string]string{"email": "traced@example.com", "password": "password123"},
+		map[string]string{"traceparent": "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	spans := map[string]tracetest.SpanStub{}
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+		if got := span.SpanContext.TraceID().String(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+			t.Errorf("%s doit appartenir à la trace de l'appelant, Reçu : %s", span.Name, got)
+		}
+	}
+
+	server, ok := spans["POST /login"]
+	if !ok || server.SpanKind != trace.SpanKindServer || server.Parent.SpanID().String() != "00f067aa0ba902b7" {
+		t.Fatalf("Span serveur attendu, enfant du span de l'appelant : %+v", server)
+	}
+	// Chaque étape de la connexion apparaît sous le span parent attendu
+	parents := map[string]string{
+		"UserService.Login":       "POST /login",
+		"UserRepository.Login":    "UserService.Login",
+		"bcrypt.Compare":          "UserRepository.Login",
+		"UserRepository.FindByID": "UserService.Login",
+	}
+	for name, parent := range parents {
+		span, ok := spans[name]
+		if !ok {
+			t.Errorf("Span %s manquant", name)
+			continue
+		}
+		if span.Parent.SpanID() != spans[parent].SpanContext.SpanID() {
+			t.Errorf("%s doit être enfant de %s", name, parent)
+		}
+	}
+	var system string
+	for _, attr := range spans["UserRepository.Login"].Attributes {
+		if attr.Key == "db.system" {
+			system = attr.Value.AsString()
+		}
+	}
+	if system != "sqlite" {
+		t.Errorf("Le moteur SQL doit être indiqué, Reçu : %q", system)
+	}
+}
+
+func TestStdoutTraceExporter(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	cfg := config.Default().Tracing
+	cfg.Exporter = "stdout"
+	cfg.File = filepath.Join(t.TempDir(), "traces.json")
+	shutdown, err := tracing.Setup(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, span := tracing.Start(context.Background(), "local.check")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, _ := os.ReadFile(cfg.File)
+	if !strings.Contains(string(raw), `"Name":"local.check"`) || !strings.Contains(string(raw), "authentificationgo") {
+		t.Errorf("Le span doit être écrit dans le fichier avec le nom du service :\n%s", raw)
+	}
+}
+
+func TestTracingConfigValidation(t *testing.T) {
+	t.Setenv("JWT_SECRET", strongSecret)
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+	t.Setenv("TRACING_SAMPLE_RATIO", "1.5")
+
+	_, err := config.Load(nil)
+	for _, expected := range []string{"tracing.exporter", "tracing.sample_ratio"} {
+		if err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%q manque dans : %v", expected, err)
+		}
+	}
+}
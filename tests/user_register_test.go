@@ -2,12 +2,13 @@ package tests
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
-	"github.com/pathi14/AuthentificationGO/internal/infrastructure/database"
+	"github.com/pathi14/AuthentificationGO/internal/user"
 )
 
 func TestRegisterSucess(t *testing.T) {
@@ -49,25 +50,15 @@ func TestRegisterInvalidInput(t *testing.T) {
 }
 
 func TestRegisterDuplicateEmail(t *testing.T) {
-	db, err := database.ConnectTestDB()
+	_, err := testUsers.Create(context.Background(), user.User{Name: "ExistingUser", Email: "existing@example.com", Password: "password123"},
+		user.Identity{Provider: user.PasswordProvider})
 	if err != nil {
-		t.Fatalf("Erreur lors de la connexion à la DB de test : %v", err)
-	}
-	defer db.Close()
-
-	_, err = db.Exec("DELETE FROM users WHERE email = 'test@example.com'")
-	if err != nil {
-		t.Fatalf("Erreur lors de la suppression d'un utilisateur existant : %v", err)
-	}
-
-	_, err = db.Exec("INSERT INTO users (name, email, password) VALUES ('ExistingUser', 'test@example.com', 'password123')")
-	if err != nil {
-		t.Fatalf("Erreur lors de l'insertion d'un utilisateur dans la DB de test : %v", err)
+		t.Fatalf("Erreur lors de l'insertion d'un utilisateur existant : %v", err)
 	}
 
 	payload := map[string]string{
 		"name":     "DuplicateUser",
-		"email":    "test@example.com",
+		"email":    "existing@example.com",
 		"password": "password123",
 	}
 
@@ -81,9 +72,4 @@ func TestRegisterDuplicateEmail(t *testing.T) {
 	if w.Code != http.StatusConflict {
 		t.Errorf("Attendu : %d, Reçu : %d, Détails : %s", http.StatusConflict, w.Code, w.Body.String())
 	}
-
-	_, err = db.Exec("DELETE FROM users WHERE email = 'test@example.com'")
-	if err != nil {
-		t.Errorf("Erreur lors du nettoyage des utilisateurs de test : %v", err)
-	}
 }
@@ -0,0 +1,311 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pathi14/AuthentificationGO/internal/audit"
+	"github.com/pathi14/AuthentificationGO/internal/blacklist"
+	"github.com/pathi14/AuthentificationGO/internal/config"
+	"github.com/pathi14/AuthentificationGO/internal/middleware"
+	"github.com/pathi14/AuthentificationGO/internal/oauth"
+	"github.com/pathi14/AuthentificationGO/internal/user"
+	"github.com/pathi14/AuthentificationGO/internal/webhook"
+)
+
+// newWebhookTestRouter ajoute au routeur de compte les routes d'administration des webhooks,
+// le tout stocké dans db
+func newWebhookTestRouter(db *sql.DB) (*gin.Engine, *user.UserService) {
+	tokens := blacklist.NewBlacklistStore(db)
+	userService := user.NewUserService(user.NewUserRepository(db), tokens, oauth.NewOAuthService(nil), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	handler := webhook.NewWebhookHandler(webhook.NewWebhookService(webhook.NewWebhookRepository(db)))
+
+	r := newUserTestRouter(userService, tokens, nil)
+	webhooks := r.Group("/webhooks", middleware.JWTAuth(testAuth.JWTSecret, tokens, nil), middleware.RequireScope(oauth.ScopeAdmin))
+	webhooks.POST("", handler.Create)
+	webhooks.GET("", handler.List)
+	webhooks.DELETE("/:id", handler.Delete)
+	webhooks.GET("/:id/deliveries", handler.Deliveries)
+	webhooks.GET("/deliveries/:id", handler.Delivery)
+	webhooks.POST("/deliveries/:id/redeliver", handler.Redeliver)
+	return r, userService
+}
+
+// webhookAdmin retourne le jeton d'un administrateur
+func webhookAdmin(t *testing.T, r *gin.Engine, userService *user.UserService) string {
+	t.Helper()
+
+	registerAndLogin(t, r, "webhook-admin@example.com")
+	admin, _ := userService.FindByEmail(context.Background(), "webhook-admin@example.com")
+	if err := userService.SetRole(context.Background(), admin.ID, user.RoleAdmin); err != nil {
+		t.Fatal(err)
+	}
+	return registerAndLogin(t, r, "webhook-admin@example.com")
+}
+
+func webhookRequest(r *gin.Engine, method, path, token string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func outboxTypes(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+
+	rows, err := db.Query("SELECT type FROM outbox_events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	types := []string{}
+	for rows.Next() {
+		var eventType string
+		rows.Scan(&eventType)
+		types = append(types, eventType)
+	}
+	return types
+}
+
+func TestWebhookOutbox(t *testing.T) {
+	db := newSQLiteDB(t)
+	repo := user.NewUserRepository(db)
+	userService := user.NewUserService(repo, blacklist.NewBlacklistStore(db), oauth.NewOAuthService(nil), testAuth, testMailer, audit.NewAuditService(audit.NewAuditStore(db)))
+	ctx := context.Background()
+
+	if err := userService.Create(ctx, user.User{Name: "Outbox", Email: "outbox@example.com", Password: "password123"}); err != nil {
+		t.Fatal(err)
+	}
+	userService.Create(ctx, user.User{Name: "Other", Email: "other@example.com", Password: "password123"})
+	u, _ := userService.FindByEmail(ctx, "outbox@example.com")
+
+	// Une modification refusée ne publie rien, même si elle désactivait le compte
+	if err := repo.Update(ctx, user.User{ID: u.ID, Name: "Outbox", Email: "other@example.com", Active: false}); err == nil {
+		t.Fatal("Attendu : conflit sur l'email")
+	}
+	if err := userService.SetActive(ctx, u.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	// Une désactivation répétée n'est publiée qu'une fois
+	if err := userService.SetActive(ctx, u.ID, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := userService.SetPassword(ctx, "outbox@example.com", "new-password123"); err != nil {
+		t.Fatal(err)
+	}
+	// Retirer la méthode mot de passe rend le mot de passe inutilisable : c'est un changement publié
+	if err := userService.LinkIdentity(ctx, u.ID, "github", "outbox", "outbox@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	identities, _ := userService.ListIdentities(ctx, u.ID)
+	for _, identity := range identities {
+		if identity.Provider == user.PasswordProvider {
+			if err := userService.UnlinkIdentity(ctx, u.ID, identity.ID); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := userService.DeleteUser(ctx, u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		webhook.EventUserRegistered,
+		webhook.EventUserRegistered,
+		webhook.EventUserDisabled,
+		webhook.EventUserPasswordChanged,
+		webhook.EventUserPasswordChanged,
+		webhook.EventUserDeleted,
+	}
+	if got := outboxTypes(t, db); strings.Join(got, " ") != strings.Join(expected, " ") {
+		t.Errorf("Attendu : %v, Reçu : %v", expected, got)
+	}
+
+	var payload string
+	db.QueryRow("SELECT payload FROM outbox_events WHERE type = ?", webhook.EventUserDeleted).Scan(&payload)
+	var event struct {
+		ID   string
+		Type string
+		Data webhook.UserData
+	}
+	if err := json.Unmarshal([]byte(payload), &event); err != nil || event.ID == "" || event.Data.UserID != u.ID || event.Data.Email != "outbox@example.com" {
+		t.Errorf("Événement mal formé : %s (%v)", payload, err)
+	}
+}
+
+// webhookReceiver enregistre les requêtes reçues et répond status
+type webhookReceiver struct {
+	mu       sync.Mutex
+	status   int
+	requests []*http.Request
+	bodies   [][]byte
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.requests = append(rcv.requests, r)
+	rcv.bodies = append(rcv.bodies, body)
+	w.WriteHeader(rcv.status)
+}
+
+func (rcv *webhookReceiver) setStatus(status int) {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.status = status
+}
+
+func (rcv *webhookReceiver) count() int {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return len(rcv.requests)
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	db := newSQLiteDB(t)
+	r, userService := newWebhookTestRouter(db)
+	admin := webhookAdmin(t, r, userService)
+
+	receiver := &webhookReceiver{status: http.StatusNoContent}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	if w := postJSON(r, "/webhooks", map[string]any{"url": "ftp://example.com"}, map[string]string{"Authorization": "Bearer " + admin}); w.Code != http.StatusBadRequest {
+		t.Errorf("URL non HTTP — Attendu : %d, Reçu : %d", http.StatusBadRequest, w.Code)
+	}
+	if w := postJSON(r, "/webhooks", map[string]any{"url": server.URL, "events": []string{"user.unknown"}}, map[string]string{"Authorization": "Bearer " + admin}); w.Code != http.StatusBadRequest {
+		t.Errorf("Type inconnu — Attendu : %d, Reçu : %d", http.StatusBadRequest, w.Code)
+	}
+
+	w := postJSON(r, "/webhooks", map[string]any{"url": server.URL, "events": []string{webhook.EventUserRegistered}}, map[string]string{"Authorization": "Bearer " + admin})
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Attendu : %d, Reçu : %d (%s)", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var created struct{ Endpoint webhook.Endpoint }
+	json.Unmarshal(w.Body.Bytes(), &created)
+	if created.Endpoint.Secret == "" {
+		t.Fatal("Le secret de signature doit être retourné à la création")
+	}
+
+	var list struct{ Endpoints []webhook.Endpoint }
+	json.Unmarshal(webhookRequest(r, "GET", "/webhooks", admin).Body.Bytes(), &list)
+	if len(list.Endpoints) != 1 || list.Endpoints[0].Secret != "" {
+		t.Errorf("Le secret ne doit plus être affiché : %+v", list.Endpoints)
+	}
+
+	// L'inscription de l'administrateur, pas encore répartie, est livrée au nouvel endpoint
+	dispatcher := webhook.NewDispatcher(webhook.NewWebhookRepository(db), config.Default().Webhook)
+	if err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	receiver.mu.Lock()
+	receiver.requests, receiver.bodies = nil, nil
+	receiver.mu.Unlock()
+
+	registerAndLogin(t, r, "webhook-user@example.com")
+	u, _ := userService.FindByEmail(context.Background(), "webhook-user@example.com")
+	userService.SetPassword(context.Background(), "webhook-user@example.com", "another-password")
+	if err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if receiver.count() != 1 {
+		t.Fatalf("Seule l'inscription doit être livrée, Reçu : %d requêtes", receiver.count())
+	}
+	req, body := receiver.requests[0], receiver.bodies[0]
+	signature := req.Header.Get(webhook.HeaderSignature)
+	timestamp, _ := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+	if expected := webhook.Sign(created.Endpoint.Secret, time.Unix(timestamp, 0), body); signature != expected {
+		t.Errorf("Signature — Attendu : %s, Reçu : %s", expected, signature)
+	}
+	var event webhook.Event
+	json.Unmarshal(body, &event)
+	var data webhook.UserData
+	json.Unmarshal(event.Data, &data)
+	if event.Type != webhook.EventUserRegistered || req.Header.Get(webhook.HeaderEventID) != event.ID || data.UserID != u.ID {
+		t.Errorf("Livraison inattendue : %s %v", body, req.Header)
+	}
+
+	var deliveries struct{ Deliveries []webhook.Delivery }
+	json.Unmarshal(webhookRequest(r, "GET", "/webhooks/"+strconv.Itoa(created.Endpoint.ID)+"/deliveries", admin).Body.Bytes(), &deliveries)
+	if len(deliveries.Deliveries) != 2 || deliveries.Deliveries[0].Status != webhook.StatusDelivered || deliveries.Deliveries[0].Attempts != 1 {
+		t.Errorf("Journal des livraisons inattendu : %+v", deliveries.Deliveries)
+	}
+}
+
+func TestWebhookRetryAndDeadLetter(t *testing.T) {
+	db := newSQLiteDB(t)
+	r, userService := newWebhookTestRouter(db)
+	admin := webhookAdmin(t, r, userService)
+
+	receiver := &webhookReceiver{status: http.StatusServiceUnavailable}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	w := postJSON(r, "/webhooks", map[string]any{"url": server.URL, "events": []string{webhook.EventUserDeleted}}, map[string]string{"Authorization": "Bearer " + admin})
+	var created struct{ Endpoint webhook.Endpoint }
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	registerAndLogin(t, r, "doomed@example.com")
+	u, _ := userService.FindByEmail(context.Background(), "doomed@example.com")
+	if err := userService.DeleteUser(context.Background(), u.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := config.Default().Webhook
+	cfg.MaxAttempts, cfg.RetryBase = 3, time.Millisecond
+	dispatcher := webhook.NewDispatcher(webhook.NewWebhookRepository(db), cfg)
+	for i := 0; i < 20 && receiver.count() < 3; i++ {
+		if err := dispatcher.RunOnce(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	dispatcher.RunOnce(context.Background())
+	if receiver.count() != 3 {
+		t.Fatalf("Attendu : 3 tentatives, Reçu : %d", receiver.count())
+	}
+
+	path := "/webhooks/" + strconv.Itoa(created.Endpoint.ID) + "/deliveries?status=dead"
+	var deliveries struct{ Deliveries []webhook.Delivery }
+	json.Unmarshal(webhookRequest(r, "GET", path, admin).Body.Bytes(), &deliveries)
+	if len(deliveries.Deliveries) != 1 || deliveries.Deliveries[0].Attempts != 3 || deliveries.Deliveries[0].NextAttemptAt != nil {
+		t.Fatalf("La livraison doit être en lettre morte après 3 tentatives : %+v", deliveries.Deliveries)
+	}
+	id := strconv.FormatInt(deliveries.Deliveries[0].ID, 10)
+
+	var delivery webhook.Delivery
+	json.Unmarshal(webhookRequest(r, "GET", "/webhooks/deliveries/"+id, admin).Body.Bytes(), &delivery)
+	if len(delivery.Log) != 3 || delivery.Log[2].StatusCode != http.StatusServiceUnavailable || delivery.LastError != "unexpected status 503" {
+		t.Errorf("Historique des tentatives inattendu : %+v", delivery)
+	}
+
+	// L'endpoint réparé, la livraison est relancée
+	receiver.setStatus(http.StatusOK)
+	if w := webhookRequest(r, "POST", "/webhooks/deliveries/"+id+"/redeliver", admin); w.Code != http.StatusAccepted {
+		t.Fatalf("Attendu : %d, Reçu : %d", http.StatusAccepted, w.Code)
+	}
+	if err := dispatcher.RunOnce(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	json.Unmarshal(webhookRequest(r, "GET", "/webhooks/deliveries/"+id, admin).Body.Bytes(), &delivery)
+	if delivery.Status != webhook.StatusDelivered || len(delivery.Log) != 4 {
+		t.Errorf("Attendu : livrée à la 4e tentative, Reçu : %+v", delivery)
+	}
+
+	if w := webhookRequest(r, "GET", "/webhooks/deliveries/999", admin); w.Code != http.StatusNotFound {
+		t.Errorf("Attendu : %d, Reçu : %d", http.StatusNotFound, w.Code)
+	}
+}